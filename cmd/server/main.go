@@ -1,22 +1,199 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"todo/internal/logging"
+	"todo/internal/ratelimit"
 	"todo/internal/server"
 	"todo/internal/storage"
+	"todo/internal/storage/sqlstore"
+	"todo/internal/tracing"
+	"todo/internal/validation"
 )
 
 func main() {
-	st := storage.NewStorage()
-	logger := log.Default()
-	srv := server.NewServer(st, logger)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			if !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, "migrate:", err)
+			}
+			os.Exit(2)
+		}
+		return
+	}
+
+	cfg := server.DefaultConfig()
+	flag.StringVar(&cfg.Addr, "addr", cfg.Addr, "address to listen on")
+	flag.StringVar(&cfg.GRPCAddr, "grpc-addr", "", "address to serve the gRPC API on; cleartext HTTP/2 without -tls-cert")
+	flag.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "maximum duration for reading the entire request")
+	flag.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "maximum duration before timing out writes of the response")
+	flag.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "maximum time to wait for the next request on keep-alive connections")
+	flag.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "maximum time to drain in-flight requests on shutdown")
+	flag.DurationVar(&cfg.ShutdownDelay, "shutdown-delay", cfg.ShutdownDelay, "time to keep serving with failing readiness before draining")
+	logLevel := flag.String("log-level", "info", "log level: debug, info, warn or error")
+	logFormat := flag.String("log-format", logging.FormatText, "log format: text or json")
+	traceFile := flag.String("trace-file", "", "append OTLP/JSON spans to this file")
+	traceEndpoint := flag.String("trace-endpoint", "", "OTLP/HTTP JSON endpoint, e.g. http://localhost:4318/v1/traces")
+	serviceName := flag.String("service-name", "todo", "service name reported in traces")
+	dbDriver := flag.String("db-driver", "sqlite", "database/sql driver used with -db")
+	dsn := flag.String("db", "", "keep tasks in this database, e.g. tasks.db, instead of memory; the schema is migrated on start")
+	maxTasksPerOwner := flag.Int("max-tasks-per-owner", 0, "maximum number of tasks per owner, 0 means unlimited")
+	trustProxy := flag.Bool("trust-proxy-headers", false, "take the client IP from X-Forwarded-For")
+	proxyHops := flag.Int("trusted-proxy-hops", 1, "number of proxies in front of the server that append to X-Forwarded-For, used with -trust-proxy-headers")
+	rateLimits := make(map[string]ratelimit.Rule)
+	flag.Func("rate-limit", `per-client rate limit "ROUTE=LIMIT/WINDOW[:BURST]", e.g. "POST /todos=10/1m" or "*=100/1s:200"; repeatable`,
+		func(value string) error {
+			route, spec, ok := strings.Cut(value, "=")
+			if !ok {
+				return fmt.Errorf("expected ROUTE=LIMIT/WINDOW, got %q", value)
+			}
+			rule, err := ratelimit.ParseRule(spec)
+			if err != nil {
+				return err
+			}
+			rateLimits[strings.TrimSpace(route)] = rule
+			return nil
+		})
+	limits := validation.DefaultLimits()
+	flag.Int64Var(&limits.MaxBodyBytes, "max-body-bytes", limits.MaxBodyBytes, "maximum request body size in bytes")
+	flag.IntVar(&limits.MaxHeaderLength, "max-header-length", limits.MaxHeaderLength, "maximum task header length in characters")
+	flag.IntVar(&limits.MaxDescriptionLength, "max-description-length", limits.MaxDescriptionLength, "maximum task description length in characters")
+	cors := server.DefaultCORSConfig()
+	corsOrigins := flag.String("cors-origins", "", `comma-separated origins allowed to call the API, "*" or "https://*.example.com"; empty disables CORS`)
+	corsMethods := flag.String("cors-methods", strings.Join(cors.AllowedMethods, ","), "comma-separated methods allowed in CORS requests")
+	corsHeaders := flag.String("cors-headers", strings.Join(cors.AllowedHeaders, ","), "comma-separated request headers allowed in CORS requests")
+	flag.BoolVar(&cors.AllowCredentials, "cors-credentials", cors.AllowCredentials, "allow CORS requests with credentials")
+	flag.DurationVar(&cors.MaxAge, "cors-max-age", cors.MaxAge, "how long browsers may cache preflight responses")
+	tlsCfg := &server.TLSConfig{ClientAuth: server.ClientAuthNone}
+	flag.StringVar(&tlsCfg.CertFile, "tls-cert", "", "TLS certificate file; enables HTTPS and HTTP/2")
+	flag.StringVar(&tlsCfg.KeyFile, "tls-key", "", "TLS private key file")
+	flag.StringVar(&tlsCfg.ClientCAFile, "tls-client-ca", "", "CA bundle used to verify client certificates")
+	flag.StringVar(&tlsCfg.ClientAuth, "tls-client-auth", tlsCfg.ClientAuth, "client certificate mode: none, optional or require")
+	flag.DurationVar(&tlsCfg.ReloadInterval, "tls-reload-interval", 10*time.Second, "how often to check certificate files for changes")
+	certIdentitiesFile := flag.String("cert-identities", "", `JSON file mapping client certificate subjects to identities, e.g. {"CN=alice,O=Example": "alice"}`)
+	enforceOwnership := flag.Bool("enforce-ownership", false, "only allow the task owner to update or delete it")
+	ownerKeyFile := flag.String("owner-key-file", "", "file with the secret that keys anonymous owners; a random key is used when empty")
+	flag.Parse()
 
-	http.HandleFunc("/todos", srv.LoggingMiddleware(srv.HandleTodos))
-	http.HandleFunc("/todos/", srv.LoggingMiddleware(srv.HandleTodoByID))
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger, err := logging.New(os.Stderr, *logFormat, level)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	logger.Println("Server starting on :8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		logger.Fatal(err)
+	var exporter tracing.Exporter
+	switch {
+	case *traceFile != "":
+		exporter, err = tracing.NewFileExporter(*traceFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case *traceEndpoint != "":
+		exporter = &tracing.HTTPExporter{URL: *traceEndpoint}
+	}
+	tracer := tracing.NewTracer(tracing.TracerOptions{
+		ServiceName: *serviceName,
+		Exporter:    exporter,
+		OnError: func(err error) {
+			logger.Warn("trace export failed", "error", err)
+		},
+	})
+
+	var st server.TaskStore = storage.NewStorage(storage.WithMaxTasksPerOwner(*maxTasksPerOwner))
+	if *dsn != "" {
+		if err := checkDriver(*dbDriver); err != nil {
+			log.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		st, err = sqlstore.Open(ctx, *dbDriver, *dsn, sqlstore.WithMaxTasksPerOwner(*maxTasksPerOwner))
+		cancel()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	opts := []server.Option{server.WithTracer(tracer), server.WithLimits(limits)}
+	if len(rateLimits) > 0 {
+		opts = append(opts, server.WithRateLimits(rateLimits))
+	}
+	if *corsOrigins != "" {
+		cors.AllowedOrigins = splitList(*corsOrigins)
+		cors.AllowedMethods = splitList(*corsMethods)
+		cors.AllowedHeaders = splitList(*corsHeaders)
+		opts = append(opts, server.WithCORS(cors))
+	}
+	if *certIdentitiesFile != "" {
+		identities, err := loadCertIdentities(*certIdentitiesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, server.WithClientCertIdentities(identities))
+	}
+	if *enforceOwnership {
+		opts = append(opts, server.WithOwnershipEnforcement())
+	}
+	if *ownerKeyFile != "" {
+		key, err := os.ReadFile(*ownerKeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if key = bytes.TrimSpace(key); len(key) == 0 {
+			log.Fatalf("%s is empty", *ownerKeyFile)
+		}
+		opts = append(opts, server.WithOwnerKey(key))
+	}
+	if *trustProxy {
+		opts = append(opts, server.WithTrustedProxyHeaders(*proxyHops))
+	}
+	srv := server.NewServer(st, logger, opts...)
+	if exporter != nil {
+		srv.AddWorker("trace-exporter", tracer)
+	}
+
+	if tlsCfg.CertFile != "" || tlsCfg.KeyFile != "" {
+		cfg.TLS = tlsCfg
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := srv.Run(ctx, cfg); err != nil {
+		logger.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func loadCertIdentities(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var identities map[string]string
+	if err := json.Unmarshal(data, &identities); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
 	}
+	return identities, nil
 }
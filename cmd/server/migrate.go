@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"todo/internal/storage"
+	"todo/internal/storage/sqlstore"
+	"todo/internal/taskio"
+	"todo/internal/tracing"
+)
+
+// checkDriver reports a -db-driver that no imported package has registered.
+func checkDriver(driver string) error {
+	if !slices.Contains(sql.Drivers(), driver) {
+		return fmt.Errorf("database driver %q is not registered; available: %s", driver, strings.Join(sql.Drivers(), ", "))
+	}
+	return nil
+}
+
+// runMigrate implements "server migrate": it brings the database schema up to date and
+// optionally copies tasks into the database from an export file or a running server.
+func runMigrate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	driver := fs.String("db-driver", "sqlite", "database/sql driver name")
+	dsn := fs.String("db", "", "database to migrate, e.g. tasks.db")
+	from := fs.String("from", "", "import tasks from a .csv, .txt, .md or .ics export, or from the /todos/export of a server at this http(s) URL")
+	token := fs.String("token", "", "bearer token sent to the server given by -from")
+	traceEndpoint := fs.String("trace-endpoint", "", "OTLP/HTTP JSON endpoint for the spans of the migration")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dsn == "" {
+		return errors.New("-db is required")
+	}
+	if err := checkDriver(*driver); err != nil {
+		return err
+	}
+
+	var exporter tracing.Exporter
+	if *traceEndpoint != "" {
+		exporter = &tracing.HTTPExporter{URL: *traceEndpoint}
+	}
+	tracer := tracing.NewTracer(tracing.TracerOptions{ServiceName: "todo-migrate", Exporter: exporter})
+	tracerCtx, stopTracer := context.WithCancel(context.Background())
+	tracerDone := make(chan struct{})
+	go func() {
+		defer close(tracerDone)
+		tracer.Run(tracerCtx)
+	}()
+	defer func() {
+		stopTracer()
+		<-tracerDone
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "migrate", tracing.KindInternal)
+	defer span.End()
+
+	var tasks []storage.Task
+	if *from != "" {
+		var err error
+		client := &http.Client{Transport: &tracing.Transport{Tracer: tracer}}
+		if tasks, err = readSnapshot(ctx, client, *from, *token); err != nil {
+			return fmt.Errorf("read %s: %w", *from, err)
+		}
+	}
+
+	db, err := sql.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	applied, err := sqlstore.Migrate(ctx, db)
+	db.Close()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "schema is at version %d, applied %d migrations\n", sqlstore.LatestVersion(), len(applied))
+	if *from == "" {
+		return nil
+	}
+
+	st, err := sqlstore.Open(ctx, *driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	if err := st.Import(ctx, tasks); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "imported %d tasks\n", len(tasks))
+	return nil
+}
+
+// readSnapshot reads every task of a file or of a running server's CSV export. Tasks keep
+// their IDs; the ones exported without an ID get new ones.
+func readSnapshot(ctx context.Context, client *http.Client, from, token string) ([]storage.Task, error) {
+	var records []taskio.Record
+	if u, err := url.Parse(from); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		body, err := fetchExport(ctx, client, u, token)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		records, _, err = taskio.CSVReader{KeepOwner: true}.Read(body)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		f, err := os.Open(from)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		switch ext := strings.ToLower(filepath.Ext(from)); ext {
+		case ".csv":
+			records, _, err = taskio.CSVReader{KeepOwner: true}.Read(f)
+		case ".txt":
+			records, err = taskio.ReadTodoTxt(f)
+		case ".md":
+			records, err = taskio.ReadMarkdown(f)
+		case ".ics":
+			records, err = taskio.ReadICal(f)
+		default:
+			return nil, fmt.Errorf("unknown file type %q, expected .csv, .txt, .md or .ics", ext)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tasks := make([]storage.Task, len(records))
+	for i, rec := range records {
+		if len(rec.Errors) > 0 {
+			return nil, fmt.Errorf("line %d: %s: %s", rec.Line, rec.Errors[0].Field, rec.Errors[0].Message)
+		}
+		if rec.Task.Header == "" {
+			return nil, fmt.Errorf("line %d: header is empty", rec.Line)
+		}
+		tasks[i] = rec.Task
+		if !rec.HasID {
+			tasks[i].TaskID = -1
+		}
+	}
+	return tasks, nil
+}
+
+func fetchExport(ctx context.Context, client *http.Client, base *url.URL, token string) (io.ReadCloser, error) {
+	u := base.JoinPath("todos", "export")
+	u.RawQuery = "format=csv"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return resp.Body, nil
+}
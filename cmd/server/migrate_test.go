@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"todo/internal/server"
+	"todo/internal/storage"
+	"todo/internal/storage/sqlstore"
+	"todo/internal/taskio"
+	"todo/internal/tracing"
+)
+
+func snapshotTasks() []storage.Task {
+	return []storage.Task{
+		{TaskID: 3, Header: "Write report", Description: "Quarterly", Status: storage.InProgress, Owner: "alice"},
+		{TaskID: 7, Header: "Buy milk", Status: storage.Completed, Owner: "bob"},
+	}
+}
+
+func TestReadSnapshotFile(t *testing.T) {
+	tests := []struct {
+		ext   string
+		write func(io.Writer, []storage.Task) error
+		owner bool
+	}{
+		{".csv", taskio.WriteCSV, true},
+		{".txt", taskio.WriteTodoTxt, false},
+		{".md", taskio.WriteMarkdown, false},
+		{".ics", taskio.ICalWriter{Name: "todo", Stamp: time.Now()}.Write, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.write(&buf, snapshotTasks()); err != nil {
+				t.Fatalf("failed to write snapshot: %v", err)
+			}
+			path := filepath.Join(t.TempDir(), "tasks"+tt.ext)
+			if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			tasks, err := readSnapshot(context.Background(), http.DefaultClient, path, "")
+			if err != nil {
+				t.Fatalf("readSnapshot: %v", err)
+			}
+			want := snapshotTasks()
+			if !tt.owner {
+				for i := range want {
+					want[i].Owner = ""
+				}
+			}
+			if len(tasks) != len(want) {
+				t.Fatalf("expected %d tasks, got %d: %+v", len(want), len(tasks), tasks)
+			}
+			for i := range want {
+				if tasks[i].TaskID != want[i].TaskID || tasks[i].Header != want[i].Header ||
+					tasks[i].Status != want[i].Status || tasks[i].Owner != want[i].Owner {
+					t.Errorf("task %d: expected %+v, got %+v", i, want[i], tasks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadSnapshotFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"tasks.json", "[]", `unknown file type ".json"`},
+		{"empty.csv", "id,header\n1,\n", "header is empty"},
+		{"missing.csv", "", "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := readSnapshot(context.Background(), http.DefaultClient, path, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReadSnapshotServer(t *testing.T) {
+	st := storage.NewStorage()
+	ctx := context.Background()
+	for _, task := range append([]storage.Task{{Header: "Removed"}}, snapshotTasks()...) {
+		if _, err := st.CreateTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.Delete(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	want, err := st.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(want, func(a, b storage.Task) int { return a.TaskID - b.TaskID })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := server.NewServer(st, logger).Handler()
+	var headers http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	tracer := tracing.NewTracer(tracing.TracerOptions{})
+	client := &http.Client{Transport: &tracing.Transport{Tracer: tracer}}
+	spanCtx, span := tracer.Start(ctx, "migrate", tracing.KindInternal)
+	defer span.End()
+
+	tasks, err := readSnapshot(spanCtx, client, ts.URL, "secret")
+	if err != nil {
+		t.Fatalf("readSnapshot: %v", err)
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("expected %d tasks, got %d: %+v", len(want), len(tasks), tasks)
+	}
+	for i := range want {
+		if tasks[i].TaskID != want[i].TaskID || tasks[i].Owner != want[i].Owner || tasks[i].Header != want[i].Header {
+			t.Errorf("task %d: expected %+v, got %+v", i, want[i], tasks[i])
+		}
+	}
+
+	if got := headers.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("expected the token to be sent, got %q", got)
+	}
+	sc, err := tracing.ParseTraceparent(headers.Get(tracing.TraceparentHeader))
+	if err != nil {
+		t.Fatalf("expected a traceparent header: %v", err)
+	}
+	if sc.TraceID != span.SpanContext().TraceID {
+		t.Errorf("expected trace %s, got %s", span.SpanContext().TraceID, sc.TraceID)
+	}
+}
+
+func TestReadSnapshotServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	_, err := readSnapshot(context.Background(), http.DefaultClient, ts.URL, "")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a 403 error, got %v", err)
+	}
+}
+
+func TestRunMigrateArguments(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{nil, "-db is required"},
+		{[]string{"-db", "tasks.db", "-db-driver", "nosuch"}, `database driver "nosuch"`},
+	}
+
+	for _, tt := range tests {
+		err := runMigrate(tt.args, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("runMigrate(%q): expected error containing %q, got %v", tt.args, tt.wantErr, err)
+		}
+	}
+}
+
+func TestRunMigrate(t *testing.T) {
+	dir := t.TempDir()
+	from := filepath.Join(dir, "tasks.csv")
+	var buf bytes.Buffer
+	if err := taskio.WriteCSV(&buf, snapshotTasks()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(from, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dsn := filepath.Join(dir, "tasks.db")
+
+	var out strings.Builder
+	if err := runMigrate([]string{"-db", dsn, "-from", from}, &out); err != nil {
+		t.Fatalf("runMigrate: %v", err)
+	}
+	want := fmt.Sprintf("schema is at version %d, applied %d migrations\nimported 2 tasks\n", sqlstore.LatestVersion(), sqlstore.LatestVersion())
+	if out.String() != want {
+		t.Errorf("expected output %q, got %q", want, out.String())
+	}
+
+	st, err := sqlstore.Open(context.Background(), "sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	tasks, err := st.GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(tasks, func(a, b storage.Task) int { return a.TaskID - b.TaskID })
+	if !slices.Equal(tasks, snapshotTasks()) {
+		t.Errorf("expected %+v, got %+v", snapshotTasks(), tasks)
+	}
+
+	out.Reset()
+	if err := runMigrate([]string{"-db", dsn}, &out); err != nil {
+		t.Fatalf("runMigrate: %v", err)
+	}
+	if want := fmt.Sprintf("schema is at version %d, applied 0 migrations\n", sqlstore.LatestVersion()); out.String() != want {
+		t.Errorf("expected output %q, got %q", want, out.String())
+	}
+}
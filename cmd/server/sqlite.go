@@ -0,0 +1,4 @@
+package main
+
+// The pure-Go SQLite driver registers itself as "sqlite", the default -db-driver.
+import _ "modernc.org/sqlite"
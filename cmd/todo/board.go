@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"todo/pkg/client"
+	"unicode/utf8"
+)
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+
+	boardHelp = "←→↑↓ move  <> status  e edit  n new  x delete  / search  r refresh  q quit"
+)
+
+type boardMode int
+
+const (
+	modeNormal boardMode = iota
+	modeEdit
+	modeNew
+	modeSearch
+	modeConfirmDelete
+)
+
+type board struct {
+	api *client.Client
+
+	tasks   []client.Task
+	columns [][]client.Task
+	col     int
+	row     int
+	offsets []int
+
+	mode    boardMode
+	input   []rune
+	search  string
+	message string
+
+	width  int
+	height int
+}
+
+func newBoard(api *client.Client) *board {
+	return &board{
+		api:     api,
+		columns: make([][]client.Task, len(client.Statuses)),
+		offsets: make([]int, len(client.Statuses)),
+		width:   80,
+		height:  24,
+	}
+}
+
+func runBoard(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("board", nil)
+	interval := fs.Duration("refresh", 5*time.Second, "how often to reload tasks from the server, 0 disables")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected argument %q", fs.Arg(0))
+	}
+
+	term, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer term.restore()
+
+	fmt.Fprint(a.stdout, enterScreen)
+	defer fmt.Fprint(a.stdout, leaveScreen)
+
+	b := newBoard(a.client)
+	b.width, b.height = term.size()
+	b.refresh(ctx)
+
+	keys := make(chan []key)
+	go readKeys(os.Stdin, keys)
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+
+	var tick <-chan time.Time
+	if *interval > 0 {
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		if _, err := io.WriteString(a.stdout, b.view()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case batch, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range batch {
+				if b.handle(ctx, k) {
+					return nil
+				}
+			}
+		case <-resize:
+			b.width, b.height = term.size()
+		case <-tick:
+			b.refresh(ctx)
+		}
+	}
+}
+
+func (b *board) selected() (client.Task, bool) {
+	column := b.columns[b.col]
+	if b.row < 0 || b.row >= len(column) {
+		return client.Task{}, false
+	}
+	return column[b.row], true
+}
+
+// layout splits the tasks into columns by status, applying the search filter, and keeps the
+// cursor on the task with keepID when it is still visible.
+func (b *board) layout(keepID int) {
+	needle := strings.ToLower(b.search)
+	for i := range b.columns {
+		b.columns[i] = b.columns[i][:0]
+	}
+	for _, task := range b.tasks {
+		col := slices.Index(client.Statuses, task.Status)
+		if col < 0 {
+			continue
+		}
+		if needle != "" && !strings.Contains(strings.ToLower(task.Header+"\n"+task.Description), needle) {
+			continue
+		}
+		b.columns[col] = append(b.columns[col], task)
+	}
+
+	for col, column := range b.columns {
+		for row, task := range column {
+			if task.TaskID == keepID {
+				b.col, b.row = col, row
+				return
+			}
+		}
+	}
+	b.row = min(b.row, len(b.columns[b.col])-1)
+	b.row = max(b.row, 0)
+}
+
+func (b *board) selectedID() int {
+	if task, ok := b.selected(); ok {
+		return task.TaskID
+	}
+	return -1
+}
+
+func (b *board) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tasks, err := b.api.All(ctx)
+	if err != nil {
+		b.message = "Refresh failed: " + err.Error()
+		return
+	}
+	b.tasks = tasks
+	b.layout(b.selectedID())
+}
+
+func (b *board) replace(task client.Task) {
+	i := slices.IndexFunc(b.tasks, func(t client.Task) bool { return t.TaskID == task.TaskID })
+	if i < 0 {
+		b.tasks = append(b.tasks, task)
+	} else {
+		b.tasks[i] = task
+	}
+	b.layout(task.TaskID)
+}
+
+// handle applies one key press and reports whether the board should exit.
+func (b *board) handle(ctx context.Context, k key) bool {
+	if k.code == keyCtrlC {
+		return true
+	}
+
+	switch b.mode {
+	case modeEdit, modeNew:
+		b.handleInput(ctx, k)
+	case modeSearch:
+		b.handleSearch(k)
+	case modeConfirmDelete:
+		b.mode = modeNormal
+		if k.code == keyRune && (k.r == 'y' || k.r == 'Y') {
+			b.deleteSelected(ctx)
+		} else {
+			b.message = "Delete cancelled"
+		}
+	default:
+		return b.handleNormal(ctx, k)
+	}
+	return false
+}
+
+func (b *board) handleNormal(ctx context.Context, k key) bool {
+	b.message = ""
+	switch {
+	case k.code == keyRune && k.r == 'q':
+		return true
+	case k.code == keyLeft, k.code == keyRune && k.r == 'h':
+		b.moveCursor(-1, 0)
+	case k.code == keyRight, k.code == keyRune && k.r == 'l':
+		b.moveCursor(1, 0)
+	case k.code == keyUp, k.code == keyRune && k.r == 'k':
+		b.moveCursor(0, -1)
+	case k.code == keyDown, k.code == keyRune && k.r == 'j':
+		b.moveCursor(0, 1)
+	case k.code == keyShiftLeft, k.code == keyRune && (k.r == '<' || k.r == 'H'):
+		b.moveTask(ctx, -1)
+	case k.code == keyShiftRight, k.code == keyRune && (k.r == '>' || k.r == 'L'):
+		b.moveTask(ctx, 1)
+	case k.code == keyEnter, k.code == keyRune && k.r == 'e':
+		if task, ok := b.selected(); ok {
+			b.mode = modeEdit
+			b.input = []rune(task.Header)
+		}
+	case k.code == keyRune && (k.r == 'n' || k.r == 'a'):
+		b.mode = modeNew
+		b.input = nil
+	case k.code == keyDelete, k.code == keyRune && k.r == 'x':
+		if _, ok := b.selected(); ok {
+			b.mode = modeConfirmDelete
+		}
+	case k.code == keyRune && k.r == '/':
+		b.mode = modeSearch
+		b.input = []rune(b.search)
+	case k.code == keyEsc:
+		b.search = ""
+		b.layout(b.selectedID())
+	case k.code == keyRune && k.r == 'r':
+		b.refresh(ctx)
+	}
+	return false
+}
+
+func (b *board) handleInput(ctx context.Context, k key) {
+	if !b.editLine(k) {
+		return
+	}
+	mode := b.mode
+	b.mode = modeNormal
+	if k.code == keyEsc {
+		return
+	}
+
+	header := strings.TrimSpace(string(b.input))
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if mode == modeNew {
+		created, err := b.api.Create(ctx, client.Task{Header: header, Status: client.Statuses[b.col]})
+		if err != nil {
+			b.message = "Create failed: " + err.Error()
+			return
+		}
+		b.replace(*created)
+		b.message = fmt.Sprintf("Created #%d", created.TaskID)
+		return
+	}
+
+	task, ok := b.selected()
+	if !ok {
+		return
+	}
+	task.Header = header
+	updated, err := b.api.Update(ctx, task.TaskID, task)
+	if err != nil {
+		b.message = "Update failed: " + err.Error()
+		return
+	}
+	b.replace(*updated)
+	b.message = fmt.Sprintf("Updated #%d", updated.TaskID)
+}
+
+func (b *board) handleSearch(k key) {
+	done := b.editLine(k)
+	if k.code == keyEsc {
+		b.input = nil
+	}
+	b.search = string(b.input)
+	b.layout(b.selectedID())
+	if done {
+		b.mode = modeNormal
+	}
+}
+
+// editLine applies k to the input line and reports whether it was finished with Enter or Esc.
+func (b *board) editLine(k key) bool {
+	switch k.code {
+	case keyEnter, keyEsc:
+		return true
+	case keyBackspace:
+		if len(b.input) > 0 {
+			b.input = b.input[:len(b.input)-1]
+		}
+	case keyCtrlU:
+		b.input = nil
+	case keyRune:
+		b.input = append(b.input, k.r)
+	}
+	return false
+}
+
+func (b *board) moveCursor(dcol, drow int) {
+	if dcol != 0 {
+		b.col = max(0, min(len(b.columns)-1, b.col+dcol))
+		b.row = min(b.row, len(b.columns[b.col])-1)
+	}
+	b.row = max(0, min(len(b.columns[b.col])-1, b.row+drow))
+}
+
+func (b *board) moveTask(ctx context.Context, dir int) {
+	task, ok := b.selected()
+	target := b.col + dir
+	if !ok || target < 0 || target >= len(client.Statuses) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	task.Status = client.Statuses[target]
+	updated, err := b.api.Update(ctx, task.TaskID, task)
+	if err != nil {
+		b.message = "Move failed: " + err.Error()
+		return
+	}
+	b.replace(*updated)
+	b.message = fmt.Sprintf("Moved #%d to %s", updated.TaskID, updated.Status)
+}
+
+func (b *board) deleteSelected(ctx context.Context) {
+	task, ok := b.selected()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := b.api.Delete(ctx, task.TaskID); err != nil {
+		b.message = "Delete failed: " + err.Error()
+		return
+	}
+	b.tasks = slices.DeleteFunc(b.tasks, func(t client.Task) bool { return t.TaskID == task.TaskID })
+	b.layout(-1)
+	b.message = fmt.Sprintf("Deleted #%d", task.TaskID)
+}
+
+// view renders a full frame. Lines end with \r\n because raw mode disables output processing.
+func (b *board) view() string {
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	line := func(s string) {
+		sb.WriteString(s)
+		sb.WriteString("\x1b[K\r\n")
+	}
+
+	title := " TODO board"
+	if b.search != "" {
+		title += "  search: " + b.search
+	}
+	line("\x1b[7m" + fit(title, b.width) + "\x1b[0m")
+
+	colWidth := max(b.width/len(b.columns), 4)
+	var header, rule strings.Builder
+	for i, column := range b.columns {
+		name := fmt.Sprintf(" %s (%d)", client.Statuses[i], len(column))
+		header.WriteString("\x1b[1m" + fit(name, colWidth-1) + "\x1b[0m│")
+		rule.WriteString(strings.Repeat("─", colWidth-1) + "┼")
+	}
+	line(header.String())
+	line(rule.String())
+
+	rows := max(b.height-5, 1)
+	off := &b.offsets[b.col]
+	*off = max(min(*off, b.row), b.row-rows+1, 0)
+
+	for r := 0; r < rows; r++ {
+		var row strings.Builder
+		for i, column := range b.columns {
+			cell := ""
+			idx := b.offsets[i] + r
+			if idx < len(column) {
+				cell = fmt.Sprintf(" #%d %s", column[idx].TaskID, singleLine(column[idx].Header))
+			}
+			cell = fit(cell, colWidth-1)
+			if i == b.col && idx == b.row && idx < len(column) {
+				cell = "\x1b[7m" + cell + "\x1b[0m"
+			}
+			row.WriteString(cell + "│")
+		}
+		line(row.String())
+	}
+
+	line(fit(" "+b.message, b.width))
+	switch b.mode {
+	case modeEdit:
+		sb.WriteString(fit(" Header: "+string(b.input)+"█", b.width))
+	case modeNew:
+		sb.WriteString(fit(fmt.Sprintf(" New %s task: %s█", client.Statuses[b.col], string(b.input)), b.width))
+	case modeSearch:
+		sb.WriteString(fit(" Search: "+string(b.input)+"█", b.width))
+	case modeConfirmDelete:
+		task, _ := b.selected()
+		sb.WriteString(fit(fmt.Sprintf(" Delete #%d %s? (y/n)", task.TaskID, singleLine(task.Header)), b.width))
+	default:
+		sb.WriteString("\x1b[2m" + fit(" "+boardHelp, b.width) + "\x1b[0m")
+	}
+	sb.WriteString("\x1b[K\x1b[J")
+	return sb.String()
+}
+
+// fit pads or truncates s to exactly width runes, marking truncation with an ellipsis.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	n := utf8.RuneCountInString(s)
+	if n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
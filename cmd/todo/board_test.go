@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo/internal/server"
+	"todo/internal/storage"
+	"todo/pkg/client"
+)
+
+func newTestBoard(t *testing.T, tasks ...client.Task) *board {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ts := httptest.NewServer(server.NewServer(storage.NewStorage(), logger).Handler())
+	t.Cleanup(ts.Close)
+
+	c, err := client.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range tasks {
+		if _, err := c.Create(context.Background(), task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := newBoard(c)
+	b.refresh(context.Background())
+	return b
+}
+
+func (b *board) press(t *testing.T, input string) bool {
+	t.Helper()
+
+	for _, k := range parseKeys([]byte(input)) {
+		if b.handle(context.Background(), k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *board) serverTask(t *testing.T, id int) client.Task {
+	t.Helper()
+
+	task, err := b.api.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *task
+}
+
+func TestBoardColumns(t *testing.T) {
+	b := newTestBoard(t,
+		client.Task{Header: "First"},
+		client.Task{Header: "Second", Status: client.InProgress},
+		client.Task{Header: "Third", Status: client.Dropped},
+	)
+
+	for i, want := range []int{1, 1, 0, 1} {
+		if len(b.columns[i]) != want {
+			t.Errorf("column %s: expected %d tasks, got %d", client.Statuses[i], want, len(b.columns[i]))
+		}
+	}
+
+	frame := b.view()
+	for _, want := range []string{"Assigned (1)", "InProgress (1)", "Completed (0)", "#0 First", "#2 Third", "q quit"} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("frame does not contain %q", want)
+		}
+	}
+}
+
+func TestBoardMoveTask(t *testing.T) {
+	b := newTestBoard(t, client.Task{Header: "Task"})
+
+	b.press(t, ">")
+	if got := b.serverTask(t, 0).Status; got != client.InProgress {
+		t.Fatalf("expected InProgress on the server, got %v", got)
+	}
+	if b.col != 1 || b.row != 0 {
+		t.Errorf("expected the cursor to follow the task, got column %d row %d", b.col, b.row)
+	}
+
+	b.press(t, "\x1b[1;2CL")
+	if got := b.serverTask(t, 0).Status; got != client.Dropped {
+		t.Errorf("expected Dropped on the server, got %v", got)
+	}
+
+	b.press(t, ">")
+	if got := b.serverTask(t, 0).Status; got != client.Dropped {
+		t.Errorf("expected the last column to stay Dropped, got %v", got)
+	}
+
+	b.press(t, "H")
+	if got := b.serverTask(t, 0).Status; got != client.Completed {
+		t.Errorf("expected Completed on the server, got %v", got)
+	}
+}
+
+func TestBoardNavigation(t *testing.T) {
+	b := newTestBoard(t,
+		client.Task{Header: "A1"},
+		client.Task{Header: "A2"},
+		client.Task{Header: "B1", Status: client.InProgress},
+	)
+
+	b.press(t, "jj")
+	if b.row != 1 {
+		t.Errorf("expected the cursor to stop at the last row, got %d", b.row)
+	}
+	b.press(t, "\x1b[C")
+	if b.col != 1 || b.row != 0 {
+		t.Errorf("expected column 1 row 0, got column %d row %d", b.col, b.row)
+	}
+	b.press(t, "llll")
+	if b.col != 3 {
+		t.Errorf("expected the cursor to stop at the last column, got %d", b.col)
+	}
+	if _, ok := b.selected(); ok {
+		t.Error("expected no selection in an empty column")
+	}
+}
+
+func TestBoardInlineEditing(t *testing.T) {
+	b := newTestBoard(t, client.Task{Header: "Old", Description: "Kept"})
+
+	b.press(t, "e\x15New header\r")
+	task := b.serverTask(t, 0)
+	if task.Header != "New header" || task.Description != "Kept" {
+		t.Errorf("unexpected task after edit: %+v", task)
+	}
+
+	b.press(t, "eXYZ\x1b")
+	if got := b.serverTask(t, 0).Header; got != "New header" {
+		t.Errorf("expected Esc to cancel the edit, got %q", got)
+	}
+
+	b.press(t, "e\x15\r")
+	if !strings.Contains(b.message, "Update failed") {
+		t.Errorf("expected the validation error to be shown, got %q", b.message)
+	}
+
+	b.press(t, "lnFresh\x7fh\r")
+	if b.col != 1 || b.selectedID() != 1 {
+		t.Fatalf("expected the new task to be selected, got column %d id %d", b.col, b.selectedID())
+	}
+	if task := b.serverTask(t, 1); task.Header != "Fresh" || task.Status != client.InProgress {
+		t.Errorf("unexpected created task: %+v", task)
+	}
+}
+
+func TestBoardSearch(t *testing.T) {
+	b := newTestBoard(t,
+		client.Task{Header: "Buy milk"},
+		client.Task{Header: "Write report", Description: "quarterly numbers"},
+	)
+
+	b.press(t, "/QUART")
+	if len(b.columns[0]) != 1 || b.columns[0][0].Header != "Write report" {
+		t.Errorf("expected the search to filter while typing, got %+v", b.columns[0])
+	}
+	b.press(t, "\r")
+	if b.mode != modeNormal || b.search != "QUART" {
+		t.Errorf("expected Enter to keep the filter, got mode %d search %q", b.mode, b.search)
+	}
+	if !strings.Contains(b.view(), "search: QUART") {
+		t.Error("expected the active search in the title")
+	}
+
+	b.press(t, "\x1b")
+	if len(b.columns[0]) != 2 {
+		t.Errorf("expected Esc to clear the filter, got %d tasks", len(b.columns[0]))
+	}
+}
+
+func TestBoardDelete(t *testing.T) {
+	b := newTestBoard(t, client.Task{Header: "Task"})
+
+	b.press(t, "xn")
+	if len(b.columns[0]) != 1 {
+		t.Fatal("expected the delete to be cancelled")
+	}
+
+	b.press(t, "xy")
+	if len(b.columns[0]) != 0 {
+		t.Error("expected the task to be removed from the board")
+	}
+	if _, err := b.api.Get(context.Background(), 0); err == nil {
+		t.Error("expected the task to be deleted on the server")
+	}
+}
+
+func TestBoardRefreshKeepsSelection(t *testing.T) {
+	b := newTestBoard(t, client.Task{Header: "A"}, client.Task{Header: "B"})
+	b.press(t, "j")
+
+	if _, err := b.api.Update(context.Background(), 1, client.Task{Header: "B", Status: client.Completed}); err != nil {
+		t.Fatal(err)
+	}
+	b.refresh(context.Background())
+
+	if b.col != 2 || b.selectedID() != 1 {
+		t.Errorf("expected the selection to follow task 1 to Completed, got column %d id %d", b.col, b.selectedID())
+	}
+}
+
+func TestBoardQuit(t *testing.T) {
+	b := newTestBoard(t)
+	if !b.press(t, "q") {
+		t.Error("expected q to quit")
+	}
+	if !newTestBoard(t).press(t, "/abc\x03") {
+		t.Error("expected Ctrl-C to quit from any mode")
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		input string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abc…"},
+		{"жжж", 3, "жжж"},
+		{"abc", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := fit(tt.input, tt.width); got != tt.want {
+			t.Errorf("fit(%q, %d) = %q, want %q", tt.input, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"todo/pkg/client"
+)
+
+func (a *app) flags(name string, output *outputFlag) *flag.FlagSet {
+	fs := flag.NewFlagSet("todo "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(a.stderr, "Usage: todo", commands()[name].usage)
+		fs.PrintDefaults()
+	}
+	if output != nil {
+		*output = formatTable
+		fs.Var(output, "o", "output format: table, json or plain")
+	}
+	return fs
+}
+
+func parseIDs(args []string) ([]int, error) {
+	if len(args) == 0 {
+		return nil, usagef("missing task ID")
+	}
+	ids := make([]int, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+		if err != nil || id < 0 {
+			return nil, usagef("invalid task ID %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+type statusFlag struct {
+	status *client.TaskStatus
+}
+
+func (f statusFlag) String() string {
+	if f.status == nil {
+		return ""
+	}
+	return f.status.String()
+}
+
+func (f statusFlag) Set(value string) error {
+	status, err := client.ParseTaskStatus(value)
+	if err != nil {
+		return err
+	}
+	*f.status = status
+	return nil
+}
+
+func runAdd(ctx context.Context, a *app, args []string) error {
+	var output outputFlag
+	var task client.Task
+	fs := a.flags("add", &output)
+	fs.StringVar(&task.Description, "d", "", "description")
+	edit := fs.Bool("e", false, "write the description in $EDITOR")
+	fs.Var(statusFlag{&task.Status}, "s", "status: assigned, in-progress, completed or dropped")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	task.Header = strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(task.Header) == "" {
+		return usagef("missing task header")
+	}
+	if *edit {
+		description, err := a.editText(task.Description)
+		if err != nil {
+			return err
+		}
+		task.Description = description
+	}
+
+	created, err := a.client.Create(ctx, task)
+	if err != nil {
+		return err
+	}
+	return printTask(a.stdout, output, *created)
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	var output outputFlag
+	fs := a.flags("list", &output)
+	statuses := fs.String("s", "", "comma-separated statuses to show")
+	owner := fs.String("owner", "", "only tasks of this owner")
+	query := fs.String("q", "", "only tasks whose header or description contains this text (case-insensitive)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected argument %q", fs.Arg(0))
+	}
+
+	filter := client.Filter{Owner: *owner, Query: *query}
+	for _, name := range strings.Split(*statuses, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		status, err := client.ParseTaskStatus(name)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	var tasks []client.Task
+	for page, err := range a.client.Find(ctx, filter, 100) {
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, page...)
+	}
+	return printTasks(a.stdout, output, tasks)
+}
+
+func runShow(ctx context.Context, a *app, args []string) error {
+	var output outputFlag
+	fs := a.flags("show", &output)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		task, err := a.client.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if i > 0 && output == formatTable {
+			fmt.Fprintln(a.stdout)
+		}
+		if err := printTask(a.stdout, output, *task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runEdit(ctx context.Context, a *app, args []string) error {
+	var output outputFlag
+	var status client.TaskStatus
+	fs := a.flags("edit", &output)
+	header := fs.String("header", "", "new header")
+	description := fs.String("d", "", "new description")
+	edit := fs.Bool("e", false, "edit the description in $EDITOR")
+	fs.Var(statusFlag{&status}, "s", "new status")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(ids) != 1 {
+		return usagef("edit takes exactly one task ID")
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if !set["header"] && !set["d"] && !set["e"] && !set["s"] {
+		return usagef("nothing to change; use -header, -d, -e or -s")
+	}
+
+	task, err := a.client.Get(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	if set["header"] {
+		task.Header = *header
+	}
+	if set["d"] {
+		task.Description = *description
+	}
+	if set["s"] {
+		task.Status = status
+	}
+	if *edit {
+		task.Description, err = a.editText(task.Description)
+		if err != nil {
+			return err
+		}
+	}
+
+	updated, err := a.client.Update(ctx, task.TaskID, *task)
+	if err != nil {
+		return err
+	}
+	return printTask(a.stdout, output, *updated)
+}
+
+func runDone(ctx context.Context, a *app, args []string) error {
+	return a.setStatus(ctx, "done", args, client.Completed)
+}
+
+func runDrop(ctx context.Context, a *app, args []string) error {
+	return a.setStatus(ctx, "drop", args, client.Dropped)
+}
+
+func (a *app) setStatus(ctx context.Context, name string, args []string, status client.TaskStatus) error {
+	var output outputFlag
+	fs := a.flags(name, &output)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var tasks []client.Task
+	for _, id := range ids {
+		task, err := a.client.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		task.Status = status
+		updated, err := a.client.Update(ctx, id, *task)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, *updated)
+	}
+	return printTasks(a.stdout, output, tasks)
+}
+
+func runRemove(ctx context.Context, a *app, args []string) error {
+	fs := a.flags("rm", nil)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := a.client.Delete(ctx, id); err != nil {
+			return fmt.Errorf("delete %d: %w", id, err)
+		}
+	}
+	return nil
+}
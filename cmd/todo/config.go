@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultServer = "http://localhost:8080"
+
+type config struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+// loadConfig reads the config file and applies $TODO_SERVER and $TODO_TOKEN on top of it.
+// A missing file is only an error when its path was given explicitly.
+func loadConfig(path string, getenv func(string) string) (config, error) {
+	cfg := config{Server: defaultServer}
+
+	explicit := path != ""
+	if !explicit {
+		path = getenv("TODO_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "todo", "config.json")
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				return config{}, fmt.Errorf("parse %s: %w", path, err)
+			}
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		default:
+			return config{}, err
+		}
+	}
+
+	if v := getenv("TODO_SERVER"); v != "" {
+		cfg.Server = v
+	}
+	if v := getenv("TODO_TOKEN"); v != "" {
+		cfg.Token = v
+	}
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	return cfg, nil
+}
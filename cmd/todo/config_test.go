@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(file, []byte(`{"server":"https://todo.example.com","token":"from-file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.json")
+	if err := os.WriteFile(empty, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		env     map[string]string
+		want    config
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			path: empty,
+			want: config{Server: defaultServer},
+		},
+		{
+			name: "explicit file",
+			path: file,
+			want: config{Server: "https://todo.example.com", Token: "from-file"},
+		},
+		{
+			name: "file from environment",
+			env:  map[string]string{"TODO_CONFIG": file},
+			want: config{Server: "https://todo.example.com", Token: "from-file"},
+		},
+		{
+			name: "environment overrides file",
+			path: file,
+			env:  map[string]string{"TODO_SERVER": "http://localhost:9000", "TODO_TOKEN": "from-env"},
+			want: config{Server: "http://localhost:9000", Token: "from-env"},
+		},
+		{
+			name:    "missing explicit file",
+			path:    filepath.Join(dir, "missing.json"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid file",
+			path:    invalid,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadConfig(tt.path, func(key string) string { return tt.env[key] })
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editText opens initial in $VISUAL or $EDITOR (vi by default) and returns the saved text
+// without trailing whitespace.
+func (a *app) editText(initial string) (string, error) {
+	argv := a.editorCommand()
+
+	f, err := os.CreateTemp("", "todo-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(initial); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(argv[0], append(argv[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = a.stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s: %w", argv[0], err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), " \t\r\n"), nil
+}
+
+// editorCommand splits the first of $VISUAL and $EDITOR that is not blank, since editors such
+// as "code --wait" come with arguments.
+func (a *app) editorCommand() []string {
+	for _, key := range []string{"VISUAL", "EDITOR"} {
+		if argv := strings.Fields(a.getenv(key)); len(argv) > 0 {
+			return argv
+		}
+	}
+	return []string{"vi"}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyShiftLeft
+	keyShiftRight
+	keyEnter
+	keyEsc
+	keyBackspace
+	keyDelete
+	keyCtrlC
+	keyCtrlU
+	keyUnknown
+)
+
+type key struct {
+	code keyCode
+	r    rune
+}
+
+var escapeSequences = map[string]keyCode{
+	"[A": keyUp, "[B": keyDown, "[C": keyRight, "[D": keyLeft,
+	"OA": keyUp, "OB": keyDown, "OC": keyRight, "OD": keyLeft,
+	"[1;2C": keyShiftRight, "[1;2D": keyShiftLeft,
+	"[3~": keyDelete,
+}
+
+// parseKeys decodes one read from a raw-mode terminal. A lone ESC is the Escape key, since
+// terminals send escape sequences in a single write.
+func parseKeys(buf []byte) []key {
+	var keys []key
+	for len(buf) > 0 {
+		switch c := buf[0]; {
+		case c == 0x1b:
+			if len(buf) == 1 {
+				return append(keys, key{code: keyEsc})
+			}
+			n := escapeLength(buf[1:])
+			code, ok := escapeSequences[string(buf[1:1+n])]
+			if !ok {
+				code = keyUnknown
+			}
+			keys = append(keys, key{code: code})
+			buf = buf[1+n:]
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{code: keyEnter})
+			buf = buf[1:]
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+			buf = buf[1:]
+		case c == 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+			buf = buf[1:]
+		case c == 0x15:
+			keys = append(keys, key{code: keyCtrlU})
+			buf = buf[1:]
+		case c < 0x20:
+			keys = append(keys, key{code: keyUnknown})
+			buf = buf[1:]
+		default:
+			r, size := utf8.DecodeRune(buf)
+			keys = append(keys, key{code: keyRune, r: r})
+			buf = buf[size:]
+		}
+	}
+	return keys
+}
+
+// escapeLength returns the length of the CSI or SS3 sequence at the start of b (after ESC).
+func escapeLength(b []byte) int {
+	if b[0] != '[' && b[0] != 'O' {
+		return 1
+	}
+	for i := 1; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(b)
+}
+
+func readKeys(r io.Reader, out chan<- []key) {
+	defer close(out)
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			out <- parseKeys(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
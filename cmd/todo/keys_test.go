@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []key
+	}{
+		{"runes", "aж", []key{{code: keyRune, r: 'a'}, {code: keyRune, r: 'ж'}}},
+		{"arrows", "\x1b[A\x1b[B\x1bOC\x1b[D", []key{{code: keyUp}, {code: keyDown}, {code: keyRight}, {code: keyLeft}}},
+		{"shift arrows", "\x1b[1;2C\x1b[1;2D", []key{{code: keyShiftRight}, {code: keyShiftLeft}}},
+		{"lone escape", "\x1b", []key{{code: keyEsc}}},
+		{"control keys", "\r\x7f\x03\x15", []key{{code: keyEnter}, {code: keyBackspace}, {code: keyCtrlC}, {code: keyCtrlU}}},
+		{"delete", "\x1b[3~", []key{{code: keyDelete}}},
+		{"unknown sequence", "\x1b[15~x", []key{{code: keyUnknown}, {code: keyRune, r: 'x'}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseKeys([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
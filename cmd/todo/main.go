@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"todo/pkg/client"
+)
+
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+
+	client *client.Client
+}
+
+type command struct {
+	usage   string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+func commands() map[string]command {
+	return map[string]command{
+		"add":   {"add [-d TEXT | -e] [-s STATUS] HEADER...", "create a task", runAdd},
+		"list":  {"list [-s STATUS,...] [-owner OWNER] [-q TEXT]", "list tasks", runList},
+		"show":  {"show ID", "show a task", runShow},
+		"edit":  {"edit [-header TEXT] [-d TEXT | -e] [-s STATUS] ID", "change a task", runEdit},
+		"done":  {"done ID...", "mark tasks as completed", runDone},
+		"drop":  {"drop ID...", "mark tasks as dropped", runDrop},
+		"rm":    {"rm ID...", "delete tasks", runRemove},
+		"board": {"board [-refresh DURATION]", "interactive kanban board", runBoard},
+		"sync":  {"sync [-n] FILE", "two-way sync with a todo.txt file", runSync},
+	}
+}
+
+// usageError is reported with the usage text and exit status 2.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{stdout: os.Stdout, stderr: os.Stderr, getenv: os.Getenv}
+	err := a.run(ctx, os.Args[1:])
+
+	var usageErr *usageError
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.As(err, &usageErr):
+		fmt.Fprintln(os.Stderr, "todo:", err)
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "todo:", err)
+		os.Exit(1)
+	}
+}
+
+func (a *app) run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("todo", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = a.usage(fs)
+	serverURL := fs.String("server", "", "API base URL (default from $TODO_SERVER, the config file or "+defaultServer+")")
+	token := fs.String("token", "", "bearer token (default from $TODO_TOKEN or the config file)")
+	configPath := fs.String("config", "", "config file (default $TODO_CONFIG or <user config dir>/todo/config.json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return usagef("missing command")
+	}
+	name := fs.Arg(0)
+	if name == "help" {
+		fs.Usage()
+		return nil
+	}
+	cmd, ok := commands()[name]
+	if !ok {
+		fs.Usage()
+		return usagef("unknown command %q", name)
+	}
+
+	cfg, err := loadConfig(*configPath, a.getenv)
+	if err != nil {
+		return err
+	}
+	if *serverURL != "" {
+		cfg.Server = *serverURL
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+
+	a.client, err = client.New(cfg.Server, client.WithToken(cfg.Token), client.WithUserAgent("todo-cli"))
+	if err != nil {
+		return err
+	}
+	return cmd.run(ctx, a, fs.Args()[1:])
+}
+
+func (a *app) usage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(a.stderr, "Usage: todo [flags] COMMAND [command flags] [args]")
+		fmt.Fprintln(a.stderr, "\nCommands:")
+		cmds := commands()
+		names := make([]string, 0, len(cmds))
+		for name := range cmds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(a.stderr, "  %-6s %s\n", name, cmds[name].summary)
+		}
+		fmt.Fprintln(a.stderr, "\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(a.stderr, "\nRun 'todo COMMAND -h' for command flags.")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"todo/internal/server"
+	"todo/internal/storage"
+	"todo/pkg/client"
+)
+
+type testEnv struct {
+	t   *testing.T
+	url string
+	env map[string]string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ts := httptest.NewServer(server.NewServer(storage.NewStorage(), logger, server.WithOwnerKey([]byte("test"))).Handler())
+	t.Cleanup(ts.Close)
+
+	// An empty config file keeps the tests away from the real user config.
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &testEnv{t: t, url: ts.URL, env: map[string]string{"TODO_CONFIG": configFile}}
+}
+
+func (e *testEnv) run(args ...string) (string, error) {
+	e.t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	a := &app{
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(key string) string { return e.env[key] },
+	}
+	err := a.run(context.Background(), append([]string{"-server", e.url}, args...))
+	return stdout.String(), err
+}
+
+func (e *testEnv) mustRun(args ...string) string {
+	e.t.Helper()
+
+	out, err := e.run(args...)
+	if err != nil {
+		e.t.Fatalf("todo %s: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+func decodeTasks(t *testing.T, out string) []client.Task {
+	t.Helper()
+
+	var tasks []client.Task
+	if err := json.Unmarshal([]byte(out), &tasks); err != nil {
+		t.Fatalf("failed to decode %q: %v", out, err)
+	}
+	return tasks
+}
+
+func TestAddShowEditRemove(t *testing.T) {
+	e := newTestEnv(t)
+
+	out := e.mustRun("add", "-o", "json", "-d", "At the store", "Buy", "milk")
+	var created client.Task
+	if err := json.Unmarshal([]byte(out), &created); err != nil {
+		t.Fatalf("failed to decode %q: %v", out, err)
+	}
+	if created.Header != "Buy milk" || created.Description != "At the store" {
+		t.Errorf("unexpected task: %+v", created)
+	}
+
+	out = e.mustRun("show", "0")
+	for _, want := range []string{"ID:", "Buy milk", "Assigned", "At the store"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("show output %q does not contain %q", out, want)
+		}
+	}
+
+	out = e.mustRun("edit", "-o", "plain", "-s", "in-progress", "-header", "Buy oat milk", "0")
+	if out != "0\tInProgress\tBuy oat milk\n" {
+		t.Errorf("unexpected edit output %q", out)
+	}
+
+	e.mustRun("rm", "0")
+	if _, err := e.run("show", "0"); !errors.Is(err, client.ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestDoneAndDrop(t *testing.T) {
+	e := newTestEnv(t)
+	e.mustRun("add", "First")
+	e.mustRun("add", "Second")
+	e.mustRun("add", "Third")
+
+	e.mustRun("done", "0", "#1")
+	e.mustRun("drop", "2")
+
+	tasks := decodeTasks(t, e.mustRun("list", "-o", "json"))
+	want := []client.TaskStatus{client.Completed, client.Completed, client.Dropped}
+	for i, task := range tasks {
+		if task.Status != want[i] {
+			t.Errorf("task %d: expected %v, got %v", task.TaskID, want[i], task.Status)
+		}
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	e := newTestEnv(t)
+	e.mustRun("add", "-d", "weekly shopping", "Groceries")
+	e.mustRun("add", "-s", "in-progress", "Write report")
+	e.mustRun("add", "-s", "completed", "Call Bob")
+	e.env["TODO_TOKEN"] = "alice"
+	e.mustRun("add", "Alice's task")
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"all", nil, []string{"Groceries", "Write report", "Call Bob", "Alice's task"}},
+		{"single status", []string{"-s", "inprogress"}, []string{"Write report"}},
+		{"several statuses", []string{"-s", "completed,in-progress"}, []string{"Write report", "Call Bob"}},
+		{"text in description", []string{"-q", "SHOPPING"}, []string{"Groceries"}},
+		{"owner", []string{"-owner", "ip:08bd7b3f7d005739"}, []string{"Groceries", "Write report", "Call Bob"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := decodeTasks(t, e.mustRun(append([]string{"list", "-o", "json"}, tt.args...)...))
+			var got []string
+			for _, task := range tasks {
+				got = append(got, task.Header)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestListFormats(t *testing.T) {
+	e := newTestEnv(t)
+	e.mustRun("add", "Groceries")
+
+	table := e.mustRun("list")
+	if !strings.HasPrefix(table, "ID  STATUS    OWNER") || !strings.Contains(table, "Groceries") {
+		t.Errorf("unexpected table output %q", table)
+	}
+	if plain := e.mustRun("list", "-o", "plain"); plain != "0\tAssigned\tGroceries\n" {
+		t.Errorf("unexpected plain output %q", plain)
+	}
+	if empty := e.mustRun("list", "-o", "json", "-s", "dropped"); strings.TrimSpace(empty) != "[]" {
+		t.Errorf("expected an empty JSON array, got %q", empty)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	e := newTestEnv(t)
+	script := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf 'Written in editor\\n\\n' >> \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	e.env["EDITOR"] = script
+
+	e.mustRun("add", "-e", "-d", "Draft. ", "Task")
+	task := decodeTasks(t, e.mustRun("list", "-o", "json"))[0]
+	if task.Description != "Draft. Written in editor" {
+		t.Errorf("unexpected description %q", task.Description)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	e := newTestEnv(t)
+
+	tests := [][]string{
+		{},
+		{"frobnicate"},
+		{"add"},
+		{"show"},
+		{"show", "abc"},
+		{"edit", "0"},
+		{"list", "-s", "unknown"},
+		{"list", "extra"},
+	}
+
+	for _, args := range tests {
+		_, err := e.run(args...)
+		var usageErr *usageError
+		if !errors.As(err, &usageErr) {
+			t.Errorf("todo %v: expected a usage error, got %v", args, err)
+		}
+	}
+
+	if _, err := e.run("list", "-o", "yaml"); err == nil {
+		t.Error("expected an error for an unknown output format")
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"default", nil, []string{"vi"}},
+		{"visual first", map[string]string{"VISUAL": "code --wait", "EDITOR": "nano"}, []string{"code", "--wait"}},
+		{"blank visual", map[string]string{"VISUAL": "  ", "EDITOR": "nano"}, []string{"nano"}},
+		{"blank editor", map[string]string{"EDITOR": "\t "}, []string{"vi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &app{getenv: func(key string) string { return tt.env[key] }}
+			if got := a.editorCommand(); !slices.Equal(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"todo/pkg/client"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatPlain = "plain"
+)
+
+type outputFlag string
+
+func (o *outputFlag) String() string {
+	return string(*o)
+}
+
+func (o *outputFlag) Set(value string) error {
+	switch value {
+	case formatTable, formatJSON, formatPlain:
+		*o = outputFlag(value)
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q, expected table, json or plain", value)
+	}
+}
+
+func printTasks(w io.Writer, format outputFlag, tasks []client.Task) error {
+	switch format {
+	case formatJSON:
+		if tasks == nil {
+			tasks = []client.Task{}
+		}
+		return writeJSON(w, tasks)
+	case formatPlain:
+		for _, task := range tasks {
+			if _, err := fmt.Fprintln(w, plainLine(task)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTATUS\tOWNER\tHEADER")
+		for _, task := range tasks {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", task.TaskID, task.Status, task.Owner, singleLine(task.Header))
+		}
+		return tw.Flush()
+	}
+}
+
+func printTask(w io.Writer, format outputFlag, task client.Task) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, task)
+	case formatPlain:
+		_, err := fmt.Fprintln(w, plainLine(task))
+		return err
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "ID:\t%d\n", task.TaskID)
+		fmt.Fprintf(tw, "Header:\t%s\n", task.Header)
+		fmt.Fprintf(tw, "Status:\t%s\n", task.Status)
+		fmt.Fprintf(tw, "Owner:\t%s\n", task.Owner)
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if task.Description != "" {
+			_, err := fmt.Fprintf(w, "\n%s\n", task.Description)
+			return err
+		}
+		return nil
+	}
+}
+
+func plainLine(task client.Task) string {
+	return fmt.Sprintf("%d\t%s\t%s", task.TaskID, task.Status, singleLine(task.Header))
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
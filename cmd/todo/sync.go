@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"todo/pkg/client"
+)
+
+// runSync pushes a todo.txt file to the server and rewrites it with the server's tasks. Lines
+// carrying an id:N tag update that task, the others become new tasks and get their tag on the
+// way back. Tasks deleted from the file are not deleted on the server.
+func runSync(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("sync", nil)
+	dryRun := flags.Bool("n", false, "only report what would change")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usagef("sync takes exactly one file")
+	}
+	path := flags.Arg(0)
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	report, err := a.client.Import(ctx, client.FormatTodoTxt, data, client.ImportOptions{Mode: client.ImportUpsert, DryRun: *dryRun})
+	if report != nil {
+		for _, row := range report.Rows {
+			for _, e := range row.Errors {
+				fmt.Fprintf(a.stderr, "%s:%d: %s: %s\n", path, row.Line, e.Field, e.Message)
+			}
+		}
+	}
+	switch {
+	case err != nil:
+		return err
+	case !report.Valid:
+		return fmt.Errorf("%s has invalid lines", path)
+	case report.Failed > 0:
+		return fmt.Errorf("%d of %d lines could not be saved", report.Failed, len(report.Rows))
+	}
+	if *dryRun {
+		fmt.Fprintf(a.stdout, "%d to create, %d to update\n", report.Created, report.Updated)
+		return nil
+	}
+
+	data, err = a.client.Export(ctx, client.FormatTodoTxt)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "%d created, %d updated\n", report.Created, report.Updated)
+	return nil
+}
+
+// writeFileAtomic replaces path so an interrupted sync never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	e := newTestEnv(t)
+	e.mustRun("add", "Buy", "milk")
+	path := filepath.Join(t.TempDir(), "todo.txt")
+
+	if out := e.mustRun("sync", path); out != "0 created, 0 updated\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "Buy milk id:0") {
+		t.Fatalf("expected the server's task in the file, got %q", data)
+	}
+
+	edited := "x " + string(data) + "(A) Call mum +family\n"
+	if err := os.WriteFile(path, []byte(edited), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if out := e.mustRun("sync", "-n", path); out != "1 to create, 1 to update\n" {
+		t.Errorf("unexpected dry-run output %q", out)
+	}
+	if data, _ := os.ReadFile(path); string(data) != edited {
+		t.Errorf("expected a dry run to leave the file alone, got %q", data)
+	}
+
+	if out := e.mustRun("sync", path); out != "1 created, 1 updated\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	data, _ = os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "x Buy milk id:0") || !strings.HasPrefix(lines[1], "(A) Call mum +family id:1") {
+		t.Errorf("unexpected file after sync:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the file mode to be kept, got %v", info.Mode().Perm())
+	}
+	if tasks := decodeTasks(t, e.mustRun("list", "-o", "json", "-s", "completed")); len(tasks) != 1 {
+		t.Errorf("expected the checked item to be completed on the server, got %+v", tasks)
+	}
+}
+
+func TestSyncInvalidFile(t *testing.T) {
+	e := newTestEnv(t)
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	content := "Buy milk status:someday\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.run("sync", path); err == nil {
+		t.Fatal("expected an error")
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("expected the file to be left alone, got %q", data)
+	}
+	if tasks := decodeTasks(t, e.mustRun("list", "-o", "json")); len(tasks) != 0 {
+		t.Errorf("expected nothing to be imported, got %+v", tasks)
+	}
+}
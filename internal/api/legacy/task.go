@@ -0,0 +1,34 @@
+// Package legacy is the task contract of the unversioned routes, frozen in the shape they had
+// before /v1 so that existing clients keep working when storage.Task changes.
+package legacy
+
+import "todo/internal/storage"
+
+type Task struct {
+	TaskID      int    `json:"TaskID" xml:"TaskID"`
+	Header      string `json:"Header" xml:"Header"`
+	Description string `json:"Description" xml:"Description"`
+	Status      int    `json:"Status" xml:"Status"`
+	Owner       string `json:"Owner" xml:"Owner"`
+}
+
+func FromTask(task storage.Task) Task {
+	return Task{
+		TaskID:      task.TaskID,
+		Header:      task.Header,
+		Description: task.Description,
+		Status:      int(task.Status),
+		Owner:       task.Owner,
+	}
+}
+
+// Storage converts t for the storage; unknown statuses are left to validation.
+func (t Task) Storage() (storage.Task, error) {
+	return storage.Task{
+		TaskID:      t.TaskID,
+		Header:      t.Header,
+		Description: t.Description,
+		Status:      storage.TaskStatus(t.Status),
+		Owner:       t.Owner,
+	}, nil
+}
@@ -0,0 +1,115 @@
+// Package v1 is the task contract served under /v1. Field names and status values are part of
+// the API and must not change; anything incompatible belongs in a new version package.
+package v1
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+// Status is a task status by name. Requests may also use the numeric values of the
+// unversioned API, as a JSON number or a string of digits.
+type Status string
+
+const (
+	StatusAssigned   Status = "assigned"
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+	StatusDropped    Status = "dropped"
+)
+
+// Statuses lists every status in workflow order.
+var Statuses = []Status{StatusAssigned, StatusInProgress, StatusCompleted, StatusDropped}
+
+var storedStatuses = map[Status]storage.TaskStatus{
+	StatusAssigned:   storage.Assigned,
+	StatusInProgress: storage.InProgress,
+	StatusCompleted:  storage.Completed,
+	StatusDropped:    storage.Dropped,
+}
+
+func StatusOf(status storage.TaskStatus) Status {
+	for name, stored := range storedStatuses {
+		if stored == status {
+			return name
+		}
+	}
+	return Status(strconv.Itoa(int(status)))
+}
+
+// Storage returns the stored status; an empty status means Assigned.
+func (s Status) Storage() (storage.TaskStatus, bool) {
+	if s == "" {
+		return storage.Assigned, true
+	}
+	if status, ok := storedStatuses[s]; ok {
+		return status, true
+	}
+	n, err := strconv.Atoi(string(s))
+	if err != nil {
+		return 0, false
+	}
+	for _, status := range storage.Statuses {
+		if int(status) == n {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		*s = Status(n.String())
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return &json.UnmarshalTypeError{Value: "value", Type: reflect.TypeFor[string](), Field: "status"}
+	}
+	*s = Status(name)
+	return nil
+}
+
+type Task struct {
+	TaskID      int    `json:"id" xml:"id"`
+	Header      string `json:"header" xml:"header"`
+	Description string `json:"description" xml:"description"`
+	Status      Status `json:"status" xml:"status"`
+	Owner       string `json:"owner" xml:"owner"`
+}
+
+func FromTask(task storage.Task) Task {
+	return Task{
+		TaskID:      task.TaskID,
+		Header:      task.Header,
+		Description: task.Description,
+		Status:      StatusOf(task.Status),
+		Owner:       task.Owner,
+	}
+}
+
+// Storage converts t for the storage; an unknown status is reported as a *validation.Error.
+func (t Task) Storage() (storage.Task, error) {
+	status, ok := t.Status.Storage()
+	if !ok {
+		names := make([]string, len(Statuses))
+		for i, s := range Statuses {
+			names[i] = string(s)
+		}
+		return storage.Task{}, &validation.Error{Fields: []validation.FieldError{
+			{Field: "status", Message: "must be one of " + strings.Join(names, ", ")},
+		}}
+	}
+	return storage.Task{
+		TaskID:      t.TaskID,
+		Header:      t.Header,
+		Description: t.Description,
+		Status:      status,
+		Owner:       t.Owner,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+func TestTaskJSON(t *testing.T) {
+	task := FromTask(storage.Task{TaskID: 7, Header: "Buy milk", Status: storage.InProgress, Owner: "ip:10.0.0.1"})
+	data, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":7,"header":"Buy milk","description":"","status":"in_progress","owner":"ip:10.0.0.1"}`
+	if string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+}
+
+func TestTaskStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    storage.TaskStatus
+		wantErr bool
+	}{
+		{"name", `{"header":"x","status":"completed"}`, storage.Completed, false},
+		{"missing", `{"header":"x"}`, storage.Assigned, false},
+		{"integer", `{"header":"x","status":3}`, storage.Dropped, false},
+		{"digits", `{"header":"x","status":"1"}`, storage.InProgress, false},
+		{"unknown name", `{"header":"x","status":"InProgress"}`, 0, true},
+		{"unknown integer", `{"header":"x","status":9}`, 0, true},
+		{"fraction", `{"header":"x","status":1.5}`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dto Task
+			if err := json.Unmarshal([]byte(tt.body), &dto); err != nil {
+				t.Fatal(err)
+			}
+			task, err := dto.Storage()
+			if tt.wantErr {
+				var verr *validation.Error
+				if !errors.As(err, &verr) || verr.Fields[0].Field != "status" {
+					t.Errorf("expected a status field error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if task.Status != tt.want || task.Header != "x" {
+				t.Errorf("unexpected task %+v", task)
+			}
+		})
+	}
+}
+
+func TestStatusRejectsOtherTypes(t *testing.T) {
+	var dto Task
+	err := json.Unmarshal([]byte(`{"status":true}`), &dto)
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "status" {
+		t.Errorf("expected a type error for status, got %v", err)
+	}
+}
+
+func TestStatusOfRoundTrips(t *testing.T) {
+	for _, status := range storage.Statuses {
+		if got, ok := StatusOf(status).Storage(); !ok || got != status {
+			t.Errorf("%v: got %v, %v", status, got, ok)
+		}
+	}
+}
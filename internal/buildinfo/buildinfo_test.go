@@ -0,0 +1,33 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGetUsesInjectedValues(t *testing.T) {
+	oldVersion, oldCommit, oldBuildTime := Version, Commit, BuildTime
+	defer func() { Version, Commit, BuildTime = oldVersion, oldCommit, oldBuildTime }()
+
+	Version, Commit, BuildTime = "1.2.3", "abc123", "2024-01-01T00:00:00Z"
+
+	info := Get()
+	if info.Version != "1.2.3" {
+		t.Errorf("expected version '1.2.3', got %s", info.Version)
+	}
+	if info.Commit != "abc123" {
+		t.Errorf("expected commit 'abc123', got %s", info.Commit)
+	}
+	if info.BuildTime != "2024-01-01T00:00:00Z" {
+		t.Errorf("expected build time '2024-01-01T00:00:00Z', got %s", info.BuildTime)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("expected go version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+}
+
+func TestGetDefaults(t *testing.T) {
+	if info := Get(); info.Version != "dev" {
+		t.Errorf("expected version 'dev', got %s", info.Version)
+	}
+}
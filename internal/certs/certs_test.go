@@ -0,0 +1,120 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSelfSigned(t *testing.T, dir, cn string, modTime time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	_ = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	_ = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	_ = os.Chtimes(certFile, modTime, modTime)
+	_ = os.Chtimes(keyFile, modTime, modTime)
+	return certFile, keyFile
+}
+
+func leafCN(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloaderPicksUpNewCertificate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeSelfSigned(t, dir, "first.example.com", start)
+
+	r, err := NewReloader(certFile, keyFile, time.Second, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cn := leafCN(t, r); cn != "first.example.com" {
+		t.Fatalf("expected first certificate, got %s", cn)
+	}
+
+	if reloaded, err := r.MaybeReload(); reloaded || err != nil {
+		t.Fatalf("expected no reload for unchanged files, got %v %v", reloaded, err)
+	}
+
+	writeSelfSigned(t, dir, "second.example.com", start.Add(time.Second))
+	reloaded, err := r.MaybeReload()
+	if err != nil || !reloaded {
+		t.Fatalf("expected reload, got %v %v", reloaded, err)
+	}
+	if cn := leafCN(t, r); cn != "second.example.com" {
+		t.Errorf("expected second certificate, got %s", cn)
+	}
+}
+
+func TestReloaderKeepsCertificateOnBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeSelfSigned(t, dir, "first.example.com", start)
+
+	r, err := NewReloader(certFile, keyFile, time.Second, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_ = os.WriteFile(certFile, []byte("garbage"), 0o600)
+	if _, err := r.MaybeReload(); err == nil {
+		t.Fatal("expected error for broken certificate")
+	}
+	if cn := leafCN(t, r); cn != "first.example.com" {
+		t.Errorf("expected previous certificate to stay active, got %s", cn)
+	}
+}
+
+func TestNewReloaderMissingFiles(t *testing.T) {
+	if _, err := NewReloader("missing.crt", "missing.key", time.Second, nil); err == nil {
+		t.Error("expected error for missing files")
+	}
+}
+
+func TestLoadCertPool(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeSelfSigned(t, dir, "ca", time.Now())
+
+	if _, err := LoadCertPool(certFile); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if _, err := LoadCertPool(keyFile); err == nil {
+		t.Error("expected error for a file without certificates")
+	}
+}
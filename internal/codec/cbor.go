@@ -0,0 +1,295 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CBOR implements RFC 8949 for the JSON data model. Tags are skipped and their content decoded,
+// byte strings decode to base64 strings and undefined to null; indefinite lengths are accepted.
+type CBOR struct{}
+
+func (CBOR) MediaType() string {
+	return "application/cbor"
+}
+
+const (
+	cborUint = iota
+	cborNegint
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const cborBreak = 0xff
+
+func (CBOR) Encode(w io.Writer, v any) error {
+	n, err := toNode(v)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	writeCBOR(bw, n)
+	return bw.Flush()
+}
+
+func writeCBORHead(w *bufio.Writer, major byte, n uint64) {
+	var buf [9]byte
+	switch {
+	case n < 24:
+		w.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		w.Write([]byte{major<<5 | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf[0] = major<<5 | 25
+		binary.BigEndian.PutUint16(buf[1:], uint16(n))
+		w.Write(buf[:3])
+	case n <= math.MaxUint32:
+		buf[0] = major<<5 | 26
+		binary.BigEndian.PutUint32(buf[1:], uint32(n))
+		w.Write(buf[:5])
+	default:
+		buf[0] = major<<5 | 27
+		binary.BigEndian.PutUint64(buf[1:], n)
+		w.Write(buf[:9])
+	}
+}
+
+func writeCBOR(w *bufio.Writer, n node) {
+	switch n.kind {
+	case kindNull:
+		w.WriteByte(0xf6)
+	case kindBool:
+		if n.bool {
+			w.WriteByte(0xf5)
+		} else {
+			w.WriteByte(0xf4)
+		}
+	case kindNumber:
+		if v, err := strconv.ParseInt(n.text, 10, 64); err == nil {
+			if v >= 0 {
+				writeCBORHead(w, cborUint, uint64(v))
+			} else {
+				writeCBORHead(w, cborNegint, uint64(-1-v))
+			}
+			return
+		}
+		if v, err := strconv.ParseUint(n.text, 10, 64); err == nil {
+			writeCBORHead(w, cborUint, v)
+			return
+		}
+		f, _ := strconv.ParseFloat(n.text, 64)
+		var buf [9]byte
+		buf[0] = cborSimple<<5 | 27
+		binary.BigEndian.PutUint64(buf[1:], math.Float64bits(f))
+		w.Write(buf[:])
+	case kindString:
+		writeCBORHead(w, cborText, uint64(len(n.text)))
+		w.WriteString(n.text)
+	case kindArray:
+		writeCBORHead(w, cborArray, uint64(len(n.items)))
+		for _, item := range n.items {
+			writeCBOR(w, item)
+		}
+	case kindObject:
+		writeCBORHead(w, cborMap, uint64(len(n.items)))
+		for i, item := range n.items {
+			writeCBOR(w, node{kind: kindString, text: n.keys[i]})
+			writeCBOR(w, item)
+		}
+	}
+}
+
+func (CBOR) Decode(data []byte, v any) error {
+	r := &binaryReader{format: "CBOR", data: data}
+	return decodeBinary(r, func() (node, error) { return readCBOR(r) }, v)
+}
+
+// readCBORHead returns the major type and argument of the next item; indefinite is set for
+// strings, arrays and maps whose length is given by a terminating break.
+func readCBORHead(r *binaryReader) (major byte, arg uint64, indefinite bool, err error) {
+	c, err := r.byte()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	major, info := c>>5, c&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info <= 27:
+		arg, err = r.uint(1 << (info - 24))
+		return major, arg, false, err
+	case info == 31 && major >= cborBytes && major <= cborMap:
+		return major, 0, true, nil
+	}
+	r.pos--
+	return 0, 0, false, r.fail("invalid additional information %d", info)
+}
+
+func readCBOR(r *binaryReader) (node, error) {
+	start := r.pos
+	major, arg, indefinite, err := readCBORHead(r)
+	if err != nil {
+		return node{}, err
+	}
+
+	switch major {
+	case cborUint:
+		return node{kind: kindNumber, text: strconv.FormatUint(arg, 10)}, nil
+	case cborNegint:
+		v := new(big.Int).SetUint64(arg)
+		return node{kind: kindNumber, text: v.Neg(v.Add(v, big.NewInt(1))).String()}, nil
+	case cborBytes, cborText:
+		b, err := readCBORString(r, major, arg, indefinite)
+		if err != nil {
+			return node{}, err
+		}
+		if major == cborBytes {
+			return node{kind: kindString, text: base64.StdEncoding.EncodeToString(b)}, nil
+		}
+		if !utf8.Valid(b) {
+			r.pos = start
+			return node{}, r.fail("text string is not valid UTF-8")
+		}
+		return node{kind: kindString, text: string(b)}, nil
+	case cborArray, cborMap:
+		return readCBORContainer(r, major, arg, indefinite)
+	case cborTag:
+		if err := r.enter(); err != nil {
+			return node{}, err
+		}
+		defer func() { r.depth-- }()
+		return readCBOR(r)
+	}
+
+	var f float64
+	switch info := r.data[start] & 0x1f; {
+	case info == 20, info == 21:
+		return node{kind: kindBool, bool: info == 21}, nil
+	case info == 22, info == 23:
+		return node{kind: kindNull}, nil
+	case info == 25:
+		f = halfFloat(uint16(arg))
+	case info == 26:
+		f = float64(math.Float32frombits(uint32(arg)))
+	case info == 27:
+		f = math.Float64frombits(arg)
+	default:
+		r.pos = start
+		return node{}, r.fail("unsupported simple value %d", arg)
+	}
+	n, ok := floatNode(f)
+	if !ok {
+		r.pos = start
+		return node{}, r.fail("%v cannot be represented", f)
+	}
+	return n, nil
+}
+
+func readCBORString(r *binaryReader, major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		return r.next(n)
+	}
+	var b []byte
+	for {
+		if r.pos < len(r.data) && r.data[r.pos] == cborBreak {
+			r.pos++
+			return b, nil
+		}
+		chunkMajor, size, chunkIndefinite, err := readCBORHead(r)
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkIndefinite {
+			return nil, r.fail("invalid chunk in indefinite-length string")
+		}
+		chunk, err := r.next(size)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+func readCBORContainer(r *binaryReader, major byte, n uint64, indefinite bool) (node, error) {
+	if err := r.enter(); err != nil {
+		return node{}, err
+	}
+	defer func() { r.depth-- }()
+
+	out := node{kind: kindArray}
+	perItem := uint64(1)
+	if major == cborMap {
+		out.kind, perItem = kindObject, 2
+	}
+	count := -1
+	if !indefinite {
+		c, err := r.count(n, perItem)
+		if err != nil {
+			return node{}, err
+		}
+		count = c
+	}
+
+	for i := 0; count < 0 || i < count; i++ {
+		if count < 0 {
+			if r.pos >= len(r.data) {
+				return node{}, r.fail("unexpected end of data")
+			}
+			if r.data[r.pos] == cborBreak {
+				r.pos++
+				break
+			}
+		}
+		if major == cborMap {
+			start := r.pos
+			key, err := readCBOR(r)
+			if err != nil {
+				return node{}, err
+			}
+			if key.kind != kindString || r.data[start]>>5 != cborText {
+				r.pos = start
+				return node{}, r.fail("map keys must be text strings")
+			}
+			out.keys = append(out.keys, key.text)
+		}
+		item, err := readCBOR(r)
+		if err != nil {
+			return node{}, err
+		}
+		out.items = append(out.items, item)
+	}
+	return out, nil
+}
+
+// halfFloat converts an IEEE 754 half-precision value.
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
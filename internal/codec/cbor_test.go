@@ -0,0 +1,120 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newLike returns an addressable zero value of the type of v.
+func newLike(v any) reflect.Value {
+	return reflect.New(reflect.TypeOf(v)).Elem()
+}
+
+func equalJSON(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return bytes.Equal(x, y)
+}
+
+// Vectors from RFC 8949, Appendix A.
+func TestCBOREncode(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"0", 0, "00"},
+		{"23", 23, "17"},
+		{"24", 24, "1818"},
+		{"1000", 1000, "1903e8"},
+		{"1000000", 1000000, "1a000f4240"},
+		{"1000000000000", 1000000000000, "1b000000e8d4a51000"},
+		{"-1", -1, "20"},
+		{"-1000", -1000, "3903e7"},
+		{"1.1", 1.1, "fb3ff199999999999a"},
+		{"false", false, "f4"},
+		{"null", nil, "f6"},
+		{"text", "IETF", "6449455446"},
+		{"unicode", "水", "63e6b0b4"},
+		{"array", []int{1, 2, 3}, "83010203"},
+		{"map", map[string]any{"a": 1, "b": []int{2, 3}}, "a26161016162820203"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (CBOR{}).Encode(&buf, tt.v); err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(buf.Bytes()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCBORDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  any
+	}{
+		{"uint64 max", "1bffffffffffffffff", uint64(18446744073709551615)},
+		{"negative", "3863", -100},
+		{"half float", "f93e00", 1.5},
+		{"half float subnormal", "f90001", 5.960464477539063e-08},
+		{"single float", "fa47c35000", 100000.0},
+		{"true", "f5", true},
+		{"undefined as null", "f7", (*int)(nil)},
+		{"tagged date string", "c074323031332d30332d32315432303a30343a30305a", "2013-03-21T20:04:00Z"},
+		{"byte string as base64", "4401020304", "AQIDBA=="},
+		{"indefinite text", "7f657374726561646d696e67ff", "streaming"},
+		{"indefinite array", "9f018202039f0405ffff", []any{1, []any{2, 3}, []any{4, 5}}},
+		{"indefinite map", "bf61610161629f0203ffff", map[string]any{"a": 1, "b": []any{2, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			got := newLike(tt.want)
+			if err := (CBOR{}).Decode(data, got.Addr().Interface()); err != nil {
+				t.Fatal(err)
+			}
+			if !equalJSON(got.Interface(), tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got.Interface())
+			}
+		})
+	}
+}
+
+func TestCBORMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"truncated", "1903"},
+		{"reserved additional information", "1c"},
+		{"huge map length", "bbffffffffffffffff"},
+		{"integer key", "a10102"},
+		{"invalid UTF-8", "62c328"},
+		{"unterminated indefinite array", "9f01"},
+		{"nested indefinite string chunk", "7f7fff"},
+		{"simple value", "f0"},
+		{"infinity", "f97c00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			var v any
+			var syntaxErr *SyntaxError
+			if err := (CBOR{}).Decode(data, &v); !errors.As(err, &syntaxErr) {
+				t.Errorf("expected a SyntaxError, got %v", err)
+			}
+		})
+	}
+}
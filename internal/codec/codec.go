@@ -0,0 +1,176 @@
+// Package codec encodes API values in the wire formats the server negotiates with clients.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrTrailingData is returned when a body holds more than one value.
+	ErrTrailingData = errors.New("body must contain a single value")
+	// ErrInvalidUTF8 is returned for text bodies that are not valid UTF-8.
+	ErrInvalidUTF8 = errors.New("body must be valid UTF-8")
+)
+
+// SyntaxError reports a malformed body in one of the binary formats.
+type SyntaxError struct {
+	Format string
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("malformed %s at offset %d: %s", e.Format, e.Offset, e.Msg)
+}
+
+// UnknownFieldError reports an XML element or attribute that does not map to a field of the
+// decoded value; the other formats report unknown fields through encoding/json.
+type UnknownFieldError struct {
+	Name string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Name)
+}
+
+type Codec interface {
+	// MediaType is written as the Content-Type of encoded values.
+	MediaType() string
+	Encode(w io.Writer, v any) error
+	// Decode reads exactly one value into v.
+	Decode(data []byte, v any) error
+}
+
+// Registry holds the codecs a server speaks, in order of preference.
+type Registry struct {
+	codecs     []Codec
+	aliases    [][]string
+	mediaTypes map[string]Codec
+}
+
+func NewRegistry() *Registry {
+	return &Registry{mediaTypes: make(map[string]Codec)}
+}
+
+// Default returns a registry with JSON, which is preferred, XML, MessagePack and CBOR.
+func Default() *Registry {
+	r := NewRegistry()
+	r.Register(JSON{})
+	r.Register(XML{}, "text/xml")
+	r.Register(MessagePack{}, "application/x-msgpack", "application/vnd.msgpack")
+	r.Register(CBOR{})
+	return r
+}
+
+// Register adds c, which is also chosen for the alias media types in Content-Type and Accept.
+func (r *Registry) Register(c Codec, aliases ...string) {
+	names := append([]string{c.MediaType()}, aliases...)
+	r.codecs = append(r.codecs, c)
+	r.aliases = append(r.aliases, names)
+	for _, mediaType := range names {
+		r.mediaTypes[strings.ToLower(mediaType)] = c
+	}
+}
+
+// MediaTypes lists the canonical media types in order of preference.
+func (r *Registry) MediaTypes() []string {
+	types := make([]string, len(r.codecs))
+	for i, c := range r.codecs {
+		types[i] = c.MediaType()
+	}
+	return types
+}
+
+// Preferred is the codec used when the client expresses no preference.
+func (r *Registry) Preferred() Codec {
+	return r.codecs[0]
+}
+
+// Lookup finds the codec for a Content-Type header value; parameters such as charset are ignored.
+func (r *Registry) Lookup(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	c, ok := r.mediaTypes[mediaType]
+	return c, ok
+}
+
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// Negotiate picks the codec for an Accept header value following RFC 9110: every codec gets
+// the quality of the most specific range that matches it or one of its aliases, and the highest
+// quality above zero wins, ties going to the registry order. An empty header accepts anything.
+func (r *Registry) Negotiate(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return r.Preferred(), true
+	}
+	ranges := parseAccept(accept)
+
+	var best Codec
+	bestQ := 0.0
+	for i, c := range r.codecs {
+		for _, mediaType := range r.aliases[i] {
+			if q := quality(ranges, mediaType); q > bestQ {
+				best, bestQ = c, q
+			}
+		}
+	}
+	return best, best != nil
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{typ, subtype, q})
+	}
+	// Exact types first, then type/*, then */*.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i]) > specificity(ranges[j])
+	})
+	return ranges
+}
+
+func specificity(a acceptRange) int {
+	switch {
+	case a.typ == "*":
+		return 0
+	case a.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func quality(ranges []acceptRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	for _, a := range ranges {
+		if (a.typ == "*" || a.typ == typ) && (a.subtype == "*" || a.subtype == subtype) {
+			return a.q
+		}
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type sample struct {
+	ID    int               `json:"id"`
+	Name  string            `json:"name"`
+	Score float64           `json:"score"`
+	OK    bool              `json:"ok"`
+	Tags  []string          `json:"tags,omitempty"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+	Next  *sample           `json:"next"`
+}
+
+func TestNegotiate(t *testing.T) {
+	r := Default()
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/xml", "application/xml"},
+		{"application/cbor, application/msgpack", "application/msgpack"},
+		{"application/cbor;q=0.9, application/msgpack;q=0.5", "application/cbor"},
+		{"application/*;q=0.5, application/json;q=0.1", "application/xml"},
+		{"*/*;q=0.8, application/json;q=0", "application/xml"},
+		{"text/html, application/xhtml+xml, */*;q=0.8", "application/json"},
+		{"APPLICATION/CBOR", "application/cbor"},
+		{"text/xml", "application/xml"},
+		{"application/x-msgpack;q=0.5, application/json;q=0.4", "application/msgpack"},
+		{"text/csv", ""},
+		{"application/json;q=0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			c, ok := r.Negotiate(tt.accept)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("expected no codec, got %s", c.MediaType())
+				}
+				return
+			}
+			if !ok || c.MediaType() != tt.want {
+				t.Errorf("expected %s, got %v", tt.want, c)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	r := Default()
+
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"application/json; charset=utf-8", "application/json"},
+		{"text/xml", "application/xml"},
+		{"application/x-msgpack", "application/msgpack"},
+		{"Application/CBOR", "application/cbor"},
+		{"text/plain", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			c, ok := r.Lookup(tt.contentType)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("expected no codec, got %s", c.MediaType())
+				}
+				return
+			}
+			if !ok || c.MediaType() != tt.want {
+				t.Errorf("expected %s, got %v", tt.want, c)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := sample{
+		ID: -70000, Name: "Ünïcode \"quoted\" <tag>", Score: 1.5, OK: true,
+		Tags:  []string{"a", ""},
+		Attrs: map[string]string{"k": "v"},
+		Next:  &sample{ID: 1 << 40, Score: -0.25},
+	}
+
+	for _, c := range []Codec{JSON{}, MessagePack{}, CBOR{}} {
+		t.Run(c.MediaType(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := c.Encode(&buf, in); err != nil {
+				t.Fatal(err)
+			}
+			var out sample
+			if err := c.Decode(buf.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(in, out) {
+				t.Errorf("expected %+v, got %+v", in, out)
+			}
+		})
+	}
+}
+
+func TestDecodeIsStrict(t *testing.T) {
+	type small struct {
+		ID int `json:"id"`
+	}
+	unknownField := map[string]any{"id": 1, "extra": true}
+
+	for _, c := range []Codec{JSON{}, MessagePack{}, CBOR{}} {
+		t.Run(c.MediaType(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := c.Encode(&buf, unknownField); err != nil {
+				t.Fatal(err)
+			}
+			var v small
+			if err := c.Decode(buf.Bytes(), &v); err == nil {
+				t.Error("expected unknown fields to be rejected")
+			}
+
+			buf.Reset()
+			_ = c.Encode(&buf, small{ID: 1})
+			_ = c.Encode(&buf, small{ID: 2})
+			if err := c.Decode(buf.Bytes(), &v); !errors.Is(err, ErrTrailingData) {
+				t.Errorf("expected ErrTrailingData, got %v", err)
+			}
+
+			if err := c.Decode(nil, &v); !errors.Is(err, io.EOF) {
+				t.Errorf("expected io.EOF for an empty body, got %v", err)
+			}
+		})
+	}
+}
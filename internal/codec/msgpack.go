@@ -0,0 +1,298 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// MessagePack implements https://github.com/msgpack/msgpack/blob/master/spec.md for the
+// JSON data model. Binary values decode to base64 strings, as encoding/json expects for []byte;
+// extension types are rejected.
+type MessagePack struct{}
+
+func (MessagePack) MediaType() string {
+	return "application/msgpack"
+}
+
+func (MessagePack) Encode(w io.Writer, v any) error {
+	n, err := toNode(v)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	writeMsgpack(bw, n)
+	return bw.Flush()
+}
+
+func writeMsgpack(w *bufio.Writer, n node) {
+	switch n.kind {
+	case kindNull:
+		w.WriteByte(0xc0)
+	case kindBool:
+		if n.bool {
+			w.WriteByte(0xc3)
+		} else {
+			w.WriteByte(0xc2)
+		}
+	case kindNumber:
+		writeMsgpackNumber(w, n.text)
+	case kindString:
+		writeMsgpackLength(w, len(n.text), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		w.WriteString(n.text)
+	case kindArray:
+		writeMsgpackLength(w, len(n.items), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range n.items {
+			writeMsgpack(w, item)
+		}
+	case kindObject:
+		writeMsgpackLength(w, len(n.items), 0x80, 16, 0, 0xde, 0xdf)
+		for i, item := range n.items {
+			writeMsgpack(w, node{kind: kindString, text: n.keys[i]})
+			writeMsgpack(w, item)
+		}
+	}
+}
+
+// writeMsgpackLength writes the header of a string, array or map: the fix form below fixLimit,
+// then the 8-bit form when the type has one, the 16-bit and the 32-bit form.
+func writeMsgpackLength(w *bufio.Writer, n int, fix byte, fixLimit int, code8, code16, code32 byte) {
+	var buf [5]byte
+	switch {
+	case n < fixLimit:
+		w.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		w.Write([]byte{code8, byte(n)})
+	case n <= math.MaxUint16:
+		buf[0] = code16
+		binary.BigEndian.PutUint16(buf[1:], uint16(n))
+		w.Write(buf[:3])
+	default:
+		buf[0] = code32
+		binary.BigEndian.PutUint32(buf[1:], uint32(n))
+		w.Write(buf[:5])
+	}
+}
+
+func writeMsgpackNumber(w *bufio.Writer, text string) {
+	var buf [9]byte
+	if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+		switch {
+		case v >= 0 && v < 128, v < 0 && v >= -32:
+			w.WriteByte(byte(v))
+		case v >= 0:
+			writeMsgpackUint(w, uint64(v))
+		case v >= math.MinInt8:
+			w.Write([]byte{0xd0, byte(v)})
+		case v >= math.MinInt16:
+			buf[0] = 0xd1
+			binary.BigEndian.PutUint16(buf[1:], uint16(v))
+			w.Write(buf[:3])
+		case v >= math.MinInt32:
+			buf[0] = 0xd2
+			binary.BigEndian.PutUint32(buf[1:], uint32(v))
+			w.Write(buf[:5])
+		default:
+			buf[0] = 0xd3
+			binary.BigEndian.PutUint64(buf[1:], uint64(v))
+			w.Write(buf[:9])
+		}
+		return
+	}
+	if v, err := strconv.ParseUint(text, 10, 64); err == nil {
+		writeMsgpackUint(w, v)
+		return
+	}
+	f, _ := strconv.ParseFloat(text, 64)
+	buf[0] = 0xcb
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(f))
+	w.Write(buf[:9])
+}
+
+func writeMsgpackUint(w *bufio.Writer, v uint64) {
+	var buf [9]byte
+	switch {
+	case v <= math.MaxUint8:
+		w.Write([]byte{0xcc, byte(v)})
+	case v <= math.MaxUint16:
+		buf[0] = 0xcd
+		binary.BigEndian.PutUint16(buf[1:], uint16(v))
+		w.Write(buf[:3])
+	case v <= math.MaxUint32:
+		buf[0] = 0xce
+		binary.BigEndian.PutUint32(buf[1:], uint32(v))
+		w.Write(buf[:5])
+	default:
+		buf[0] = 0xcf
+		binary.BigEndian.PutUint64(buf[1:], v)
+		w.Write(buf[:9])
+	}
+}
+
+func (MessagePack) Decode(data []byte, v any) error {
+	r := &binaryReader{format: "MessagePack", data: data}
+	return decodeBinary(r, func() (node, error) { return readMsgpack(r) }, v)
+}
+
+func readMsgpack(r *binaryReader) (node, error) {
+	start := r.pos
+	c, err := r.byte()
+	if err != nil {
+		return node{}, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return node{kind: kindNumber, text: strconv.Itoa(int(c))}, nil
+	case c >= 0xe0:
+		return node{kind: kindNumber, text: strconv.Itoa(int(int8(c)))}, nil
+	case c >= 0x80 && c <= 0x8f:
+		return readMsgpackMap(r, uint64(c&0x0f))
+	case c >= 0x90 && c <= 0x9f:
+		return readMsgpackArray(r, uint64(c&0x0f))
+	case c >= 0xa0 && c <= 0xbf:
+		return readMsgpackString(r, uint64(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return node{kind: kindNull}, nil
+	case 0xc2, 0xc3:
+		return node{kind: kindBool, bool: c == 0xc3}, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.uint(1 << (c - 0xc4))
+		if err != nil {
+			return node{}, err
+		}
+		b, err := r.next(n)
+		if err != nil {
+			return node{}, err
+		}
+		return node{kind: kindString, text: base64.StdEncoding.EncodeToString(b)}, nil
+	case 0xca, 0xcb:
+		var f float64
+		if c == 0xca {
+			bits, err := r.uint(4)
+			if err != nil {
+				return node{}, err
+			}
+			f = float64(math.Float32frombits(uint32(bits)))
+		} else {
+			bits, err := r.uint(8)
+			if err != nil {
+				return node{}, err
+			}
+			f = math.Float64frombits(bits)
+		}
+		n, ok := floatNode(f)
+		if !ok {
+			r.pos = start
+			return node{}, r.fail("%v cannot be represented", f)
+		}
+		return n, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := r.uint(1 << (c - 0xcc))
+		if err != nil {
+			return node{}, err
+		}
+		return node{kind: kindNumber, text: strconv.FormatUint(v, 10)}, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		v, err := r.uint(size)
+		if err != nil {
+			return node{}, err
+		}
+		// Sign-extend from the encoded width.
+		shift := 64 - 8*size
+		return node{kind: kindNumber, text: strconv.FormatInt(int64(v<<shift)>>shift, 10)}, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (c - 0xd9))
+		if err != nil {
+			return node{}, err
+		}
+		return readMsgpackString(r, n)
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (c - 0xdc))
+		if err != nil {
+			return node{}, err
+		}
+		return readMsgpackArray(r, n)
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (c - 0xde))
+		if err != nil {
+			return node{}, err
+		}
+		return readMsgpackMap(r, n)
+	}
+	r.pos = start
+	return node{}, r.fail("unsupported type 0x%02x", c)
+}
+
+func readMsgpackString(r *binaryReader, n uint64) (node, error) {
+	start := r.pos
+	b, err := r.next(n)
+	if err != nil {
+		return node{}, err
+	}
+	if !utf8.Valid(b) {
+		r.pos = start
+		return node{}, r.fail("string is not valid UTF-8")
+	}
+	return node{kind: kindString, text: string(b)}, nil
+}
+
+func readMsgpackArray(r *binaryReader, n uint64) (node, error) {
+	count, err := r.count(n, 1)
+	if err != nil {
+		return node{}, err
+	}
+	if err := r.enter(); err != nil {
+		return node{}, err
+	}
+	defer func() { r.depth-- }()
+
+	arr := node{kind: kindArray, items: make([]node, 0, count)}
+	for range count {
+		item, err := readMsgpack(r)
+		if err != nil {
+			return node{}, err
+		}
+		arr.items = append(arr.items, item)
+	}
+	return arr, nil
+}
+
+func readMsgpackMap(r *binaryReader, n uint64) (node, error) {
+	count, err := r.count(n, 2)
+	if err != nil {
+		return node{}, err
+	}
+	if err := r.enter(); err != nil {
+		return node{}, err
+	}
+	defer func() { r.depth-- }()
+
+	obj := node{kind: kindObject, keys: make([]string, 0, count), items: make([]node, 0, count)}
+	for range count {
+		start := r.pos
+		key, err := readMsgpack(r)
+		if err != nil {
+			return node{}, err
+		}
+		if key.kind != kindString {
+			r.pos = start
+			return node{}, r.fail("map keys must be strings")
+		}
+		value, err := readMsgpack(r)
+		if err != nil {
+			return node{}, err
+		}
+		obj.keys = append(obj.keys, key.text)
+		obj.items = append(obj.items, value)
+	}
+	return obj, nil
+}
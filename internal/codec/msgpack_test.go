@@ -0,0 +1,103 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestMessagePackEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"nil", nil, "c0"},
+		{"bools", []bool{false, true}, "92c2c3"},
+		{"positive fixint", 127, "7f"},
+		{"uint8", 128, "cc80"},
+		{"uint16", 65535, "cdffff"},
+		{"uint32", 1 << 31, "ce80000000"},
+		{"uint64", uint64(1 << 63), "cf8000000000000000"},
+		{"negative fixint", -32, "e0"},
+		{"int8", -33, "d0df"},
+		{"int16", -129, "d1ff7f"},
+		{"int32", -40000, "d2ffff63c0"},
+		{"int64", int64(-1 << 40), "d3ffffff0000000000"},
+		{"float64", 1.5, "cb3ff8000000000000"},
+		{"fixstr", "abc", "a3616263"},
+		{"str8", string(bytes.Repeat([]byte("a"), 32)), "d920" + hex.EncodeToString(bytes.Repeat([]byte("a"), 32))},
+		{"fixmap in field order", struct {
+			B int `json:"b"`
+			A int `json:"a"`
+		}{1, 2}, "82a16201a16102"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (MessagePack{}).Encode(&buf, tt.v); err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(buf.Bytes()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMessagePackDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  any
+	}{
+		{"float32", "ca3fc00000", 1.5},
+		{"uint64", "cfffffffffffffffff", uint64(18446744073709551615)},
+		{"str16", "da0003616263", "abc"},
+		{"bin8 as base64", "c4026869", "aGk="},
+		{"array16", "dc0002c2c3", []any{false, true}},
+		{"map16", "de0001a161c0", map[string]any{"a": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			got := newLike(tt.want)
+			if err := (MessagePack{}).Decode(data, got.Addr().Interface()); err != nil {
+				t.Fatal(err)
+			}
+			if !equalJSON(got.Interface(), tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got.Interface())
+			}
+		})
+	}
+}
+
+func TestMessagePackMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"truncated string", "a5616263"},
+		{"huge array length", "ddffffffff"},
+		{"non-string key", "810102"},
+		{"invalid UTF-8", "a2c328"},
+		{"extension type", "d40100"},
+		{"reserved code", "c1"},
+		{"NaN", "cb7ff8000000000000"},
+		{"too deep", string(bytes.Repeat([]byte("91"), maxDepth+1)) + "c0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.input)
+			var v any
+			var syntaxErr *SyntaxError
+			if err := (MessagePack{}).Decode(data, &v); !errors.As(err, &syntaxErr) {
+				t.Errorf("expected a SyntaxError, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+type kind int
+
+const (
+	kindNull kind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindArray
+	kindObject
+)
+
+// node is a value in the JSON data model with object keys kept in order. The formats other
+// than JSON encode and decode nodes, so every format shares the field names, omitempty rules
+// and strict decoding of encoding/json.
+type node struct {
+	kind  kind
+	bool  bool
+	text  string // number or string
+	items []node // array items or object values
+	keys  []string
+}
+
+func toNode(v any) (node, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return node{}, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return readNode(dec)
+}
+
+func readNode(dec *json.Decoder) (node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return node{}, err
+	}
+	switch tok := tok.(type) {
+	case nil:
+		return node{kind: kindNull}, nil
+	case bool:
+		return node{kind: kindBool, bool: tok}, nil
+	case json.Number:
+		return node{kind: kindNumber, text: tok.String()}, nil
+	case string:
+		return node{kind: kindString, text: tok}, nil
+	case json.Delim:
+		n := node{kind: kindArray}
+		if tok == '{' {
+			n.kind = kindObject
+		}
+		for dec.More() {
+			if n.kind == kindObject {
+				key, err := dec.Token()
+				if err != nil {
+					return node{}, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			item, err := readNode(dec)
+			if err != nil {
+				return node{}, err
+			}
+			n.items = append(n.items, item)
+		}
+		_, err := dec.Token()
+		return n, err
+	}
+	return node{}, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// decodeNode converts n to JSON and decodes it like a JSON body.
+func decodeNode(n node, v any) error {
+	var buf bytes.Buffer
+	if err := writeJSONNode(&buf, n); err != nil {
+		return err
+	}
+	return JSON{}.Decode(buf.Bytes(), v)
+}
+
+func writeJSONNode(buf *bytes.Buffer, n node) error {
+	switch n.kind {
+	case kindNull:
+		buf.WriteString("null")
+	case kindBool:
+		buf.WriteString(strconv.FormatBool(n.bool))
+	case kindNumber:
+		buf.WriteString(n.text)
+	case kindString:
+		data, err := json.Marshal(n.text)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	case kindArray, kindObject:
+		open, end := byte('['), byte(']')
+		if n.kind == kindObject {
+			open, end = '{', '}'
+		}
+		buf.WriteByte(open)
+		for i, item := range n.items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if n.kind == kindObject {
+				key, _ := json.Marshal(n.keys[i])
+				buf.Write(key)
+				buf.WriteByte(':')
+			}
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(end)
+	}
+	return nil
+}
+
+// floatNode formats a decoded float; JSON has no representation for NaN and infinities.
+func floatNode(f float64) (node, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return node{}, false
+	}
+	return node{kind: kindNumber, text: strconv.FormatFloat(f, 'g', -1, 64)}, true
+}
+
+// binaryReader is shared by the MessagePack and CBOR decoders.
+type binaryReader struct {
+	format string
+	data   []byte
+	pos    int
+	depth  int
+}
+
+const maxDepth = 100
+
+func (r *binaryReader) fail(format string, args ...any) error {
+	return &SyntaxError{Format: r.format, Offset: r.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (r *binaryReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.pos) {
+		return nil, r.fail("unexpected end of data")
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *binaryReader) byte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *binaryReader) uint(size int) (uint64, error) {
+	b, err := r.next(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// count checks a declared item count against the remaining data, so a few bytes cannot make
+// the decoder allocate gigabytes.
+func (r *binaryReader) count(n uint64, minSize uint64) (int, error) {
+	if n > uint64(len(r.data)-r.pos)/minSize {
+		return 0, r.fail("length %d exceeds the remaining data", n)
+	}
+	return int(n), nil
+}
+
+func (r *binaryReader) enter() error {
+	r.depth++
+	if r.depth > maxDepth {
+		return r.fail("nesting deeper than %d levels", maxDepth)
+	}
+	return nil
+}
+
+// decodeBinary reads one value with read and rejects anything after it.
+func decodeBinary(r *binaryReader, read func() (node, error), v any) error {
+	if len(r.data) == 0 {
+		return io.EOF
+	}
+	n, err := read()
+	if err != nil {
+		return err
+	}
+	if r.pos != len(r.data) {
+		return ErrTrailingData
+	}
+	return decodeNode(n, v)
+}
@@ -0,0 +1,257 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// XML encodes values with the same element names as the JSON field names. The root element is
+// named after the Go type; slices become a "<Type>List" element with one "<Type>" per item.
+// Nested arrays are written as "item" elements and null as an element with nil="true".
+type XML struct{}
+
+func (XML) MediaType() string {
+	return "application/xml"
+}
+
+func (XML) Encode(w io.Writer, v any) error {
+	n, err := toNode(v)
+	if err != nil {
+		return err
+	}
+	root, item := xmlNames(reflect.TypeOf(v))
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeXMLNode(enc, root, item, n); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func xmlNames(t reflect.Type) (root, item string) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		item = xmlTypeName(t.Elem(), "item")
+		return item + "List", item
+	}
+	return xmlTypeName(t, "value"), "item"
+}
+
+func xmlTypeName(t reflect.Type, fallback string) string {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return fallback
+	}
+	r, size := utf8.DecodeRuneInString(t.Name())
+	return string(unicode.ToUpper(r)) + t.Name()[size:]
+}
+
+func writeXMLNode(enc *xml.Encoder, name, item string, n node) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !validXMLName(name) {
+		start = xml.StartElement{
+			Name: xml.Name{Local: "entry"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: name}},
+		}
+	}
+	if n.kind == kindNull {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch n.kind {
+	case kindBool:
+		text := "false"
+		if n.bool {
+			text = "true"
+		}
+		if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	case kindNumber, kindString:
+		if err := enc.EncodeToken(xml.CharData(n.text)); err != nil {
+			return err
+		}
+	case kindArray:
+		for _, child := range n.items {
+			if err := writeXMLNode(enc, item, "item", child); err != nil {
+				return err
+			}
+		}
+	case kindObject:
+		for i, child := range n.items {
+			if err := writeXMLNode(enc, n.keys[i], "item", child); err != nil {
+				return err
+			}
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func validXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Decode uses encoding/xml, so struct fields match elements by field name or xml tag.
+// Elements and attributes without a field are rejected, as JSON rejects unknown fields.
+func (XML) Decode(data []byte, v any) error {
+	if !utf8.Valid(data) {
+		return ErrInvalidUTF8
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return checkXMLFields(data, reflect.TypeOf(v))
+		}
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) > 0 {
+				return ErrTrailingData
+			}
+		case xml.Comment, xml.ProcInst:
+		default:
+			return ErrTrailingData
+		}
+	}
+}
+
+// checkXMLFields walks the root element of a body that decoded into t and reports the first
+// element or attribute that encoding/xml skipped because no field matches it.
+func checkXMLFields(data []byte, t reflect.Type) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return checkXMLElement(dec, start, t)
+		}
+	}
+}
+
+var xmlUnmarshalerType = reflect.TypeFor[xml.Unmarshaler]()
+
+func checkXMLElement(dec *xml.Decoder, start xml.StartElement, t reflect.Type) error {
+	t = indirect(t)
+	if t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(xmlUnmarshalerType) {
+		return dec.Skip()
+	}
+
+	elements, attrs, anyElement := xmlFields(t)
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		if !attrs[attr.Name.Local] {
+			return &UnknownFieldError{Name: attr.Name.Local}
+		}
+	}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			field, ok := elements[tok.Name.Local]
+			switch {
+			case ok:
+				err = checkXMLElement(dec, tok, field)
+			case anyElement:
+				err = dec.Skip()
+			default:
+				return &UnknownFieldError{Name: tok.Name.Local}
+			}
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// xmlFields lists the element and attribute names encoding/xml maps to the fields of t, with
+// slices standing for their repeated items; anyElement is set by a field tagged ",any".
+func xmlFields(t reflect.Type) (elements map[string]reflect.Type, attrs map[string]bool, anyElement bool) {
+	elements, attrs = make(map[string]reflect.Type), make(map[string]bool)
+	if t.Kind() != reflect.Struct {
+		return elements, attrs, false
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		name, opts, _ := strings.Cut(f.Tag.Get("xml"), ",")
+		embedded := f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct
+		if !f.IsExported() || f.Name == "XMLName" || name == "-" || embedded {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		element := true
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "attr":
+				attrs[name] = true
+			case "any":
+				anyElement = true
+			case "chardata", "cdata", "innerxml", "comment":
+			default:
+				continue
+			}
+			element = false
+		}
+		if element {
+			ft := f.Type
+			for ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+				ft = ft.Elem()
+			}
+			elements[name] = ft
+		}
+	}
+	return elements, attrs, anyElement
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
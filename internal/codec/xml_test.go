@@ -0,0 +1,116 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type note struct {
+	TaskID int
+	Header string
+}
+
+func TestXMLEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{
+			name: "struct",
+			v:    &note{TaskID: 1, Header: "a < b & c"},
+			want: "<Note><TaskID>1</TaskID><Header>a &lt; b &amp; c</Header></Note>",
+		},
+		{
+			name: "slice",
+			v:    []note{{TaskID: 1}, {TaskID: 2}},
+			want: "<NoteList><Note><TaskID>1</TaskID><Header></Header></Note>" +
+				"<Note><TaskID>2</TaskID><Header></Header></Note></NoteList>",
+		},
+		{
+			name: "json names, nested arrays, null and map keys",
+			v: struct {
+				Err     string            `json:"error"`
+				Details []string          `json:"details"`
+				Missing *int              `json:"missing"`
+				Map     map[string]string `json:"components"`
+			}{"bad", []string{"x", "y"}, nil, map[string]string{"1st": "ok"}},
+			want: "<value><error>bad</error><details><item>x</item><item>y</item></details>" +
+				`<missing nil="true"></missing><components><entry name="1st">ok</entry></components></value>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (XML{}).Encode(&buf, tt.v); err != nil {
+				t.Fatal(err)
+			}
+			want := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + tt.want + "\n"
+			if buf.String() != want {
+				t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+			}
+		})
+	}
+}
+
+func TestXMLDecode(t *testing.T) {
+	var task note
+	if err := (XML{}).Decode([]byte(`<?xml version="1.0"?><Task><TaskID>3</TaskID><Header>Buy milk</Header></Task>`), &task); err != nil {
+		t.Fatal(err)
+	}
+	if task != (note{TaskID: 3, Header: "Buy milk"}) {
+		t.Errorf("unexpected task %+v", task)
+	}
+
+	if err := (XML{}).Decode([]byte(`<Task></Task><Task></Task>`), &task); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("expected ErrTrailingData, got %v", err)
+	}
+	if err := (XML{}).Decode([]byte("<Task>\xff</Task>"), &task); !errors.Is(err, ErrInvalidUTF8) {
+		t.Errorf("expected ErrInvalidUTF8, got %v", err)
+	}
+}
+
+func TestXMLDecodeUnknownFields(t *testing.T) {
+	type item struct {
+		Name string `xml:"name"`
+	}
+	type embedded struct {
+		Extra string
+	}
+	type doc struct {
+		embedded
+		ID    int    `xml:"id,attr"`
+		Items []item `xml:"item"`
+		Skip  string `xml:"-"`
+		Text  string `xml:",chardata"`
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		unknown string
+	}{
+		{"known", `<doc id="1" xmlns="urn:x">text<item><name>a</name></item><item><name>b</name></item><Extra>e</Extra></doc>`, ""},
+		{"unknown element", `<doc><Bogus>1</Bogus></doc>`, "Bogus"},
+		{"unknown attribute", `<doc kind="x"></doc>`, "kind"},
+		{"unknown nested element", `<doc><item><name>a</name><size>2</size></item></doc>`, "size"},
+		{"element inside a value", `<doc><item><name><b>a</b></name></item></doc>`, "b"},
+		{"ignored field", `<doc><Skip>x</Skip></doc>`, "Skip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v doc
+			err := (XML{}).Decode([]byte(tt.body), &v)
+			var fieldErr *UnknownFieldError
+			switch {
+			case tt.unknown == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case tt.unknown != "" && (!errors.As(err, &fieldErr) || fieldErr.Name != tt.unknown):
+				t.Errorf("expected unknown field %q, got %v", tt.unknown, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package graphql
+
+// Location is a 1-based position in the query source.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type OperationKind string
+
+const (
+	Query        OperationKind = "query"
+	Mutation     OperationKind = "mutation"
+	Subscription OperationKind = "subscription"
+)
+
+// Document is a parsed executable document: operations and the fragments they spread.
+type Document struct {
+	Operations []*Operation
+	Fragments  []*Fragment
+}
+
+type Operation struct {
+	Kind       OperationKind
+	Name       string
+	Variables  []*VariableDefinition
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+type VariableDefinition struct {
+	Name    string
+	Type    *TypeRef
+	Default *Value
+	Loc     Location
+}
+
+// TypeRef is a type as written in a variable definition: a named type, or a list of Elem.
+type TypeRef struct {
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+}
+
+func (t *TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment.
+type Selection interface {
+	location() Location
+}
+
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  []*Argument
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+// ResponseKey is the key of the field in the result.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Loc        Location
+}
+
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	Selections    []Selection
+	Loc           Location
+}
+
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	Selections    []Selection
+	Loc           Location
+}
+
+func (f *Field) location() Location          { return f.Loc }
+func (f *FragmentSpread) location() Location { return f.Loc }
+func (f *InlineFragment) location() Location { return f.Loc }
+
+type Argument struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
+
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+	Loc       Location
+}
+
+type ValueKind int
+
+const (
+	VariableValue ValueKind = iota
+	IntValue
+	FloatValue
+	StringValue
+	BooleanValue
+	NullValue
+	EnumValue
+	ListValue
+	ObjectValue
+)
+
+// Value is a literal or variable. Raw holds the variable or enum name, the digits of numbers,
+// the decoded string, or "true"/"false".
+type Value struct {
+	Kind   ValueKind
+	Raw    string
+	List   []*Value
+	Fields []*ObjectField
+	Loc    Location
+}
+
+type ObjectField struct {
+	Name  string
+	Value *Value
+}
+
+// String prints the value back in GraphQL syntax.
+func (v *Value) String() string {
+	switch v.Kind {
+	case VariableValue:
+		return "$" + v.Raw
+	case StringValue:
+		return quote(v.Raw)
+	case NullValue:
+		return "null"
+	case ListValue:
+		s := "["
+		for i, item := range v.List {
+			if i > 0 {
+				s += ", "
+			}
+			s += item.String()
+		}
+		return s + "]"
+	case ObjectValue:
+		s := "{"
+		for i, f := range v.Fields {
+			if i > 0 {
+				s += ", "
+			}
+			s += f.Name + ": " + f.Value.String()
+		}
+		return s + "}"
+	default:
+		return v.Raw
+	}
+}
@@ -0,0 +1,58 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is a GraphQL error as sent in the "errors" list of a response.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the resolver error behind a field error.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// ExtendedError is implemented by resolver errors that add machine-readable details, such as a
+// code, to the "extensions" of the error.
+type ExtendedError interface {
+	error
+	Extensions() map[string]any
+}
+
+func errorf(loc Location, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+// fieldError wraps an error returned by a resolver.
+func fieldError(err error, loc Location, path []any) *Error {
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		e := *gqlErr
+		if e.Locations == nil {
+			e.Locations = []Location{loc}
+		}
+		if e.Path == nil {
+			e.Path = path
+		}
+		return &e
+	}
+
+	e := &Error{Message: err.Error(), Locations: []Location{loc}, Path: path, err: err}
+	var extended ExtendedError
+	if errors.As(err, &extended) {
+		e.Extensions = extended.Extensions()
+	}
+	return e
+}
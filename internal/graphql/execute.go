@@ -0,0 +1,413 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+)
+
+// Request is a GraphQL request as sent over HTTP or a websocket.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is sent, possibly as null, once execution has
+// started; requests rejected before that only carry errors.
+type Response struct {
+	Data     any
+	Errors   []*Error
+	executed bool
+}
+
+func (r *Response) MarshalJSON() ([]byte, error) {
+	if !r.executed {
+		return json.Marshal(struct {
+			Errors []*Error `json:"errors"`
+		}{r.Errors})
+	}
+	return json.Marshal(struct {
+		Data   any      `json:"data"`
+		Errors []*Error `json:"errors,omitempty"`
+	}{r.Data, r.Errors})
+}
+
+// Executed reports whether execution started, as opposed to the request being rejected by
+// parsing, validation or variable coercion.
+func (r *Response) Executed() bool {
+	return r.executed
+}
+
+// object is a result map that keeps the order of the selection set.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Prepared is a parsed and validated request with its operation picked and variables coerced.
+type Prepared struct {
+	schema    *Schema
+	op        *Operation
+	fragments map[string]*Fragment
+	vars      map[string]any
+}
+
+// Prepare checks req; on failure it returns the response to send instead.
+func (s *Schema) Prepare(req Request) (*Prepared, *Response) {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return nil, &Response{Errors: []*Error{err.(*Error)}}
+	}
+	if errs := s.validate(doc); len(errs) > 0 {
+		return nil, &Response{Errors: errs}
+	}
+
+	var op *Operation
+	switch {
+	case req.OperationName != "":
+		i := slices.IndexFunc(doc.Operations, func(op *Operation) bool { return op.Name == req.OperationName })
+		if i < 0 {
+			return nil, &Response{Errors: []*Error{{Message: "Unknown operation named \"" + req.OperationName + "\"."}}}
+		}
+		op = doc.Operations[i]
+	case len(doc.Operations) == 1:
+		op = doc.Operations[0]
+	default:
+		return nil, &Response{Errors: []*Error{{Message: "Must provide operation name if query contains multiple operations."}}}
+	}
+
+	vars, errs := s.coerceVariables(op, req.Variables)
+	if len(errs) > 0 {
+		return nil, &Response{Errors: errs}
+	}
+
+	p := &Prepared{schema: s, op: op, vars: vars, fragments: make(map[string]*Fragment, len(doc.Fragments))}
+	for _, f := range doc.Fragments {
+		p.fragments[f.Name] = f
+	}
+	return p, nil
+}
+
+func (p *Prepared) Kind() OperationKind {
+	return p.op.Kind
+}
+
+// Execute runs a query or mutation; mutation fields run one after another in document order.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	p, resp := s.Prepare(req)
+	if resp != nil {
+		return resp
+	}
+	return p.Execute(ctx)
+}
+
+func (p *Prepared) Execute(ctx context.Context) *Response {
+	if p.op.Kind == Subscription {
+		return &Response{Errors: []*Error{errorf(p.op.Loc, "Subscriptions need a streaming transport such as a websocket.")}}
+	}
+	return p.run(ctx, nil, false)
+}
+
+func (p *Prepared) run(ctx context.Context, root any, event bool) *Response {
+	e := &executor{Prepared: p, event: event}
+	data, _ := e.selectionSet(ctx, p.schema.root(p.op.Kind), root, p.op.Selections, nil)
+	resp := &Response{Errors: e.errs, executed: true}
+	if data != nil {
+		resp.Data = data
+	}
+	return resp
+}
+
+// Subscribe opens the source stream of a subscription and executes the selection set for
+// every event. The channel is closed when the stream ends or ctx is cancelled; an error event
+// is delivered as a last response carrying only errors.
+func (p *Prepared) Subscribe(ctx context.Context) (<-chan *Response, *Response) {
+	if p.op.Kind != Subscription {
+		return nil, &Response{Errors: []*Error{errorf(p.op.Loc, "Only subscriptions open a stream.")}}
+	}
+
+	root := p.schema.Subscription
+	e := &executor{Prepared: p}
+	groups := e.collect(root, p.op.Selections)
+	if len(groups.keys) == 0 {
+		// Every root field was skipped.
+		return nil, &Response{Errors: []*Error{errorf(p.op.Loc, "Subscription selected no fields.")}}
+	}
+	key := groups.keys[0]
+	f := groups.byKey[key][0]
+	def := root.field(f.Name)
+
+	args, argErr := coerceArguments(def.Args, f.Arguments, p.vars, f.Loc)
+	if argErr != nil {
+		return nil, &Response{Errors: []*Error{argErr}}
+	}
+	if def.Subscribe == nil {
+		return nil, &Response{Errors: []*Error{errorf(f.Loc, "Field \"%s\" has no event stream.", def.Name)}}
+	}
+	source, err := def.Subscribe(ResolveParams{Context: ctx, Args: args})
+	if err != nil {
+		return nil, &Response{Errors: []*Error{fieldError(err, f.Loc, []any{key})}}
+	}
+
+	out := make(chan *Response)
+	go func() {
+		defer close(out)
+		for {
+			var event any
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-source:
+				if !ok {
+					return
+				}
+				event = ev
+			}
+
+			err, failed := event.(error)
+			resp := &Response{}
+			if failed {
+				resp.Errors = []*Error{fieldError(err, f.Loc, []any{key})}
+			} else {
+				resp = p.run(ctx, event, true)
+			}
+			select {
+			case out <- resp:
+			case <-ctx.Done():
+				return
+			}
+			if failed {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+type executor struct {
+	*Prepared
+	// event is set while executing a subscription event, which is the root value.
+	event bool
+	errs  []*Error
+}
+
+type fieldGroups struct {
+	keys  []string
+	byKey map[string][]*Field
+}
+
+// collect groups the fields of a selection set by response key, expanding fragments and
+// applying @skip and @include.
+func (e *executor) collect(t *Object, selections []Selection) *fieldGroups {
+	groups := &fieldGroups{byKey: map[string][]*Field{}}
+	visited := map[string]bool{}
+	var walk func(selections []Selection)
+	walk = func(selections []Selection) {
+		for _, sel := range selections {
+			switch sel := sel.(type) {
+			case *Field:
+				if !e.include(sel.Directives) {
+					continue
+				}
+				key := sel.ResponseKey()
+				if _, ok := groups.byKey[key]; !ok {
+					groups.keys = append(groups.keys, key)
+				}
+				groups.byKey[key] = append(groups.byKey[key], sel)
+			case *InlineFragment:
+				if e.include(sel.Directives) && (sel.TypeCondition == "" || sel.TypeCondition == t.Name) {
+					walk(sel.Selections)
+				}
+			case *FragmentSpread:
+				f := e.fragments[sel.Name]
+				if visited[sel.Name] || !e.include(sel.Directives) || f.TypeCondition != t.Name {
+					continue
+				}
+				visited[sel.Name] = true
+				walk(f.Selections)
+			}
+		}
+	}
+	walk(selections)
+	return groups
+}
+
+func (e *executor) include(list []*Directive) bool {
+	for _, d := range list {
+		args, err := coerceArguments(directives[d.Name], d.Arguments, e.vars, d.Loc)
+		if err != nil {
+			continue
+		}
+		if cond, _ := args["if"].(bool); cond == (d.Name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// selectionSet returns ok=false when a non-null field failed and the whole object is null.
+func (e *executor) selectionSet(ctx context.Context, t *Object, source any, selections []Selection, path []any) (*object, bool) {
+	groups := e.collect(t, selections)
+	result := &object{keys: groups.keys, values: make(map[string]any, len(groups.keys))}
+	for _, key := range groups.keys {
+		value, ok := e.field(ctx, t, source, groups.byKey[key], append(slices.Clip(path), key))
+		if !ok {
+			return nil, false
+		}
+		result.values[key] = value
+	}
+	return result, true
+}
+
+func (e *executor) field(ctx context.Context, t *Object, source any, fields []*Field, path []any) (any, bool) {
+	f := fields[0]
+	if f.Name == "__typename" {
+		return t.Name, true
+	}
+	def := t.field(f.Name)
+
+	args, argErr := coerceArguments(def.Args, f.Arguments, e.vars, f.Loc)
+	if argErr != nil {
+		argErr.Path = path
+		e.errs = append(e.errs, argErr)
+		return nil, !isNonNull(def.Type)
+	}
+
+	var result any
+	var err error
+	switch {
+	case def.Resolve != nil:
+		result, err = def.Resolve(ResolveParams{Context: ctx, Source: source, Args: args})
+	case e.event && len(path) == 1:
+		result = source
+	default:
+		if m, ok := source.(map[string]any); ok {
+			result = m[f.Name]
+		}
+	}
+	if err != nil {
+		e.errs = append(e.errs, fieldError(err, f.Loc, path))
+		return nil, !isNonNull(def.Type)
+	}
+	return e.complete(ctx, def.Type, fields, result, path)
+}
+
+func isNonNull(t Type) bool {
+	_, ok := t.(*NonNull)
+	return ok
+}
+
+// complete shapes a resolved value by its type. ok=false asks the parent to become null,
+// which happens when a non-null position ends up null.
+func (e *executor) complete(ctx context.Context, t Type, fields []*Field, result any, path []any) (any, bool) {
+	nonNull := isNonNull(t)
+	value, ok := e.completeNullable(ctx, nullable(t), fields, result, path)
+	if ok && value == nil && nonNull {
+		e.errs = append(e.errs, &Error{
+			Message:   "Cannot return null for non-nullable field.",
+			Locations: []Location{fields[0].Loc},
+			Path:      path,
+		})
+		ok = false
+	}
+	if !ok {
+		return nil, !nonNull
+	}
+	return value, true
+}
+
+// completeNullable returns ok=false after recording an error.
+func (e *executor) completeNullable(ctx context.Context, t Type, fields []*Field, result any, path []any) (any, bool) {
+	if isNil(result) {
+		return nil, true
+	}
+
+	switch t := t.(type) {
+	case *Scalar:
+		value, ok := t.serialize(result)
+		if !ok {
+			e.errs = append(e.errs, &Error{
+				Message:   t.Name + " cannot represent value: " + jsonString(result),
+				Locations: []Location{fields[0].Loc},
+				Path:      path,
+			})
+		}
+		return value, ok
+	case *Enum:
+		for _, v := range t.Values {
+			if v.Value == result {
+				return v.Name, true
+			}
+		}
+		e.errs = append(e.errs, &Error{
+			Message:   "Enum \"" + t.Name + "\" cannot represent value: " + jsonString(result),
+			Locations: []Location{fields[0].Loc},
+			Path:      path,
+		})
+		return nil, false
+	case *List:
+		items := reflect.ValueOf(result)
+		if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+			e.errs = append(e.errs, &Error{
+				Message:   "Expected a list for field of type \"" + t.String() + "\".",
+				Locations: []Location{fields[0].Loc},
+				Path:      path,
+			})
+			return nil, false
+		}
+		list := make([]any, items.Len())
+		for i := range list {
+			item, ok := e.complete(ctx, t.OfType, fields, items.Index(i).Interface(), append(slices.Clip(path), i))
+			if !ok {
+				return nil, false
+			}
+			list[i] = item
+		}
+		return list, true
+	case *Object:
+		var selections []Selection
+		for _, f := range fields {
+			selections = append(selections, f.Selections...)
+		}
+		obj, ok := e.selectionSet(ctx, t, result, selections, path)
+		if !ok {
+			return nil, false
+		}
+		return obj, true
+	}
+	return nil, false
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,425 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type codedError struct{ code string }
+
+func (e codedError) Error() string              { return "coded failure" }
+func (e codedError) Extensions() map[string]any { return map[string]any{"code": e.code} }
+
+var testItems = []map[string]any{
+	{"id": 1, "name": "milk", "kind": "red", "tags": []string{"dairy"}},
+	{"id": 2, "name": "bread", "kind": "blue", "tags": []string{}},
+	{"id": 3, "name": "eggs", "kind": "red", "tags": []string{"dairy", "fresh"}},
+}
+
+// testSchema builds a small schema over testItems; added collects the names passed to the
+// add mutation in call order.
+func testSchema(t *testing.T, added *[]string) *Schema {
+	t.Helper()
+
+	kind := &Enum{Name: "Kind", Description: "Colour of an item.", Values: []*EnumValueDef{
+		{Name: "RED", Value: "red"},
+		{Name: "BLUE", Value: "blue", Description: "Not red."},
+	}}
+	item := &Object{Name: "Item", Fields: []*FieldDef{
+		{Name: "id", Type: NonNullOf(ID)},
+		{Name: "name", Type: NonNullOf(String)},
+		{Name: "kind", Type: kind},
+		{Name: "tags", Type: NonNullOf(ListOf(NonNullOf(String)))},
+		{Name: "broken", Type: NonNullOf(String), Resolve: func(ResolveParams) (any, error) {
+			return nil, nil
+		}},
+		{Name: "fails", Type: String, DeprecationReason: "Always fails.", Resolve: func(ResolveParams) (any, error) {
+			return nil, codedError{"TEAPOT"}
+		}},
+	}}
+	echoInput := &InputObject{Name: "EchoInput", Fields: []*ArgumentDef{
+		{Name: "text", Type: NonNullOf(String)},
+		{Name: "times", Type: Int, Default: 1},
+	}}
+
+	query := &Object{Name: "Query", Fields: []*FieldDef{
+		{
+			Name: "item",
+			Type: item,
+			Args: []*ArgumentDef{{Name: "id", Type: NonNullOf(ID)}},
+			Resolve: func(p ResolveParams) (any, error) {
+				for _, it := range testItems {
+					if fmt.Sprint(it["id"]) == p.Args["id"] {
+						return it, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+		{
+			Name:        "items",
+			Description: "Items in order,\noptionally of one kind.",
+			Type:        NonNullOf(ListOf(NonNullOf(item))),
+			Args:        []*ArgumentDef{{Name: "kind", Type: kind}, {Name: "first", Type: Int, Default: 2}},
+			Resolve: func(p ResolveParams) (any, error) {
+				var result []map[string]any
+				for _, it := range testItems {
+					if k, ok := p.Args["kind"]; (!ok || k == nil || k == it["kind"]) && len(result) < p.Args["first"].(int) {
+						result = append(result, it)
+					}
+				}
+				return result, nil
+			},
+		},
+		{
+			Name: "echo",
+			Type: NonNullOf(String),
+			Args: []*ArgumentDef{{Name: "input", Type: NonNullOf(echoInput)}},
+			Resolve: func(p ResolveParams) (any, error) {
+				in := p.Args["input"].(map[string]any)
+				return strings.Repeat(in["text"].(string), in["times"].(int)), nil
+			},
+		},
+	}}
+	mutation := &Object{Name: "Mutation", Fields: []*FieldDef{{
+		Name: "add",
+		Type: NonNullOf(item),
+		Args: []*ArgumentDef{{Name: "name", Type: NonNullOf(String)}},
+		Resolve: func(p ResolveParams) (any, error) {
+			name := p.Args["name"].(string)
+			*added = append(*added, name)
+			return map[string]any{"id": len(*added), "name": name, "tags": []string{}}, nil
+		},
+	}}}
+	subscription := &Object{Name: "Subscription", Fields: []*FieldDef{
+		{
+			Name: "ticks",
+			Type: NonNullOf(item),
+			Args: []*ArgumentDef{{Name: "count", Type: NonNullOf(Int)}},
+			Subscribe: func(p ResolveParams) (<-chan any, error) {
+				ch := make(chan any, p.Args["count"].(int))
+				for _, it := range testItems[:p.Args["count"].(int)] {
+					ch <- it
+				}
+				close(ch)
+				return ch, nil
+			},
+		},
+		{
+			Name: "failing",
+			Type: item,
+			Subscribe: func(ResolveParams) (<-chan any, error) {
+				ch := make(chan any, 2)
+				ch <- testItems[0]
+				ch <- codedError{"GONE"}
+				return ch, nil
+			},
+		},
+		{
+			Name: "refused",
+			Type: item,
+			Subscribe: func(ResolveParams) (<-chan any, error) {
+				return nil, codedError{"FORBIDDEN"}
+			},
+		},
+	}}
+
+	schema, err := NewSchema(query, mutation, subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func decodeVariables(t *testing.T, data string) map[string]any {
+	t.Helper()
+	if data == "" {
+		return nil
+	}
+	var vars map[string]any
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	return vars
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables string
+		operation string
+		want      string
+	}{
+		{
+			name:  "scalars, enums and lists",
+			query: `{ item(id: 1) { id name kind tags } }`,
+			want:  `{"data":{"item":{"id":"1","name":"milk","kind":"RED","tags":["dairy"]}}}`,
+		},
+		{
+			name:  "aliases, fragments and typename",
+			query: `{ b: item(id: "2") { ...F } a: item(id: 1) { name ... on Item { id } } none: item(id: 9) { name } } fragment F on Item { __typename name }`,
+			want:  `{"data":{"b":{"__typename":"Item","name":"bread"},"a":{"name":"milk","id":"1"},"none":null}}`,
+		},
+		{
+			name:  "merged fields",
+			query: `{ item(id: 1) { name } item(id: 1) { tags } }`,
+			want:  `{"data":{"item":{"name":"milk","tags":["dairy"]}}}`,
+		},
+		{
+			name:  "argument default",
+			query: `{ items { name } }`,
+			want:  `{"data":{"items":[{"name":"milk"},{"name":"bread"}]}}`,
+		},
+		{
+			name:      "enum variable",
+			query:     `query ($k: Kind, $n: Int = 10) { items(kind: $k, first: $n) { name } }`,
+			variables: `{"k": "RED"}`,
+			want:      `{"data":{"items":[{"name":"milk"},{"name":"eggs"}]}}`,
+		},
+		{
+			name:      "skip and include",
+			query:     `query ($s: Boolean!) { item(id: 1) { name @skip(if: $s) kind @include(if: $s) id @include(if: false) } }`,
+			variables: `{"s": true}`,
+			want:      `{"data":{"item":{"kind":"RED"}}}`,
+		},
+		{
+			name:  "input object literal with default",
+			query: `{ echo(input: {text: "ab"}) }`,
+			want:  `{"data":{"echo":"ab"}}`,
+		},
+		{
+			name:      "input object variable",
+			query:     `query ($in: EchoInput!) { echo(input: $in) }`,
+			variables: `{"in": {"text": "x", "times": 3}}`,
+			want:      `{"data":{"echo":"xxx"}}`,
+		},
+		{
+			name:      "variable inside a literal",
+			query:     `query ($t: String!, $n: Int) { echo(input: {text: $t, times: $n}) }`,
+			variables: `{"t": "yo"}`,
+			want:      `{"data":{"echo":"yo"}}`,
+		},
+		{
+			name:      "operation name",
+			query:     `query A { item(id: 1) { name } } query B { item(id: 2) { name } }`,
+			operation: "B",
+			want:      `{"data":{"item":{"name":"bread"}}}`,
+		},
+		{
+			name:  "resolver error with extensions",
+			query: `{ item(id: 1) { name fails } }`,
+			want:  `{"data":{"item":{"name":"milk","fails":null}},"errors":[{"message":"coded failure","locations":[{"line":1,"column":22}],"path":["item","fails"],"extensions":{"code":"TEAPOT"}}]}`,
+		},
+		{
+			name:  "null in a non-null field nulls the parent",
+			query: `{ item(id: 1) { name broken } }`,
+			want:  `{"data":{"item":null},"errors":[{"message":"Cannot return null for non-nullable field.","locations":[{"line":1,"column":22}],"path":["item","broken"]}]}`,
+		},
+		{
+			name:  "null propagates up to data",
+			query: `{ items { broken } }`,
+			want:  `{"data":null,"errors":[{"message":"Cannot return null for non-nullable field.","locations":[{"line":1,"column":11}],"path":["items",0,"broken"]}]}`,
+		},
+		{
+			name:  "syntax error",
+			query: `{ item(id: 1) { name }`,
+			want:  `{"errors":[{"message":"Syntax Error: Expected Name, found \u003cEOF\u003e.","locations":[{"line":1,"column":23}]}]}`,
+		},
+		{
+			name:  "validation error",
+			query: `{ item(id: 1) { nope } }`,
+			want:  `{"errors":[{"message":"Cannot query field \"nope\" on type \"Item\".","locations":[{"line":1,"column":17}]}]}`,
+		},
+		{
+			name:  "missing variable",
+			query: `query ($id: ID!) { item(id: $id) { name } }`,
+			want:  `{"errors":[{"message":"Variable \"$id\" of required type \"ID!\" was not provided.","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:      "invalid variable",
+			query:     `query ($n: Int) { items(first: $n) { name } }`,
+			variables: `{"n": 3000000000}`,
+			want:      `{"errors":[{"message":"Variable \"$n\" got invalid value 3000000000; Int cannot represent 3000000000","locations":[{"line":1,"column":8}]}]}`,
+		},
+		{
+			name:  "ambiguous operation",
+			query: `query A { items { name } } query B { items { name } }`,
+			want:  `{"errors":[{"message":"Must provide operation name if query contains multiple operations."}]}`,
+		},
+		{
+			name:      "unknown operation",
+			query:     `query A { items { name } }`,
+			operation: "C",
+			want:      `{"errors":[{"message":"Unknown operation named \"C\"."}]}`,
+		},
+		{
+			name:  "subscription without a stream",
+			query: `subscription { ticks(count: 1) { name } }`,
+			want:  `{"errors":[{"message":"Subscriptions need a streaming transport such as a websocket.","locations":[{"line":1,"column":1}]}]}`,
+		},
+	}
+
+	var added []string
+	schema := testSchema(t, &added)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := schema.Execute(context.Background(), Request{
+				Query:         tt.query,
+				OperationName: tt.operation,
+				Variables:     decodeVariables(t, tt.variables),
+			})
+			got, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecuteMutationsInOrder(t *testing.T) {
+	var added []string
+	schema := testSchema(t, &added)
+
+	resp := schema.Execute(context.Background(), Request{Query: `mutation {
+		b: add(name: "b") { id }
+		a: add(name: "a") { id }
+		c: add(name: "c") { id name }
+	}`})
+	got, _ := json.Marshal(resp)
+	if want := `{"data":{"b":{"id":"1"},"a":{"id":"2"},"c":{"id":"3","name":"c"}}}`; string(got) != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if strings.Join(added, "") != "bac" {
+		t.Errorf("expected mutations in document order, got %v", added)
+	}
+
+	added = nil
+	resp = schema.Execute(context.Background(), Request{Query: `mutation { add(name: "x") { nope } }`})
+	if resp.Executed() || len(added) != 0 {
+		t.Errorf("expected an invalid mutation not to run, got %v", added)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	schema := testSchema(t, new([]string))
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+		err   string
+	}{
+		{
+			name:  "every event",
+			query: `subscription { ticks(count: 2) { name } }`,
+			want:  []string{`{"data":{"ticks":{"name":"milk"}}}`, `{"data":{"ticks":{"name":"bread"}}}`},
+		},
+		{
+			name:  "error event ends the stream",
+			query: `subscription S { alias: failing { id } }`,
+			want: []string{
+				`{"data":{"alias":{"id":"1"}}}`,
+				`{"errors":[{"message":"coded failure","locations":[{"line":1,"column":18}],"path":["alias"],"extensions":{"code":"GONE"}}]}`,
+			},
+		},
+		{
+			name:  "refused stream",
+			query: `subscription { refused { id } }`,
+			err:   `{"errors":[{"message":"coded failure","locations":[{"line":1,"column":16}],"path":["refused"],"extensions":{"code":"FORBIDDEN"}}]}`,
+		},
+		{
+			name:  "all fields skipped",
+			query: `subscription { ticks(count: 1) @skip(if: true) { id } }`,
+			err:   `{"errors":[{"message":"Subscription selected no fields.","locations":[{"line":1,"column":1}]}]}`,
+		},
+		{
+			name:  "not a subscription",
+			query: `{ items { id } }`,
+			err:   `{"errors":[{"message":"Only subscriptions open a stream.","locations":[{"line":1,"column":1}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, resp := schema.Prepare(Request{Query: tt.query})
+			if resp != nil {
+				t.Fatalf("unexpected response %v", resp.Errors)
+			}
+			events, resp := p.Subscribe(context.Background())
+			if tt.err != "" {
+				got, _ := json.Marshal(resp)
+				if string(got) != tt.err {
+					t.Errorf("expected %s, got %s", tt.err, got)
+				}
+				return
+			}
+
+			var got []string
+			for resp := range events {
+				data, _ := json.Marshal(resp)
+				got = append(got, string(data))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestSubscribeStopsOnCancel(t *testing.T) {
+	source := make(chan any)
+	sub := &Object{Name: "Subscription", Fields: []*FieldDef{{
+		Name: "forever",
+		Type: String,
+		Subscribe: func(ResolveParams) (<-chan any, error) {
+			return source, nil
+		},
+	}}}
+	query := &Object{Name: "Query", Fields: []*FieldDef{{Name: "ok", Type: Boolean}}}
+	schema, err := NewSchema(query, nil, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, resp := schema.Prepare(Request{Query: `subscription { forever }`})
+	if resp != nil {
+		t.Fatal(resp.Errors)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events, _ := p.Subscribe(ctx)
+
+	source <- "tick"
+	if resp := <-events; resp.Data.(*object).values["forever"] != "tick" {
+		t.Errorf("unexpected event %+v", resp.Data)
+	}
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected no more events")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the stream did not end")
+	}
+}
+
+func TestFieldErrorKeepsCause(t *testing.T) {
+	var added []string
+	schema := testSchema(t, &added)
+	resp := schema.Execute(context.Background(), Request{Query: `{ item(id: 1) { fails } }`})
+	var coded codedError
+	if len(resp.Errors) != 1 || !errors.As(resp.Errors[0], &coded) || coded.code != "TEAPOT" {
+		t.Errorf("expected the resolver error to be kept, got %v", resp.Errors)
+	}
+}
@@ -0,0 +1,299 @@
+package graphql
+
+// maxDepth bounds the nesting of selections and values so hostile documents cannot exhaust the stack.
+const maxDepth = 64
+
+type parser struct {
+	lex   *lexer
+	tok   token
+	depth int
+}
+
+// Parse reads an executable document. Type system definitions are rejected; the schema is
+// built in Go.
+func Parse(source string) (doc *Document, err error) {
+	p := &parser{lex: newLexer(source)}
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, syntaxErr
+		}
+	}()
+
+	p.advance()
+	doc = &Document{}
+	if p.tok.kind == tokEOF {
+		p.fail(p.tok.loc, "Unexpected <EOF>.")
+	}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek(tokPunct, "{"):
+			op := &Operation{Kind: Query, Loc: p.tok.loc}
+			op.Selections = p.selectionSet()
+			doc.Operations = append(doc.Operations, op)
+		case p.peek(tokName, "query"), p.peek(tokName, "mutation"), p.peek(tokName, "subscription"):
+			doc.Operations = append(doc.Operations, p.operation())
+		case p.peek(tokName, "fragment"):
+			doc.Fragments = append(doc.Fragments, p.fragment())
+		default:
+			p.unexpected()
+		}
+	}
+	return doc, nil
+}
+
+// fail aborts parsing; Parse turns the panic back into an error.
+func (p *parser) fail(loc Location, message string) {
+	panic(&Error{Message: "Syntax Error: " + message, Locations: []Location{loc}})
+}
+
+func (p *parser) unexpected() {
+	p.fail(p.tok.loc, "Unexpected "+p.tok.String()+".")
+}
+
+func (p *parser) advance() {
+	tok, err := p.lex.next()
+	if err != nil {
+		panic(err)
+	}
+	p.tok = tok
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+// skip consumes the punctuator if it is next.
+func (p *parser) skip(value string) bool {
+	if p.peek(tokPunct, value) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(value string) {
+	if !p.skip(value) {
+		p.fail(p.tok.loc, "Expected \""+value+"\", found "+p.tok.String()+".")
+	}
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokName {
+		p.fail(p.tok.loc, "Expected Name, found "+p.tok.String()+".")
+	}
+	name := p.tok.value
+	p.advance()
+	return name
+}
+
+func (p *parser) enter() {
+	if p.depth++; p.depth > maxDepth {
+		p.fail(p.tok.loc, "Document is nested too deeply.")
+	}
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) operation() *Operation {
+	op := &Operation{Kind: OperationKind(p.tok.value), Loc: p.tok.loc}
+	p.advance()
+	if p.tok.kind == tokName {
+		op.Name = p.name()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			op.Variables = append(op.Variables, p.variableDefinition())
+		}
+		if len(op.Variables) == 0 {
+			p.fail(op.Loc, "Expected a variable definition.")
+		}
+	}
+	op.Directives = p.directives(false)
+	op.Selections = p.selectionSet()
+	return op
+}
+
+func (p *parser) variableDefinition() *VariableDefinition {
+	def := &VariableDefinition{Loc: p.tok.loc}
+	p.expect("$")
+	def.Name = p.name()
+	p.expect(":")
+	def.Type = p.typeRef()
+	if p.skip("=") {
+		def.Default = p.value(true)
+	}
+	p.directives(true)
+	return def
+}
+
+func (p *parser) typeRef() *TypeRef {
+	p.enter()
+	defer p.leave()
+
+	t := &TypeRef{}
+	if p.skip("[") {
+		t.Elem = p.typeRef()
+		p.expect("]")
+	} else {
+		t.Name = p.name()
+	}
+	t.NonNull = p.skip("!")
+	return t
+}
+
+func (p *parser) fragment() *Fragment {
+	f := &Fragment{Loc: p.tok.loc}
+	p.advance()
+	if p.peek(tokName, "on") {
+		p.unexpected()
+	}
+	f.Name = p.name()
+	if !p.peek(tokName, "on") {
+		p.fail(p.tok.loc, "Expected \"on\", found "+p.tok.String()+".")
+	}
+	p.advance()
+	f.TypeCondition = p.name()
+	f.Directives = p.directives(false)
+	f.Selections = p.selectionSet()
+	return f
+}
+
+func (p *parser) selectionSet() []Selection {
+	p.enter()
+	defer p.leave()
+
+	p.expect("{")
+	if p.peek(tokPunct, "}") {
+		p.fail(p.tok.loc, "Expected a selection.")
+	}
+	var selections []Selection
+	for !p.skip("}") {
+		selections = append(selections, p.selection())
+	}
+	return selections
+}
+
+func (p *parser) selection() Selection {
+	loc := p.tok.loc
+	if !p.skip("...") {
+		return p.field()
+	}
+
+	if p.tok.kind == tokName && p.tok.value != "on" {
+		return &FragmentSpread{Name: p.name(), Directives: p.directives(false), Loc: loc}
+	}
+	f := &InlineFragment{Loc: loc}
+	if p.peek(tokName, "on") {
+		p.advance()
+		f.TypeCondition = p.name()
+	}
+	f.Directives = p.directives(false)
+	f.Selections = p.selectionSet()
+	return f
+}
+
+func (p *parser) field() *Field {
+	f := &Field{Loc: p.tok.loc}
+	f.Name = p.name()
+	if p.skip(":") {
+		f.Alias, f.Name = f.Name, p.name()
+	}
+	f.Arguments = p.arguments(false)
+	f.Directives = p.directives(false)
+	if p.peek(tokPunct, "{") {
+		f.Selections = p.selectionSet()
+	}
+	return f
+}
+
+func (p *parser) arguments(constant bool) []*Argument {
+	if !p.skip("(") {
+		return nil
+	}
+	var args []*Argument
+	for !p.skip(")") {
+		arg := &Argument{Loc: p.tok.loc}
+		arg.Name = p.name()
+		p.expect(":")
+		arg.Value = p.value(constant)
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		p.fail(p.tok.loc, "Expected an argument.")
+	}
+	return args
+}
+
+func (p *parser) directives(constant bool) []*Directive {
+	var directives []*Directive
+	for p.peek(tokPunct, "@") {
+		d := &Directive{Loc: p.tok.loc}
+		p.advance()
+		d.Name = p.name()
+		d.Arguments = p.arguments(constant)
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+// value reads a literal; constant values may not reference variables.
+func (p *parser) value(constant bool) *Value {
+	p.enter()
+	defer p.leave()
+
+	v := &Value{Loc: p.tok.loc, Raw: p.tok.value}
+	switch p.tok.kind {
+	case tokInt:
+		v.Kind = IntValue
+	case tokFloat:
+		v.Kind = FloatValue
+	case tokString:
+		v.Kind = StringValue
+	case tokName:
+		switch v.Raw {
+		case "true", "false":
+			v.Kind = BooleanValue
+		case "null":
+			v.Kind = NullValue
+		default:
+			v.Kind = EnumValue
+		}
+	case tokPunct:
+		switch v.Raw {
+		case "$":
+			if constant {
+				p.unexpected()
+			}
+			p.advance()
+			v.Kind, v.Raw = VariableValue, p.name()
+			return v
+		case "[":
+			p.advance()
+			v.Kind = ListValue
+			for !p.skip("]") {
+				v.List = append(v.List, p.value(constant))
+			}
+			return v
+		case "{":
+			p.advance()
+			v.Kind = ObjectValue
+			for !p.skip("}") {
+				name := p.name()
+				p.expect(":")
+				v.Fields = append(v.Fields, &ObjectField{Name: name, Value: p.value(constant)})
+			}
+			return v
+		}
+		p.unexpected()
+	default:
+		p.unexpected()
+	}
+	p.advance()
+	return v
+}
@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# Comments and commas are ignored.
+		query Tasks($first: Int = 10, $ids: [ID!]!) @skip(if: false) {
+			list: tasks(first: $first, filter: {query: "milk", statuses: [ASSIGNED, DROPPED]}) {
+				...TaskFields
+				... on Task @include(if: true) { owner }
+				... { id }
+			}
+		}
+		fragment TaskFields on Task { id, header }
+		{ short }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Operations) != 2 || len(doc.Fragments) != 1 {
+		t.Fatalf("expected 2 operations and 1 fragment, got %d and %d", len(doc.Operations), len(doc.Fragments))
+	}
+
+	op := doc.Operations[0]
+	if op.Kind != Query || op.Name != "Tasks" || op.Loc != (Location{Line: 3, Column: 3}) {
+		t.Errorf("unexpected operation %+v", op)
+	}
+	if got := op.Variables[1].Type.String(); got != "[ID!]!" {
+		t.Errorf("expected type [ID!]!, got %s", got)
+	}
+	if got := op.Variables[0].Default.String(); got != "10" {
+		t.Errorf("expected default 10, got %s", got)
+	}
+
+	list := op.Selections[0].(*Field)
+	if list.ResponseKey() != "list" || list.Name != "tasks" {
+		t.Errorf("unexpected field %+v", list)
+	}
+	if got := list.Arguments[1].Value.String(); got != `{query: "milk", statuses: [ASSIGNED, DROPPED]}` {
+		t.Errorf("unexpected argument %s", got)
+	}
+	if _, ok := list.Selections[0].(*FragmentSpread); !ok {
+		t.Errorf("expected a fragment spread, got %T", list.Selections[0])
+	}
+	if inline := list.Selections[1].(*InlineFragment); inline.TypeCondition != "Task" || inline.Directives[0].Name != "include" {
+		t.Errorf("unexpected inline fragment %+v", inline)
+	}
+	if inline := list.Selections[2].(*InlineFragment); inline.TypeCondition != "" {
+		t.Errorf("expected no type condition, got %q", inline.TypeCondition)
+	}
+	if short := doc.Operations[1]; short.Kind != Query || short.Name != "" {
+		t.Errorf("unexpected shorthand operation %+v", short)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		literal string
+		kind    ValueKind
+		raw     string
+	}{
+		{`-12`, IntValue, "-12"},
+		{`0`, IntValue, "0"},
+		{`1.5e-3`, FloatValue, "1.5e-3"},
+		{`"a\"b\\cé\u{1F600}😀"`, StringValue, "a\"b\\cé😀😀"},
+		{"\"\"\"\n    first\n      second\n  \"\"\"", StringValue, "first\n  second"},
+		{`true`, BooleanValue, "true"},
+		{`null`, NullValue, "null"},
+		{`IN_PROGRESS`, EnumValue, "IN_PROGRESS"},
+		{`$id`, VariableValue, "id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.literal, func(t *testing.T) {
+			doc, err := Parse("{ f(a: " + tt.literal + ") }")
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := doc.Operations[0].Selections[0].(*Field).Arguments[0].Value
+			if v.Kind != tt.kind || v.Raw != tt.raw {
+				t.Errorf("expected %v %q, got %v %q", tt.kind, tt.raw, v.Kind, v.Raw)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		message string
+		loc     Location
+	}{
+		{``, "Syntax Error: Unexpected <EOF>.", Location{1, 1}},
+		{`{ a`, "Syntax Error: Expected Name, found <EOF>.", Location{1, 4}},
+		{`{}`, "Syntax Error: Expected a selection.", Location{1, 2}},
+		{"{\n  a(b: 01) }", "Syntax Error: Invalid number, unexpected digit after 0.", Location{2, 9}},
+		{`{ a(b: 1.) }`, "Syntax Error: Invalid number, expected digit after \".\".", Location{1, 10}},
+		{`{ a(b: "x) }`, "Syntax Error: Unterminated string.", Location{1, 13}},
+		{`{ a(b: "\q") }`, `Syntax Error: Invalid character escape sequence: \q.`, Location{1, 9}},
+		{`{ a(b: "\ud800") }`, "Syntax Error: Invalid Unicode escape sequence.", Location{1, 9}},
+		{`{ a(b: ?) }`, "Syntax Error: Unexpected character '?'.", Location{1, 8}},
+		{`fragment on on T { a }`, "Syntax Error: Unexpected on.", Location{1, 10}},
+		{`query ($a: Int = $b) { a }`, "Syntax Error: Unexpected $.", Location{1, 18}},
+		{`type Query { a: Int }`, "Syntax Error: Unexpected type.", Location{1, 1}},
+		{"{" + strings.Repeat("a {", 100) + "b" + strings.Repeat("}", 101), "Syntax Error: Document is nested too deeply.", Location{1, 193}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			var gqlErr *Error
+			if !errors.As(err, &gqlErr) {
+				t.Fatalf("expected a syntax error, got %v", err)
+			}
+			if gqlErr.Message != tt.message || !reflect.DeepEqual(gqlErr.Locations, []Location{tt.loc}) {
+				t.Errorf("expected %q at %v, got %q at %v", tt.message, tt.loc, gqlErr.Message, gqlErr.Locations)
+			}
+		})
+	}
+}
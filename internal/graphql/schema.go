@@ -0,0 +1,497 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Type is a *Scalar, *Enum, *Object, *InputObject, *List or *NonNull.
+type Type interface {
+	String() string
+}
+
+// Scalar converts leaf values: serialize for results, parse for variables decoded from JSON,
+// parseLiteral for values written in the query. The converters report false for values they
+// cannot represent.
+type Scalar struct {
+	Name         string
+	Description  string
+	serialize    func(v any) (any, bool)
+	parse        func(v any) (any, bool)
+	parseLiteral func(v *Value) (any, bool)
+}
+
+type Enum struct {
+	Name        string
+	Description string
+	Values      []*EnumValueDef
+}
+
+// EnumValueDef maps a GraphQL name to the Go value resolvers return and receive.
+type EnumValueDef struct {
+	Name        string
+	Description string
+	Value       any
+}
+
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*FieldDef
+}
+
+// FieldDef is a field of an object type. Resolve defaults to reading Name from a
+// map[string]any source. Subscribe is only used on the subscription root: it opens the source
+// stream, and Resolve, when set, maps each event to the field value.
+type FieldDef struct {
+	Name              string
+	Description       string
+	Type              Type
+	Args              []*ArgumentDef
+	Resolve           ResolveFunc
+	Subscribe         SubscribeFunc
+	DeprecationReason string
+}
+
+// ArgumentDef is an argument of a field or a field of an input object. Default is a Go value
+// as resolvers receive it.
+type ArgumentDef struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     any
+}
+
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      []*ArgumentDef
+}
+
+type List struct {
+	OfType Type
+}
+
+type NonNull struct {
+	OfType Type
+}
+
+func ListOf(t Type) *List {
+	return &List{OfType: t}
+}
+
+func NonNullOf(t Type) *NonNull {
+	return &NonNull{OfType: t}
+}
+
+func (t *Scalar) String() string      { return t.Name }
+func (t *Enum) String() string        { return t.Name }
+func (t *Object) String() string      { return t.Name }
+func (t *InputObject) String() string { return t.Name }
+func (t *List) String() string        { return "[" + t.OfType.String() + "]" }
+func (t *NonNull) String() string     { return t.OfType.String() + "!" }
+
+func (t *Object) field(name string) *FieldDef {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (t *Enum) byName(name string) (*EnumValueDef, bool) {
+	for _, v := range t.Values {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// ResolveParams is what resolvers get: the parent value, the coerced arguments (absent
+// arguments without a default are missing from Args) and the request context.
+type ResolveParams struct {
+	Context context.Context
+	Source  any
+	Args    map[string]any
+}
+
+type ResolveFunc func(p ResolveParams) (any, error)
+
+// SubscribeFunc opens an event stream that ends when the channel is closed or the context is
+// cancelled. An error sent as an event ends the subscription with that error.
+type SubscribeFunc func(p ResolveParams) (<-chan any, error)
+
+// Schema is the root operation types and every named type reachable from them.
+type Schema struct {
+	Query        *Object
+	Mutation     *Object
+	Subscription *Object
+	types        []Type
+	typesByName  map[string]Type
+}
+
+// NewSchema collects the reachable types; mutation and subscription may be nil.
+func NewSchema(query, mutation, subscription *Object) (*Schema, error) {
+	if query == nil {
+		return nil, fmt.Errorf("schema needs a query type")
+	}
+	s := &Schema{Query: query, Mutation: mutation, Subscription: subscription, typesByName: map[string]Type{}}
+	for _, t := range []Type{Int, Float, String, Boolean, ID} {
+		s.typesByName[t.String()] = t
+	}
+	for _, root := range []*Object{query, mutation, subscription} {
+		if root != nil {
+			if err := s.collect(root); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) collect(t Type) error {
+	switch t := t.(type) {
+	case *List:
+		return s.collect(t.OfType)
+	case *NonNull:
+		return s.collect(t.OfType)
+	}
+
+	name := t.String()
+	if existing, ok := s.typesByName[name]; ok {
+		if existing != t {
+			return fmt.Errorf("two different types are named %s", name)
+		}
+		return nil
+	}
+	s.typesByName[name] = t
+	s.types = append(s.types, t)
+
+	switch t := t.(type) {
+	case *Object:
+		for _, f := range t.Fields {
+			if err := s.collect(f.Type); err != nil {
+				return err
+			}
+			for _, arg := range f.Args {
+				if err := s.collectInput(arg.Type); err != nil {
+					return err
+				}
+			}
+		}
+	case *InputObject:
+		for _, f := range t.Fields {
+			if err := s.collectInput(f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) collectInput(t Type) error {
+	if !isInputType(t) {
+		return fmt.Errorf("%s cannot be used as an input type", t)
+	}
+	return s.collect(t)
+}
+
+// typeOf resolves a type reference of a variable definition.
+func (s *Schema) typeOf(ref *TypeRef) Type {
+	var t Type
+	if ref.Elem != nil {
+		elem := s.typeOf(ref.Elem)
+		if elem == nil {
+			return nil
+		}
+		t = ListOf(elem)
+	} else if t = s.typesByName[ref.Name]; t == nil {
+		return nil
+	}
+	if ref.NonNull {
+		t = NonNullOf(t)
+	}
+	return t
+}
+
+func named(t Type) Type {
+	for {
+		switch wrapper := t.(type) {
+		case *List:
+			t = wrapper.OfType
+		case *NonNull:
+			t = wrapper.OfType
+		default:
+			return t
+		}
+	}
+}
+
+func isInputType(t Type) bool {
+	switch named(t).(type) {
+	case *Scalar, *Enum, *InputObject:
+		return true
+	}
+	return false
+}
+
+func isLeafType(t Type) bool {
+	switch named(t).(type) {
+	case *Scalar, *Enum:
+		return true
+	}
+	return false
+}
+
+// SDL prints the schema in the GraphQL schema definition language.
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	if s.Query.Name != "Query" || (s.Mutation != nil && s.Mutation.Name != "Mutation") ||
+		(s.Subscription != nil && s.Subscription.Name != "Subscription") {
+		b.WriteString("schema {\n  query: " + s.Query.Name + "\n")
+		if s.Mutation != nil {
+			b.WriteString("  mutation: " + s.Mutation.Name + "\n")
+		}
+		if s.Subscription != nil {
+			b.WriteString("  subscription: " + s.Subscription.Name + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	for i, t := range s.types {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch t := t.(type) {
+		case *Scalar:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("scalar " + t.Name + "\n")
+		case *Enum:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("enum " + t.Name + " {\n")
+			for _, v := range t.Values {
+				writeDescription(&b, "  ", v.Description)
+				b.WriteString("  " + v.Name + "\n")
+			}
+			b.WriteString("}\n")
+		case *Object:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("type " + t.Name + " {\n")
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + f.Name)
+				if len(f.Args) > 0 {
+					b.WriteString("(")
+					for j, arg := range f.Args {
+						if j > 0 {
+							b.WriteString(", ")
+						}
+						b.WriteString(inputValueSDL(arg))
+					}
+					b.WriteString(")")
+				}
+				b.WriteString(": " + f.Type.String())
+				if f.DeprecationReason != "" {
+					b.WriteString(" @deprecated(reason: " + quote(f.DeprecationReason) + ")")
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("}\n")
+		case *InputObject:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("input " + t.Name + " {\n")
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + inputValueSDL(f) + "\n")
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	if !strings.Contains(description, "\n") {
+		b.WriteString(indent + quote(description) + "\n")
+		return
+	}
+	b.WriteString(indent + `"""` + "\n")
+	for _, line := range strings.Split(description, "\n") {
+		b.WriteString(indent + strings.ReplaceAll(line, `"""`, `\"""`) + "\n")
+	}
+	b.WriteString(indent + `"""` + "\n")
+}
+
+func inputValueSDL(arg *ArgumentDef) string {
+	s := arg.Name + ": " + arg.Type.String()
+	if arg.Default != nil {
+		s += " = " + literal(arg.Type, arg.Default)
+	}
+	return s
+}
+
+// literal prints a Go input value in GraphQL syntax.
+func literal(t Type, v any) string {
+	if nn, ok := t.(*NonNull); ok {
+		t = nn.OfType
+	}
+	switch t := t.(type) {
+	case *Enum:
+		for _, ev := range t.Values {
+			if ev.Value == v {
+				return ev.Name
+			}
+		}
+	case *List:
+		if items, ok := v.([]any); ok {
+			parts := make([]string, len(items))
+			for i, item := range items {
+				parts[i] = literal(t.OfType, item)
+			}
+			return "[" + strings.Join(parts, ", ") + "]"
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Built-in scalars. Int is 32-bit as the specification requires; ID results accept strings
+// and integers and are always sent as strings.
+var (
+	Int = &Scalar{
+		Name:      "Int",
+		serialize: toInt32,
+		parse:     toInt32,
+		parseLiteral: func(v *Value) (any, bool) {
+			if v.Kind != IntValue {
+				return nil, false
+			}
+			n, err := strconv.ParseInt(v.Raw, 10, 32)
+			return int(n), err == nil
+		},
+	}
+	Float = &Scalar{
+		Name:      "Float",
+		serialize: toFloat,
+		parse:     toFloat,
+		parseLiteral: func(v *Value) (any, bool) {
+			if v.Kind != IntValue && v.Kind != FloatValue {
+				return nil, false
+			}
+			f, err := strconv.ParseFloat(v.Raw, 64)
+			return f, err == nil && !math.IsInf(f, 0)
+		},
+	}
+	String = &Scalar{
+		Name: "String",
+		serialize: func(v any) (any, bool) {
+			s, ok := v.(string)
+			return s, ok
+		},
+		parse: func(v any) (any, bool) {
+			s, ok := v.(string)
+			return s, ok
+		},
+		parseLiteral: func(v *Value) (any, bool) {
+			return v.Raw, v.Kind == StringValue
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		serialize: func(v any) (any, bool) {
+			b, ok := v.(bool)
+			return b, ok
+		},
+		parse: func(v any) (any, bool) {
+			b, ok := v.(bool)
+			return b, ok
+		},
+		parseLiteral: func(v *Value) (any, bool) {
+			return v.Raw == "true", v.Kind == BooleanValue
+		},
+	}
+	ID = &Scalar{
+		Name:      "ID",
+		serialize: toID,
+		parse:     toID,
+		parseLiteral: func(v *Value) (any, bool) {
+			return v.Raw, v.Kind == StringValue || v.Kind == IntValue
+		},
+	}
+)
+
+func toInt32(v any) (any, bool) {
+	var n int64
+	switch v := v.(type) {
+	case int:
+		n = int64(v)
+	case int32:
+		n = int64(v)
+	case int64:
+		n = v
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > math.MaxInt32+1 {
+			return nil, false
+		}
+		n = int64(v)
+	case json.Number:
+		var err error
+		if n, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		return nil, false
+	}
+	return int(n), true
+}
+
+func toFloat(v any) (any, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return nil, false
+}
+
+func toID(v any) (any, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case int, int32, int64:
+		return fmt.Sprint(v), true
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return string(v), true
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return strconv.FormatInt(int64(v), 10), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,114 @@
+package graphql
+
+import (
+	"testing"
+)
+
+func TestSDL(t *testing.T) {
+	schema := testSchema(t, new([]string))
+
+	want := `type Query {
+  item(id: ID!): Item
+  """
+  Items in order,
+  optionally of one kind.
+  """
+  items(kind: Kind, first: Int = 2): [Item!]!
+  echo(input: EchoInput!): String!
+}
+
+type Item {
+  id: ID!
+  name: String!
+  kind: Kind
+  tags: [String!]!
+  broken: String!
+  fails: String @deprecated(reason: "Always fails.")
+}
+
+"Colour of an item."
+enum Kind {
+  RED
+  "Not red."
+  BLUE
+}
+
+input EchoInput {
+  text: String!
+  times: Int = 1
+}
+
+type Mutation {
+  add(name: String!): Item!
+}
+
+type Subscription {
+  ticks(count: Int!): Item!
+  failing: Item
+  refused: Item
+}
+`
+	if got := schema.SDL(); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestNewSchemaErrors(t *testing.T) {
+	item := &Object{Name: "Item", Fields: []*FieldDef{{Name: "id", Type: ID}}}
+	other := &Object{Name: "Item", Fields: []*FieldDef{{Name: "name", Type: String}}}
+
+	tests := []struct {
+		name  string
+		query *Object
+		want  string
+	}{
+		{"no query", nil, "schema needs a query type"},
+		{"duplicate names", &Object{Name: "Query", Fields: []*FieldDef{
+			{Name: "a", Type: item},
+			{Name: "b", Type: other},
+		}}, "two different types are named Item"},
+		{"object argument", &Object{Name: "Query", Fields: []*FieldDef{
+			{Name: "a", Type: ID, Args: []*ArgumentDef{{Name: "x", Type: item}}},
+		}}, "Item cannot be used as an input type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSchema(tt.query, nil, nil)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestScalars(t *testing.T) {
+	tests := []struct {
+		name   string
+		scalar *Scalar
+		in     any
+		want   any
+		ok     bool
+	}{
+		{"int", Int, 7, 7, true},
+		{"int from float", Int, 7.0, 7, true},
+		{"int fraction", Int, 7.5, nil, false},
+		{"int overflow", Int, int64(1) << 40, nil, false},
+		{"float from int", Float, 2, 2.0, true},
+		{"string", String, "a", "a", true},
+		{"string from int", String, 1, nil, false},
+		{"boolean", Boolean, true, true, true},
+		{"id from int", ID, 42, "42", true},
+		{"id from float", ID, 42.0, "42", true},
+		{"id from bool", ID, true, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.scalar.serialize(tt.in)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("expected %v %v, got %v %v", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
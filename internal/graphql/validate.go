@@ -0,0 +1,464 @@
+package graphql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// directives are the built-in @skip and @include; both take a single `if: Boolean!`.
+var directives = map[string][]*ArgumentDef{
+	"skip":    {{Name: "if", Type: NonNullOf(Boolean)}},
+	"include": {{Name: "if", Type: NonNullOf(Boolean)}},
+}
+
+// variableUsage is a variable in a position expecting type t; hasDefault is set when the
+// position has a default of its own.
+type variableUsage struct {
+	name       string
+	t          Type
+	hasDefault bool
+	loc        Location
+}
+
+type validator struct {
+	schema    *Schema
+	fragments map[string]*Fragment
+	errs      []*Error
+
+	// Per operation: fragments already walked and the variables used.
+	visited map[string]bool
+	usages  []variableUsage
+}
+
+func (v *validator) errorf(loc Location, format string, args ...any) {
+	v.errs = append(v.errs, errorf(loc, format, args...))
+}
+
+// validate checks the document against the schema before anything executes, so a request
+// with a mistake anywhere in it never runs a mutation.
+func (s *Schema) validate(doc *Document) []*Error {
+	v := &validator{schema: s, fragments: make(map[string]*Fragment, len(doc.Fragments))}
+
+	for _, f := range doc.Fragments {
+		if _, dup := v.fragments[f.Name]; dup {
+			v.errorf(f.Loc, "There can be only one fragment named \"%s\".", f.Name)
+		}
+		v.fragments[f.Name] = f
+	}
+	names := map[string]bool{}
+	for _, op := range doc.Operations {
+		switch {
+		case op.Name == "" && len(doc.Operations) > 1:
+			v.errorf(op.Loc, "This anonymous operation must be the only defined operation.")
+		case op.Name != "" && names[op.Name]:
+			v.errorf(op.Loc, "There can be only one operation named \"%s\".", op.Name)
+		}
+		names[op.Name] = true
+	}
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+
+	if !v.checkFragmentCycles(doc) {
+		// Walking the selections of a cycle would never end.
+		return v.errs
+	}
+	used := map[string]bool{}
+	for _, op := range doc.Operations {
+		v.operation(op)
+		for name := range v.visited {
+			used[name] = true
+		}
+	}
+	for _, f := range doc.Fragments {
+		if !used[f.Name] {
+			v.errorf(f.Loc, "Fragment \"%s\" is never used.", f.Name)
+		}
+	}
+	return v.errs
+}
+
+func (v *validator) checkFragmentCycles(doc *Document) bool {
+	state := map[string]int{} // 1 while on the current path, 2 when done
+	var visit func(f *Fragment) bool
+	visit = func(f *Fragment) bool {
+		switch state[f.Name] {
+		case 1:
+			v.errorf(f.Loc, "Cannot spread fragment \"%s\" within itself.", f.Name)
+			return false
+		case 2:
+			return true
+		}
+		state[f.Name] = 1
+		ok := true
+		for _, name := range spreads(f.Selections) {
+			if next, known := v.fragments[name]; known && ok {
+				ok = visit(next)
+			}
+		}
+		state[f.Name] = 2
+		return ok
+	}
+	for _, f := range doc.Fragments {
+		if !visit(f) {
+			return false
+		}
+	}
+	return true
+}
+
+func spreads(selections []Selection) []string {
+	var names []string
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *Field:
+			names = append(names, spreads(sel.Selections)...)
+		case *InlineFragment:
+			names = append(names, spreads(sel.Selections)...)
+		case *FragmentSpread:
+			names = append(names, sel.Name)
+		}
+	}
+	return names
+}
+
+func (v *validator) operation(op *Operation) {
+	v.visited = map[string]bool{}
+	v.usages = nil
+
+	root := v.schema.root(op.Kind)
+	if root == nil {
+		v.errorf(op.Loc, "Schema is not configured for %ss.", op.Kind)
+		return
+	}
+
+	defs := map[string]*VariableDefinition{}
+	for _, def := range op.Variables {
+		if _, dup := defs[def.Name]; dup {
+			v.errorf(def.Loc, "There can be only one variable named \"$%s\".", def.Name)
+		}
+		defs[def.Name] = def
+		t := v.schema.typeOf(def.Type)
+		switch {
+		case t == nil:
+			v.errorf(def.Loc, "Unknown type \"%s\".", baseName(def.Type))
+		case !isInputType(t):
+			v.errorf(def.Loc, "Variable \"$%s\" cannot be non-input type \"%s\".", def.Name, def.Type)
+		case def.Default != nil:
+			if _, err := coerceLiteral(t, def.Default, nil); err != nil || def.Default.Kind == VariableValue {
+				v.errorf(def.Loc, "Variable \"$%s\" has invalid default value %s.", def.Name, def.Default)
+			}
+		}
+	}
+
+	v.directives(op.Directives)
+	if op.Kind == Subscription {
+		fields := map[string]string{}
+		v.rootFields(op.Selections, fields)
+		if len(fields) != 1 {
+			v.errorf(op.Loc, "Subscription must select only one top level field.")
+		}
+		for _, name := range fields {
+			if strings.HasPrefix(name, "__") {
+				v.errorf(op.Loc, "Subscription must not select an introspection top level field.")
+			}
+		}
+	}
+	v.selections(root, op.Selections)
+
+	used := map[string]bool{}
+	for _, u := range v.usages {
+		used[u.name] = true
+		def, ok := defs[u.name]
+		if !ok {
+			v.errorf(u.loc, "Variable \"$%s\" is not defined%s.", u.name, operationSuffix(op))
+			continue
+		}
+		varType := v.schema.typeOf(def.Type)
+		if varType != nil && !usageAllowed(varType, def.Default != nil, u.t, u.hasDefault) {
+			v.errorf(u.loc, "Variable \"$%s\" of type \"%s\" used in position expecting type \"%s\".", u.name, varType, u.t)
+		}
+	}
+	for _, def := range op.Variables {
+		if !used[def.Name] {
+			v.errorf(def.Loc, "Variable \"$%s\" is never used%s.", def.Name, operationSuffix(op))
+		}
+	}
+}
+
+func baseName(ref *TypeRef) string {
+	for ref.Elem != nil {
+		ref = ref.Elem
+	}
+	return ref.Name
+}
+
+func operationSuffix(op *Operation) string {
+	if op.Name == "" {
+		return ""
+	}
+	return " in operation \"" + op.Name + "\""
+}
+
+// rootFields maps the response keys selected at the top level to field names.
+func (v *validator) rootFields(selections []Selection, keys map[string]string) {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *Field:
+			keys[sel.ResponseKey()] = sel.Name
+		case *InlineFragment:
+			v.rootFields(sel.Selections, keys)
+		case *FragmentSpread:
+			if f, ok := v.fragments[sel.Name]; ok {
+				v.rootFields(f.Selections, keys)
+			}
+		}
+	}
+}
+
+func (s *Schema) root(kind OperationKind) *Object {
+	switch kind {
+	case Query:
+		return s.Query
+	case Mutation:
+		return s.Mutation
+	case Subscription:
+		return s.Subscription
+	}
+	return nil
+}
+
+func (v *validator) selections(parent *Object, selections []Selection) {
+	v.checkMerge(parent, selections)
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *Field:
+			v.field(parent, sel)
+		case *InlineFragment:
+			v.directives(sel.Directives)
+			if t := v.condition(parent, sel.TypeCondition, "", sel.Loc); t != nil {
+				v.selections(t, sel.Selections)
+			}
+		case *FragmentSpread:
+			v.directives(sel.Directives)
+			f, ok := v.fragments[sel.Name]
+			if !ok {
+				v.errorf(sel.Loc, "Unknown fragment \"%s\".", sel.Name)
+				continue
+			}
+			t := v.condition(parent, f.TypeCondition, f.Name, sel.Loc)
+			if v.visited[f.Name] {
+				continue
+			}
+			v.visited[f.Name] = true
+			v.directives(f.Directives)
+			if t == nil {
+				// Still check the fragment against its own type when the spread is misplaced.
+				t, _ = v.schema.typesByName[f.TypeCondition].(*Object)
+			}
+			if t != nil {
+				v.selections(t, f.Selections)
+			}
+		}
+	}
+}
+
+// condition resolves a type condition; objects are the only composite types, so a fragment
+// applies only to the very type it names.
+func (v *validator) condition(parent *Object, name, fragment string, loc Location) *Object {
+	if name == "" {
+		return parent
+	}
+	t, ok := v.schema.typesByName[name]
+	if !ok {
+		v.errorf(loc, "Unknown type \"%s\".", name)
+		return nil
+	}
+	obj, ok := t.(*Object)
+	if !ok {
+		v.errorf(loc, "Fragment cannot condition on non composite type \"%s\".", name)
+		return nil
+	}
+	if obj != parent {
+		if fragment != "" {
+			v.errorf(loc, "Fragment \"%s\" cannot be spread here as objects of type \"%s\" can never be of type \"%s\".", fragment, parent.Name, name)
+		} else {
+			v.errorf(loc, "Fragment cannot be spread here as objects of type \"%s\" can never be of type \"%s\".", parent.Name, name)
+		}
+		return nil
+	}
+	return obj
+}
+
+func (v *validator) field(parent *Object, f *Field) {
+	v.directives(f.Directives)
+	if f.Name == "__typename" {
+		v.arguments(f.Name, nil, f.Arguments, f.Loc)
+		if len(f.Selections) > 0 {
+			v.errorf(f.Loc, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields.")
+		}
+		return
+	}
+
+	def := parent.field(f.Name)
+	if def == nil {
+		v.errorf(f.Loc, "Cannot query field \"%s\" on type \"%s\".", f.Name, parent.Name)
+		return
+	}
+	v.arguments(parent.Name+"."+f.Name, def.Args, f.Arguments, f.Loc)
+
+	if isLeafType(def.Type) {
+		if len(f.Selections) > 0 {
+			v.errorf(f.Loc, "Field \"%s\" must not have a selection since type \"%s\" has no subfields.", f.Name, def.Type)
+		}
+		return
+	}
+	if len(f.Selections) == 0 {
+		v.errorf(f.Loc, "Field \"%s\" of type \"%s\" must have a selection of subfields. Did you mean \"%s { ... }\"?", f.Name, def.Type, f.Name)
+		return
+	}
+	v.selections(named(def.Type).(*Object), f.Selections)
+}
+
+func (v *validator) directives(list []*Directive) {
+	seen := map[string]bool{}
+	for _, d := range list {
+		defs, ok := directives[d.Name]
+		if !ok {
+			v.errorf(d.Loc, "Unknown directive \"@%s\".", d.Name)
+			continue
+		}
+		if seen[d.Name] {
+			v.errorf(d.Loc, "The directive \"@%s\" can only be used once at this location.", d.Name)
+		}
+		seen[d.Name] = true
+		v.arguments("@"+d.Name, defs, d.Arguments, d.Loc)
+	}
+}
+
+// arguments checks names, required arguments and literal values, and records variable usages.
+func (v *validator) arguments(owner string, defs []*ArgumentDef, args []*Argument, loc Location) {
+	seen := map[string]bool{}
+	for _, arg := range args {
+		if seen[arg.Name] {
+			v.errorf(arg.Loc, "There can be only one argument named \"%s\".", arg.Name)
+		}
+		seen[arg.Name] = true
+		i := slices.IndexFunc(defs, func(d *ArgumentDef) bool { return d.Name == arg.Name })
+		if i < 0 {
+			v.errorf(arg.Loc, "Unknown argument \"%s\" on \"%s\".", arg.Name, owner)
+			continue
+		}
+		v.collectUsages(defs[i].Type, defs[i].Default != nil, arg.Value)
+	}
+	if _, err := coerceArguments(defs, args, nil, loc); err != nil {
+		v.errs = append(v.errs, err)
+	}
+}
+
+func (v *validator) collectUsages(t Type, hasDefault bool, value *Value) {
+	switch value.Kind {
+	case VariableValue:
+		v.usages = append(v.usages, variableUsage{name: value.Raw, t: t, hasDefault: hasDefault, loc: value.Loc})
+	case ListValue:
+		if list, ok := nullable(t).(*List); ok {
+			for _, item := range value.List {
+				v.collectUsages(list.OfType, false, item)
+			}
+		}
+	case ObjectValue:
+		if obj, ok := nullable(t).(*InputObject); ok {
+			for _, f := range value.Fields {
+				if i := slices.IndexFunc(obj.Fields, func(d *ArgumentDef) bool { return d.Name == f.Name }); i >= 0 {
+					v.collectUsages(obj.Fields[i].Type, obj.Fields[i].Default != nil, f.Value)
+				}
+			}
+		}
+	}
+}
+
+func nullable(t Type) Type {
+	if nn, ok := t.(*NonNull); ok {
+		return nn.OfType
+	}
+	return t
+}
+
+// usageAllowed lets a nullable variable fill a non-null position only when a default covers
+// the missing value.
+func usageAllowed(varType Type, varHasDefault bool, locType Type, locHasDefault bool) bool {
+	if nn, ok := locType.(*NonNull); ok {
+		if _, varNonNull := varType.(*NonNull); !varNonNull {
+			if !varHasDefault && !locHasDefault {
+				return false
+			}
+			return compatible(varType, nn.OfType)
+		}
+	}
+	return compatible(varType, locType)
+}
+
+func compatible(varType, locType Type) bool {
+	if nn, ok := locType.(*NonNull); ok {
+		varNN, ok := varType.(*NonNull)
+		return ok && compatible(varNN.OfType, nn.OfType)
+	}
+	if nn, ok := varType.(*NonNull); ok {
+		return compatible(nn.OfType, locType)
+	}
+	if list, ok := locType.(*List); ok {
+		varList, ok := varType.(*List)
+		return ok && compatible(varList.OfType, list.OfType)
+	}
+	if _, ok := varType.(*List); ok {
+		return false
+	}
+	return varType == locType
+}
+
+// checkMerge reports fields that share a response key in one selection set but select
+// different fields or arguments.
+func (v *validator) checkMerge(parent *Object, selections []Selection) {
+	seen := map[string]*Field{}
+	visited := map[string]bool{}
+	var walk func(selections []Selection)
+	walk = func(selections []Selection) {
+		for _, sel := range selections {
+			switch sel := sel.(type) {
+			case *Field:
+				key := sel.ResponseKey()
+				other, ok := seen[key]
+				if !ok {
+					seen[key] = sel
+					continue
+				}
+				if other.Name != sel.Name {
+					v.errorf(sel.Loc, "Fields \"%s\" conflict because \"%s\" and \"%s\" are different fields. Use different aliases on the fields to fetch both if this was intentional.", key, other.Name, sel.Name)
+				} else if argumentsString(other.Arguments) != argumentsString(sel.Arguments) {
+					v.errorf(sel.Loc, "Fields \"%s\" conflict because they have differing arguments. Use different aliases on the fields to fetch both if this was intentional.", key)
+				}
+			case *InlineFragment:
+				if sel.TypeCondition == "" || sel.TypeCondition == parent.Name {
+					walk(sel.Selections)
+				}
+			case *FragmentSpread:
+				if f, ok := v.fragments[sel.Name]; ok && !visited[sel.Name] && f.TypeCondition == parent.Name {
+					visited[sel.Name] = true
+					walk(f.Selections)
+				}
+			}
+		}
+	}
+	walk(selections)
+}
+
+func argumentsString(args []*Argument) string {
+	sorted := slices.Clone(args)
+	slices.SortFunc(sorted, func(a, b *Argument) int { return strings.Compare(a.Name, b.Name) })
+	s := ""
+	for _, arg := range sorted {
+		s += fmt.Sprintf("%s:%s,", arg.Name, arg.Value)
+	}
+	return s
+}
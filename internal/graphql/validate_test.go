@@ -0,0 +1,86 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`{ item(id: 1) { name } items { ...F } } fragment F on Item { id }`, nil},
+		{`query A { items { id } } query A { items { id } }`, []string{`There can be only one operation named "A".`}},
+		{`{ items { id } } query B { items { id } }`, []string{`This anonymous operation must be the only defined operation.`}},
+		{`{ items { ...F } } fragment F on Item { id } fragment F on Item { name }`, []string{`There can be only one fragment named "F".`}},
+		{`{ items { ...A } } fragment A on Item { ...B } fragment B on Item { ...A }`, []string{`Cannot spread fragment "A" within itself.`}},
+		{`{ items { id } } fragment F on Item { id }`, []string{`Fragment "F" is never used.`}},
+		{`{ items { ...Nope } }`, []string{`Unknown fragment "Nope".`}},
+		{`{ items { ... on Nope { id } } }`, []string{`Unknown type "Nope".`}},
+		{`{ items { ... on Kind { id } } }`, []string{`Fragment cannot condition on non composite type "Kind".`}},
+		{`{ items { ...F } } fragment F on Query { items { id } }`, []string{`Fragment "F" cannot be spread here as objects of type "Item" can never be of type "Query".`}},
+		{`{ items { nope } }`, []string{`Cannot query field "nope" on type "Item".`}},
+		{`{ items { name { x } } }`, []string{`Field "name" must not have a selection since type "String!" has no subfields.`}},
+		{`{ items }`, []string{`Field "items" of type "[Item!]!" must have a selection of subfields. Did you mean "items { ... }"?`}},
+		{`{ __typename { x } }`, []string{`Field "__typename" must not have a selection since type "String!" has no subfields.`}},
+		{`{ item { id } }`, []string{`Argument "id" of required type "ID!" was not provided.`}},
+		{`{ item(id: 1, id: 2, nope: 3) { id } }`, []string{`There can be only one argument named "id".`, `Unknown argument "nope" on "Query.item".`}},
+		{`{ items(first: "ten") { id } }`, []string{`Argument "first" has invalid value "ten": Int cannot represent "ten".`}},
+		{`{ items(kind: GREEN) { id } }`, []string{`Argument "kind" has invalid value GREEN: value GREEN does not exist in "Kind" enum.`}},
+		{`{ echo(input: {times: 2}) }`, []string{`Argument "input" has invalid value {times: 2}: field "text" of required type "String!" was not provided.`}},
+		{`{ items @nope { id } }`, []string{`Unknown directive "@nope".`}},
+		{`{ items @skip(if: true) @skip(if: false) { id } }`, []string{`The directive "@skip" can only be used once at this location.`}},
+		{`{ items @include { id } }`, []string{`Argument "if" of required type "Boolean!" was not provided.`}},
+		{`query ($x: Nope) { items { id } }`, []string{`Unknown type "Nope".`, `Variable "$x" is never used.`}},
+		{`query ($x: Item) { items { id } }`, []string{`Variable "$x" cannot be non-input type "Item".`, `Variable "$x" is never used.`}},
+		{`query ($x: Int = "a") { items(first: $x) { id } }`, []string{`Variable "$x" has invalid default value "a".`}},
+		{`query ($x: Int, $x: Int) { items(first: $x) { id } }`, []string{`There can be only one variable named "$x".`}},
+		{`query Q { items(first: $n) { id } }`, []string{`Variable "$n" is not defined in operation "Q".`}},
+		{`query ($n: Int) { items { id } }`, []string{`Variable "$n" is never used.`}},
+		{`query ($k: String) { items(kind: $k) { id } }`, []string{`Variable "$k" of type "String" used in position expecting type "Kind".`}},
+		{`query ($id: ID) { item(id: $id) { id } }`, []string{`Variable "$id" of type "ID" used in position expecting type "ID!".`}},
+		{`query ($id: ID = 1) { item(id: $id) { id } }`, nil},
+		{`query ($t: [String]) { echo(input: {text: $t}) }`, []string{`Variable "$t" of type "[String]" used in position expecting type "String!".`}},
+		{`subscription { ticks(count: 1) { id } failing { id } }`, []string{`Subscription must select only one top level field.`}},
+		{`subscription { __typename }`, []string{`Subscription must not select an introspection top level field.`}},
+		{`{ items { id: name id } }`, []string{`Fields "id" conflict because "name" and "id" are different fields. Use different aliases on the fields to fetch both if this was intentional.`}},
+		{`{ item(id: 1) { id } item(id: 2) { id } }`, []string{`Fields "item" conflict because they have differing arguments. Use different aliases on the fields to fetch both if this was intentional.`}},
+	}
+
+	schema := testSchema(t, new([]string))
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp := schema.Execute(context.Background(), Request{Query: tt.query})
+			if tt.want == nil {
+				if !resp.Executed() {
+					t.Errorf("expected a valid document, got %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Executed() {
+				t.Fatal("expected the document to be rejected")
+			}
+			got := make([]string, len(resp.Errors))
+			for i, err := range resp.Errors {
+				got[i] = err.Message
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateWithoutMutations(t *testing.T) {
+	query := &Object{Name: "Query", Fields: []*FieldDef{{Name: "ok", Type: Boolean}}}
+	schema, err := NewSchema(query, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := schema.Execute(context.Background(), Request{Query: `mutation { ok }`})
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != "Schema is not configured for mutations." {
+		t.Errorf("unexpected errors %v", resp.Errors)
+	}
+}
@@ -0,0 +1,253 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// unknownVariable stands in for variables while literals are checked during validation, before
+// variable values are known.
+type unknownVariable struct{}
+
+// coerceVariables converts the JSON-decoded variables of a request to the declared types.
+// Variables that are neither provided nor defaulted stay out of the result.
+func (s *Schema) coerceVariables(op *Operation, values map[string]any) (map[string]any, []*Error) {
+	coerced := make(map[string]any, len(op.Variables))
+	var errs []*Error
+	for _, def := range op.Variables {
+		t := s.typeOf(def.Type)
+		value, provided := values[def.Name]
+		_, nonNull := t.(*NonNull)
+
+		switch {
+		case !provided && def.Default != nil:
+			v, err := coerceLiteral(t, def.Default, nil)
+			if err != nil {
+				errs = append(errs, errorf(def.Loc, "Variable \"$%s\" has invalid default value: %v", def.Name, err))
+				continue
+			}
+			coerced[def.Name] = v
+		case !provided && nonNull:
+			errs = append(errs, errorf(def.Loc, "Variable \"$%s\" of required type \"%s\" was not provided.", def.Name, t))
+		case provided:
+			v, err := coerceInput(t, value)
+			if err != nil {
+				errs = append(errs, errorf(def.Loc, "Variable \"$%s\" got invalid value %s; %v", def.Name, jsonString(value), err))
+				continue
+			}
+			coerced[def.Name] = v
+		}
+	}
+	return coerced, errs
+}
+
+func jsonString(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// coerceInput converts a JSON-decoded value to type t.
+func coerceInput(t Type, v any) (any, error) {
+	if nn, ok := t.(*NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("expected non-nullable type \"%s\" not to be null", t)
+		}
+		return coerceInput(nn.OfType, v)
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *Scalar:
+		parsed, ok := t.parse(v)
+		if !ok {
+			return nil, fmt.Errorf("%s cannot represent %s", t.Name, jsonString(v))
+		}
+		return parsed, nil
+	case *Enum:
+		name, _ := v.(string)
+		if value, ok := t.byName(name); ok {
+			return value.Value, nil
+		}
+		return nil, fmt.Errorf("value %s does not exist in \"%s\" enum", jsonString(v), t.Name)
+	case *List:
+		items, ok := v.([]any)
+		if !ok {
+			// A single value stands for a list of one.
+			item, err := coerceInput(t.OfType, v)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+		list := make([]any, len(items))
+		for i, item := range items {
+			coerced, err := coerceInput(t.OfType, item)
+			if err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+			list[i] = coerced
+		}
+		return list, nil
+	case *InputObject:
+		fields, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected type \"%s\" to be an object", t.Name)
+		}
+		return coerceInputObject(t, fields, nil, coerceInput)
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// coerceInputObject is shared by variables and literals; given reports whether a sent field
+// counts as provided.
+func coerceInputObject[V any](t *InputObject, fields map[string]V, given func(V) bool, coerce func(Type, V) (any, error)) (any, error) {
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if !slices.ContainsFunc(t.Fields, func(f *ArgumentDef) bool { return f.Name == name }) {
+			return nil, fmt.Errorf("field \"%s\" is not defined by type \"%s\"", name, t.Name)
+		}
+	}
+
+	result := make(map[string]any, len(t.Fields))
+	for _, f := range t.Fields {
+		raw, ok := fields[f.Name]
+		if ok && given != nil {
+			ok = given(raw)
+		}
+		if !ok {
+			if f.Default != nil {
+				result[f.Name] = f.Default
+			} else if _, nonNull := f.Type.(*NonNull); nonNull {
+				return nil, fmt.Errorf("field \"%s\" of required type \"%s\" was not provided", f.Name, f.Type)
+			}
+			continue
+		}
+		value, err := coerce(f.Type, raw)
+		if err != nil {
+			return nil, fmt.Errorf("at \"%s\": %w", f.Name, err)
+		}
+		result[f.Name] = value
+	}
+	return result, nil
+}
+
+// coerceLiteral converts a value written in the query to type t. With nil vars, variables are
+// accepted as they are and only the literal parts are checked.
+func coerceLiteral(t Type, v *Value, vars map[string]any) (any, error) {
+	if v.Kind == VariableValue {
+		if vars == nil {
+			return unknownVariable{}, nil
+		}
+		value := vars[v.Raw]
+		if _, nonNull := t.(*NonNull); nonNull && value == nil {
+			return nil, fmt.Errorf("expected non-nullable type \"%s\" not to be null", t)
+		}
+		return value, nil
+	}
+
+	if nn, ok := t.(*NonNull); ok {
+		if v.Kind == NullValue {
+			return nil, fmt.Errorf("expected non-nullable type \"%s\" not to be null", t)
+		}
+		return coerceLiteral(nn.OfType, v, vars)
+	}
+	if v.Kind == NullValue {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *Scalar:
+		parsed, ok := t.parseLiteral(v)
+		if !ok {
+			return nil, fmt.Errorf("%s cannot represent %s", t.Name, v)
+		}
+		return parsed, nil
+	case *Enum:
+		if v.Kind == EnumValue {
+			if value, ok := t.byName(v.Raw); ok {
+				return value.Value, nil
+			}
+		}
+		return nil, fmt.Errorf("value %s does not exist in \"%s\" enum", v, t.Name)
+	case *List:
+		if v.Kind != ListValue {
+			item, err := coerceLiteral(t.OfType, v, vars)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+		list := make([]any, len(v.List))
+		for i, item := range v.List {
+			coerced, err := coerceLiteral(t.OfType, item, vars)
+			if err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+			list[i] = coerced
+		}
+		return list, nil
+	case *InputObject:
+		if v.Kind != ObjectValue {
+			return nil, fmt.Errorf("expected type \"%s\" to be an object", t.Name)
+		}
+		fields := make(map[string]*Value, len(v.Fields))
+		for _, f := range v.Fields {
+			if _, dup := fields[f.Name]; dup {
+				return nil, fmt.Errorf("there can be only one input field named \"%s\"", f.Name)
+			}
+			fields[f.Name] = f.Value
+		}
+		// An unset variable leaves the field out, so its default applies.
+		given := func(v *Value) bool {
+			if v.Kind != VariableValue || vars == nil {
+				return true
+			}
+			_, ok := vars[v.Raw]
+			return ok
+		}
+		return coerceInputObject(t, fields, given, func(t Type, v *Value) (any, error) {
+			return coerceLiteral(t, v, vars)
+		})
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// coerceArguments converts the arguments of the field or directive at loc.
+func coerceArguments(defs []*ArgumentDef, args []*Argument, vars map[string]any, loc Location) (map[string]any, *Error) {
+	result := make(map[string]any, len(defs))
+	for _, def := range defs {
+		var arg *Argument
+		for _, a := range args {
+			if a.Name == def.Name {
+				arg = a
+				break
+			}
+		}
+		present := arg != nil
+		if present && arg.Value.Kind == VariableValue && vars != nil {
+			_, present = vars[arg.Value.Raw]
+		}
+
+		if !present {
+			if def.Default != nil {
+				result[def.Name] = def.Default
+			} else if _, nonNull := def.Type.(*NonNull); nonNull {
+				return nil, errorf(loc, "Argument \"%s\" of required type \"%s\" was not provided.", def.Name, def.Type)
+			}
+			continue
+		}
+
+		value, err := coerceLiteral(def.Type, arg.Value, vars)
+		if err != nil {
+			return nil, errorf(arg.Loc, "Argument \"%s\" has invalid value %s: %v.", def.Name, arg.Value, err)
+		}
+		result[def.Name] = value
+	}
+	return result, nil
+}
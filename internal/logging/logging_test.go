@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"todo/internal/tracing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected slog.Level
+		wantErr  bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			level, err := ParseLevel(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if level != tt.expected {
+				t.Errorf("expected level %v, got %v", tt.expected, level)
+			}
+		})
+	}
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", slog.LevelInfo); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestRequestIDIsLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-42")
+	logger.With("component", "test").InfoContext(ctx, "hello")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log line: %v", err)
+	}
+	if record["request_id"] != "req-42" {
+		t.Errorf("expected request_id 'req-42', got %v", record["request_id"])
+	}
+	if record["component"] != "test" {
+		t.Errorf("expected component 'test', got %v", record["component"])
+	}
+}
+
+func TestLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, FormatText, slog.LevelWarn)
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestTraceIDIsLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, FormatJSON, slog.LevelInfo)
+
+	tracer := tracing.NewTracer(tracing.TracerOptions{})
+	ctx, span := tracer.Start(context.Background(), "op", tracing.KindInternal)
+	logger.InfoContext(ctx, "inside span")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log line: %v", err)
+	}
+	if record["trace_id"] != span.SpanContext().TraceID.String() {
+		t.Errorf("expected trace_id %s, got %v", span.SpanContext().TraceID, record["trace_id"])
+	}
+	if record["span_id"] != span.SpanContext().SpanID.String() {
+		t.Errorf("expected span_id %s, got %v", span.SpanContext().SpanID, record["span_id"])
+	}
+}
@@ -0,0 +1,339 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mutex      sync.Mutex
+	names      map[string]bool
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mutex.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mutex.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			http.Error(w, "Method is not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.WriteText(w)
+	})
+}
+
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// family keeps one child per distinct combination of label values.
+type family[T any] struct {
+	desc
+	mutex    sync.RWMutex
+	children map[string]*child[T]
+	create   func() *T
+}
+
+type child[T any] struct {
+	values []string
+	metric *T
+}
+
+func newFamily[T any](d desc, create func() *T) *family[T] {
+	return &family[T]{desc: d, children: make(map[string]*child[T]), create: create}
+}
+
+func (f *family[T]) with(values []string) *T {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mutex.RLock()
+	c, ok := f.children[key]
+	f.mutex.RUnlock()
+	if ok {
+		return c.metric
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if c, ok := f.children[key]; ok {
+		return c.metric
+	}
+	c = &child[T]{values: append([]string(nil), values...), metric: f.create()}
+	f.children[key] = c
+	return c.metric
+}
+
+func (f *family[T]) sorted() []*child[T] {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	result := make([]*child[T], 0, len(f.children))
+	for _, c := range f.children {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.Join(result[i].values, "\xff") < strings.Join(result[j].values, "\xff")
+	})
+	return result
+}
+
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+type Counter struct {
+	v value
+}
+
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.add(delta)
+}
+
+type CounterVec struct {
+	f *family[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	cv := &CounterVec{f: newFamily(desc{name, help, "counter", labels}, func() *Counter { return &Counter{} })}
+	r.register(name, cv)
+	return cv
+}
+
+func (cv *CounterVec) WithLabelValues(values ...string) *Counter {
+	return cv.f.with(values)
+}
+
+func (cv *CounterVec) write(w *bufio.Writer) {
+	cv.f.writeHeader(w)
+	for _, c := range cv.f.sorted() {
+		writeSample(w, cv.f.name, cv.f.labels, c.values, "", "", c.metric.v.get())
+	}
+}
+
+type Gauge struct {
+	v value
+}
+
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.v.add(delta)
+}
+
+func (g *Gauge) Set(val float64) {
+	g.v.bits.Store(math.Float64bits(val))
+}
+
+type GaugeVec struct {
+	f *family[Gauge]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	gv := &GaugeVec{f: newFamily(desc{name, help, "gauge", labels}, func() *Gauge { return &Gauge{} })}
+	r.register(name, gv)
+	return gv
+}
+
+func (gv *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return gv.f.with(values)
+}
+
+func (gv *GaugeVec) write(w *bufio.Writer) {
+	gv.f.writeHeader(w)
+	for _, c := range gv.f.sorted() {
+		writeSample(w, gv.f.name, gv.f.labels, c.values, "", "", c.metric.v.get())
+	}
+}
+
+// GaugeFunc is evaluated on every scrape, which suits values owned by someone else.
+type GaugeFunc struct {
+	desc
+	collect func(emit func(val float64, labelValues ...string))
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func(emit func(val float64, labelValues ...string))) {
+	r.register(name, &GaugeFunc{desc: desc{name, help, "gauge", labels}, collect: collect})
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	g.collect(func(val float64, labelValues ...string) {
+		writeSample(w, g.name, g.labels, labelValues, "", "", val)
+	})
+}
+
+type Histogram struct {
+	upper  []float64
+	counts []atomic.Uint64
+	count  atomic.Uint64
+	sum    value
+}
+
+func (h *Histogram) Observe(val float64) {
+	i := sort.SearchFloat64s(h.upper, val)
+	if i < len(h.counts) {
+		h.counts[i].Add(1)
+	}
+	h.count.Add(1)
+	h.sum.add(val)
+}
+
+type HistogramVec struct {
+	f *family[Histogram]
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	upper := append([]float64(nil), buckets...)
+	sort.Float64s(upper)
+	hv := &HistogramVec{f: newFamily(desc{name, help, "histogram", labels}, func() *Histogram {
+		return &Histogram{upper: upper, counts: make([]atomic.Uint64, len(upper))}
+	})}
+	r.register(name, hv)
+	return hv
+}
+
+func (hv *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return hv.f.with(values)
+}
+
+func (hv *HistogramVec) write(w *bufio.Writer) {
+	hv.f.writeHeader(w)
+	for _, c := range hv.f.sorted() {
+		h := c.metric
+		var cumulative uint64
+		for i, upper := range h.upper {
+			cumulative += h.counts[i].Load()
+			writeSample(w, hv.f.name+"_bucket", hv.f.labels, c.values, "le", formatFloat(upper), float64(cumulative))
+		}
+		count := h.count.Load()
+		writeSample(w, hv.f.name+"_bucket", hv.f.labels, c.values, "le", "+Inf", float64(count))
+		writeSample(w, hv.f.name+"_sum", hv.f.labels, c.values, "", "", h.sum.get())
+		writeSample(w, hv.f.name+"_count", hv.f.labels, c.values, "", "", float64(count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, val float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabelValue(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(val))
+	w.WriteByte('\n')
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return sb.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	cv := r.NewCounterVec("requests_total", "Total requests.", "method", "code")
+
+	cv.WithLabelValues("GET", "200").Inc()
+	cv.WithLabelValues("GET", "200").Add(2)
+	cv.WithLabelValues("POST", "201").Inc()
+
+	expected := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 3
+requests_total{method="POST",code="201"} 1
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("unexpected exposition:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestGaugeVec(t *testing.T) {
+	r := NewRegistry()
+	gv := r.NewGaugeVec("in_flight", "In-flight requests.", "route")
+
+	g := gv.WithLabelValues("/todos")
+	g.Inc()
+	g.Inc()
+	g.Dec()
+
+	if got := render(t, r); !strings.Contains(got, `in_flight{route="/todos"} 1`+"\n") {
+		t.Errorf("unexpected exposition:\n%s", got)
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("tasks", "Tasks by status.", []string{"status"}, func(emit func(float64, ...string)) {
+		emit(2, "Assigned")
+		emit(0, "Dropped")
+	})
+
+	got := render(t, r)
+	for _, line := range []string{"# TYPE tasks gauge", `tasks{status="Assigned"} 2`, `tasks{status="Dropped"} 0`} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, got)
+		}
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	hv := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+
+	h := hv.WithLabelValues("get")
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	expected := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 2
+latency_seconds_bucket{op="get",le="1"} 3
+latency_seconds_bucket{op="get",le="+Inf"} 4
+latency_seconds_sum{op="get"} 3.65
+latency_seconds_count{op="get"} 4
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("unexpected exposition:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	cv := r.NewCounterVec("escaped_total", "Help with \\ and\nnewline.", "path")
+	cv.WithLabelValues("a\"b\\c\nd").Inc()
+
+	got := render(t, r)
+	if !strings.Contains(got, `# HELP escaped_total Help with \\ and\nnewline.`) {
+		t.Errorf("help is not escaped:\n%s", got)
+	}
+	if !strings.Contains(got, `escaped_total{path="a\"b\\c\nd"} 1`) {
+		t.Errorf("label value is not escaped:\n%s", got)
+	}
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("dup_total", "Dup.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	r.NewGaugeVec("dup_total", "Dup.")
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	cv := r.NewCounterVec("concurrent_total", "Concurrent.", "worker")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cv.WithLabelValues("w").Inc()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := render(t, r); !strings.Contains(got, `concurrent_total{worker="w"} 1000`) {
+		t.Errorf("unexpected exposition:\n%s", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("handler_total", "Handler.").WithLabelValues().Inc()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	r.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("expected content type %q, got %q", ContentType, ct)
+	}
+	if !strings.Contains(w.Body.String(), "handler_total 1\n") {
+		t.Errorf("unexpected body:\n%s", w.Body.String())
+	}
+}
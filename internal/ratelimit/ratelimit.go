@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule allows Limit requests per Window with bursts of up to Burst requests.
+type Rule struct {
+	Limit  int
+	Window time.Duration
+	Burst  int
+}
+
+// ParseRule parses "limit/window[:burst]", e.g. "10/1m" or "100/1s:200".
+func ParseRule(s string) (Rule, error) {
+	spec, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	limitStr, windowStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("rate limit %q: expected limit/window", s)
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return Rule{}, fmt.Errorf("rate limit %q: limit must be a positive integer", s)
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		return Rule{}, fmt.Errorf("rate limit %q: window must be a positive duration", s)
+	}
+
+	rule := Rule{Limit: limit, Window: window, Burst: limit}
+	if hasBurst {
+		rule.Burst, err = strconv.Atoi(burstStr)
+		if err != nil || rule.Burst <= 0 {
+			return Rule{}, fmt.Errorf("rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	return rule, nil
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%d/%s:%d", r.Limit, r.Window, r.Burst)
+}
+
+func (r Rule) ratePerSecond() float64 {
+	return float64(r.Limit) / r.Window.Seconds()
+}
+
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type Limiter struct {
+	rule    Rule
+	now     func() time.Time
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewLimiter(rule Rule) *Limiter {
+	if rule.Burst <= 0 {
+		rule.Burst = rule.Limit
+	}
+	return &Limiter{
+		rule:    rule,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *Limiter) Rule() Rule {
+	return l.rule
+}
+
+func (l *Limiter) Allow(key string) Decision {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+
+	rate := l.rule.ratePerSecond()
+	decision := Decision{Limit: l.rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	decision.Remaining = int(math.Floor(b.tokens))
+	decision.Reset = seconds((float64(l.rule.Burst) - b.tokens) / rate)
+	return decision
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = math.Min(float64(l.rule.Burst), b.tokens+elapsed*l.rule.ratePerSecond())
+	b.last = now
+}
+
+// Cleanup forgets buckets that have refilled completely; they are indistinguishable from new ones.
+func (l *Limiter) Cleanup() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	removed := 0
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= float64(l.rule.Burst) {
+			delete(l.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (l *Limiter) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.buckets)
+}
+
+// Run periodically calls Cleanup so the number of tracked clients stays bounded.
+func (l *Limiter) Run(ctx context.Context) error {
+	interval := l.rule.Window
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			l.Cleanup()
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
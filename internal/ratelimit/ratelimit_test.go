@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestLimiter(rule Rule) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	l := NewLimiter(rule)
+	l.now = clock.Now
+	return l, clock
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Rule
+		wantErr  bool
+	}{
+		{"10/1m", Rule{Limit: 10, Window: time.Minute, Burst: 10}, false},
+		{"100/1s:200", Rule{Limit: 100, Window: time.Second, Burst: 200}, false},
+		{"10", Rule{}, true},
+		{"0/1s", Rule{}, true},
+		{"10/forever", Rule{}, true},
+		{"10/1s:-1", Rule{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rule, err := ParseRule(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if rule != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, rule)
+			}
+		})
+	}
+}
+
+func TestAllowBurstThenReject(t *testing.T) {
+	l, _ := newTestLimiter(Rule{Limit: 3, Window: 3 * time.Second})
+
+	for i := 0; i < 3; i++ {
+		d := l.Allow("client")
+		if !d.Allowed {
+			t.Fatalf("request %d: expected allowed", i)
+		}
+		if d.Remaining != 2-i {
+			t.Errorf("request %d: expected remaining %d, got %d", i, 2-i, d.Remaining)
+		}
+	}
+
+	d := l.Allow("client")
+	if d.Allowed {
+		t.Fatal("expected request over the burst to be rejected")
+	}
+	if d.RetryAfter != time.Second {
+		t.Errorf("expected retry after 1s, got %v", d.RetryAfter)
+	}
+	if d.Reset != 3*time.Second {
+		t.Errorf("expected reset in 3s, got %v", d.Reset)
+	}
+}
+
+func TestRefill(t *testing.T) {
+	l, clock := newTestLimiter(Rule{Limit: 1, Window: time.Second})
+
+	if !l.Allow("client").Allowed {
+		t.Fatal("expected first request to be allowed")
+	}
+	if l.Allow("client").Allowed {
+		t.Fatal("expected second request to be rejected")
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if !l.Allow("client").Allowed {
+		t.Error("expected request to be allowed after refill")
+	}
+}
+
+func TestKeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter(Rule{Limit: 1, Window: time.Minute})
+
+	if !l.Allow("a").Allowed || !l.Allow("b").Allowed {
+		t.Fatal("expected first request of each client to be allowed")
+	}
+	if l.Allow("a").Allowed {
+		t.Error("expected client a to be limited")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	l, clock := newTestLimiter(Rule{Limit: 2, Window: time.Second})
+
+	l.Allow("idle")
+	l.Allow("busy")
+	l.Allow("busy")
+
+	clock.now = clock.now.Add(600 * time.Millisecond)
+	if removed := l.Cleanup(); removed != 1 {
+		t.Errorf("expected 1 bucket to be removed, got %d", removed)
+	}
+	if l.Len() != 1 {
+		t.Errorf("expected 1 tracked bucket, got %d", l.Len())
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	l.Cleanup()
+	if l.Len() != 0 {
+		t.Errorf("expected all buckets to be removed, got %d", l.Len())
+	}
+}
@@ -0,0 +1,58 @@
+// Progressive enhancement: every form also works without JavaScript.
+(function () {
+  "use strict";
+  document.documentElement.classList.add("js");
+
+  document.addEventListener("DOMContentLoaded", function () {
+    var filter = document.querySelector("form.filter");
+    if (filter) {
+      filter.querySelector("select").addEventListener("change", function () { filter.submit(); });
+    }
+
+    document.querySelectorAll("form[data-confirm]").forEach(function (form) {
+      form.addEventListener("submit", function (event) {
+        if (!window.confirm(form.dataset.confirm)) {
+          event.preventDefault();
+        }
+      });
+    });
+
+    document.querySelectorAll("form.status-form").forEach(function (form) {
+      form.addEventListener("submit", function (event) {
+        var button = event.submitter;
+        if (!button || !window.fetch || form.dataset.fallback) {
+          return;
+        }
+        event.preventDefault();
+
+        var body = new FormData(form);
+        body.append(button.name, button.value);
+        fetch(form.action, { method: "POST", body: body, headers: { "Accept": "application/json" } })
+          .then(function (resp) {
+            if (!resp.ok) {
+              throw new Error(resp.status);
+            }
+            return resp.json();
+          })
+          .then(function (task) {
+            var container = form.closest("[data-task]");
+            var badge = container && container.querySelector("[data-status]");
+            if (badge) {
+              badge.textContent = button.textContent;
+              badge.className = "badge " + button.textContent.toLowerCase();
+            }
+            form.querySelectorAll("button").forEach(function (b) {
+              var current = Number(b.value) === task.Status;
+              b.disabled = current;
+              b.setAttribute("aria-pressed", current ? "true" : "false");
+            });
+          })
+          .catch(function () {
+            // Fall back to a regular submission, which shows the server's error page.
+            form.dataset.fallback = "1";
+            form.requestSubmit(button);
+          });
+      });
+    });
+  });
+})();
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"todo/internal/storage"
+)
+
+// SubscriptionTokenMiddleware lets calendar applications, which cannot send headers, pass the
+// bearer token as a "token" query parameter. The parameter is removed from the URL so it is
+// handled exactly like an Authorization header from here on.
+func SubscriptionTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if token := query.Get("token"); token != "" {
+			r = r.Clone(r.Context())
+			if _, ok := bearerToken(r); !ok {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			query.Del("token")
+			r.URL.RawQuery = query.Encode()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleCalendar serves the tasks as an iCalendar feed and accepts .ics uploads, which update
+// exported tasks by UID and create the rest. A caller identified by a token or certificate
+// gets a personal feed of their own tasks.
+func (s *Server) HandleCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), SecToTimeout*time.Second)
+	defer cancel()
+
+	r = r.WithContext(ctx)
+
+	switch r.Method {
+	case http.MethodGet:
+		var keep func(storage.Task) bool
+		if id := IdentityFromContext(r.Context()); id.Kind != "ip" {
+			owner := id.String()
+			keep = func(task storage.Task) bool { return task.Owner == owner }
+		}
+		s.exportTodos(w, r, taskFormats()["ical"], keep)
+	case http.MethodPost:
+		s.importTodos(w, r, taskFormats()["ical"], importUpsert)
+	case http.MethodOptions:
+		s.handleOptions(w, http.MethodGet, http.MethodPost)
+	default:
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+)
+
+func tokenOwner(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
+
+func TestCalendarFeed(t *testing.T) {
+	server := setupServer()
+	ctx := context.Background()
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "Alice's task", Status: storage.InProgress, Owner: tokenOwner("alice-secret")})
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "Bob's task", Status: storage.Dropped, Owner: tokenOwner("bob-secret")})
+
+	tests := []struct {
+		name          string
+		target        string
+		authorization string
+		want          []string
+		notWant       []string
+	}{
+		{"anonymous", "/todos.ics", "", []string{"SUMMARY:Alice's task", "STATUS:IN-PROCESS", "SUMMARY:Bob's task", "STATUS:CANCELLED"}, nil},
+		{"query token", "/todos.ics?token=alice-secret", "", []string{"UID:task-0@todo", "SUMMARY:Alice's task"}, []string{"Bob's task"}},
+		{"bearer header", "/todos.ics", "Bearer bob-secret", []string{"UID:task-1@todo", "SUMMARY:Bob's task"}, []string{"Alice's task"}},
+		{"unknown token", "/todos.ics?token=nobody", "", []string{"BEGIN:VCALENDAR"}, []string{"BEGIN:VTODO"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+				t.Errorf("expected a calendar, got %q", ct)
+			}
+			body := w.Body.String()
+			for _, s := range tt.want {
+				if !strings.Contains(body, s+"\r\n") {
+					t.Errorf("expected %q in:\n%s", s, body)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(body, s) {
+					t.Errorf("unexpected %q in:\n%s", s, body)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionTokenMiddleware(t *testing.T) {
+	var got *http.Request
+	handler := SubscriptionTokenMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/todos.ics?token=s3cret&mode=create", nil))
+	if token, _ := bearerToken(got); token != "s3cret" {
+		t.Errorf("expected the query token as bearer token, got %q", token)
+	}
+	if got.URL.RawQuery != "mode=create" {
+		t.Errorf("expected the token to be removed from the query, got %q", got.URL.RawQuery)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/todos.ics?token=query", nil)
+	req.Header.Set("Authorization", "Bearer header")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if token, _ := bearerToken(got); token != "header" {
+		t.Errorf("expected the Authorization header to take precedence, got %q", token)
+	}
+}
+
+func TestCalendarUpload(t *testing.T) {
+	server := setupServer()
+	owner := tokenOwner("alice-secret")
+	_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Write report", Owner: owner})
+
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VTODO\r\nUID:task-0@todo\r\nSUMMARY:Write report\r\nSTATUS:COMPLETED\r\nEND:VTODO\r\n" +
+		"BEGIN:VTODO\r\nUID:1234@phone.example\r\nSUMMARY:Call mum\r\nDESCRIPTION:Sunday\\, 10am\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+	req := httptest.NewRequest(http.MethodPost, "/todos.ics?token=alice-secret", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/calendar")
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	report := decodeReport(t, w)
+	if report.Mode != importUpsert || report.Updated != 1 || report.Created != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	updated, _ := server.storage.GetByID(context.Background(), 0)
+	if updated.Status != storage.Completed {
+		t.Errorf("expected the task to be completed by UID, got %+v", updated)
+	}
+	created, _ := server.storage.GetByID(context.Background(), *report.Rows[1].TaskID)
+	if created.Header != "Call mum" || created.Description != "Sunday, 10am" || created.Owner != owner {
+		t.Errorf("unexpected created task %+v", created)
+	}
+}
+
+func TestImportDetectsFormatFromContentType(t *testing.T) {
+	server := setupServer()
+
+	body := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:From a calendar\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	req := httptest.NewRequest(http.MethodPost, "/todos/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if report := decodeReport(t, w); report.Created != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
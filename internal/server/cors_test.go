@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo/internal/ratelimit"
+	"todo/internal/storage"
+)
+
+func corsServer(origins []string, credentials bool, opts ...Option) *Server {
+	cfg := DefaultCORSConfig()
+	cfg.AllowedOrigins = origins
+	cfg.AllowCredentials = credentials
+	return setupServerWith(storage.NewStorage(), append(opts, WithCORS(cfg))...)
+}
+
+func preflight(handler http.Handler, path, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "content-type")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := corsServer([]string{"https://dashboard.example.com"}, false).Handler()
+
+	tests := []struct {
+		name          string
+		path          string
+		origin        string
+		expectedAllow string
+		expectedCORS  string
+	}{
+		{"collection", "/todos", "https://dashboard.example.com", "GET, POST, OPTIONS", "https://dashboard.example.com"},
+		{"item", "/todos/1", "https://dashboard.example.com", "GET, PUT, DELETE, OPTIONS", "https://dashboard.example.com"},
+		{"graphql", "/graphql", "https://dashboard.example.com", "GET, POST, OPTIONS", "https://dashboard.example.com"},
+		{"disallowed origin", "/todos", "https://evil.example.org", "GET, POST, OPTIONS", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := preflight(handler, tt.path, tt.origin)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("expected status %d, got %d", http.StatusNoContent, w.Code)
+			}
+			if got := w.Header().Get("Allow"); got != tt.expectedAllow {
+				t.Errorf("expected Allow %q, got %q", tt.expectedAllow, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedCORS {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.expectedCORS, got)
+			}
+			if tt.expectedCORS == "" {
+				return
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, DELETE" {
+				t.Errorf("unexpected Access-Control-Allow-Methods %q", got)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("expected Access-Control-Max-Age 600, got %q", got)
+			}
+			if w.Header().Get("Access-Control-Allow-Headers") == "" {
+				t.Error("expected Access-Control-Allow-Headers to be set")
+			}
+		})
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	tests := []struct {
+		name               string
+		origins            []string
+		credentials        bool
+		origin             string
+		expectedOrigin     string
+		expectedCredential string
+	}{
+		{"wildcard without credentials", []string{"*"}, false, "https://a.example.com", "*", ""},
+		{"wildcard with credentials echoes origin", []string{"*"}, true, "https://a.example.com", "https://a.example.com", "true"},
+		{"subdomain pattern", []string{"https://*.example.com"}, false, "https://app.example.com", "https://app.example.com", ""},
+		{"subdomain pattern rejects apex", []string{"https://*.example.com"}, false, "https://example.com", "", ""},
+		{"no origin header", []string{"*"}, false, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := corsServer(tt.origins, tt.credentials).Handler()
+
+			req := httptest.NewRequest(http.MethodGet, "/todos", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.expectedOrigin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.expectedOrigin, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.expectedCredential {
+				t.Errorf("expected Access-Control-Allow-Credentials %q, got %q", tt.expectedCredential, got)
+			}
+			if tt.expectedOrigin != "" && w.Header().Get("Access-Control-Expose-Headers") == "" {
+				t.Error("expected Access-Control-Expose-Headers to be set")
+			}
+			if w.Header().Get("Vary") != "Origin" {
+				t.Errorf("expected Vary: Origin, got %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestCORSPreflightIsNotRateLimited(t *testing.T) {
+	handler := corsServer([]string{"*"}, false, WithRateLimits(map[string]ratelimit.Rule{
+		"*": {Limit: 1, Window: time.Minute},
+	})).Handler()
+
+	for i := 0; i < 3; i++ {
+		if w := preflight(handler, "/todos", "https://a.example.com"); w.Code != http.StatusNoContent {
+			t.Fatalf("preflight %d: expected status %d, got %d", i, http.StatusNoContent, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(`{"Header":"Task"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+}
+
+func TestOptionsWithoutCORS(t *testing.T) {
+	server := setupServer()
+
+	req := httptest.NewRequest(http.MethodOptions, "/todos", nil)
+	w := httptest.NewRecorder()
+
+	server.HandleTodos(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("expected no CORS headers when CORS is disabled")
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	v1 "todo/internal/api/v1"
+	"todo/internal/codec"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+func WithLimits(limits validation.Limits) Option {
+	return func(s *Server) {
+		s.limits = limits
+	}
+}
+
+type requestError struct {
+	status  int
+	message string
+	details []validation.FieldError
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...any) *requestError {
+	return &requestError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// decodeBody reads exactly one value into v in the format named by the Content-Type, JSON when
+// it is missing, rejecting oversized bodies, invalid UTF-8, unknown fields and trailing data.
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, v any) *requestError {
+	c := s.codecs.Preferred()
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var ok bool
+		if c, ok = s.codecs.Lookup(contentType); !ok {
+			return &requestError{
+				status:  http.StatusUnsupportedMediaType,
+				message: "Unsupported media type",
+				details: []validation.FieldError{{Field: "Content-Type", Message: "must be one of " + strings.Join(s.codecs.MediaTypes(), ", ")}},
+			}
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return &requestError{
+				status:  http.StatusRequestEntityTooLarge,
+				message: fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit),
+			}
+		}
+		return badRequest("Invalid request body")
+	}
+
+	if err := c.Decode(body, v); err != nil {
+		return describeDecodeError(c, err)
+	}
+	return nil
+}
+
+func describeDecodeError(c codec.Codec, err error) *requestError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var codecErr *codec.SyntaxError
+	var xmlErr *xml.SyntaxError
+	var fieldErr *codec.UnknownFieldError
+
+	switch {
+	case errors.Is(err, codec.ErrInvalidUTF8):
+		return badRequest("Request body must be valid UTF-8")
+	case errors.Is(err, codec.ErrTrailingData):
+		if _, ok := c.(codec.JSON); ok {
+			return badRequest("Request body must contain a single JSON object")
+		}
+		return badRequest("Request body must contain a single value")
+	case errors.As(err, &syntaxErr):
+		return badRequest("Invalid request body: malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &codecErr):
+		return badRequest("Invalid request body: %s", codecErr.Error())
+	case errors.As(err, &fieldErr):
+		return badRequest("Invalid request body: unknown field %q", fieldErr.Name)
+	case errors.As(err, &xmlErr):
+		return badRequest("Invalid request body: malformed XML at line %d", xmlErr.Line)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return badRequest("Invalid request body: expected a JSON object")
+		}
+		return badRequest("Invalid request body: field %q must be of type %s", typeErr.Field, typeErr.Type)
+	case errors.Is(err, io.EOF):
+		return badRequest("Invalid request body: body must not be empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return badRequest("Invalid request body: malformed JSON")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return badRequest("Invalid request body: unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	default:
+		return badRequest("Invalid request body")
+	}
+}
+
+// decodeTask reads a task in the contract of the request's API version and validates it.
+func (s *Server) decodeTask(w http.ResponseWriter, r *http.Request) (storage.Task, bool) {
+	v := versionOf(r)
+	task, reqErr := v.decode(func(dst any) *requestError {
+		return s.decodeBody(w, r, dst)
+	})
+	if reqErr != nil {
+		s.writeRequestError(w, r, reqErr)
+		return task, false
+	}
+
+	if err := s.limits.Task(&task); err != nil {
+		reqErr := invalidTask(err)
+		reqErr.details = v.fieldErrors(reqErr.details)
+		s.writeRequestError(w, r, reqErr)
+		return task, false
+	}
+
+	return task, true
+}
+
+func invalidTask(err error) *requestError {
+	reqErr := badRequest("Invalid task")
+	var verr *validation.Error
+	if errors.As(err, &verr) {
+		reqErr.details = verr.Fields
+	}
+	return reqErr
+}
+
+var v1TaskFields = jsonFieldNames(reflect.TypeFor[v1.Task]())
+
+// commandTask validates the task of a websocket or JSON-RPC command, given in the /v1
+// contract; validation details name the fields as "task.<field>".
+func (s *Server) commandTask(dto *v1.Task) (storage.Task, error) {
+	if dto == nil {
+		return storage.Task{}, badRequest("task is required")
+	}
+	task, err := dto.Storage()
+	if err == nil {
+		err = s.limits.Task(&task)
+	}
+	if err != nil {
+		reqErr := invalidTask(err)
+		for i, f := range reqErr.details {
+			if name, ok := v1TaskFields[f.Field]; ok {
+				f.Field = name
+			}
+			reqErr.details[i].Field = "task." + f.Field
+		}
+		return storage.Task{}, reqErr
+	}
+	return task, nil
+}
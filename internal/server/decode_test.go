@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+func TestCreateTodoStrictDecoding(t *testing.T) {
+	limits := validation.DefaultLimits()
+	limits.MaxBodyBytes = 128
+	limits.MaxHeaderLength = 10
+	server := setupServerWith(storage.NewStorage(), WithLimits(limits))
+
+	tests := []struct {
+		name           string
+		payload        string
+		expectedStatus int
+		expectedError  string
+		detailField    string
+	}{
+		{
+			name:           "valid",
+			payload:        `{"Header":"Task","Status":1}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "unknown field",
+			payload:        `{"Header":"Task","Title":"oops"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  `unknown field "Title"`,
+		},
+		{
+			name:           "trailing garbage",
+			payload:        `{"Header":"Task"} garbage`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "single JSON object",
+		},
+		{
+			name:           "second object",
+			payload:        `{"Header":"Task"}{"Header":"Task"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "single JSON object",
+		},
+		{
+			name:           "trailing whitespace is fine",
+			payload:        "{\"Header\":\"Task\"}\n\n",
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "wrong type",
+			payload:        `{"Header":"Task","Status":"done"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  `field "Status" must be of type`,
+		},
+		{
+			name:           "syntax error",
+			payload:        `{"Header":}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "malformed JSON at offset",
+		},
+		{
+			name:           "empty body",
+			payload:        ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "must not be empty",
+		},
+		{
+			name:           "array instead of object",
+			payload:        `[]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "expected a JSON object",
+		},
+		{
+			name:           "too large",
+			payload:        `{"Header":"Task","Description":"` + strings.Repeat("a", 200) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedError:  "must not exceed 128 bytes",
+		},
+		{
+			name:           "invalid UTF-8",
+			payload:        "{\"Header\":\"Task \xff\"}",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "valid UTF-8",
+		},
+		{
+			name:           "header too long",
+			payload:        `{"Header":"A very long header"}`,
+			expectedStatus: http.StatusBadRequest,
+			detailField:    "Header",
+		},
+		{
+			name:           "control characters",
+			payload:        `{"Header":"Task","Description":"\u0000"}`,
+			expectedStatus: http.StatusBadRequest,
+			detailField:    "Description",
+		},
+		{
+			name:           "unknown status",
+			payload:        `{"Header":"Task","Status":9}`,
+			expectedStatus: http.StatusBadRequest,
+			detailField:    "Status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(tt.payload))
+			w := httptest.NewRecorder()
+
+			server.HandleTodos(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus < http.StatusBadRequest {
+				return
+			}
+
+			var body errorResponse
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if tt.expectedError != "" && !strings.Contains(body.Error, tt.expectedError) {
+				t.Errorf("expected error containing %q, got %q", tt.expectedError, body.Error)
+			}
+			if tt.detailField != "" && (len(body.Details) == 0 || body.Details[0].Field != tt.detailField) {
+				t.Errorf("expected details for field %s, got %v", tt.detailField, body.Details)
+			}
+		})
+	}
+}
+
+func TestCreateTodoStrictXML(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		payload        string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"valid", "/todos", `<Task><Header>x</Header><Status>1</Status></Task>`, http.StatusCreated, ""},
+		{"unknown element", "/todos", `<Task><Header>x</Header><Bogus>1</Bogus></Task>`, http.StatusBadRequest, `unknown field "Bogus"`},
+		{"unknown attribute", "/todos", `<Task id="1"><Header>x</Header></Task>`, http.StatusBadRequest, `unknown field "id"`},
+		{"element inside a value", "/todos", `<Task><Header>x<b>y</b></Header></Task>`, http.StatusBadRequest, `unknown field "b"`},
+		{"v1 valid", "/v1/todos", `<task><header>x</header><status>assigned</status></task>`, http.StatusCreated, ""},
+		{"v1 legacy name", "/v1/todos", `<task><Header>x</Header></task>`, http.StatusBadRequest, `unknown field "Header"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/xml")
+			w := httptest.NewRecorder()
+
+			setupServer().Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus < http.StatusBadRequest {
+				return
+			}
+
+			var body errorResponse
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !strings.Contains(body.Error, tt.expectedError) {
+				t.Errorf("expected error containing %q, got %q", tt.expectedError, body.Error)
+			}
+		})
+	}
+}
+
+func TestUpdateTodoTrimsHeader(t *testing.T) {
+	server := setupServer()
+	created, _ := server.storage.CreateTask(context.Background(), storage.Task{Header: "Task"})
+
+	req := httptest.NewRequest(http.MethodPut, "/todos/0", bytes.NewBufferString(`{"Header":"  Renamed  ","Status":2}`))
+	w := httptest.NewRecorder()
+
+	server.HandleTodoByID(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	got, _ := server.storage.GetByID(context.Background(), created.TaskID)
+	if got.Header != "Renamed" {
+		t.Errorf("expected header 'Renamed', got %q", got.Header)
+	}
+}
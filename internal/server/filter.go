@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	v1 "todo/internal/api/v1"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+// filterParams are the query parameters of a task list read by parseFilter.
+var filterParams = []string{"status", "owner", "q"}
+
+// parseFilter reads the status, owner and q parameters of a task list. Statuses may be
+// repeated or comma-separated, by name or number.
+func parseFilter(query url.Values) (storage.Filter, *requestError) {
+	filter := storage.Filter{Owner: query.Get("owner"), Query: query.Get("q")}
+	var details []validation.FieldError
+	for _, value := range query["status"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			status, ok := v1.Status(name).Storage()
+			if !ok {
+				details = append(details, validation.FieldError{Field: "status", Message: "unknown status " + strconv.Quote(name)})
+				continue
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	if len(details) > 0 {
+		return storage.Filter{}, &requestError{status: http.StatusBadRequest, message: "Invalid query parameters", details: details}
+	}
+	return filter, nil
+}
+
+// TaskFinder is implemented by stores that filter tasks themselves, such as a database with
+// indexes; tasks of the other stores are filtered after GetAll.
+type TaskFinder interface {
+	Find(ctx context.Context, filter storage.Filter) ([]storage.Task, error)
+}
+
+// findTasks returns the tasks matching filter in no particular order.
+func (s *Server) findTasks(ctx context.Context, filter storage.Filter) ([]storage.Task, error) {
+	if finder, ok := s.storage.(TaskFinder); ok {
+		return finder.Find(ctx, filter)
+	}
+	return findAll(ctx, s.storage, filter)
+}
+
+func findAll(ctx context.Context, st TaskStore, filter storage.Filter) ([]storage.Task, error) {
+	tasks, err := st.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matched := tasks[:0]
+	for _, task := range tasks {
+		if filter.Match(task) {
+			matched = append(matched, task)
+		}
+	}
+	return matched, nil
+}
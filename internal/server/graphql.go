@@ -0,0 +1,573 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo/internal/graphql"
+	"todo/internal/storage"
+	"todo/internal/validation"
+	"todo/internal/websocket"
+)
+
+// graphqlError is a resolver error with a machine-readable code in its extensions.
+type graphqlError struct {
+	message string
+	code    string
+	details []validation.FieldError
+}
+
+func (e *graphqlError) Error() string {
+	return e.message
+}
+
+func (e *graphqlError) Extensions() map[string]any {
+	ext := map[string]any{"code": e.code}
+	if len(e.details) > 0 {
+		ext["details"] = e.details
+	}
+	return ext
+}
+
+// graphqlErr maps errors the same way the HTTP handlers map them to status codes.
+func graphqlErr(err error) error {
+	var coded *graphqlError
+	var invalid *validation.Error
+	switch {
+	case errors.As(err, &coded):
+		return coded
+	case errors.Is(err, errNotOwner):
+		return &graphqlError{message: err.Error(), code: "FORBIDDEN"}
+	case errors.As(err, &invalid):
+		details := make([]validation.FieldError, len(invalid.Fields))
+		for i, f := range invalid.Fields {
+			details[i] = validation.FieldError{Field: "input." + strings.ToLower(f.Field), Message: f.Message}
+		}
+		return &graphqlError{message: "Invalid task", code: "BAD_USER_INPUT", details: details}
+	case errors.Is(err, storage.ErrWrongArgument):
+		return &graphqlError{message: err.Error(), code: "BAD_USER_INPUT"}
+	case errors.Is(err, storage.ErrTaskNotFound):
+		return &graphqlError{message: "Task not found", code: "NOT_FOUND"}
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return &graphqlError{message: err.Error(), code: "QUOTA_EXCEEDED"}
+	case errors.Is(err, storage.ErrStorageClosed):
+		return &graphqlError{message: err.Error(), code: "UNAVAILABLE"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &graphqlError{message: err.Error(), code: "TIMEOUT"}
+	default:
+		return &graphqlError{message: err.Error(), code: "INTERNAL"}
+	}
+}
+
+// resolve wraps a resolver so its errors carry codes.
+func resolve(fn graphql.ResolveFunc) graphql.ResolveFunc {
+	return func(p graphql.ResolveParams) (any, error) {
+		v, err := fn(p)
+		if err != nil {
+			return nil, graphqlErr(err)
+		}
+		return v, nil
+	}
+}
+
+// taskConnection is a page of tasks; total counts the matches on every page.
+type taskConnection struct {
+	nodes []storage.Task
+	total int
+	more  bool
+}
+
+type statusCount struct {
+	status storage.TaskStatus
+	count  int
+}
+
+// graphqlSchema describes tasks for GraphQL clients; GET /graphql/schema.graphql prints it.
+func (s *Server) graphqlSchema() *graphql.Schema {
+	taskStatus := &graphql.Enum{Name: "TaskStatus", Values: []*graphql.EnumValueDef{
+		{Name: "ASSIGNED", Value: storage.Assigned},
+		{Name: "IN_PROGRESS", Value: storage.InProgress},
+		{Name: "COMPLETED", Value: storage.Completed},
+		{Name: "DROPPED", Value: storage.Dropped},
+	}}
+
+	taskField := func(name string, t graphql.Type, get func(task storage.Task) any) *graphql.FieldDef {
+		return &graphql.FieldDef{Name: name, Type: t, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return get(p.Source.(storage.Task)), nil
+		}}
+	}
+	task := &graphql.Object{Name: "Task", Fields: []*graphql.FieldDef{
+		taskField("id", graphql.NonNullOf(graphql.ID), func(t storage.Task) any { return t.TaskID }),
+		taskField("header", graphql.NonNullOf(graphql.String), func(t storage.Task) any { return t.Header }),
+		taskField("description", graphql.NonNullOf(graphql.String), func(t storage.Task) any { return t.Description }),
+		taskField("status", graphql.NonNullOf(taskStatus), func(t storage.Task) any { return t.Status }),
+		{
+			Name:        "owner",
+			Description: "Identity of the creator, empty for tasks created before owners were recorded.",
+			Type:        graphql.NonNullOf(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(storage.Task).Owner, nil
+			},
+		},
+	}}
+
+	pageInfo := &graphql.Object{Name: "PageInfo", Fields: []*graphql.FieldDef{
+		{Name: "hasNextPage", Type: graphql.NonNullOf(graphql.Boolean), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(*taskConnection).more, nil
+		}},
+		{
+			Name:        "endCursor",
+			Description: "Pass as `after` to get the next page.",
+			Type:        graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				conn := p.Source.(*taskConnection)
+				if len(conn.nodes) == 0 {
+					return nil, nil
+				}
+				return strconv.Itoa(conn.nodes[len(conn.nodes)-1].TaskID), nil
+			},
+		},
+	}}
+	connection := &graphql.Object{Name: "TaskConnection", Fields: []*graphql.FieldDef{
+		{Name: "nodes", Type: graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(task))), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(*taskConnection).nodes, nil
+		}},
+		{
+			Name:        "totalCount",
+			Description: "Tasks matching the filter on all pages.",
+			Type:        graphql.NonNullOf(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*taskConnection).total, nil
+			},
+		},
+		{Name: "pageInfo", Type: graphql.NonNullOf(pageInfo), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source, nil
+		}},
+	}}
+	count := &graphql.Object{Name: "StatusCount", Fields: []*graphql.FieldDef{
+		{Name: "status", Type: graphql.NonNullOf(taskStatus), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(statusCount).status, nil
+		}},
+		{Name: "count", Type: graphql.NonNullOf(graphql.Int), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(statusCount).count, nil
+		}},
+	}}
+
+	filter := &graphql.InputObject{
+		Name:        "TaskFilter",
+		Description: "Conditions are combined with AND; query matches header and description, ignoring case.",
+		Fields: []*graphql.ArgumentDef{
+			{Name: "statuses", Type: graphql.ListOf(graphql.NonNullOf(taskStatus))},
+			{Name: "owner", Type: graphql.String},
+			{Name: "query", Type: graphql.String},
+		},
+	}
+	input := &graphql.InputObject{Name: "TaskInput", Fields: []*graphql.ArgumentDef{
+		{Name: "header", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "description", Type: graphql.String, Default: ""},
+		{Name: "status", Type: taskStatus, Default: storage.Assigned},
+	}}
+	idArg := []*graphql.ArgumentDef{{Name: "id", Type: graphql.NonNullOf(graphql.ID)}}
+
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.FieldDef{
+		{
+			Name:        "task",
+			Description: "The task with the given ID, or null if there is none.",
+			Type:        task,
+			Args:        idArg,
+			Resolve:     resolve(s.resolveTask),
+		},
+		{
+			Name:        "tasks",
+			Description: "Tasks ordered by ID, all of them unless first is given.",
+			Type:        graphql.NonNullOf(connection),
+			Args: []*graphql.ArgumentDef{
+				{Name: "filter", Type: filter},
+				{Name: "first", Type: graphql.Int},
+				{Name: "after", Type: graphql.String},
+			},
+			Resolve: resolve(s.resolveTasks),
+		},
+		{
+			Name:    "taskCounts",
+			Type:    graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(count))),
+			Resolve: resolve(s.resolveTaskCounts),
+		},
+	}}
+	mutation := &graphql.Object{Name: "Mutation", Fields: []*graphql.FieldDef{
+		{
+			Name:    "createTask",
+			Type:    graphql.NonNullOf(task),
+			Args:    []*graphql.ArgumentDef{{Name: "input", Type: graphql.NonNullOf(input)}},
+			Resolve: resolve(s.resolveCreateTask),
+		},
+		{
+			Name:    "updateTask",
+			Type:    graphql.NonNullOf(task),
+			Args:    append(idArg, &graphql.ArgumentDef{Name: "input", Type: graphql.NonNullOf(input)}),
+			Resolve: resolve(s.resolveUpdateTask),
+		},
+		{
+			Name:        "deleteTask",
+			Description: "Returns the ID of the deleted task.",
+			Type:        graphql.NonNullOf(graphql.ID),
+			Args:        idArg,
+			Resolve:     resolve(s.resolveDeleteTask),
+		},
+	}}
+
+	eventType := &graphql.Enum{Name: "TaskEventType", Values: []*graphql.EnumValueDef{
+		{Name: "CREATED", Value: storage.EventCreated},
+		{Name: "UPDATED", Value: storage.EventUpdated},
+		{Name: "DELETED", Value: storage.EventDeleted},
+	}}
+	event := &graphql.Object{Name: "TaskEvent", Fields: []*graphql.FieldDef{
+		{Name: "type", Type: graphql.NonNullOf(eventType), Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(storage.Event).Type, nil
+		}},
+		{
+			Name:        "task",
+			Description: "The task after the change; deleted tasks as they were.",
+			Type:        graphql.NonNullOf(task),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(storage.Event).Task, nil
+			},
+		},
+	}}
+	subscription := &graphql.Object{Name: "Subscription", Fields: []*graphql.FieldDef{{
+		Name:        "taskChanged",
+		Description: "Changes to tasks matching the filter, from the time of subscribing.",
+		Type:        graphql.NonNullOf(event),
+		Args:        []*graphql.ArgumentDef{{Name: "filter", Type: filter}},
+		Subscribe:   s.subscribeTaskChanged,
+	}}}
+
+	schema, err := graphql.NewSchema(query, mutation, subscription)
+	if err != nil {
+		// The schema is fixed, so this is a programming error.
+		panic(err)
+	}
+	return schema
+}
+
+func parseGraphQLID(v any) (int, error) {
+	id, err := strconv.Atoi(v.(string))
+	if err != nil || id < 0 {
+		return 0, &graphqlError{message: "Invalid task ID " + strconv.Quote(v.(string)), code: "BAD_USER_INPUT"}
+	}
+	return id, nil
+}
+
+// filterArg reads a TaskFilter argument, which may be absent or null.
+func filterArg(v any) storage.Filter {
+	fields, _ := v.(map[string]any)
+	var statuses []storage.TaskStatus
+	list, _ := fields["statuses"].([]any)
+	for _, status := range list {
+		statuses = append(statuses, status.(storage.TaskStatus))
+	}
+	owner, _ := fields["owner"].(string)
+	query, _ := fields["query"].(string)
+	return storage.Filter{Statuses: statuses, Owner: owner, Query: query}
+}
+
+// inputTask validates a TaskInput argument.
+func (s *Server) inputTask(v any) (storage.Task, error) {
+	fields := v.(map[string]any)
+	task := storage.Task{Header: fields["header"].(string)}
+	if description, ok := fields["description"].(string); ok {
+		task.Description = description
+	}
+	if status, ok := fields["status"].(storage.TaskStatus); ok {
+		task.Status = status
+	}
+	if err := s.limits.Task(&task); err != nil {
+		return storage.Task{}, err
+	}
+	return task, nil
+}
+
+func (s *Server) resolveTask(p graphql.ResolveParams) (any, error) {
+	id, err := parseGraphQLID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.storage.GetByID(p.Context, id)
+	if errors.Is(err, storage.ErrTaskNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *Server) resolveTasks(p graphql.ResolveParams) (any, error) {
+	pg := page{after: -1}
+	var details []validation.FieldError
+	if first, ok := p.Args["first"].(int); ok {
+		if first < 0 || first > MaxPageSize {
+			details = append(details, validation.FieldError{Field: "first", Message: "must be between 0 and " + strconv.Itoa(MaxPageSize)})
+		}
+		pg.limit = first
+	}
+	if after, ok := p.Args["after"].(string); ok {
+		n, err := strconv.Atoi(after)
+		if err != nil || n < 0 {
+			details = append(details, validation.FieldError{Field: "after", Message: "must be an endCursor"})
+		}
+		pg.after = n
+	}
+	if len(details) > 0 {
+		return nil, &graphqlError{message: "Invalid page", code: "BAD_USER_INPUT", details: details}
+	}
+
+	matched, err := s.findTasks(p.Context, filterArg(p.Args["filter"]))
+	if err != nil {
+		return nil, err
+	}
+	total := len(matched)
+
+	conn := &taskConnection{total: total}
+	if first, ok := p.Args["first"].(int); ok && first == 0 {
+		// page treats a zero limit as no limit; here it asks for the count only.
+		conn.nodes = []storage.Task{}
+		conn.more = total > 0
+		return conn, nil
+	}
+	var next string
+	conn.nodes, next = pg.apply(matched)
+	conn.more = next != ""
+	return conn, nil
+}
+
+func (s *Server) resolveTaskCounts(p graphql.ResolveParams) (any, error) {
+	counts := s.storage.CountByStatus()
+	result := make([]statusCount, len(storage.Statuses))
+	for i, status := range storage.Statuses {
+		result[i] = statusCount{status: status, count: counts[status]}
+	}
+	return result, nil
+}
+
+func (s *Server) resolveCreateTask(p graphql.ResolveParams) (any, error) {
+	task, err := s.inputTask(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+	task.Owner = s.owner(p.Context)
+	created, err := s.storage.CreateTask(p.Context, task)
+	if err != nil {
+		return nil, err
+	}
+	return *created, nil
+}
+
+func (s *Server) resolveUpdateTask(p graphql.ResolveParams) (any, error) {
+	id, err := parseGraphQLID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.inputTask(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(p.Context, id); err != nil {
+		return nil, err
+	}
+	updated, err := s.storage.Update(p.Context, id, &task)
+	if err != nil {
+		return nil, err
+	}
+	return *updated, nil
+}
+
+func (s *Server) resolveDeleteTask(p graphql.ResolveParams) (any, error) {
+	id, err := parseGraphQLID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(p.Context, id); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Delete(p.Context, id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// subscribeTaskChanged forwards storage events. A watcher that falls behind is dropped like in
+// the gRPC Watch call; the stream then ends with an error and the client queries again.
+func (s *Server) subscribeTaskChanged(p graphql.ResolveParams) (<-chan any, error) {
+	if s.watcher == nil {
+		return nil, &graphqlError{message: "The storage does not publish changes", code: "UNAVAILABLE"}
+	}
+	filter := filterArg(p.Args["filter"])
+	events, cancel := s.watcher.Subscribe(watchBuffer)
+
+	out := make(chan any)
+	go func() {
+		defer close(out)
+		defer cancel()
+		for {
+			var item any
+			select {
+			case <-p.Context.Done():
+				return
+			case event, ok := <-events:
+				switch {
+				case !ok && s.shuttingDown.Load():
+					item = &graphqlError{message: "Server is shutting down", code: "UNAVAILABLE"}
+				case !ok:
+					item = &graphqlError{message: "Subscriber fell behind, query the tasks and subscribe again", code: "RESOURCE_EXHAUSTED"}
+				case !filter.Match(event.Task):
+					continue
+				default:
+					item = event
+				}
+			}
+
+			select {
+			case out <- item:
+			case <-p.Context.Done():
+				return
+			}
+			if _, failed := item.(error); failed {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// HandleGraphQL serves queries and mutations as JSON over HTTP, and every operation including
+// subscriptions over a websocket speaking graphql-transport-ws on the same path.
+func (s *Server) HandleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsUpgrade(r) {
+		s.serveGraphQLWS(w, r)
+		return
+	}
+
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		var errMsg string
+		if req, errMsg = graphqlQueryRequest(r.URL.Query()); errMsg != "" {
+			s.writeGraphQLError(w, r, http.StatusBadRequest, errMsg)
+			return
+		}
+	case http.MethodPost:
+		if status, errMsg := s.decodeGraphQLBody(w, r, &req); errMsg != "" {
+			s.writeGraphQLError(w, r, status, errMsg)
+			return
+		}
+	case http.MethodOptions:
+		s.handleOptions(w, http.MethodGet, http.MethodPost)
+		return
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		s.writeGraphQLError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+		return
+	}
+
+	prepared, resp := s.graphql.Prepare(req)
+	if resp != nil {
+		s.writeGraphQL(w, r, http.StatusBadRequest, resp)
+		return
+	}
+	if r.Method == http.MethodGet && prepared.Kind() != graphql.Query {
+		// GET must be safe, so only queries may use it.
+		w.Header().Set("Allow", http.MethodPost)
+		s.writeGraphQLError(w, r, http.StatusMethodNotAllowed, "Only queries can be sent with GET")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), SecToTimeout*time.Second)
+	defer cancel()
+	resp = prepared.Execute(ctx)
+	status := http.StatusOK
+	if !resp.Executed() {
+		status = http.StatusBadRequest
+	}
+	s.writeGraphQL(w, r, status, resp)
+}
+
+// HandleGraphQLSchema prints the schema in SDL for code generators and editors.
+func (s *Server) HandleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, s.graphql.SDL())
+}
+
+func graphqlQueryRequest(query url.Values) (graphql.Request, string) {
+	req := graphql.Request{Query: query.Get("query"), OperationName: query.Get("operationName")}
+	if req.Query == "" {
+		return req, "The query parameter is required"
+	}
+	if raw := query.Get("variables"); raw != "" {
+		if err := decodeGraphQLJSON(strings.NewReader(raw), &req.Variables); err != nil {
+			return req, "The variables parameter must be a JSON object"
+		}
+	}
+	return req, ""
+}
+
+// decodeGraphQLBody reads a JSON request body; it returns a status and message on failure.
+func (s *Server) decodeGraphQLBody(w http.ResponseWriter, r *http.Request, req *graphql.Request) (int, string) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return http.StatusUnsupportedMediaType, "Content-Type must be application/json"
+	}
+	err := decodeGraphQLJSON(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes), req)
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge, "Request body must not exceed " + strconv.FormatInt(maxBytesErr.Limit, 10) + " bytes"
+	case err != nil:
+		return http.StatusBadRequest, "Request body must be a JSON object with a query"
+	case req.Query == "":
+		return http.StatusBadRequest, "The query field is required"
+	}
+	return 0, ""
+}
+
+// decodeGraphQLJSON keeps numbers as json.Number so Int and ID variables are exact.
+func decodeGraphQLJSON(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("trailing data")
+	}
+	return nil
+}
+
+// writeGraphQL sends a GraphQL response; unlike the REST API, GraphQL responses are always
+// JSON.
+func (s *Server) writeGraphQL(w http.ResponseWriter, r *http.Request, status int, resp *graphql.Response) {
+	contentType := "application/json"
+	if strings.Contains(r.Header.Get("Accept"), "application/graphql-response+json") {
+		contentType = "application/graphql-response+json"
+	}
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to encode graphql response", "error", err)
+	}
+}
+
+func (s *Server) writeGraphQLError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	s.writeGraphQL(w, r, status, &graphql.Response{Errors: []*graphql.Error{{Message: message}}})
+}
@@ -0,0 +1,489 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"todo/internal/storage"
+	"todo/internal/websocket"
+)
+
+func postGraphQL(t *testing.T, handler http.Handler, token, query string, variables map[string]any) (int, string) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func TestGraphQLTasks(t *testing.T) {
+	handler := setupServer().Handler()
+
+	steps := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		want      string
+	}{
+		{
+			name:  "create",
+			query: `mutation { a: createTask(input: {header: "Buy milk"}) { id header description status } b: createTask(input: {header: "Walk", description: "dog", status: COMPLETED}) { id status } }`,
+			want:  `{"data":{"a":{"id":"0","header":"Buy milk","description":"","status":"ASSIGNED"},"b":{"id":"1","status":"COMPLETED"}}}`,
+		},
+		{
+			name:      "create with variables",
+			query:     `mutation ($in: TaskInput!) { createTask(input: $in) { id owner } }`,
+			variables: map[string]any{"in": map[string]any{"header": "Call mom", "status": "IN_PROGRESS"}},
+			want:      `{"data":{"createTask":{"id":"2","owner":"ip:9dff98970af6f08a"}}}`,
+		},
+		{
+			name:  "update",
+			query: `mutation { updateTask(id: 0, input: {header: "Buy oat milk", status: IN_PROGRESS}) { header status } }`,
+			want:  `{"data":{"updateTask":{"header":"Buy oat milk","status":"IN_PROGRESS"}}}`,
+		},
+		{
+			name:  "get",
+			query: `{ task(id: "0") { header } missing: task(id: 42) { header } }`,
+			want:  `{"data":{"task":{"header":"Buy oat milk"},"missing":null}}`,
+		},
+		{
+			name:  "filter and page",
+			query: `{ tasks(filter: {statuses: [IN_PROGRESS]}, first: 1) { totalCount nodes { id } pageInfo { hasNextPage endCursor } } }`,
+			want:  `{"data":{"tasks":{"totalCount":2,"nodes":[{"id":"0"}],"pageInfo":{"hasNextPage":true,"endCursor":"0"}}}}`,
+		},
+		{
+			name:  "next page",
+			query: `{ tasks(filter: {statuses: [IN_PROGRESS]}, first: 1, after: "0") { nodes { id } pageInfo { hasNextPage endCursor } } }`,
+			want:  `{"data":{"tasks":{"nodes":[{"id":"2"}],"pageInfo":{"hasNextPage":false,"endCursor":"2"}}}}`,
+		},
+		{
+			name:  "search and count only",
+			query: `{ tasks(filter: {query: "DOG"}, first: 0) { totalCount nodes { id } pageInfo { endCursor } } }`,
+			want:  `{"data":{"tasks":{"totalCount":1,"nodes":[],"pageInfo":{"endCursor":null}}}}`,
+		},
+		{
+			name:  "related data in one round-trip",
+			query: `{ taskCounts { status count } all: tasks { totalCount } }`,
+			want:  `{"data":{"taskCounts":[{"status":"ASSIGNED","count":0},{"status":"IN_PROGRESS","count":2},{"status":"COMPLETED","count":1},{"status":"DROPPED","count":0}],"all":{"totalCount":3}}}`,
+		},
+		{
+			name:  "delete",
+			query: `mutation { deleteTask(id: 1) }`,
+			want:  `{"data":{"deleteTask":"1"}}`,
+		},
+		{
+			name:  "deleted",
+			query: `{ task(id: 1) { id } }`,
+			want:  `{"data":{"task":null}}`,
+		},
+	}
+
+	for _, step := range steps {
+		code, body := postGraphQL(t, handler, "", step.query, step.variables)
+		if code != http.StatusOK || body != step.want {
+			t.Fatalf("%s: expected 200 %s, got %d %s", step.name, step.want, code, body)
+		}
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	st := storage.NewStorage()
+	handler := setupServerWith(st, WithOwnershipEnforcement()).Handler()
+	postGraphQL(t, handler, "alice", `mutation { createTask(input: {header: "Alice's"}) { id } }`, nil)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "invalid input",
+			query: `mutation { createTask(input: {header: "   "}) { id } }`,
+			want:  `{"data":null,"errors":[{"message":"Invalid task","locations":[{"line":1,"column":12}],"path":["createTask"],"extensions":{"code":"BAD_USER_INPUT","details":[{"field":"input.header","message":"must not be empty"}]}}]}`,
+		},
+		{
+			name:  "invalid id",
+			query: `{ task(id: "abc") { id } }`,
+			want:  `{"data":{"task":null},"errors":[{"message":"Invalid task ID \"abc\"","locations":[{"line":1,"column":3}],"path":["task"],"extensions":{"code":"BAD_USER_INPUT"}}]}`,
+		},
+		{
+			name:  "not found",
+			query: `mutation { deleteTask(id: 7) }`,
+			want:  `{"data":null,"errors":[{"message":"Task not found","locations":[{"line":1,"column":12}],"path":["deleteTask"],"extensions":{"code":"NOT_FOUND"}}]}`,
+		},
+		{
+			name:  "other owner",
+			query: `mutation { updateTask(id: 0, input: {header: "Mine"}) { id } }`,
+			want:  `{"data":null,"errors":[{"message":"Task belongs to another owner","locations":[{"line":1,"column":12}],"path":["updateTask"],"extensions":{"code":"FORBIDDEN"}}]}`,
+		},
+		{
+			name:  "bad page",
+			query: `{ tasks(first: 5000, after: "x") { totalCount } }`,
+			want:  `{"data":null,"errors":[{"message":"Invalid page","locations":[{"line":1,"column":3}],"path":["tasks"],"extensions":{"code":"BAD_USER_INPUT","details":[{"field":"first","message":"must be between 0 and 1000"},{"field":"after","message":"must be an endCursor"}]}}]}`,
+		},
+		{
+			name:  "partial data",
+			query: `{ ok: task(id: 0) { header } bad: task(id: "-1") { header } }`,
+			want:  `{"data":{"ok":{"header":"Alice's"},"bad":null},"errors":[{"message":"Invalid task ID \"-1\"","locations":[{"line":1,"column":30}],"path":["bad"],"extensions":{"code":"BAD_USER_INPUT"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := postGraphQL(t, handler, "bob", tt.query, nil)
+			if code != http.StatusOK || body != tt.want {
+				t.Errorf("expected 200 %s, got %d %s", tt.want, code, body)
+			}
+		})
+	}
+
+	if task, _ := st.GetByID(context.Background(), 0); task.Header != "Alice's" {
+		t.Errorf("expected the task to be unchanged, got %q", task.Header)
+	}
+}
+
+func TestGraphQLHTTP(t *testing.T) {
+	handler := setupServer().Handler()
+	get := func(params url.Values) *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/graphql?"+params.Encode(), nil)
+	}
+	post := func(contentType, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		return req
+	}
+
+	tests := []struct {
+		name        string
+		req         *http.Request
+		accept      string
+		status      int
+		contentType string
+		body        string
+		allow       string
+	}{
+		{
+			name:   "get query with variables",
+			req:    get(url.Values{"query": {`query ($n: Int) { tasks(first: $n) { totalCount } }`}, "variables": {`{"n": 5}`}}),
+			status: http.StatusOK,
+			body:   `{"data":{"tasks":{"totalCount":0}}}`,
+		},
+		{
+			name:   "get mutation",
+			req:    get(url.Values{"query": {`mutation { deleteTask(id: 0) }`}}),
+			status: http.StatusMethodNotAllowed,
+			body:   `{"errors":[{"message":"Only queries can be sent with GET"}]}`,
+			allow:  "POST",
+		},
+		{
+			name:   "get without query",
+			req:    get(url.Values{}),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"The query parameter is required"}]}`,
+		},
+		{
+			name:   "get with bad variables",
+			req:    get(url.Values{"query": {`{ taskCounts { count } }`}, "variables": {`[1]`}}),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"The variables parameter must be a JSON object"}]}`,
+		},
+		{
+			name:   "other method",
+			req:    httptest.NewRequest(http.MethodPut, "/graphql", nil),
+			status: http.StatusMethodNotAllowed,
+			body:   `{"errors":[{"message":"Method is not allowed"}]}`,
+			allow:  "GET, POST",
+		},
+		{
+			name:   "wrong content type",
+			req:    post("text/plain", `{"query": "{ taskCounts { count } }"}`),
+			status: http.StatusUnsupportedMediaType,
+			body:   `{"errors":[{"message":"Content-Type must be application/json"}]}`,
+		},
+		{
+			name:   "malformed body",
+			req:    post("application/json", `{"query": `),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"Request body must be a JSON object with a query"}]}`,
+		},
+		{
+			name:   "trailing data",
+			req:    post("application/json", `{"query": "{ taskCounts { count } }"} {}`),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"Request body must be a JSON object with a query"}]}`,
+		},
+		{
+			name:   "missing query",
+			req:    post("application/json; charset=utf-8", `{"variables": {}}`),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"The query field is required"}]}`,
+		},
+		{
+			name:   "invalid document",
+			req:    post("application/json", `{"query": "{ tasks { nope } }"}`),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"Cannot query field \"nope\" on type \"TaskConnection\".","locations":[{"line":1,"column":11}]}]}`,
+		},
+		{
+			name:   "subscription over http",
+			req:    post("application/json", `{"query": "subscription { taskChanged { type } }"}`),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"Subscriptions need a streaming transport such as a websocket.","locations":[{"line":1,"column":1}]}]}`,
+		},
+		{
+			name:        "graphql response media type",
+			req:         post("application/json", `{"query": "{ taskCounts { count } }", "operationName": null}`),
+			accept:      "application/graphql-response+json, application/json",
+			status:      http.StatusOK,
+			contentType: "application/graphql-response+json; charset=utf-8",
+			body:        `{"data":{"taskCounts":[{"count":0},{"count":0},{"count":0},{"count":0}]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.accept != "" {
+				tt.req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.req)
+
+			if w.Code != tt.status || strings.TrimSpace(w.Body.String()) != tt.body {
+				t.Errorf("expected %d %s, got %d %s", tt.status, tt.body, w.Code, w.Body.String())
+			}
+			if tt.contentType == "" {
+				tt.contentType = "application/json; charset=utf-8"
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("expected Content-Type %s, got %s", tt.contentType, got)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("expected Allow %q, got %q", tt.allow, got)
+			}
+		})
+	}
+}
+
+func TestGraphQLSchemaSDL(t *testing.T) {
+	handler := setupServer().Handler()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graphql/schema.graphql", nil))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"enum TaskStatus {\n  ASSIGNED\n  IN_PROGRESS\n  COMPLETED\n  DROPPED\n}",
+		"  tasks(filter: TaskFilter, first: Int, after: String): TaskConnection!\n",
+		"input TaskInput {\n  header: String!\n  description: String = \"\"\n  status: TaskStatus = ASSIGNED\n}",
+		"  taskChanged(filter: TaskFilter): TaskEvent!\n",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("expected the schema to contain %q, got\n%s", want, w.Body.String())
+		}
+	}
+}
+
+// graphqlWSClient speaks graphql-transport-ws in tests.
+type graphqlWSClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func dialGraphQL(t *testing.T, baseURL string, protocol string) *graphqlWSClient {
+	t.Helper()
+	header := http.Header{}
+	if protocol != "" {
+		header.Set("Sec-WebSocket-Protocol", protocol)
+	}
+	conn, _, err := websocket.Dial(context.Background(), baseURL+"/graphql", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &graphqlWSClient{t: t, conn: conn}
+}
+
+func (c *graphqlWSClient) send(msg string) {
+	c.t.Helper()
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *graphqlWSClient) read() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return string(data)
+}
+
+func (c *graphqlWSClient) expect(want string) {
+	c.t.Helper()
+	if got := c.read(); got != want {
+		c.t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func (c *graphqlWSClient) expectClose(code int) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, data, err := c.conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			if closeErr.Code != code {
+				c.t.Errorf("expected close %d, got %v", code, closeErr)
+			}
+			return
+		}
+		if err != nil {
+			c.t.Fatalf("expected close %d, got %v", code, err)
+		}
+		c.t.Logf("skipping message %s", data)
+	}
+}
+
+func (c *graphqlWSClient) init() {
+	c.t.Helper()
+	c.send(`{"type":"connection_init","payload":{}}`)
+	c.expect(`{"type":"connection_ack"}`)
+}
+
+func TestGraphQLWebsocket(t *testing.T) {
+	srv := httptest.NewServer(setupServer().Handler())
+	defer srv.Close()
+	c := dialGraphQL(t, srv.URL, "graphql-transport-ws")
+	if c.conn.Subprotocol() != "graphql-transport-ws" {
+		t.Fatalf("expected graphql-transport-ws, got %q", c.conn.Subprotocol())
+	}
+	c.init()
+
+	c.send(`{"type":"ping"}`)
+	c.expect(`{"type":"pong"}`)
+
+	c.send(`{"id":"sub","type":"subscribe","payload":{"query":"subscription ($s: [TaskStatus!]) { taskChanged(filter: {statuses: $s}) { type task { id status } } }","variables":{"s":["COMPLETED"]}}}`)
+	c.send(`{"id":"m1","type":"subscribe","payload":{"query":"mutation { createTask(input: {header: \"a\"}) { id } }"}}`)
+	c.expect(`{"id":"m1","type":"next","payload":{"data":{"createTask":{"id":"0"}}}}`)
+	c.expect(`{"id":"m1","type":"complete"}`)
+
+	c.send(`{"id":"m2","type":"subscribe","payload":{"query":"mutation { updateTask(id: 0, input: {header: \"a\", status: COMPLETED}) { id } }"}}`)
+	// The order of the event and the mutation result is up to the scheduler.
+	got := []string{c.read(), c.read(), c.read()}
+	for _, want := range []string{
+		`{"id":"sub","type":"next","payload":{"data":{"taskChanged":{"type":"UPDATED","task":{"id":"0","status":"COMPLETED"}}}}}`,
+		`{"id":"m2","type":"next","payload":{"data":{"updateTask":{"id":"0"}}}}`,
+		`{"id":"m2","type":"complete"}`,
+	} {
+		if !strings.Contains(strings.Join(got, "\n"), want) {
+			t.Errorf("expected %s among\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+
+	c.send(`{"id":"sub","type":"complete"}`)
+	c.send(`{"id":"q","type":"subscribe","payload":{"query":"{ nope }"}}`)
+	c.expect(`{"id":"q","type":"error","payload":[{"message":"Cannot query field \"nope\" on type \"Query\".","locations":[{"line":1,"column":3}]}]}`)
+	c.send(`{"id":"m3","type":"subscribe","payload":{"query":"mutation { deleteTask(id: 0) }"}}`)
+	// The completed subscription stays silent about the deletion.
+	c.expect(`{"id":"m3","type":"next","payload":{"data":{"deleteTask":"0"}}}`)
+	c.expect(`{"id":"m3","type":"complete"}`)
+
+	if err := c.conn.Close(websocket.CloseNormal, ""); err != nil {
+		t.Fatal(err)
+	}
+	c.expectClose(websocket.CloseNormal)
+}
+
+func TestGraphQLWebsocketProtocolErrors(t *testing.T) {
+	defer func(timeout time.Duration) { graphqlInitTimeout = timeout }(graphqlInitTimeout)
+	graphqlInitTimeout = 200 * time.Millisecond
+	srv := httptest.NewServer(setupServer().Handler())
+	defer srv.Close()
+
+	subscription := `{"id":"1","type":"subscribe","payload":{"query":"subscription { taskChanged { type } }"}}`
+	tests := []struct {
+		name     string
+		protocol string
+		init     bool
+		messages []string
+		code     int
+	}{
+		{"no subprotocol", "graphql-ws", false, nil, 4406},
+		{"init timeout", "graphql-transport-ws", false, nil, 4408},
+		{"subscribe before init", "graphql-transport-ws", false, []string{subscription}, 4401},
+		{"second init", "graphql-transport-ws", true, []string{`{"type":"connection_init"}`}, 4429},
+		{"duplicate id", "graphql-transport-ws", true, []string{subscription, subscription}, 4409},
+		{"invalid json", "graphql-transport-ws", true, []string{`{"type":`}, 4400},
+		{"unknown type", "graphql-transport-ws", true, []string{`{"type":"start"}`}, 4400},
+		{"subscribe without id", "graphql-transport-ws", true, []string{`{"type":"subscribe","payload":{"query":"{ taskCounts { count } }"}}`}, 4400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := dialGraphQL(t, srv.URL, tt.protocol)
+			if tt.init {
+				c.init()
+			}
+			for _, msg := range tt.messages {
+				c.send(msg)
+			}
+			c.expectClose(tt.code)
+		})
+	}
+}
+
+func TestGraphQLWebsocketRejectsBadHandshake(t *testing.T) {
+	handler := setupServer().Handler()
+	req := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUpgradeRequired {
+		t.Errorf("expected status %d, got %d", http.StatusUpgradeRequired, w.Code)
+	}
+}
+
+func TestShutdownEndsGraphQLSubscriptions(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := setupServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.serve(ctx, ln, nil, DefaultConfig()) }()
+
+	c := dialGraphQL(t, "http://"+ln.Addr().String(), "graphql-transport-ws")
+	c.init()
+	c.send(`{"id":"1","type":"subscribe","payload":{"query":"subscription { taskChanged { type } }"}}`)
+	c.send(`{"type":"ping"}`)
+	c.expect(`{"type":"pong"}`)
+
+	cancel()
+	c.expectClose(websocket.CloseGoingAway)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
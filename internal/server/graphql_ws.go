@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+	"todo/internal/graphql"
+	"todo/internal/websocket"
+)
+
+// graphqlWSProtocol is the graphql-transport-ws subprotocol, see
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+const graphqlWSProtocol = "graphql-transport-ws"
+
+// Close codes of graphql-transport-ws.
+const (
+	closeBadRequest          = 4400
+	closeUnauthorized        = 4401
+	closeSubprotocol         = 4406
+	closeInitTimeout         = 4408
+	closeSubscriberExists    = 4409
+	closeTooManyInitRequests = 4429
+)
+
+const graphqlWSWriteTimeout = 10 * time.Second
+
+// graphqlInitTimeout is how long a client may take to send connection_init.
+var graphqlInitTimeout = 10 * time.Second
+
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlSession is one websocket connection; the read loop owns it and every operation runs
+// in a goroutine of its own.
+type graphqlSession struct {
+	s    *Server
+	conn *websocket.Conn
+	ctx  context.Context
+
+	mu      sync.Mutex
+	acked   bool
+	closing bool
+	ops     map[string]context.CancelFunc
+	running sync.WaitGroup
+}
+
+func (s *Server) serveGraphQLWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r, []string{graphqlWSProtocol})
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	sess := &graphqlSession{s: s, conn: conn, ctx: ctx, ops: map[string]context.CancelFunc{}}
+	if conn.Subprotocol() != graphqlWSProtocol {
+		sess.close(closeSubprotocol, "Subprotocol not acceptable")
+	}
+
+	initTimer := time.AfterFunc(graphqlInitTimeout, func() {
+		sess.mu.Lock()
+		acked := sess.acked
+		sess.mu.Unlock()
+		if !acked {
+			sess.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	go func() {
+		select {
+		case <-s.streamsDone:
+			sess.close(websocket.CloseGoingAway, "Server is shutting down")
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		if typ != websocket.TextMessage {
+			sess.close(closeBadRequest, "Messages must be text")
+			continue
+		}
+		sess.handle(data)
+	}
+
+	// Stop the operations before the handler returns and the request context goes away.
+	cancel()
+	sess.running.Wait()
+}
+
+// close starts the closing handshake; messages that arrive meanwhile are ignored.
+func (g *graphqlSession) close(code int, reason string) {
+	g.mu.Lock()
+	g.closing = true
+	for _, cancel := range g.ops {
+		cancel()
+	}
+	g.mu.Unlock()
+	g.conn.Close(code, reason)
+}
+
+func (g *graphqlSession) send(msg graphqlWSMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	g.conn.SetWriteDeadline(time.Now().Add(graphqlWSWriteTimeout))
+	return g.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (g *graphqlSession) sendPayload(id, typ string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return g.send(graphqlWSMessage{ID: id, Type: typ, Payload: data})
+}
+
+func (g *graphqlSession) handle(data []byte) {
+	var msg graphqlWSMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+		g.close(closeBadRequest, "Invalid message received")
+		return
+	}
+
+	g.mu.Lock()
+	if g.closing {
+		g.mu.Unlock()
+		return
+	}
+	acked := g.acked
+	g.mu.Unlock()
+
+	switch msg.Type {
+	case "connection_init":
+		if acked {
+			g.close(closeTooManyInitRequests, "Too many initialisation requests")
+			return
+		}
+		g.mu.Lock()
+		g.acked = true
+		g.mu.Unlock()
+		g.send(graphqlWSMessage{Type: "connection_ack"})
+	case "ping":
+		g.send(graphqlWSMessage{Type: "pong"})
+	case "pong":
+	case "subscribe":
+		if !acked {
+			g.close(closeUnauthorized, "Unauthorized")
+			return
+		}
+		g.subscribe(msg)
+	case "complete":
+		g.mu.Lock()
+		if cancel, ok := g.ops[msg.ID]; ok {
+			cancel()
+			delete(g.ops, msg.ID)
+		}
+		g.mu.Unlock()
+	default:
+		g.close(closeBadRequest, "Invalid message received")
+	}
+}
+
+func (g *graphqlSession) subscribe(msg graphqlWSMessage) {
+	var req graphql.Request
+	if msg.ID == "" || decodeGraphQLJSON(bytes.NewReader(msg.Payload), &req) != nil {
+		g.close(closeBadRequest, "Invalid message received")
+		return
+	}
+
+	g.mu.Lock()
+	if _, exists := g.ops[msg.ID]; exists {
+		g.mu.Unlock()
+		g.close(closeSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+		return
+	}
+	ctx, cancel := context.WithCancel(g.ctx)
+	g.ops[msg.ID] = cancel
+	g.mu.Unlock()
+
+	prepared, resp := g.s.graphql.Prepare(req)
+	if resp != nil {
+		cancel()
+		if g.finish(msg.ID) {
+			g.sendPayload(msg.ID, "error", resp.Errors)
+		}
+		return
+	}
+
+	if prepared.Kind() == graphql.Subscription {
+		// Open the stream before reading on, so changes made by later messages are seen.
+		events, resp := prepared.Subscribe(ctx)
+		if resp != nil {
+			cancel()
+			if g.finish(msg.ID) {
+				g.sendPayload(msg.ID, "error", resp.Errors)
+			}
+			return
+		}
+		g.running.Add(1)
+		go func() {
+			defer g.running.Done()
+			defer cancel()
+			g.stream(ctx, msg.ID, events)
+		}()
+		return
+	}
+
+	g.running.Add(1)
+	go func() {
+		defer g.running.Done()
+		defer cancel()
+		opCtx, opCancel := context.WithTimeout(ctx, SecToTimeout*time.Second)
+		resp := prepared.Execute(opCtx)
+		opCancel()
+		if ctx.Err() == nil {
+			g.sendPayload(msg.ID, "next", resp)
+		}
+		if g.finish(msg.ID) {
+			g.send(graphqlWSMessage{ID: msg.ID, Type: "complete"})
+		}
+	}()
+}
+
+// stream forwards subscription events until the source ends or the client completes.
+func (g *graphqlSession) stream(ctx context.Context, id string, events <-chan *graphql.Response) {
+	for resp := range events {
+		if err := g.sendPayload(id, "next", resp); err != nil {
+			// A client that cannot keep up is disconnected rather than buffered for.
+			g.close(websocket.ClosePolicyViolation, "Too slow")
+			return
+		}
+	}
+	if ctx.Err() == nil && g.finish(id) {
+		g.send(graphqlWSMessage{ID: id, Type: "complete"})
+	}
+}
+
+// finish removes a running operation and reports whether the client was still waiting for it.
+func (g *graphqlSession) finish(id string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.ops[id]; !ok || g.closing {
+		return false
+	}
+	delete(g.ops, id)
+	return true
+}
@@ -0,0 +1,423 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"todo/internal/storage"
+	"todo/internal/todopb"
+	"todo/internal/validation"
+)
+
+// TaskWatcher is implemented by stores that publish their changes; the Watch call needs it.
+type TaskWatcher interface {
+	Subscribe(buffer int) (<-chan storage.Event, func())
+}
+
+// watchBuffer is how many events a watcher may lag behind before it is dropped.
+const watchBuffer = 256
+
+// grpcCall serves one method: it decodes the request from data and either returns the single
+// response or, for streaming methods, writes its messages to w itself.
+type grpcCall func(w http.ResponseWriter, r *http.Request, data []byte) (todopb.Message, error)
+
+// unary adapts a typed unary method to grpcCall.
+func unary[Req any, PReq interface {
+	*Req
+	todopb.Message
+}](fn func(r *http.Request, req PReq) (todopb.Message, error)) grpcCall {
+	return func(_ http.ResponseWriter, r *http.Request, data []byte) (todopb.Message, error) {
+		req := PReq(new(Req))
+		if err := req.Unmarshal(data); err != nil {
+			return nil, err
+		}
+		return fn(r, req)
+	}
+}
+
+// GRPCHandler serves todo.v1.TaskService (see internal/todopb/todo.proto). gRPC needs HTTP/2,
+// so the handler is meant for a listener of its own that speaks it over TLS or as h2c.
+// Every method is a route of its own, named by its path, for rate limits, metrics and traces.
+func (s *Server) GRPCHandler() http.Handler {
+	calls := map[string]grpcCall{
+		"CreateTask": unary(s.grpcCreateTask),
+		"GetTask":    unary(s.grpcGetTask),
+		"UpdateTask": unary(s.grpcUpdateTask),
+		"DeleteTask": unary(s.grpcDeleteTask),
+		"ListTasks":  unary(s.grpcListTasks),
+		"Watch":      s.grpcWatch,
+	}
+	routes := make(map[string]http.HandlerFunc, len(calls))
+	for name, call := range calls {
+		routes[name] = s.grpcRoute(todopb.ServiceName+"/"+name, call)
+	}
+	// Unknown methods share a route, so clients cannot add label values to the metrics.
+	unknown := s.grpcRoute(todopb.ServiceName+"/unknown", nil)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.ProtoMajor != 2:
+			http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+			return
+		case r.Method != http.MethodPost:
+			http.Error(w, "Method is not allowed", http.StatusMethodNotAllowed)
+			return
+		case !isGRPCContentType(r.Header.Get("Content-Type")):
+			http.Error(w, "Content-Type must be application/grpc", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		name, _ := strings.CutPrefix(r.URL.Path, todopb.ServiceName+"/")
+		if route, ok := routes[name]; ok {
+			route(w, r)
+		} else {
+			unknown(w, r)
+		}
+	})
+}
+
+// grpcRoute wraps a method in the middleware of the HTTP routes. Rate limiting is part of the
+// call, as a rejected call still answers with a gRPC status, and the call logs itself.
+func (s *Server) grpcRoute(route string, call grpcCall) http.HandlerFunc {
+	next := func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		w.Header().Set("Content-Type", "application/grpc")
+
+		err := s.serveGRPC(w, r, route, call)
+
+		st := grpcStatus(err)
+		for key, value := range st.Trailers() {
+			w.Header().Set(http.TrailerPrefix+key, value)
+		}
+
+		level := slog.LevelInfo
+		switch st.Code {
+		case todopb.OK, todopb.Canceled:
+		case todopb.Internal, todopb.Unknown, todopb.Unavailable:
+			level = slog.LevelError
+		default:
+			level = slog.LevelWarn
+		}
+		s.logger.Log(r.Context(), level, "grpc call completed",
+			"method", r.URL.Path,
+			"code", st.Code.String(),
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		)
+	}
+	next = s.MetricsMiddleware(route, next)
+	next = s.TracingMiddleware(route, next)
+	next = s.IdentityMiddleware(next)
+	return s.RequestIDMiddleware(next)
+}
+
+func isGRPCContentType(contentType string) bool {
+	return contentType == "application/grpc" || strings.HasPrefix(contentType, "application/grpc+proto") ||
+		strings.HasPrefix(contentType, "application/grpc;")
+}
+
+func (s *Server) serveGRPC(w http.ResponseWriter, r *http.Request, route string, call grpcCall) error {
+	if call == nil {
+		return todopb.Errorf(todopb.Unimplemented, "unknown method %s", r.URL.Path)
+	}
+	if !s.allowRequest(w, r, route) {
+		return todopb.Errorf(todopb.ResourceExhausted, "rate limit exceeded, retry in %ss", w.Header().Get("Retry-After"))
+	}
+
+	ctx := r.Context()
+	if raw := r.Header.Get("Grpc-Timeout"); raw != "" {
+		timeout, ok := parseGRPCTimeout(raw)
+		if !ok {
+			return todopb.Errorf(todopb.InvalidArgument, "malformed grpc-timeout %q", raw)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	r = r.WithContext(ctx)
+
+	data, err := todopb.ReadFrame(r.Body)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return todopb.Errorf(todopb.InvalidArgument, "missing request message")
+		}
+		return err
+	}
+
+	resp, err := call(w, r, data)
+	if err != nil {
+		return err
+	}
+	if resp != nil {
+		return todopb.WriteFrame(w, resp)
+	}
+	return nil
+}
+
+// parseGRPCTimeout reads a grpc-timeout value: at most 8 digits and a unit.
+func parseGRPCTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(value[:len(value)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// grpcStatus maps errors the same way the HTTP handlers map them to status codes.
+func grpcStatus(err error) *todopb.Status {
+	var st *todopb.Status
+	var invalid *validation.Error
+	switch {
+	case err == nil:
+		return &todopb.Status{Code: todopb.OK}
+	case errors.As(err, &st):
+		return st
+	case errors.As(err, &invalid):
+		st = &todopb.Status{Code: todopb.InvalidArgument, Message: "Invalid task"}
+		for _, f := range invalid.Fields {
+			st.Violations = append(st.Violations, todopb.FieldViolation{
+				Field:       "task." + strings.ToLower(f.Field),
+				Description: f.Message,
+			})
+		}
+		return st
+	case errors.Is(err, todopb.ErrMalformed), errors.Is(err, storage.ErrWrongArgument):
+		return todopb.Errorf(todopb.InvalidArgument, "%v", err)
+	case errors.Is(err, storage.ErrTaskNotFound):
+		return todopb.Errorf(todopb.NotFound, "Task not found")
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return todopb.Errorf(todopb.ResourceExhausted, "%v", err)
+	case errors.Is(err, storage.ErrStorageClosed):
+		return todopb.Errorf(todopb.Unavailable, "%v", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return todopb.Errorf(todopb.DeadlineExceeded, "%v", err)
+	case errors.Is(err, context.Canceled):
+		return todopb.Errorf(todopb.Canceled, "%v", err)
+	default:
+		return todopb.Errorf(todopb.Internal, "%v", err)
+	}
+}
+
+// unaryContext bounds unary calls like HTTP requests; a shorter client deadline still wins.
+func unaryContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), SecToTimeout*time.Second)
+}
+
+func storageStatuses(statuses []todopb.TaskStatus) []storage.TaskStatus {
+	result := make([]storage.TaskStatus, len(statuses))
+	for i, status := range statuses {
+		result[i] = storage.TaskStatus(status)
+	}
+	return result
+}
+
+func protoTask(task storage.Task) *todopb.Task {
+	return &todopb.Task{
+		Id:          int64(task.TaskID),
+		Header:      task.Header,
+		Description: task.Description,
+		Status:      todopb.TaskStatus(task.Status),
+		Owner:       task.Owner,
+	}
+}
+
+// storageTask validates the client-controlled fields of task.
+func (s *Server) storageTask(task *todopb.Task) (storage.Task, error) {
+	if task == nil {
+		return storage.Task{}, &todopb.Status{
+			Code:       todopb.InvalidArgument,
+			Message:    "Invalid task",
+			Violations: []todopb.FieldViolation{{Field: "task", Description: "is required"}},
+		}
+	}
+	result := storage.Task{
+		Header:      task.Header,
+		Description: task.Description,
+		Status:      storage.TaskStatus(task.Status),
+	}
+	if err := s.limits.Task(&result); err != nil {
+		return storage.Task{}, err
+	}
+	return result, nil
+}
+
+func (s *Server) grpcCreateTask(r *http.Request, req *todopb.CreateTaskRequest) (todopb.Message, error) {
+	ctx, cancel := unaryContext(r)
+	defer cancel()
+
+	task, err := s.storageTask(req.Task)
+	if err != nil {
+		return nil, err
+	}
+	task.Owner = s.owner(ctx)
+	created, err := s.storage.CreateTask(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return protoTask(*created), nil
+}
+
+func (s *Server) grpcGetTask(r *http.Request, req *todopb.GetTaskRequest) (todopb.Message, error) {
+	ctx, cancel := unaryContext(r)
+	defer cancel()
+
+	task, err := s.storage.GetByID(ctx, int(req.Id))
+	if err != nil {
+		return nil, err
+	}
+	return protoTask(task), nil
+}
+
+func (s *Server) grpcUpdateTask(r *http.Request, req *todopb.UpdateTaskRequest) (todopb.Message, error) {
+	ctx, cancel := unaryContext(r)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	task, err := s.storageTask(req.Task)
+	if err != nil {
+		return nil, err
+	}
+	id := int(req.Task.Id)
+	if err := s.grpcAuthorize(r, id); err != nil {
+		return nil, err
+	}
+	updated, err := s.storage.Update(ctx, id, &task)
+	if err != nil {
+		return nil, err
+	}
+	return protoTask(*updated), nil
+}
+
+func (s *Server) grpcDeleteTask(r *http.Request, req *todopb.DeleteTaskRequest) (todopb.Message, error) {
+	ctx, cancel := unaryContext(r)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	if err := s.grpcAuthorize(r, int(req.Id)); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Delete(ctx, int(req.Id)); err != nil {
+		return nil, err
+	}
+	return &todopb.DeleteTaskResponse{}, nil
+}
+
+// grpcAuthorize is authorizeTask for gRPC calls.
+func (s *Server) grpcAuthorize(r *http.Request, id int) error {
+	if !s.enforceOwnership {
+		return nil
+	}
+	task, err := s.storage.GetByID(r.Context(), id)
+	if err != nil {
+		return nil
+	}
+	if !s.authorize(r, task.Owner) {
+		return todopb.Errorf(todopb.PermissionDenied, "Task belongs to another owner")
+	}
+	return nil
+}
+
+func (s *Server) grpcListTasks(r *http.Request, req *todopb.ListTasksRequest) (todopb.Message, error) {
+	ctx, cancel := unaryContext(r)
+	defer cancel()
+
+	p := page{limit: int(req.PageSize), after: -1}
+	var violations []todopb.FieldViolation
+	if req.PageSize < 0 || req.PageSize > MaxPageSize {
+		violations = append(violations, todopb.FieldViolation{
+			Field:       "page_size",
+			Description: "must be between 0 and " + strconv.Itoa(MaxPageSize),
+		})
+	}
+	if req.PageToken != "" {
+		after, err := strconv.Atoi(req.PageToken)
+		if err != nil || after < 0 {
+			violations = append(violations, todopb.FieldViolation{Field: "page_token", Description: "must be a next_page_token"})
+		}
+		p.after = after
+	}
+	if len(violations) > 0 {
+		return nil, &todopb.Status{Code: todopb.InvalidArgument, Message: "Invalid list request", Violations: violations}
+	}
+
+	filter := storage.Filter{Statuses: storageStatuses(req.Statuses), Owner: req.Owner, Query: req.Query}
+	matched, err := s.findTasks(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	matched, next := p.apply(matched)
+	resp := &todopb.ListTasksResponse{Tasks: make([]*todopb.Task, len(matched))}
+	for i, task := range matched {
+		resp.Tasks[i] = protoTask(task)
+	}
+	if next != "" {
+		resp.NextPageToken = strconv.Itoa(matched[len(matched)-1].TaskID)
+	}
+	return resp, nil
+}
+
+// grpcWatch streams changes until the client leaves, the server shuts down or the watcher
+// falls behind; the client then lists again and resumes watching.
+func (s *Server) grpcWatch(w http.ResponseWriter, r *http.Request, data []byte) (todopb.Message, error) {
+	var req todopb.WatchRequest
+	if err := req.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	if s.watcher == nil {
+		return nil, todopb.Errorf(todopb.Unimplemented, "the storage does not publish changes")
+	}
+
+	events, cancel := s.watcher.Subscribe(watchBuffer)
+	defer cancel()
+
+	// Send the headers now so the client knows the subscription is in place.
+	flusher := http.NewResponseController(w)
+	w.WriteHeader(http.StatusOK)
+	if err := flusher.Flush(); err != nil {
+		return nil, err
+	}
+
+	filter := storage.Filter{Statuses: storageStatuses(req.Statuses), Owner: req.Owner}
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-s.streamsDone:
+			return nil, todopb.Errorf(todopb.Unavailable, "server is shutting down")
+		case event, ok := <-events:
+			if !ok {
+				if s.shuttingDown.Load() {
+					return nil, todopb.Errorf(todopb.Unavailable, "server is shutting down")
+				}
+				return nil, todopb.Errorf(todopb.ResourceExhausted, "watcher fell behind, list the tasks and watch again")
+			}
+			if !filter.Match(event.Task) {
+				continue
+			}
+			msg := &todopb.TaskEvent{Type: todopb.EventType(event.Type), Task: protoTask(event.Task)}
+			if err := todopb.WriteFrame(w, msg); err != nil {
+				return nil, err
+			}
+			if err := flusher.Flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
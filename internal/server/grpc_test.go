@@ -0,0 +1,532 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"todo/internal/ratelimit"
+	"todo/internal/storage"
+	"todo/internal/todopb"
+)
+
+type grpcTestClient struct {
+	url    string
+	client *http.Client
+	token  string
+}
+
+// pipeListener is an in-memory net.Listener in the manner of grpc's bufconn: the tests serve
+// gRPC over TLS and HTTP/2 without opening a socket.
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr is a loopback address, which the test server's certificate is valid for.
+func (l *pipeListener) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+func (l *pipeListener) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func startGRPCServer(t *testing.T, server *Server) grpcTestClient {
+	t.Helper()
+	ln := newPipeListener()
+	ts := httptest.NewUnstartedServer(server.GRPCHandler())
+	ts.Listener = ln
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	client := ts.Client()
+	client.Transport.(*http.Transport).DialContext = ln.DialContext
+	return grpcTestClient{url: ts.URL, client: client}
+}
+
+func (c grpcTestClient) open(ctx context.Context, t *testing.T, method string, req todopb.Message) *http.Response {
+	t.Helper()
+	var body bytes.Buffer
+	if err := todopb.WriteFrame(&body, req); err != nil {
+		t.Fatal(err)
+	}
+	httpReq, _ := http.NewRequestWithContext(ctx, http.MethodPost, c.url+todopb.ServiceName+"/"+method, &body)
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	httpReq.Header.Set("TE", "trailers")
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/grpc" {
+		t.Fatalf("%s: unexpected response %d %q", method, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return resp
+}
+
+// call makes a unary call, filling resp on success; the result is nil for OK.
+func (c grpcTestClient) call(t *testing.T, method string, req, resp todopb.Message) *todopb.Status {
+	t.Helper()
+	httpResp := c.open(context.Background(), t, method, req)
+	defer httpResp.Body.Close()
+
+	data, err := todopb.ReadFrame(httpResp.Body)
+	if err == nil {
+		if err := resp.Unmarshal(data); err != nil {
+			t.Fatalf("%s: bad response: %v", method, err)
+		}
+		_, err = todopb.ReadFrame(httpResp.Body)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("%s: expected a single message, got %v", method, err)
+	}
+	return trailerStatus(t, httpResp)
+}
+
+func trailerStatus(t *testing.T, resp *http.Response) *todopb.Status {
+	t.Helper()
+	st, err := todopb.StatusFromTrailers(resp.Trailer.Get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestGRPCTasks(t *testing.T) {
+	c := startGRPCServer(t, setupServer())
+	c.token = "secret"
+
+	created := &todopb.Task{}
+	st := c.call(t, "CreateTask", &todopb.CreateTaskRequest{Task: &todopb.Task{
+		Id: 99, Header: "  Buy milk ", Description: "2 liters", Status: todopb.TaskStatusInProgress, Owner: "someone",
+	}}, created)
+	want := &todopb.Task{Header: "Buy milk", Description: "2 liters", Status: todopb.TaskStatusInProgress, Owner: "token:2bb80d537b1da3e3"}
+	if st != nil || !reflect.DeepEqual(created, want) {
+		t.Fatalf("CreateTask: expected %+v, got %+v, %v", want, created, st)
+	}
+
+	got := &todopb.Task{}
+	if st := c.call(t, "GetTask", &todopb.GetTaskRequest{Id: created.Id}, got); st != nil || !reflect.DeepEqual(got, created) {
+		t.Errorf("GetTask: expected %+v, got %+v, %v", created, got, st)
+	}
+
+	updated := &todopb.Task{}
+	st = c.call(t, "UpdateTask", &todopb.UpdateTaskRequest{Task: &todopb.Task{
+		Id: created.Id, Header: "Buy oat milk", Status: todopb.TaskStatusCompleted,
+	}}, updated)
+	if st != nil || updated.Header != "Buy oat milk" || updated.Status != todopb.TaskStatusCompleted || updated.Description != "" {
+		t.Errorf("UpdateTask: unexpected %+v, %v", updated, st)
+	}
+
+	if st := c.call(t, "DeleteTask", &todopb.DeleteTaskRequest{Id: created.Id}, &todopb.DeleteTaskResponse{}); st != nil {
+		t.Errorf("DeleteTask: unexpected %v", st)
+	}
+	if st := c.call(t, "GetTask", &todopb.GetTaskRequest{Id: created.Id}, &todopb.Task{}); st == nil || st.Code != todopb.NotFound {
+		t.Errorf("GetTask after delete: expected NOT_FOUND, got %v", st)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(), WithOwnershipEnforcement())
+	c := startGRPCServer(t, server)
+	owned, _ := server.storage.CreateTask(context.Background(), storage.Task{Header: "Owned", Owner: "token:someone"})
+
+	tests := []struct {
+		name       string
+		method     string
+		req        todopb.Message
+		want       todopb.Code
+		violations []todopb.FieldViolation
+	}{
+		{
+			name:   "invalid task",
+			method: "CreateTask",
+			req:    &todopb.CreateTaskRequest{Task: &todopb.Task{Header: " ", Status: 7}},
+			want:   todopb.InvalidArgument,
+			violations: []todopb.FieldViolation{
+				{Field: "task.header", Description: "must not be empty"},
+				{Field: "task.status", Description: "must be one of 0 (Assigned), 1 (InProgress), 2 (Completed), 3 (Dropped)"},
+			},
+		},
+		{
+			name:       "missing task",
+			method:     "UpdateTask",
+			req:        &todopb.UpdateTaskRequest{},
+			want:       todopb.InvalidArgument,
+			violations: []todopb.FieldViolation{{Field: "task", Description: "is required"}},
+		},
+		{
+			name:   "update of a missing task",
+			method: "UpdateTask",
+			req:    &todopb.UpdateTaskRequest{Task: &todopb.Task{Id: 42, Header: "x"}},
+			want:   todopb.NotFound,
+		},
+		{
+			name:   "delete of another owner's task",
+			method: "DeleteTask",
+			req:    &todopb.DeleteTaskRequest{Id: int64(owned.TaskID)},
+			want:   todopb.PermissionDenied,
+		},
+		{
+			name:       "page size too large",
+			method:     "ListTasks",
+			req:        &todopb.ListTasksRequest{PageSize: 5000, PageToken: "x"},
+			want:       todopb.InvalidArgument,
+			violations: []todopb.FieldViolation{{Field: "page_size", Description: "must be between 0 and 1000"}, {Field: "page_token", Description: "must be a next_page_token"}},
+		},
+		{
+			name:   "unknown method",
+			method: "Explode",
+			req:    &todopb.GetTaskRequest{},
+			want:   todopb.Unimplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := c.call(t, tt.method, tt.req, &todopb.Task{})
+			if st == nil || st.Code != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, st)
+			}
+			if !reflect.DeepEqual(st.Violations, tt.violations) {
+				t.Errorf("expected violations %+v, got %+v", tt.violations, st.Violations)
+			}
+		})
+	}
+}
+
+func TestGRPCMiddleware(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(), WithRateLimits(map[string]ratelimit.Rule{
+		todopb.ServiceName + "/ListTasks": {Limit: 1, Window: time.Minute},
+	}))
+	c := startGRPCServer(t, server)
+
+	if st := c.call(t, "ListTasks", &todopb.ListTasksRequest{}, &todopb.ListTasksResponse{}); st != nil {
+		t.Fatalf("first ListTasks: unexpected status %v", st)
+	}
+	st := c.call(t, "ListTasks", &todopb.ListTasksRequest{}, &todopb.ListTasksResponse{})
+	if st == nil || st.Code != todopb.ResourceExhausted {
+		t.Errorf("second ListTasks: expected RESOURCE_EXHAUSTED, got %v", st)
+	}
+	if st := c.call(t, "GetTask", &todopb.GetTaskRequest{Id: 1}, &todopb.Task{}); st == nil || st.Code != todopb.NotFound {
+		t.Errorf("GetTask: expected NOT_FOUND from an unlimited method, got %v", st)
+	}
+	c.call(t, "NoSuchMethod", &todopb.GetTaskRequest{}, &todopb.Task{})
+
+	body := scrape(t, server.Handler())
+	for _, line := range []string{
+		`todo_http_requests_total{route="/todo.v1.TaskService/ListTasks",method="POST",status="200"} 2`,
+		`todo_http_requests_total{route="/todo.v1.TaskService/GetTask",method="POST",status="200"} 1`,
+		`todo_http_requests_total{route="/todo.v1.TaskService/unknown",method="POST",status="200"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in metrics output:\n%s", line, body)
+		}
+	}
+}
+
+func TestGRPCMalformedRequest(t *testing.T) {
+	c := startGRPCServer(t, setupServer())
+	req, _ := http.NewRequest(http.MethodPost, c.url+todopb.ServiceName+"/GetTask", bytes.NewReader([]byte{0, 0, 0, 0, 2, 0x0a, 0x05}))
+	req.Header.Set("Content-Type", "application/grpc")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if st := trailerStatus(t, resp); st == nil || st.Code != todopb.InvalidArgument {
+		t.Errorf("expected INVALID_ARGUMENT, got %v", st)
+	}
+}
+
+func TestGRPCRejectsOtherRequests(t *testing.T) {
+	handler := setupServer().GRPCHandler()
+
+	tests := []struct {
+		name        string
+		method      string
+		protoMajor  int
+		contentType string
+		want        int
+	}{
+		{"HTTP/1.1", http.MethodPost, 1, "application/grpc", http.StatusHTTPVersionNotSupported},
+		{"GET", http.MethodGet, 2, "application/grpc", http.StatusMethodNotAllowed},
+		{"JSON body", http.MethodPost, 2, "application/json", http.StatusUnsupportedMediaType},
+		{"gRPC-Web", http.MethodPost, 2, "application/grpc-web", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, todopb.ServiceName+"/GetTask", nil)
+			req.ProtoMajor = tt.protoMajor
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestGRPCListTasks(t *testing.T) {
+	server := setupServer()
+	c := startGRPCServer(t, server)
+	ctx := context.Background()
+	for _, task := range []storage.Task{
+		{Header: "Buy milk", Owner: "token:a"},
+		{Header: "Call mom", Status: storage.Completed, Owner: "token:a"},
+		{Header: "Fix bike", Description: "Buy a MILK crate for parts", Owner: "token:b"},
+		{Header: "Pay bills", Status: storage.Dropped, Owner: "token:b"},
+		{Header: "Walk", Owner: "token:a"},
+	} {
+		_, _ = server.storage.CreateTask(ctx, task)
+	}
+
+	tests := []struct {
+		name     string
+		req      *todopb.ListTasksRequest
+		wantIDs  []int64
+		wantNext string
+	}{
+		{"everything", &todopb.ListTasksRequest{}, []int64{0, 1, 2, 3, 4}, ""},
+		{"statuses", &todopb.ListTasksRequest{Statuses: []todopb.TaskStatus{todopb.TaskStatusCompleted, todopb.TaskStatusDropped}}, []int64{1, 3}, ""},
+		{"owner", &todopb.ListTasksRequest{Owner: "token:b"}, []int64{2, 3}, ""},
+		{"query matches description", &todopb.ListTasksRequest{Query: "milk"}, []int64{0, 2}, ""},
+		{"combined", &todopb.ListTasksRequest{Owner: "token:a", Statuses: []todopb.TaskStatus{todopb.TaskStatusAssigned}}, []int64{0, 4}, ""},
+		{"first page", &todopb.ListTasksRequest{PageSize: 2}, []int64{0, 1}, "1"},
+		{"next page", &todopb.ListTasksRequest{PageSize: 2, PageToken: "1"}, []int64{2, 3}, "3"},
+		{"last page", &todopb.ListTasksRequest{PageSize: 2, PageToken: "3"}, []int64{4}, ""},
+		{"filtered page", &todopb.ListTasksRequest{PageSize: 1, Owner: "token:b"}, []int64{2}, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &todopb.ListTasksResponse{}
+			if st := c.call(t, "ListTasks", tt.req, resp); st != nil {
+				t.Fatalf("unexpected %v", st)
+			}
+			var ids []int64
+			for _, task := range resp.Tasks {
+				ids = append(ids, task.Id)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || resp.NextPageToken != tt.wantNext {
+				t.Errorf("expected %v next %q, got %v next %q", tt.wantIDs, tt.wantNext, ids, resp.NextPageToken)
+			}
+		})
+	}
+}
+
+func readEvent(t *testing.T, body io.Reader) *todopb.TaskEvent {
+	t.Helper()
+	data, err := todopb.ReadFrame(body)
+	if err != nil {
+		t.Fatalf("expected an event, got %v", err)
+	}
+	event := &todopb.TaskEvent{}
+	if err := event.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestGRPCWatch(t *testing.T) {
+	server := setupServer()
+	c := startGRPCServer(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := c.open(ctx, t, "Watch", &todopb.WatchRequest{Statuses: []todopb.TaskStatus{todopb.TaskStatusCompleted}})
+	defer resp.Body.Close()
+
+	// A change over HTTP, one filtered out, then one over gRPC.
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/todos", strings.NewReader(`{"header":"Done","status":"completed"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create failed: %d %s", rec.Code, rec.Body)
+	}
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "Not done"})
+	c.call(t, "UpdateTask", &todopb.UpdateTaskRequest{Task: &todopb.Task{Id: 1, Header: "Now done", Status: todopb.TaskStatusCompleted}}, &todopb.Task{})
+	c.call(t, "DeleteTask", &todopb.DeleteTaskRequest{Id: 0}, &todopb.DeleteTaskResponse{})
+
+	want := []struct {
+		typ    todopb.EventType
+		id     int64
+		header string
+	}{
+		{todopb.EventTypeCreated, 0, "Done"},
+		{todopb.EventTypeUpdated, 1, "Now done"},
+		{todopb.EventTypeDeleted, 0, "Done"},
+	}
+	for i, w := range want {
+		event := readEvent(t, resp.Body)
+		if event.Type != w.typ || event.Task.Id != w.id || event.Task.Header != w.header {
+			t.Errorf("event %d: expected %v of task %d %q, got %+v %+v", i, w.typ, w.id, w.header, event, event.Task)
+		}
+	}
+}
+
+func TestGRPCWatchDropsSlowWatchers(t *testing.T) {
+	server := setupServer()
+	c := startGRPCServer(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp := c.open(ctx, t, "Watch", &todopb.WatchRequest{})
+	defer resp.Body.Close()
+
+	// Without reading, the stream's flow control window fills up and the handler stops
+	// draining its subscription.
+	header := strings.Repeat("x", 200)
+	description := strings.Repeat("y", 10000)
+	for i := 0; i < 4*watchBuffer; i++ {
+		_, _ = server.storage.CreateTask(ctx, storage.Task{Header: header, Description: description})
+	}
+
+	var err error
+	for err == nil {
+		_, err = todopb.ReadFrame(resp.Body)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("expected the stream to end, got %v", err)
+	}
+	if st := trailerStatus(t, resp); st == nil || st.Code != todopb.ResourceExhausted {
+		t.Errorf("expected RESOURCE_EXHAUSTED, got %v", st)
+	}
+}
+
+func TestParseGRPCTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"1S", time.Second, true},
+		{"250m", 250 * time.Millisecond, true},
+		{"99999999n", 99999999 * time.Nanosecond, true},
+		{"2H", 2 * time.Hour, true},
+		{"100", 0, false},
+		{"S", 0, false},
+		{"123456789S", 0, false},
+		{"-1S", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseGRPCTimeout(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseGRPCTimeout(%q): expected %v, %v, got %v, %v", tt.value, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestGRPCWithoutTLS(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	grpcLn, _ := net.Listen("tcp", "127.0.0.1:0")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- setupServer().serve(ctx, ln, grpcLn, DefaultConfig()) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	}()
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	defer transport.CloseIdleConnections()
+	c := grpcTestClient{url: "http://" + grpcLn.Addr().String(), client: &http.Client{Transport: transport}}
+
+	created := &todopb.Task{}
+	if st := c.call(t, "CreateTask", &todopb.CreateTaskRequest{Task: &todopb.Task{Header: "Buy milk"}}, created); st != nil {
+		t.Fatalf("CreateTask: %v", st)
+	}
+	got := &todopb.Task{}
+	if st := c.call(t, "GetTask", &todopb.GetTaskRequest{Id: created.Id}, got); st != nil || !reflect.DeepEqual(got, created) {
+		t.Errorf("GetTask: expected %+v, got %+v, %v", created, got, st)
+	}
+
+	if resp, err := http.Post(c.url+todopb.ServiceName+"/GetTask", "application/grpc", nil); err == nil {
+		resp.Body.Close()
+		t.Errorf("expected HTTP/1.1 to be refused on the h2c port, got %s", resp.Status)
+	}
+}
+
+func TestShutdownEndsWatch(t *testing.T) {
+	pki := newTestPKI(t)
+	_, certFile, keyFile := pki.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	grpcLn, _ := net.Listen("tcp", "127.0.0.1:0")
+
+	cfg := DefaultConfig()
+	cfg.TLS = &TLSConfig{CertFile: certFile, KeyFile: keyFile}
+	server := setupServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.serve(ctx, ln, grpcLn, cfg) }()
+
+	c := grpcTestClient{url: "https://" + grpcLn.Addr().String(), client: pki.client(nil)}
+	resp := c.open(context.Background(), t, "Watch", &todopb.WatchRequest{})
+	defer resp.Body.Close()
+
+	cancel()
+	if _, err := todopb.ReadFrame(resp.Body); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected the stream to end, got %v", err)
+	}
+	if st := trailerStatus(t, resp); st == nil || st.Code != todopb.Unavailable {
+		t.Errorf("expected UNAVAILABLE, got %v", st)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"todo/internal/buildinfo"
+)
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+type componentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type readinessReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentStatus `json:"components"`
+}
+
+func (s *Server) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, componentStatus{Status: statusOK})
+}
+
+func (s *Server) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+		return
+	}
+
+	report := s.readiness()
+	code := http.StatusOK
+	if report.Status != statusOK {
+		code = http.StatusServiceUnavailable
+	}
+	s.writeResponse(w, r, code, report)
+}
+
+func (s *Server) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+		return
+	}
+
+	s.writeResponse(w, r, http.StatusOK, buildinfo.Get())
+}
+
+func (s *Server) readiness() readinessReport {
+	report := readinessReport{
+		Status:     statusOK,
+		Components: make(map[string]componentStatus),
+	}
+	check := func(name string, err error) {
+		if err != nil {
+			report.Status = statusFail
+			report.Components[name] = componentStatus{Status: statusFail, Error: err.Error()}
+			return
+		}
+		report.Components[name] = componentStatus{Status: statusOK}
+	}
+
+	var shutdownErr error
+	if s.shuttingDown.Load() {
+		shutdownErr = errors.New("shutting down")
+	}
+	check("server", shutdownErr)
+	check("storage", s.storage.Ping())
+
+	for _, ws := range s.workers {
+		state, err := ws.get()
+		if state != workerRunning && err == nil {
+			err = errors.New("worker is " + state)
+		}
+		check("worker:"+ws.name, err)
+	}
+
+	return report
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"todo/internal/buildinfo"
+)
+
+func getReadiness(t *testing.T, server *Server) (int, readinessReport) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+
+	server.HandleReadyz(w, req)
+
+	var report readinessReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return w.Code, report
+}
+
+func TestHealthz(t *testing.T) {
+	server := setupServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+
+	server.HandleHealthz(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name           string
+		prepare        func(s *Server)
+		expectedStatus int
+		failing        string
+	}{
+		{
+			name:           "ready",
+			prepare:        func(s *Server) {},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "storage closed",
+			prepare:        func(s *Server) { _ = s.storage.Close() },
+			expectedStatus: http.StatusServiceUnavailable,
+			failing:        "storage",
+		},
+		{
+			name:           "shutting down",
+			prepare:        func(s *Server) { s.shuttingDown.Store(true) },
+			expectedStatus: http.StatusServiceUnavailable,
+			failing:        "server",
+		},
+		{
+			name: "worker not running",
+			prepare: func(s *Server) {
+				s.AddWorker("sync", WorkerFunc(func(ctx context.Context) error { return nil }))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			failing:        "worker:sync",
+		},
+		{
+			name: "worker failed",
+			prepare: func(s *Server) {
+				s.AddWorker("sync", WorkerFunc(func(ctx context.Context) error { return nil }))
+				s.workers[0].set(workerFailed, errors.New("boom"))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			failing:        "worker:sync",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupServer()
+			tt.prepare(server)
+
+			code, report := getReadiness(t, server)
+
+			if code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, code)
+			}
+			if tt.failing == "" {
+				if report.Status != statusOK {
+					t.Errorf("expected overall status ok, got %v", report)
+				}
+				return
+			}
+			if report.Components[tt.failing].Status != statusFail {
+				t.Errorf("expected component %s to fail, got %v", tt.failing, report.Components)
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	server := setupServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	w := httptest.NewRecorder()
+
+	server.HandleVersion(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var info map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if info["version"] != buildinfo.Version {
+		t.Errorf("expected version %s, got %v", buildinfo.Version, info["version"])
+	}
+	if info["go_version"] != runtime.Version() {
+		t.Errorf("expected go_version %s, got %v", runtime.Version(), info["go_version"])
+	}
+	if _, ok := info["goVersion"]; ok {
+		t.Error("expected snake_case keys, got goVersion")
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Identity is the caller a request is attributed to, used for rate limiting and task ownership.
+type Identity struct {
+	Kind string
+	Name string
+}
+
+func (i Identity) String() string {
+	if i.Kind == "" {
+		return ""
+	}
+	return i.Kind + ":" + i.Name
+}
+
+type identityKey struct{}
+
+func ContextWithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+func IdentityFromContext(ctx context.Context) Identity {
+	id, _ := ctx.Value(identityKey{}).(Identity)
+	return id
+}
+
+// WithClientCertIdentities maps verified client certificate subjects to identity names.
+// Keys are either the full subject DN ("CN=alice,O=Example") or just the common name;
+// unmapped certificates fall back to their common name.
+func WithClientCertIdentities(subjects map[string]string) Option {
+	return func(s *Server) {
+		s.certIdentities = subjects
+	}
+}
+
+// WithOwnerKey sets the secret that keys the owner recorded for anonymous callers. Without it
+// a random key is used, so anonymous callers get new owners when the server restarts.
+func WithOwnerKey(key []byte) Option {
+	return func(s *Server) {
+		s.ownerKey = key
+	}
+}
+
+// WithOwnershipEnforcement restricts updates and deletes to the identity that created the task.
+func WithOwnershipEnforcement() Option {
+	return func(s *Server) {
+		s.enforceOwnership = true
+	}
+}
+
+// WithTrustedProxyHeaders takes the client IP from X-Forwarded-For, as appended by hops
+// trusted proxies in front of the server. Entries to the left of theirs come from the client
+// and are ignored.
+func WithTrustedProxyHeaders(hops int) Option {
+	return func(s *Server) {
+		s.trustedProxyHops = max(hops, 0)
+	}
+}
+
+func (s *Server) IdentityMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(ContextWithIdentity(r.Context(), s.identify(r))))
+	}
+}
+
+// identify prefers a verified client certificate, then the bearer token, which is hashed
+// so raw credentials never reach logs or storage, and finally the client IP.
+func (s *Server) identify(r *http.Request) Identity {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return Identity{Kind: "cert", Name: s.certIdentity(r.TLS.VerifiedChains[0][0].Subject)}
+	}
+	if token, ok := bearerToken(r); ok {
+		sum := sha256.Sum256([]byte(token))
+		return Identity{Kind: "token", Name: hex.EncodeToString(sum[:8])}
+	}
+	return Identity{Kind: "ip", Name: s.clientIP(r)}
+}
+
+// owner is the task owner recorded for the caller. Anonymous callers are recorded by a keyed
+// hash of their IP, so tasks never reveal client addresses in responses or exports.
+func (s *Server) owner(ctx context.Context) string {
+	id := IdentityFromContext(ctx)
+	if id.Kind != "ip" {
+		return id.String()
+	}
+	mac := hmac.New(sha256.New, s.ownerKey)
+	mac.Write([]byte(id.Name))
+	return id.Kind + ":" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func (s *Server) certIdentity(subject pkix.Name) string {
+	if name, ok := s.certIdentities[subject.String()]; ok {
+		return name
+	}
+	if name, ok := s.certIdentities[subject.CommonName]; ok {
+		return name
+	}
+	return subject.CommonName
+}
+
+// authorize reports whether the caller may modify a task owned by owner.
+func (s *Server) authorize(r *http.Request, owner string) bool {
+	return s.authorizeContext(r.Context(), owner)
+}
+
+// authorizeContext is authorize for callers that only carry a context, such as GraphQL
+// resolvers and websocket commands.
+func (s *Server) authorizeContext(ctx context.Context, owner string) bool {
+	return !s.enforceOwnership || owner == "" || owner == s.owner(ctx)
+}
+
+var errNotOwner = errors.New("Task belongs to another owner")
+
+// checkOwner is authorizeTask for callers without a response to write, such as resolvers;
+// missing tasks are left for the operation to report.
+func (s *Server) checkOwner(ctx context.Context, id int) error {
+	if !s.enforceOwnership {
+		return nil
+	}
+	task, err := s.storage.GetByID(ctx, id)
+	if err != nil {
+		return nil
+	}
+	if !s.authorizeContext(ctx, task.Owner) {
+		return errNotOwner
+	}
+	return nil
+}
+
+// clientIP returns the address the nearest untrusted hop connected from: the entry the
+// outermost trusted proxy appended to X-Forwarded-For, or the connection's own address.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.trustedProxyHops > 0 {
+		var entries []string
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			entries = append(entries, strings.Split(value, ",")...)
+		}
+		if i := len(entries) - s.trustedProxyHops; i >= 0 {
+			if ip := strings.TrimSpace(entries[i]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	// ShutdownDelay keeps serving while /readyz reports failure, giving load balancers time to react.
+	ShutdownDelay time.Duration
+	// TLS enables HTTPS (and HTTP/2); nil serves plain HTTP.
+	TLS *TLSConfig
+	// GRPCAddr serves the gRPC API on a second port when set. It shares the TLS settings;
+	// without TLS the port speaks cleartext HTTP/2 (h2c) with prior knowledge only.
+	GRPCAddr string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Addr:              ":8080",
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      (SecToTimeout + 5) * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ShutdownTimeout:   15 * time.Second,
+	}
+}
+
+// Worker is a background job owned by the server. Run must return once ctx is cancelled.
+type Worker interface {
+	Run(ctx context.Context) error
+}
+
+type WorkerFunc func(ctx context.Context) error
+
+func (f WorkerFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+const (
+	workerPending = "pending"
+	workerRunning = "running"
+	workerStopped = "stopped"
+	workerFailed  = "failed"
+)
+
+type workerState struct {
+	name   string
+	worker Worker
+
+	mutex sync.Mutex
+	state string
+	err   error
+}
+
+func (ws *workerState) set(state string, err error) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.state = state
+	ws.err = err
+}
+
+func (ws *workerState) get() (string, error) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	return ws.state, ws.err
+}
+
+func (s *Server) AddWorker(name string, w Worker) {
+	s.workers = append(s.workers, &workerState{name: name, worker: w, state: workerPending})
+}
+
+func (s *Server) Run(ctx context.Context, cfg Config) error {
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	var grpcLn net.Listener
+	if cfg.GRPCAddr != "" {
+		if grpcLn, err = net.Listen("tcp", cfg.GRPCAddr); err != nil {
+			_ = ln.Close()
+			return err
+		}
+	}
+	return s.serve(ctx, ln, grpcLn, cfg)
+}
+
+// Serve accepts connections on ln until ctx is cancelled, then drains in-flight
+// requests for up to cfg.ShutdownTimeout, stops the workers and closes the storage.
+// cfg.GRPCAddr is ignored; Run opens the gRPC listener.
+func (s *Server) Serve(ctx context.Context, ln net.Listener, cfg Config) error {
+	return s.serve(ctx, ln, nil, cfg)
+}
+
+func (s *Server) serve(ctx context.Context, ln, grpcLn net.Listener, cfg Config) error {
+	httpServer := &http.Server{
+		Handler:           s.Handler(),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelError),
+	}
+
+	servers := []*http.Server{httpServer}
+
+	serve := httpServer.Serve
+	var grpcServer *http.Server
+	var serveGRPC func() error
+	if grpcLn != nil {
+		// No read or write timeouts: Watch streams stay open for as long as the client wants.
+		grpcServer = &http.Server{
+			Handler:           s.GRPCHandler(),
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			Protocols:         new(http.Protocols),
+			ErrorLog:          httpServer.ErrorLog,
+		}
+		grpcServer.Protocols.SetUnencryptedHTTP2(true)
+		servers = append(servers, grpcServer)
+		serveGRPC = func() error { return grpcServer.Serve(grpcLn) }
+	}
+	if cfg.TLS != nil {
+		tlsConfig, reloader, err := s.buildTLS(cfg.TLS)
+		if err != nil {
+			_ = ln.Close()
+			if grpcLn != nil {
+				_ = grpcLn.Close()
+			}
+			return err
+		}
+		httpServer.TLSConfig = tlsConfig
+		s.AddWorker("tls-reloader", reloader)
+		serve = func(ln net.Listener) error { return httpServer.ServeTLS(ln, "", "") }
+
+		if grpcServer != nil {
+			grpcServer.TLSConfig = tlsConfig
+			grpcServer.Protocols = nil
+			serveGRPC = func() error { return grpcServer.ServeTLS(grpcLn, "", "") }
+		}
+	}
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	workersDone := s.startWorkers(workersCtx)
+
+	serveErr := make(chan error, len(servers))
+	go func() {
+		s.logger.Info("server starting", "addr", ln.Addr().String(), "tls", cfg.TLS != nil)
+		serveErr <- serve(ln)
+	}()
+	if serveGRPC != nil {
+		go func() {
+			s.logger.Info("grpc server starting", "addr", grpcLn.Addr().String(), "tls", cfg.TLS != nil)
+			serveErr <- serveGRPC()
+		}()
+	}
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		errs = append(errs, err)
+	case <-ctx.Done():
+		s.shuttingDown.Store(true)
+		s.logger.Info("shutting down, draining in-flight requests")
+		if cfg.ShutdownDelay > 0 {
+			time.Sleep(cfg.ShutdownDelay)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	s.endStreams()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			s.logger.Error("graceful shutdown failed", "error", err)
+			errs = append(errs, err, srv.Close())
+		}
+	}
+
+	stopWorkers()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		errs = append(errs, errors.New("background workers did not stop in time"))
+	}
+
+	if err := s.storage.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	s.logger.Info("server stopped")
+	return errors.Join(errs...)
+}
+
+func (s *Server) startWorkers(ctx context.Context) <-chan struct{} {
+	var wg sync.WaitGroup
+	for _, ws := range s.workers {
+		wg.Add(1)
+		ws.set(workerRunning, nil)
+		go func(ws *workerState) {
+			defer wg.Done()
+			err := ws.worker.Run(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				s.logger.Error("worker failed", "worker", ws.name, "error", err)
+				ws.set(workerFailed, err)
+				return
+			}
+			ws.set(workerStopped, nil)
+		}(ws)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"todo/internal/storage"
+)
+
+type lineWriter struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	lines chan string
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			w.buf.WriteString(line)
+			break
+		}
+		select {
+		case w.lines <- line:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func newTestLogger(out *lineWriter) *slog.Logger {
+	return slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func waitForLine(t *testing.T, lines <-chan string, substr string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-lines:
+			if strings.Contains(line, substr) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for log line %q", substr)
+		}
+	}
+}
+
+func TestShutdownDrainsInFlightRequest(t *testing.T) {
+	out := &lineWriter{lines: make(chan string, 64)}
+	st := storage.NewStorage()
+	server := NewServer(st, newTestLogger(out))
+
+	workerStopped := make(chan struct{})
+	server.AddWorker("test", WorkerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(workerStopped)
+		return ctx.Err()
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveDone := make(chan error, 1)
+	go func() {
+		serveDone <- server.Serve(ctx, ln, DefaultConfig())
+	}()
+
+	body, bodyWriter := io.Pipe()
+	respCh := make(chan *http.Response, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		resp, err := http.Post("http://"+ln.Addr().String()+"/todos", "application/json", body)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		respCh <- resp
+	}()
+
+	_, _ = bodyWriter.Write([]byte(`{"Header":"In flight",`))
+	waitForLine(t, out.lines, `msg="request started" method=POST path=/todos`)
+
+	cancel()
+	waitForLine(t, out.lines, "shutting down")
+
+	_, _ = bodyWriter.Write([]byte(`"Description":"Finished during shutdown","Status":0}`))
+	_ = bodyWriter.Close()
+
+	select {
+	case resp := <-respCh:
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Errorf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+		}
+	case err := <-errCh:
+		t.Fatalf("in-flight request failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for in-flight response")
+	}
+
+	select {
+	case err := <-serveDone:
+		if err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+
+	select {
+	case <-workerStopped:
+	default:
+		t.Error("expected background worker to be stopped")
+	}
+
+	if tasks, _ := st.GetAll(context.Background()); len(tasks) != 1 {
+		t.Errorf("expected in-flight task to be stored, got %d tasks", len(tasks))
+	}
+	if _, err := st.CreateTask(context.Background(), storage.Task{Header: "After shutdown"}); !errors.Is(err, storage.ErrStorageClosed) {
+		t.Errorf("expected storage to be closed, got %v", err)
+	}
+}
+
+func TestReadinessFailsDuringShutdown(t *testing.T) {
+	out := &lineWriter{lines: make(chan string, 64)}
+	server := NewServer(storage.NewStorage(), newTestLogger(out))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.ShutdownDelay = 500 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveDone := make(chan error, 1)
+	go func() {
+		serveDone <- server.Serve(ctx, ln, cfg)
+	}()
+
+	url := "http://" + ln.Addr().String() + "/readyz"
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("readiness probe failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d before shutdown, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	cancel()
+	waitForLine(t, out.lines, "shutting down")
+
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatalf("readiness probe failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d during shutdown, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	if err := <-serveDone; err != nil {
+		t.Errorf("expected clean shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"todo/internal/metrics"
+	"todo/internal/storage"
+)
+
+type serverMetrics struct {
+	registry         *metrics.Registry
+	requests         *metrics.CounterVec
+	requestDuration  *metrics.HistogramVec
+	requestsInFlight *metrics.GaugeVec
+	storageDuration  *metrics.HistogramVec
+	storageInFlight  *metrics.GaugeVec
+}
+
+func newServerMetrics(st TaskStore) *serverMetrics {
+	r := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: r,
+		requests: r.NewCounterVec("todo_http_requests_total",
+			"Total number of HTTP requests.", "route", "method", "status"),
+		requestDuration: r.NewHistogramVec("todo_http_request_duration_seconds",
+			"HTTP request latency in seconds.", metrics.DefBuckets, "route", "method", "status"),
+		requestsInFlight: r.NewGaugeVec("todo_http_requests_in_flight",
+			"Number of HTTP requests currently being served.", "route"),
+		storageDuration: r.NewHistogramVec("todo_storage_operation_duration_seconds",
+			"Storage operation latency in seconds.", metrics.DefBuckets, "operation", "result"),
+		storageInFlight: r.NewGaugeVec("todo_storage_operations_in_flight",
+			"Number of storage operations currently running.", "operation"),
+	}
+
+	r.NewGaugeFunc("todo_tasks", "Number of stored tasks by status.", []string{"status"},
+		func(emit func(float64, ...string)) {
+			counts := st.CountByStatus()
+			for _, status := range storage.Statuses {
+				emit(float64(counts[status]), status.String())
+			}
+		})
+
+	return m
+}
+
+func (s *Server) MetricsMiddleware(route string, next http.HandlerFunc) http.HandlerFunc {
+	inFlight := s.metrics.requestsInFlight.WithLabelValues(route)
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		rec := newResponseRecorder(w)
+		next(rec, r)
+
+		status := strconv.Itoa(rec.status)
+		method := methodLabel(r.Method)
+		s.metrics.requests.WithLabelValues(route, method, status).Inc()
+		s.metrics.requestDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// methodLabel keeps the method label bounded: clients may send any method name.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
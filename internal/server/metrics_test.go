@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+)
+
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	server := setupServer()
+	handler := server.Handler()
+
+	requests := []struct {
+		method  string
+		path    string
+		payload string
+	}{
+		{http.MethodPost, "/todos", `{"Header":"Task","Status":1}`},
+		{http.MethodPost, "/todos", `{"Header":""}`},
+		{http.MethodGet, "/todos", ""},
+		{http.MethodGet, "/todos/999", ""},
+		{"BREW", "/todos", ""},
+		{"PROPFIND", "/todos", ""},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, bytes.NewBufferString(r.payload))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape(t, handler)
+
+	expected := []string{
+		`todo_http_requests_total{route="/todos",method="POST",status="201"} 1`,
+		`todo_http_requests_total{route="/todos",method="POST",status="400"} 1`,
+		`todo_http_requests_total{route="/todos",method="GET",status="200"} 1`,
+		`todo_http_requests_total{route="/todos/{id}",method="GET",status="404"} 1`,
+		`todo_http_request_duration_seconds_count{route="/todos",method="GET",status="200"} 1`,
+		`todo_http_requests_total{route="/todos",method="OTHER",status="405"} 2`,
+		`todo_http_requests_in_flight{route="/todos"} 0`,
+		`todo_storage_operation_duration_seconds_count{operation="create",result="ok"} 1`,
+		`todo_storage_operation_duration_seconds_count{operation="get_by_id",result="error"} 1`,
+		`todo_tasks{status="InProgress"} 1`,
+		`todo_tasks{status="Assigned"} 0`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in metrics output:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, `method="BREW"`) {
+		t.Error("expected unknown methods to share the OTHER label")
+	}
+}
+
+func TestMetricsTaskGauges(t *testing.T) {
+	server := setupServer()
+
+	for _, status := range []storage.TaskStatus{storage.Completed, storage.Completed, storage.Dropped} {
+		_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Task", Status: status})
+	}
+
+	body := scrape(t, server.Handler())
+
+	for _, line := range []string{`todo_tasks{status="Completed"} 2`, `todo_tasks{status="Dropped"} 1`} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in metrics output:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo/internal/logging"
+	"todo/internal/storage"
+	"todo/internal/tracing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	server := setupServer()
+
+	tests := []struct {
+		name      string
+		incoming  string
+		preserved bool
+	}{
+		{"generated when missing", "", false},
+		{"accepted from client", "client-id-123", true},
+		{"replaced when too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"replaced when it has spaces", "bad id", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := server.RequestIDMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/todos", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("expected response header to match context id, got %q and %q", got, seen)
+			}
+			if tt.preserved && got != tt.incoming {
+				t.Errorf("expected request id %q, got %q", tt.incoming, got)
+			}
+			if !tt.preserved && got == tt.incoming {
+				t.Errorf("expected a generated request id, got %q", got)
+			}
+		})
+	}
+}
+
+func TestRequestIDInErrorResponse(t *testing.T) {
+	server := setupServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/todos/999", nil)
+	req.Header.Set(RequestIDHeader, "trace-me")
+	w := httptest.NewRecorder()
+
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	var body errorResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.RequestID != "trace-me" {
+		t.Errorf("expected request_id 'trace-me', got %q", body.RequestID)
+	}
+	if body.Error != "Task not found" {
+		t.Errorf("expected error 'Task not found', got %q", body.Error)
+	}
+}
+
+func TestStructuredRequestLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := logging.New(&buf, logging.FormatJSON, slog.LevelDebug)
+	server := NewServer(storage.NewStorage(), logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(`{"Header":"Task"}`))
+	req.Header.Set(RequestIDHeader, "log-me")
+	server.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	var completed, storageOp map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("failed to decode log line %q: %v", line, err)
+		}
+		if record["request_id"] != "log-me" {
+			t.Errorf("expected request_id on every line, got %v", record)
+		}
+		switch record["msg"] {
+		case "request completed":
+			completed = record
+		case "storage operation":
+			storageOp = record
+		}
+	}
+
+	if completed == nil {
+		t.Fatal("expected a request completed log line")
+	}
+	if completed["status"] != float64(http.StatusCreated) {
+		t.Errorf("expected status 201, got %v", completed["status"])
+	}
+	if completed["bytes"].(float64) <= 0 {
+		t.Errorf("expected response size to be logged, got %v", completed["bytes"])
+	}
+	if storageOp == nil || storageOp["operation"] != "create" {
+		t.Errorf("expected storage operation to be logged with request id, got %v", storageOp)
+	}
+}
+
+func TestTracingSpans(t *testing.T) {
+	var traces, logs bytes.Buffer
+	tracer := tracing.NewTracer(tracing.TracerOptions{Exporter: tracing.NewWriterExporter(&traces)})
+	logger, _ := logging.New(&logs, logging.FormatJSON, slog.LevelInfo)
+	server := NewServer(storage.NewStorage(), logger, WithTracer(tracer))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tracer.Run(ctx) }()
+
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(`{"Header":"Task"}`))
+	req.Header.Set(tracing.TraceparentHeader, incoming)
+	server.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	out := traces.String()
+	for _, fragment := range []string{
+		`"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+		`"parentSpanId":"00f067aa0ba902b7"`,
+		`"name":"POST /todos"`,
+		`"name":"storage.create"`,
+	} {
+		if !strings.Contains(out, fragment) {
+			t.Errorf("expected %s in exported traces:\n%s", fragment, out)
+		}
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("failed to decode log line: %v", err)
+	}
+	if record["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected trace_id on request log, got %v", record["trace_id"])
+	}
+}
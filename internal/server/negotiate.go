@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"todo/internal/codec"
+	"todo/internal/validation"
+)
+
+// WithCodecs replaces the formats the API speaks; the first codec is used when the client
+// has no preference.
+func WithCodecs(codecs *codec.Registry) Option {
+	return func(s *Server) {
+		s.codecs = codecs
+	}
+}
+
+type codecKey struct{}
+
+// NegotiateMiddleware picks the response format from the Accept header before the handler
+// runs, so a request the server cannot answer fails with 406 without side effects.
+func (s *Server) NegotiateMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if r.Method == http.MethodOptions {
+			next(w, r)
+			return
+		}
+
+		c, ok := s.codecs.Negotiate(r.Header.Get("Accept"))
+		if !ok {
+			s.writeErrorResponse(w, r, http.StatusNotAcceptable, errorResponse{
+				Error:   "Not acceptable",
+				Details: []validation.FieldError{{Field: "Accept", Message: "must allow one of " + strings.Join(s.codecs.MediaTypes(), ", ")}},
+			})
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), codecKey{}, c)))
+	}
+}
+
+// responseCodec is the negotiated codec. Responses of routes without negotiation, such as
+// errors from file exports, still follow the Accept header when they can and use the
+// preferred codec otherwise.
+func (s *Server) responseCodec(r *http.Request) codec.Codec {
+	if c, ok := r.Context().Value(codecKey{}).(codec.Codec); ok {
+		return c
+	}
+	if c, ok := s.codecs.Negotiate(r.Header.Get("Accept")); ok {
+		return c
+	}
+	return s.codecs.Preferred()
+}
+
+func (s *Server) writeResponse(w http.ResponseWriter, r *http.Request, code int, v any) {
+	c := s.responseCodec(r)
+	w.Header().Set("Content-Type", c.MediaType())
+	w.WriteHeader(code)
+	if err := c.Encode(w, v); err != nil {
+		// The status line is already sent; the client sees a truncated body.
+		s.logger.ErrorContext(r.Context(), "encode response failed", "error", err, "content_type", c.MediaType())
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo/internal/codec"
+	"todo/internal/storage"
+)
+
+func TestNegotiatedResponses(t *testing.T) {
+	server := setupServer()
+	_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Buy milk", Status: storage.InProgress})
+
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		wantCode    int
+		wantType    string
+		wantInBody  string
+		decodeAsXML bool
+	}{
+		{"no preference", "/todos/0", "", http.StatusOK, "application/json", `"Header":"Buy milk"`, false},
+		{"wildcard", "/todos/0", "*/*", http.StatusOK, "application/json", `"Header":"Buy milk"`, false},
+		{"xml", "/todos/0", "application/xml", http.StatusOK, "application/xml", "<Header>Buy milk</Header>", false},
+		{"xml list", "/todos", "text/xml, application/json;q=0.5", http.StatusOK, "application/xml", "<TaskList><Task>", false},
+		{"quality wins", "/todos/0", "application/json;q=0.1, application/cbor", http.StatusOK, "application/cbor", "Buy milk", false},
+		{"msgpack", "/healthz", "application/vnd.msgpack", http.StatusOK, "application/msgpack", "status", false},
+		{"error in xml", "/todos/42", "application/xml", http.StatusNotFound, "application/xml", "<error>Task not found</error>", false},
+		{"not acceptable", "/todos/0", "text/html", http.StatusNotAcceptable, "application/json", `"field":"Accept"`, false},
+		{"excluded", "/todos/0", "application/json;q=0, */*;q=0", http.StatusNotAcceptable, "application/json", "Not acceptable", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.wantType {
+				t.Errorf("expected Content-Type %q, got %q", tt.wantType, ct)
+			}
+			if vary := w.Header().Values("Vary"); !containsValue(vary, "Accept") {
+				t.Errorf("expected Vary: Accept, got %q", vary)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantInBody) {
+				t.Errorf("expected %q in body %q", tt.wantInBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func containsValue(values []string, want string) bool {
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestNegotiatedRequestBodies(t *testing.T) {
+	task := storage.Task{Header: "Binary", Description: "sent as bytes", Status: storage.Completed}
+
+	for _, c := range []codec.Codec{codec.JSON{}, codec.XML{}, codec.MessagePack{}, codec.CBOR{}} {
+		t.Run(c.MediaType(), func(t *testing.T) {
+			server := setupServer()
+
+			var body bytes.Buffer
+			if err := c.Encode(&body, task); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/todos", &body)
+			req.Header.Set("Content-Type", c.MediaType())
+			req.Header.Set("Accept", c.MediaType())
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != c.MediaType() {
+				t.Errorf("expected Content-Type %q, got %q", c.MediaType(), ct)
+			}
+			var created storage.Task
+			if err := c.Decode(w.Body.Bytes(), &created); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			stored, err := server.storage.GetByID(context.Background(), created.TaskID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Header != task.Header || stored.Description != task.Description || stored.Status != task.Status {
+				t.Errorf("unexpected stored task %+v", stored)
+			}
+		})
+	}
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	server := setupServer()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantError   string
+	}{
+		{"form", "application/x-www-form-urlencoded", "Header=x", http.StatusUnsupportedMediaType, "Unsupported media type"},
+		{"msgpack alias", "application/x-msgpack", "\x81\xa6Header\xa1x", http.StatusCreated, ""},
+		{"malformed cbor", "application/cbor", "\xa1\x66Header", http.StatusBadRequest, "malformed CBOR"},
+		{"trailing msgpack", "application/msgpack", "\x81\xa6Header\xa1x\xc0", http.StatusBadRequest, "single value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantError == "" {
+				return
+			}
+			var resp errorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(resp.Error, tt.wantError) {
+				t.Errorf("expected %q in error, got %+v", tt.wantError, resp)
+			}
+		})
+	}
+}
+
+func TestNegotiationSkipsFileRoutes(t *testing.T) {
+	server := setupServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/todos/export?format=csv", nil)
+	req.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected a CSV export, got %q", ct)
+	}
+}
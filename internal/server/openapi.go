@@ -0,0 +1,606 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	v1 "todo/internal/api/v1"
+	"todo/internal/buildinfo"
+	"todo/internal/storage"
+)
+
+//go:embed assets/docs.html
+var docsPage []byte
+
+type apiOperation struct {
+	method      string
+	path        string
+	operationID string
+	summary     string
+	requestBody string
+	parameters  []string
+	responses   map[int]string
+}
+
+// apiOperations lists what the handlers implement; requestBody, parameters and response
+// values name components.
+func apiOperations() []apiOperation {
+	return []apiOperation{
+		{http.MethodGet, "/todos", "listTodos", "List tasks ordered by ID", "", []string{"Limit", "After", "Status", "Owner", "Query"},
+			map[int]string{200: "TaskList", 400: "Error", 406: "Error", 429: "TooManyRequests", 500: "Error"}},
+		{http.MethodPost, "/todos", "createTodo", "Create a task", "Task", nil,
+			map[int]string{201: "Task", 400: "Error", 403: "Error", 406: "Error", 413: "Error", 415: "Error", 429: "TooManyRequests", 500: "Error", 503: "Error"}},
+		{http.MethodOptions, "/todos", "optionsTodos", "Allowed methods and CORS preflight", "", nil,
+			map[int]string{204: "Options"}},
+		{http.MethodGet, "/todos/{id}", "getTodo", "Get a task by ID", "", nil,
+			map[int]string{200: "Task", 400: "Error", 404: "Error", 406: "Error", 429: "TooManyRequests", 500: "Error"}},
+		{http.MethodPut, "/todos/{id}", "updateTodo", "Replace a task", "Task", nil,
+			map[int]string{200: "Task", 400: "Error", 403: "Error", 404: "Error", 406: "Error", 413: "Error", 415: "Error", 429: "TooManyRequests", 500: "Error", 503: "Error"}},
+		{http.MethodDelete, "/todos/{id}", "deleteTodo", "Delete a task", "", nil,
+			map[int]string{204: "NoContent", 400: "Error", 403: "Error", 404: "Error", 406: "Error", 429: "TooManyRequests", 500: "Error", 503: "Error"}},
+		{http.MethodOptions, "/todos/{id}", "optionsTodo", "Allowed methods and CORS preflight", "", nil,
+			map[int]string{204: "Options"}},
+		{http.MethodGet, "/todos/export", "exportTodos", "Download all tasks as a file", "", []string{"Format"},
+			map[int]string{200: "Export", 400: "Error", 429: "TooManyRequests", 500: "Error"}},
+		{http.MethodOptions, "/todos/export", "optionsExport", "Allowed methods and CORS preflight", "", nil,
+			map[int]string{204: "Options"}},
+		{http.MethodPost, "/todos/import", "importTodos", "Create or update tasks from a file", "TaskImport",
+			[]string{"Format", "ImportMode", "DryRun", "ColumnMap"},
+			map[int]string{200: "ImportReport", 400: "Error", 406: "Error", 413: "Error", 422: "ImportReport", 429: "TooManyRequests", 500: "Error"}},
+		{http.MethodOptions, "/todos/import", "optionsImport", "Allowed methods and CORS preflight", "", nil,
+			map[int]string{204: "Options"}},
+		{http.MethodGet, "/todos.ics", "calendarFeed", "Tasks as an iCalendar feed of VTODO components", "", []string{"Token"},
+			map[int]string{200: "Calendar", 429: "TooManyRequests", 500: "Error"}},
+		{http.MethodPost, "/todos.ics", "importCalendar", "Create or update tasks from an .ics file", "Calendar",
+			[]string{"Token", "CalendarMode", "DryRun"},
+			map[int]string{200: "ImportReport", 400: "Error", 413: "Error", 422: "ImportReport", 429: "TooManyRequests", 500: "Error"}},
+		{http.MethodOptions, "/todos.ics", "optionsCalendar", "Allowed methods and CORS preflight", "", nil,
+			map[int]string{204: "Options"}},
+		{http.MethodPost, "/rpc", "rpc", "Call JSON-RPC 2.0 methods, one at a time or in a batch", "RPC", nil,
+			map[int]string{200: "RPCResult", 204: "RPCNotification", 400: "RPCError", 413: "RPCError", 415: "RPCError", 429: "TooManyRequests"}},
+		{http.MethodOptions, "/rpc", "optionsRPC", "Allowed methods and CORS preflight", "", nil,
+			map[int]string{204: "Options"}},
+		{http.MethodGet, "/healthz", "healthz", "Liveness probe", "", nil,
+			map[int]string{200: "Health", 406: "Error"}},
+		{http.MethodGet, "/readyz", "readyz", "Readiness of the storage and background workers", "", nil,
+			map[int]string{200: "Readiness", 406: "Error", 503: "Readiness"}},
+		{http.MethodGet, "/version", "version", "Build information", "", nil,
+			map[int]string{200: "Version", 406: "Error"}},
+		{http.MethodGet, "/metrics", "metrics", "Prometheus metrics", "", nil,
+			map[int]string{200: "Metrics"}},
+		{http.MethodGet, "/openapi.json", "openapi", "This document", "", nil,
+			map[int]string{200: "OpenAPI"}},
+		{http.MethodGet, "/docs", "docs", "Human-readable API documentation", "", nil,
+			map[int]string{200: "Docs"}},
+	}
+}
+
+var taskFieldDocs = map[string]map[string]any{
+	"TaskID":      {"description": "Assigned by the server.", "readOnly": true},
+	"Header":      {"description": "Short title; surrounding whitespace is trimmed.", "minLength": 1},
+	"Description": {"description": "Free text; only \\n, \\r and \\t control characters are allowed."},
+	"Status":      {},
+	"Owner":       {"description": "Identity that created the task, set by the server.", "readOnly": true},
+}
+
+func ref(kind, name string) map[string]any {
+	return map[string]any{"$ref": "#/components/" + kind + "/" + name}
+}
+
+// fileContent lists the media type of every export and import format.
+func fileContent() map[string]any {
+	content := map[string]any{}
+	for _, name := range formatNames() {
+		mediaType, _, _ := strings.Cut(taskFormats()[name].contentType, ";")
+		content[mediaType] = map[string]any{"schema": map[string]any{"type": "string"}}
+	}
+	return content
+}
+
+func calendarContent() map[string]any {
+	return map[string]any{"text/calendar": map[string]any{"schema": map[string]any{"type": "string"}}}
+}
+
+// codecContent lists schema under every media type the server negotiates.
+func (s *Server) codecContent(schema map[string]any) map[string]any {
+	content := map[string]any{}
+	for _, mediaType := range s.codecs.MediaTypes() {
+		content[mediaType] = map[string]any{"schema": schema}
+	}
+	return content
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// taskSchema is derived from the version's task type so new fields cannot silently go
+// undocumented.
+func (s *Server) taskSchema(v apiVersion) map[string]any {
+	properties := map[string]any{}
+	t := v.taskType
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		prop := map[string]any{}
+		switch {
+		case field.Name == "Status":
+			prop = ref("schemas", v.component("TaskStatus"))
+		case field.Type.Kind() == reflect.Int:
+			prop["type"] = "integer"
+		case field.Type.Kind() == reflect.String:
+			prop["type"] = "string"
+		}
+		for k, v := range taskFieldDocs[field.Name] {
+			prop[k] = v
+		}
+		properties[v.fields[field.Name]] = prop
+	}
+
+	header := properties[v.fields["Header"]].(map[string]any)
+	header["maxLength"] = s.limits.MaxHeaderLength
+	description := properties[v.fields["Description"]].(map[string]any)
+	description["maxLength"] = s.limits.MaxDescriptionLength
+
+	return map[string]any{
+		"type":                 "object",
+		"required":             []string{v.fields["Header"]},
+		"additionalProperties": false,
+		"properties":           properties,
+	}
+}
+
+func taskStatusSchema() map[string]any {
+	values := make([]int, 0, len(storage.Statuses))
+	names := make([]string, 0, len(storage.Statuses))
+	description := "Task status:"
+	for _, status := range storage.Statuses {
+		values = append(values, int(status))
+		names = append(names, status.String())
+		description += " " + strconv.Itoa(int(status)) + " - " + status.String() + ";"
+	}
+	return map[string]any{
+		"type":            "integer",
+		"enum":            values,
+		"x-enum-varnames": names,
+		"description":     strings.TrimSuffix(description, ";"),
+	}
+}
+
+func v1TaskStatusSchema() map[string]any {
+	names := make([]string, len(v1.Statuses))
+	for i, status := range v1.Statuses {
+		names[i] = string(status)
+	}
+	return map[string]any{
+		"type":        "string",
+		"enum":        names,
+		"description": "Task status; requests may also use the numeric values of the unversioned API.",
+	}
+}
+
+func (s *Server) openAPIComponents() map[string]any {
+	errorContent := s.codecContent(ref("schemas", "Error"))
+	requestIDHeader := map[string]any{RequestIDHeader: ref("headers", "RequestID")}
+
+	components := map[string]any{
+		"schemas": map[string]any{
+			"Error": map[string]any{
+				"type":     "object",
+				"required": []string{"error"},
+				"properties": map[string]any{
+					"error":      map[string]any{"type": "string"},
+					"details":    map[string]any{"type": "array", "items": ref("schemas", "FieldError")},
+					"request_id": map[string]any{"type": "string"},
+				},
+			},
+			"FieldError": map[string]any{
+				"type":     "object",
+				"required": []string{"field", "message"},
+				"properties": map[string]any{
+					"field":   map[string]any{"type": "string"},
+					"message": map[string]any{"type": "string"},
+				},
+			},
+			"ImportReport": map[string]any{
+				"type":     "object",
+				"required": []string{"dry_run", "mode", "valid", "created", "updated", "failed", "rows"},
+				"properties": map[string]any{
+					"dry_run":         map[string]any{"type": "boolean"},
+					"mode":            map[string]any{"type": "string", "enum": []string{importCreate, importUpsert}},
+					"valid":           map[string]any{"type": "boolean"},
+					"created":         map[string]any{"type": "integer"},
+					"updated":         map[string]any{"type": "integer"},
+					"failed":          map[string]any{"type": "integer"},
+					"ignored_columns": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+					"rows":            map[string]any{"type": "array", "items": ref("schemas", "ImportRow")},
+				},
+			},
+			"ImportRow": map[string]any{
+				"type":     "object",
+				"required": []string{"line", "action"},
+				"properties": map[string]any{
+					"line":    map[string]any{"type": "integer", "description": "Line in the source file."},
+					"action":  map[string]any{"type": "string", "enum": []string{importCreate, importUpdate}},
+					"task_id": map[string]any{"type": "integer"},
+					"errors":  map[string]any{"type": "array", "items": ref("schemas", "FieldError")},
+				},
+			},
+			"ComponentStatus": map[string]any{
+				"type":     "object",
+				"required": []string{"status"},
+				"properties": map[string]any{
+					"status": map[string]any{"type": "string", "enum": []string{statusOK, statusFail}},
+					"error":  map[string]any{"type": "string"},
+				},
+			},
+			"Readiness": map[string]any{
+				"type":     "object",
+				"required": []string{"status", "components"},
+				"properties": map[string]any{
+					"status":     map[string]any{"type": "string", "enum": []string{statusOK, statusFail}},
+					"components": map[string]any{"type": "object", "additionalProperties": ref("schemas", "ComponentStatus")},
+				},
+			},
+			"RPCRequest": map[string]any{
+				"type":     "object",
+				"required": []string{"jsonrpc", "method"},
+				"properties": map[string]any{
+					"jsonrpc": map[string]any{"const": "2.0"},
+					"method":  map[string]any{"type": "string", "enum": rpcMethods},
+					"params":  map[string]any{"type": "object", "description": "Method parameters by name; tasks use the /v1 fields."},
+					"id":      map[string]any{"type": []string{"string", "integer", "null"}, "description": "Omitted for notifications, which get no response."},
+				},
+			},
+			"RPCResponse": map[string]any{
+				"type":     "object",
+				"required": []string{"jsonrpc", "id"},
+				"properties": map[string]any{
+					"jsonrpc": map[string]any{"const": "2.0"},
+					"result":  map[string]any{"description": "Present on success."},
+					"error": map[string]any{
+						"type":     "object",
+						"required": []string{"code", "message"},
+						"properties": map[string]any{
+							"code":    map[string]any{"type": "integer"},
+							"message": map[string]any{"type": "string"},
+							"data": map[string]any{
+								"type":       "object",
+								"properties": map[string]any{"details": map[string]any{"type": "array", "items": ref("schemas", "FieldError")}},
+							},
+						},
+					},
+					"id": map[string]any{"type": []string{"string", "integer", "null"}},
+				},
+			},
+			"BuildInfo": map[string]any{
+				"type":     "object",
+				"required": []string{"version", "go_version"},
+				"properties": map[string]any{
+					"version":    map[string]any{"type": "string"},
+					"commit":     map[string]any{"type": "string"},
+					"build_time": map[string]any{"type": "string"},
+					"go_version": map[string]any{"type": "string"},
+					"modified":   map[string]any{"type": "boolean"},
+				},
+			},
+		},
+		"requestBodies": map[string]any{
+			"TaskImport": map[string]any{
+				"required":    true,
+				"description": "A file in the format selected by the format parameter or the Content-Type.",
+				"content":     fileContent(),
+			},
+			"Calendar": map[string]any{
+				"required":    true,
+				"description": "VTODO components; UIDs from the feed update the matching tasks.",
+				"content":     calendarContent(),
+			},
+			"RPC": map[string]any{
+				"required": true,
+				"content": jsonContent(map[string]any{"oneOf": []any{
+					ref("schemas", "RPCRequest"),
+					map[string]any{"type": "array", "items": ref("schemas", "RPCRequest"), "minItems": 1, "maxItems": maxRPCBatch},
+				}}),
+			},
+		},
+		"headers": map[string]any{
+			"RequestID": map[string]any{
+				"description": "Request correlation ID, echoed from the request or generated.",
+				"schema":      map[string]any{"type": "string"},
+			},
+			"RetryAfter": map[string]any{
+				"description": "Seconds until the next request is allowed.",
+				"schema":      map[string]any{"type": "integer"},
+			},
+		},
+		"parameters": map[string]any{
+			"TaskID": map[string]any{
+				"name": "id", "in": "path", "required": true,
+				"schema": map[string]any{"type": "integer"},
+			},
+			"Limit": map[string]any{
+				"name": "limit", "in": "query",
+				"description": "Page size; without it all remaining tasks are returned.",
+				"schema":      map[string]any{"type": "integer", "minimum": 1, "maximum": MaxPageSize},
+			},
+			"Format": map[string]any{
+				"name": "format", "in": "query",
+				"schema": map[string]any{"type": "string", "enum": formatNames(), "default": "csv"},
+			},
+			"ImportMode": map[string]any{
+				"name": "mode", "in": "query",
+				"description": "create always adds new tasks; upsert updates rows whose TaskID exists.",
+				"schema":      map[string]any{"type": "string", "enum": []string{importCreate, importUpsert}, "default": importCreate},
+			},
+			"CalendarMode": map[string]any{
+				"name": "mode", "in": "query",
+				"description": "create always adds new tasks; upsert updates tasks whose UID came from the feed.",
+				"schema":      map[string]any{"type": "string", "enum": []string{importCreate, importUpsert}, "default": importUpsert},
+			},
+			"Token": map[string]any{
+				"name": "token", "in": "query",
+				"description": "Bearer token for calendar applications that cannot send headers; limits the feed to the token's tasks.",
+				"schema":      map[string]any{"type": "string"},
+			},
+			"DryRun": map[string]any{
+				"name": "dry_run", "in": "query",
+				"description": "Validate and report without changing anything.",
+				"schema":      map[string]any{"type": "boolean", "default": false},
+			},
+			"ColumnMap": map[string]any{
+				"name": "map", "in": "query",
+				"description": `CSV column mapping "Column name=Field", repeatable; overrides the built-in aliases.`,
+				"schema":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				"explode":     true,
+			},
+			"After": map[string]any{
+				"name": "after", "in": "query",
+				"description": "Return tasks with IDs greater than this one.",
+				"schema":      map[string]any{"type": "integer", "minimum": 0},
+			},
+			"Status": map[string]any{
+				"name": "status", "in": "query",
+				"description": "Only tasks with one of these statuses, by name or number; repeatable or comma-separated.",
+				"schema":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				"explode":     true,
+			},
+			"Owner": map[string]any{
+				"name": "owner", "in": "query",
+				"description": "Only tasks of this owner.",
+				"schema":      map[string]any{"type": "string"},
+			},
+			"Query": map[string]any{
+				"name": "q", "in": "query",
+				"description": "Only tasks whose header or description contains this text, ignoring case.",
+				"schema":      map[string]any{"type": "string"},
+			},
+		},
+		"responses": map[string]any{
+			"Export": map[string]any{
+				"description": "All tasks ordered by ID.",
+				"headers": map[string]any{
+					RequestIDHeader:       ref("headers", "RequestID"),
+					"Content-Disposition": map[string]any{"schema": map[string]any{"type": "string"}},
+				},
+				"content": fileContent(),
+			},
+			"Calendar": map[string]any{
+				"description": "All tasks, or the caller's own when authenticated, ordered by ID.",
+				"headers":     requestIDHeader,
+				"content":     calendarContent(),
+			},
+			"ImportReport": map[string]any{
+				"description": "Per-row outcome of the import; 422 when rows are invalid and nothing was changed.",
+				"headers":     requestIDHeader,
+				"content":     s.codecContent(ref("schemas", "ImportReport")),
+			},
+			"NoContent": map[string]any{
+				"description": "The task was deleted.",
+				"headers":     requestIDHeader,
+			},
+			"Options": map[string]any{
+				"description": "Allowed methods in the Allow header; CORS headers for allowed origins.",
+				"headers":     map[string]any{"Allow": map[string]any{"schema": map[string]any{"type": "string"}}},
+			},
+			"Error": map[string]any{
+				"description": "Error with an optional list of invalid fields.",
+				"headers":     requestIDHeader,
+				"content":     errorContent,
+			},
+			"TooManyRequests": map[string]any{
+				"description": "Rate limit exceeded.",
+				"headers": map[string]any{
+					RequestIDHeader: ref("headers", "RequestID"),
+					"Retry-After":   ref("headers", "RetryAfter"),
+				},
+				"content": errorContent,
+			},
+			"RPCResult": map[string]any{
+				"description": "Responses to the calls that have an id, in order; errors of single calls are reported here too.",
+				"headers":     requestIDHeader,
+				"content": jsonContent(map[string]any{"oneOf": []any{
+					ref("schemas", "RPCResponse"),
+					map[string]any{"type": "array", "items": ref("schemas", "RPCResponse")},
+				}}),
+			},
+			"RPCNotification": map[string]any{
+				"description": "Every call was a notification.",
+				"headers":     requestIDHeader,
+			},
+			"RPCError": map[string]any{
+				"description": "The request could not be read as JSON-RPC.",
+				"headers":     requestIDHeader,
+				"content":     jsonContent(ref("schemas", "RPCResponse")),
+			},
+			"Health": map[string]any{
+				"description": "The process is alive.",
+				"content":     s.codecContent(ref("schemas", "ComponentStatus")),
+			},
+			"Readiness": map[string]any{
+				"description": "Per-component readiness report.",
+				"content":     s.codecContent(ref("schemas", "Readiness")),
+			},
+			"Version": map[string]any{
+				"description": "Build metadata.",
+				"content":     s.codecContent(ref("schemas", "BuildInfo")),
+			},
+			"Metrics": map[string]any{
+				"description": "Prometheus text exposition format.",
+				"content":     map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}},
+			},
+			"OpenAPI": map[string]any{
+				"description": "OpenAPI 3.1 document.",
+				"content":     jsonContent(map[string]any{"type": "object"}),
+			},
+			"Docs": map[string]any{
+				"description": "HTML documentation page.",
+				"content":     map[string]any{"text/html": map[string]any{"schema": map[string]any{"type": "string"}}},
+			},
+		},
+		"securitySchemes": map[string]any{
+			"bearerAuth": map[string]any{
+				"type":        "http",
+				"scheme":      "bearer",
+				"description": "Optional; identifies the caller for rate limiting and task ownership.",
+			},
+		},
+	}
+
+	schemas := components["schemas"].(map[string]any)
+	requestBodies := components["requestBodies"].(map[string]any)
+	responses := components["responses"].(map[string]any)
+	for _, v := range apiVersions() {
+		task := ref("schemas", v.component("Task"))
+		schemas[v.component("Task")] = s.taskSchema(v)
+		schemas[v.component("TaskStatus")] = v.statusSchema()
+		requestBodies[v.component("Task")] = map[string]any{
+			"required": true,
+			"content":  s.codecContent(task),
+		}
+		responses[v.component("Task")] = map[string]any{
+			"description": "The task.",
+			"headers":     requestIDHeader,
+			"content":     s.codecContent(task),
+		}
+		responses[v.component("TaskList")] = map[string]any{
+			"description": "A page of tasks.",
+			"headers": map[string]any{
+				RequestIDHeader: ref("headers", "RequestID"),
+				"Link": map[string]any{
+					"description": `Next page as <url>; rel="next", absent on the last page.`,
+					"schema":      map[string]any{"type": "string"},
+				},
+			},
+			"content": s.codecContent(map[string]any{"type": "array", "items": task}),
+		}
+	}
+	return components
+}
+
+// versionedComponents have one variant per API version.
+var versionedComponents = map[string]bool{"Task": true, "TaskList": true}
+
+func (s *Server) OpenAPI() map[string]any {
+	paths := map[string]any{}
+	for _, op := range apiOperations() {
+		if !strings.HasPrefix(op.path, "/todos") {
+			addOperation(paths, op, apiVersion{})
+			continue
+		}
+		for _, v := range apiVersions() {
+			addOperation(paths, op, v)
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "TODO API",
+			"version": buildinfo.Get().Version,
+			"description": "HTTP API for managing tasks: REST under /todos and /v1/todos and JSON-RPC 2.0 at /rpc. " +
+				"GraphQL (/graphql, schema at /graphql/schema.graphql), the /ws WebSocket and the /ui web interface are not described here.",
+		},
+		"paths":      paths,
+		"components": s.openAPIComponents(),
+	}
+}
+
+func addOperation(paths map[string]any, op apiOperation, v apiVersion) {
+	path := v.prefix() + op.path
+	item, ok := paths[path].(map[string]any)
+	if !ok {
+		item = map[string]any{}
+		if op.path == "/todos/{id}" {
+			item["parameters"] = []any{ref("parameters", "TaskID")}
+		}
+		paths[path] = item
+	}
+
+	component := func(name string) string {
+		if versionedComponents[name] {
+			return v.component(name)
+		}
+		return name
+	}
+	responses := map[string]any{}
+	for code, name := range op.responses {
+		responses[strconv.Itoa(code)] = ref("responses", component(name))
+	}
+	operation := map[string]any{
+		"operationId": v.operationID(op.operationID),
+		"summary":     op.summary,
+		"responses":   responses,
+	}
+	if v.deprecated {
+		operation["deprecated"] = true
+	}
+	if len(op.parameters) > 0 {
+		params := make([]any, 0, len(op.parameters))
+		for _, name := range op.parameters {
+			params = append(params, ref("parameters", name))
+		}
+		operation["parameters"] = params
+	}
+	if op.requestBody != "" {
+		operation["requestBody"] = ref("requestBodies", component(op.requestBody))
+	}
+	switch {
+	case strings.HasPrefix(op.path, "/todos"):
+		operation["tags"] = []string{"todos"}
+		operation["security"] = []any{map[string]any{}, map[string]any{"bearerAuth": []string{}}}
+	case op.path == "/rpc":
+		operation["tags"] = []string{"rpc"}
+		operation["security"] = []any{map[string]any{}, map[string]any{"bearerAuth": []string{}}}
+	default:
+		operation["tags"] = []string{"operations"}
+	}
+	item[map[string]string{
+		http.MethodGet: "get", http.MethodPost: "post", http.MethodPut: "put",
+		http.MethodDelete: "delete", http.MethodOptions: "options",
+	}[op.method]] = operation
+}
+
+func (s *Server) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(s.OpenAPI())
+}
+
+func (s *Server) HandleDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(docsPage)
+}
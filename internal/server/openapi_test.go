@@ -0,0 +1,232 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+)
+
+type specOperation struct {
+	Responses map[string]any `json:"responses"`
+}
+
+type specDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func fetchSpec(t *testing.T, handler http.Handler) specDocument {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var spec specDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("failed to decode spec: %v", err)
+	}
+	return spec
+}
+
+func TestOpenAPIDocumentsAllRoutes(t *testing.T) {
+	server := setupServer()
+	spec := fetchSpec(t, server.Handler())
+
+	if spec.OpenAPI != "3.1.0" {
+		t.Errorf("expected openapi 3.1.0, got %q", spec.OpenAPI)
+	}
+
+	for _, rt := range server.routes() {
+		if rt.path == "" {
+			continue
+		}
+		if _, ok := spec.Paths[rt.path]; !ok {
+			t.Errorf("route %s is not documented", rt.path)
+		}
+	}
+	for path := range spec.Paths {
+		if !slices.ContainsFunc(server.routes(), func(rt route) bool { return rt.path == path }) {
+			t.Errorf("documented path %s is not served", path)
+		}
+	}
+}
+
+// TestOpenAPIMatchesHandlers sends every method to every route and checks
+// that the spec and the handlers agree on what is allowed and what is returned.
+func TestOpenAPIMatchesHandlers(t *testing.T) {
+	spec := fetchSpec(t, setupServer().Handler())
+	methods := []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch, http.MethodOptions}
+	bodies := map[string]string{
+		http.MethodPost: `{"Header":"Task","Description":"Description","Status":1}`,
+		http.MethodPut:  `{"Header":"Updated","Description":"Description","Status":2}`,
+	}
+	v1Bodies := map[string]string{
+		http.MethodPost: `{"header":"Task","description":"Description","status":"in_progress"}`,
+		http.MethodPut:  `{"header":"Updated","description":"Description","status":"completed"}`,
+	}
+
+	for path, item := range spec.Paths {
+		for _, method := range methods {
+			t.Run(method+" "+path, func(t *testing.T) {
+				handler := setupServer().Handler()
+				created := postTodo(handler, "192.0.2.1:1234", "")
+				var task storage.Task
+				if err := json.Unmarshal(created.Body.Bytes(), &task); err != nil {
+					t.Fatalf("failed to create task: %v", err)
+				}
+
+				target := strings.Replace(path, "{id}", strconv.Itoa(task.TaskID), 1)
+				body := bodies[method]
+				if strings.HasPrefix(path, "/v1/") {
+					body = v1Bodies[method]
+				}
+				req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+				req.RemoteAddr = "192.0.2.1:1234"
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				raw, documented := item[strings.ToLower(method)]
+				if !documented {
+					if w.Code != http.StatusMethodNotAllowed {
+						t.Errorf("undocumented method returned %d, expected 405", w.Code)
+					}
+					return
+				}
+
+				var op specOperation
+				if err := json.Unmarshal(raw, &op); err != nil {
+					t.Fatalf("failed to decode operation: %v", err)
+				}
+				if _, ok := op.Responses[strconv.Itoa(w.Code)]; !ok {
+					codes := make([]string, 0, len(op.Responses))
+					for code := range op.Responses {
+						codes = append(codes, code)
+					}
+					sort.Strings(codes)
+					t.Errorf("status %d is not documented, documented: %v", w.Code, codes)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenAPISchemaMatchesResponse(t *testing.T) {
+	handler := setupServer().Handler()
+	spec := fetchSpec(t, handler)
+	postTodo(handler, "192.0.2.1:1234", "")
+
+	// Fields of partial schemas may be omitted from the response.
+	tests := []struct {
+		target  string
+		schema  string
+		partial bool
+	}{
+		{"/todos/0", "Task", false},
+		{"/v1/todos/0", "V1Task", false},
+		{"/version", "BuildInfo", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode task: %v", err)
+			}
+
+			properties := spec.Components.Schemas[tt.schema].Properties
+			for key := range body {
+				if _, ok := properties[key]; !ok {
+					t.Errorf("response field %s is not in the %s schema", key, tt.schema)
+				}
+			}
+			for key := range properties {
+				if _, ok := body[key]; !ok && !tt.partial {
+					t.Errorf("schema property %s is missing from the response", key)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAPIDescribesRPC(t *testing.T) {
+	server := setupServer()
+	handler := server.Handler()
+	spec := fetchSpec(t, handler)
+
+	for _, name := range rpcMethods {
+		if _, ok := server.rpcMethod(name); !ok {
+			t.Errorf("documented method %s is not served", name)
+		}
+	}
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"jsonrpc":"2.0","method":"task.create","params":{"task":{"header":"Buy milk"}},"id":1}`, http.StatusOK},
+		{`{"jsonrpc":"2.0","method":"task.nope","id":2}`, http.StatusOK},
+		{`[{"jsonrpc":"2.0","method":"task.list","id":3}]`, http.StatusOK},
+		{`{"jsonrpc":"2.0","method":"task.list"}`, http.StatusNoContent},
+	}
+	properties := spec.Components.Schemas["RPCResponse"].Properties
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d", tt.body, tt.status, w.Code)
+		}
+		if w.Code == http.StatusNoContent {
+			continue
+		}
+
+		var responses []map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+			var single map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &single); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			responses = append(responses, single)
+		}
+		for _, resp := range responses {
+			for key := range resp {
+				if _, ok := properties[key]; !ok {
+					t.Errorf("response field %s is not in the RPCResponse schema", key)
+				}
+			}
+		}
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	w := httptest.NewRecorder()
+	setupServer().Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected HTML, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Error("docs page does not load the specification")
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+const MaxPageSize = 1000
+
+// page is a keyset window over tasks ordered by ID: tasks with TaskID > after, at most limit of them.
+type page struct {
+	limit int
+	after int
+}
+
+func parsePage(query url.Values) (page, *requestError) {
+	p := page{after: -1}
+	var details []validation.FieldError
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPageSize {
+			details = append(details, validation.FieldError{
+				Field:   "limit",
+				Message: "must be an integer between 1 and " + strconv.Itoa(MaxPageSize),
+			})
+		}
+		p.limit = n
+	}
+	if v := query.Get("after"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			details = append(details, validation.FieldError{Field: "after", Message: "must be a non-negative task ID"})
+		}
+		p.after = n
+	}
+
+	if len(details) > 0 {
+		return page{}, &requestError{status: http.StatusBadRequest, message: "Invalid query parameters", details: details}
+	}
+	return p, nil
+}
+
+// apply sorts tasks by ID and cuts the window; next is the query of the following page, empty on the last one.
+func (p page) apply(tasks []storage.Task) ([]storage.Task, string) {
+	sortByID(tasks)
+
+	start, _ := slices.BinarySearchFunc(tasks, p.after+1, func(t storage.Task, id int) int { return t.TaskID - id })
+	tasks = tasks[start:]
+	if p.limit == 0 || len(tasks) <= p.limit {
+		return tasks, ""
+	}
+
+	tasks = tasks[:p.limit]
+	next := url.Values{}
+	next.Set("limit", strconv.Itoa(p.limit))
+	next.Set("after", strconv.Itoa(tasks[len(tasks)-1].TaskID))
+	return tasks, next.Encode()
+}
+
+func sortByID(tasks []storage.Task) {
+	slices.SortFunc(tasks, func(a, b storage.Task) int { return a.TaskID - b.TaskID })
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	v1 "todo/internal/api/v1"
+	"todo/internal/storage"
+)
+
+func TestListPagination(t *testing.T) {
+	server := setupServer()
+	handler := server.Handler()
+	for i := 0; i < 5; i++ {
+		_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Task " + strconv.Itoa(i)})
+	}
+
+	var ids []int
+	target := "/todos?limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		var tasks []storage.Task
+		if err := json.NewDecoder(w.Body).Decode(&tasks); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(tasks) > 2 {
+			t.Errorf("expected at most 2 tasks per page, got %d", len(tasks))
+		}
+		for _, task := range tasks {
+			ids = append(ids, task.TaskID)
+		}
+
+		target = ""
+		if link := w.Header().Get("Link"); link != "" {
+			target = strings.TrimPrefix(strings.SplitN(link, ">", 2)[0], "<")
+		}
+	}
+
+	if len(ids) != 5 {
+		t.Fatalf("expected 5 tasks across pages, got %v", ids)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Errorf("tasks are not ordered by ID: %v", ids)
+		}
+	}
+}
+
+func TestListPaginationInvalidParameters(t *testing.T) {
+	handler := setupServer().Handler()
+
+	tests := []struct {
+		name  string
+		query string
+		field string
+	}{
+		{"zero limit", "limit=0", "limit"},
+		{"limit too large", "limit=" + strconv.Itoa(MaxPageSize+1), "limit"},
+		{"non-numeric limit", "limit=ten", "limit"},
+		{"negative cursor", "after=-1", "after"},
+		{"unknown status", "status=later", "status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos?"+tt.query, nil))
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", w.Code)
+			}
+			var resp errorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Details) != 1 || resp.Details[0].Field != tt.field {
+				t.Errorf("expected a detail for %s, got %+v", tt.field, resp.Details)
+			}
+		})
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	server := setupServer()
+	handler := server.Handler()
+	for _, task := range []storage.Task{
+		{Header: "Write report", Status: storage.Assigned, Owner: "token:a"},
+		{Header: "Review report", Status: storage.Completed, Owner: "token:b"},
+		{Header: "Buy milk", Description: "for the REPORT party", Status: storage.Completed, Owner: "token:a"},
+		{Header: "Plan trip", Status: storage.Dropped, Owner: "token:a"},
+	} {
+		_, _ = server.storage.CreateTask(context.Background(), task)
+	}
+
+	tests := []struct {
+		target string
+		want   []int
+	}{
+		{"/todos?status=completed", []int{1, 2}},
+		{"/todos?status=0,3", []int{0, 3}},
+		{"/todos?status=assigned&status=dropped", []int{0, 3}},
+		{"/todos?owner=token:a&q=report", []int{0, 2}},
+		{"/todos?status=completed&owner=token:b", []int{1}},
+		{"/todos?q=nothing", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var tasks []storage.Task
+			if err := json.NewDecoder(w.Body).Decode(&tasks); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			ids := []int{}
+			for _, task := range tasks {
+				ids = append(ids, task.TaskID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("expected tasks %v, got %v", tt.want, ids)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/todos?status=in_progress,completed&owner=token:b", nil))
+	var v1Tasks []v1.Task
+	if err := json.NewDecoder(w.Body).Decode(&v1Tasks); err != nil || len(v1Tasks) != 1 || v1Tasks[0].TaskID != 1 {
+		t.Errorf("expected task 1 from /v1, got %+v, %v", v1Tasks, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos?limit=1&status=completed&q=report", nil))
+	link := w.Header().Get("Link")
+	if want := `</todos?after=1&limit=1&q=report&status=completed>; rel="next"`; link != want {
+		t.Errorf("expected the next page to keep the filter, got %q, want %q", link, want)
+	}
+}
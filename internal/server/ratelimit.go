@@ -0,0 +1,78 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"todo/internal/ratelimit"
+)
+
+const RateLimitAnyRoute = "*"
+
+// WithRateLimits installs token-bucket limits per client. Keys are "METHOD route", "route"
+// or RateLimitAnyRoute, with routes as reported in metrics, e.g. "POST /todos" or "/todos/{id}".
+func WithRateLimits(rules map[string]ratelimit.Rule) Option {
+	return func(s *Server) {
+		s.limiters = make(map[string]*ratelimit.Limiter, len(rules))
+		for key, rule := range rules {
+			limiter := ratelimit.NewLimiter(rule)
+			s.limiters[key] = limiter
+			s.AddWorker("ratelimit "+key, limiter)
+		}
+	}
+}
+
+func (s *Server) limiterFor(method, route string) (string, *ratelimit.Limiter) {
+	for _, key := range []string{method + " " + route, route, RateLimitAnyRoute} {
+		if limiter, ok := s.limiters[key]; ok {
+			return key, limiter
+		}
+	}
+	return "", nil
+}
+
+func (s *Server) RateLimitMiddleware(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isPreflight(r) || s.allowRequest(w, r, route) {
+			next(w, r)
+			return
+		}
+		s.writeError(w, r, http.StatusTooManyRequests, "Rate limit exceeded")
+	}
+}
+
+// allowRequest charges r to its client's bucket for route and sets the RateLimit headers,
+// plus Retry-After when the request has to be rejected.
+func (s *Server) allowRequest(w http.ResponseWriter, r *http.Request, route string) bool {
+	key, limiter := s.limiterFor(r.Method, route)
+	if limiter == nil {
+		return true
+	}
+
+	decision := limiter.Allow(key + "|" + IdentityFromContext(r.Context()).String())
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	h.Set("RateLimit-Reset", ceilSeconds(decision.Reset))
+	h.Set("RateLimit-Policy", ratePolicy(limiter.Rule()))
+	if !decision.Allowed {
+		h.Set("Retry-After", ceilSeconds(decision.RetryAfter))
+	}
+	return decision.Allowed
+}
+
+// ratePolicy describes rule as a RateLimit-Policy: the quota of requests per window, plus the
+// bucket size when bursts may exceed it.
+func ratePolicy(rule ratelimit.Rule) string {
+	policy := strconv.Itoa(rule.Limit) + ";w=" + ceilSeconds(rule.Window)
+	if rule.Burst != rule.Limit {
+		policy += ";burst=" + strconv.Itoa(rule.Burst)
+	}
+	return policy
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"todo/internal/ratelimit"
+	"todo/internal/storage"
+)
+
+func setupServerWith(st *storage.Storage, opts ...Option) *Server {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	return NewServer(st, logger, append([]Option{WithOwnerKey(testOwnerKey)}, opts...)...)
+}
+
+func postTodo(handler http.Handler, remoteAddr, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(`{"Header":"Task"}`))
+	req.RemoteAddr = remoteAddr
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(), WithRateLimits(map[string]ratelimit.Rule{
+		"POST /todos": {Limit: 2, Window: time.Minute},
+	}))
+	handler := server.Handler()
+
+	for i := 0; i < 2; i++ {
+		w := postTodo(handler, "10.0.0.1:1234", "")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusCreated, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("expected RateLimit-Limit 2, got %q", w.Header().Get("RateLimit-Limit"))
+		}
+	}
+
+	w := postTodo(handler, "10.0.0.1:1234", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+	}
+	if w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("expected RateLimit-Remaining 0, got %q", w.Header().Get("RateLimit-Remaining"))
+	}
+
+	if w := postTodo(handler, "10.0.0.2:1234", ""); w.Code != http.StatusCreated {
+		t.Errorf("expected other client to be allowed, got %d", w.Code)
+	}
+	if w := postTodo(handler, "10.0.0.1:1234", "secret"); w.Code != http.StatusCreated {
+		t.Errorf("expected token client to have its own bucket, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/todos", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	get := httptest.NewRecorder()
+	handler.ServeHTTP(get, req)
+	if get.Code != http.StatusOK || get.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("expected GET /todos to be unlimited, got %d", get.Code)
+	}
+}
+
+func TestRateLimitRuleLookup(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(), WithRateLimits(map[string]ratelimit.Rule{
+		"POST /todos": {Limit: 1, Window: time.Minute},
+		"/todos/{id}": {Limit: 2, Window: time.Minute},
+		"*":           {Limit: 3, Window: time.Minute},
+	}))
+
+	tests := []struct {
+		method      string
+		route       string
+		expectedKey string
+	}{
+		{http.MethodPost, "/todos", "POST /todos"},
+		{http.MethodGet, "/todos", "*"},
+		{http.MethodDelete, "/todos/{id}", "/todos/{id}"},
+	}
+
+	for _, tt := range tests {
+		if key, _ := server.limiterFor(tt.method, tt.route); key != tt.expectedKey {
+			t.Errorf("%s %s: expected rule %q, got %q", tt.method, tt.route, tt.expectedKey, key)
+		}
+	}
+}
+
+func TestOwnerQuota(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(storage.WithMaxTasksPerOwner(1)))
+	handler := server.Handler()
+
+	w := postTodo(handler, "10.0.0.1:1234", "alice-token")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var created storage.Task
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	if created.Owner == "" || created.Owner[:6] != "token:" {
+		t.Errorf("expected token owner, got %q", created.Owner)
+	}
+
+	if w := postTodo(handler, "10.0.0.1:1234", "alice-token"); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := postTodo(handler, "10.0.0.1:1234", ""); w.Code != http.StatusCreated {
+		t.Errorf("expected anonymous client to have its own quota, got %d", w.Code)
+	}
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	tests := []struct {
+		rule ratelimit.Rule
+		want string
+	}{
+		{ratelimit.Rule{Limit: 10, Window: time.Minute}, "10;w=60"},
+		{ratelimit.Rule{Limit: 100, Window: time.Second, Burst: 200}, "100;w=1;burst=200"},
+		{ratelimit.Rule{Limit: 5, Window: 1500 * time.Millisecond, Burst: 5}, "5;w=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			handler := setupServerWith(storage.NewStorage(), WithRateLimits(map[string]ratelimit.Rule{
+				"POST /todos": tt.rule,
+			})).Handler()
+			w := postTodo(handler, "10.0.0.1:1234", "")
+			if got := w.Header().Get("RateLimit-Policy"); got != tt.want {
+				t.Errorf("expected RateLimit-Policy %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAnonymousOwner(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(), WithOwnershipEnforcement())
+	handler := server.Handler()
+
+	w := postTodo(handler, "203.0.113.9:1234", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created storage.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Owner != "ip:328a5b3f861ba976" || strings.Contains(w.Body.String(), "203.0.113.9") {
+		t.Errorf("expected a hashed owner, got %s", w.Body.String())
+	}
+	other := setupServerWith(storage.NewStorage(), WithOwnerKey([]byte("other")))
+	if owner := postTodo(other.Handler(), "203.0.113.9:1234", ""); strings.Contains(owner.Body.String(), created.Owner) {
+		t.Errorf("expected the owner to depend on the key, got %s", owner.Body.String())
+	}
+
+	for _, tt := range []struct {
+		remoteAddr string
+		expected   int
+	}{
+		{"198.51.100.7:1234", http.StatusForbidden},
+		{"203.0.113.9:5678", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/todos/0", bytes.NewBufferString(`{"Header":"Renamed"}`))
+		req.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.expected {
+			t.Errorf("update from %s: expected %d, got %d", tt.remoteAddr, tt.expected, w.Code)
+		}
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name      string
+		hops      int
+		forwarded []string
+		auth      string
+		expected  string
+	}{
+		{"remote address", 0, nil, "", "ip:192.0.2.1"},
+		{"forwarded header ignored", 0, []string{"203.0.113.9"}, "", "ip:192.0.2.1"},
+		{"forwarded header trusted", 1, []string{"203.0.113.9"}, "", "ip:203.0.113.9"},
+		{"spoofed entries skipped", 1, []string{"198.51.100.7, 203.0.113.9"}, "", "ip:203.0.113.9"},
+		{"spoofed header line skipped", 1, []string{"198.51.100.7", "203.0.113.9"}, "", "ip:203.0.113.9"},
+		{"two trusted hops", 2, []string{"198.51.100.7, 203.0.113.9, 10.0.0.1"}, "", "ip:203.0.113.9"},
+		{"fewer entries than hops", 2, []string{"203.0.113.9"}, "", "ip:192.0.2.1"},
+		{"non bearer authorization", 0, nil, "Basic abc", "ip:192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.hops > 0 {
+				opts = append(opts, WithTrustedProxyHeaders(tt.hops))
+			}
+			server := setupServerWith(storage.NewStorage(), opts...)
+
+			req := httptest.NewRequest(http.MethodGet, "/todos", nil)
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			if got := server.identify(req).String(); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
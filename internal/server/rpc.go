@@ -0,0 +1,402 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	v1 "todo/internal/api/v1"
+	"todo/internal/codec"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+// JSON-RPC 2.0 error codes. The range -32000 to -32099 is left to the server; the codes there
+// stand for the failures the HTTP API reports with 404, 403, 503 and 504.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+
+	rpcTaskNotFound  = -32001
+	rpcForbidden     = -32003
+	rpcQuotaExceeded = -32004
+	rpcUnavailable   = -32005
+	rpcTimeout       = -32006
+)
+
+// maxRPCBatch bounds the number of calls in one batch.
+const maxRPCBatch = 100
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	// ID is nil for notifications, which get no response.
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *rpcErrorData `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type rpcErrorData struct {
+	Details []validation.FieldError `json:"details"`
+}
+
+type rpcIDParams struct {
+	ID *int `json:"id"`
+}
+
+type rpcTaskParams struct {
+	ID   *int     `json:"id,omitempty"`
+	Task *v1.Task `json:"task"`
+}
+
+type rpcListParams struct {
+	Statuses []v1.Status `json:"statuses,omitempty"`
+	Owner    string      `json:"owner,omitempty"`
+	Query    string      `json:"query,omitempty"`
+	Limit    int         `json:"limit,omitempty"`
+	After    *int        `json:"after,omitempty"`
+}
+
+// rpcTaskList is a page of task.list; next_after is passed as after to get the next one.
+type rpcTaskList struct {
+	Tasks     []v1.Task `json:"tasks"`
+	NextAfter *int      `json:"next_after,omitempty"`
+}
+
+type rpcMethod func(ctx context.Context, params json.RawMessage) (any, error)
+
+// rpcMethods lists the names rpcMethod serves.
+var rpcMethods = []string{"task.create", "task.get", "task.list", "task.update", "task.delete"}
+
+func (s *Server) rpcMethod(name string) (rpcMethod, bool) {
+	switch name {
+	case "task.create":
+		return s.rpcCreateTask, true
+	case "task.get":
+		return s.rpcGetTask, true
+	case "task.list":
+		return s.rpcListTasks, true
+	case "task.update":
+		return s.rpcUpdateTask, true
+	case "task.delete":
+		return s.rpcDeleteTask, true
+	}
+	return nil, false
+}
+
+// HandleRPC serves JSON-RPC 2.0 over POST, single calls and batches alike. Calls of a batch run
+// in order, so a script may create a task and update it in the same batch.
+func (s *Server) HandleRPC(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodOptions:
+		s.handleOptions(w, http.MethodPost)
+		return
+	default:
+		w.Header().Set("Allow", http.MethodPost)
+		s.writeRPCError(w, r, http.StatusMethodNotAllowed, rpcInvalidRequest, "Method is not allowed")
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		s.writeRPCError(w, r, http.StatusUnsupportedMediaType, rpcInvalidRequest, "Content-Type must be application/json")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.writeRPCError(w, r, http.StatusRequestEntityTooLarge, rpcInvalidRequest, "Request body must not exceed "+strconv.FormatInt(maxBytesErr.Limit, 10)+" bytes")
+			return
+		}
+		s.writeRPCError(w, r, http.StatusBadRequest, rpcParseError, "Parse error")
+		return
+	}
+	if !json.Valid(body) {
+		s.writeRPCError(w, r, http.StatusOK, rpcParseError, "Parse error")
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if body[0] != '[' {
+		if resp := s.rpcCall(r.Context(), body); resp != nil {
+			s.writeRPC(w, r, http.StatusOK, resp)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var batch []json.RawMessage
+	json.Unmarshal(body, &batch)
+	switch {
+	case len(batch) == 0:
+		s.writeRPCError(w, r, http.StatusOK, rpcInvalidRequest, "Invalid Request")
+		return
+	case len(batch) > maxRPCBatch:
+		s.writeRPCError(w, r, http.StatusOK, rpcInvalidRequest, "A batch must not contain more than "+strconv.Itoa(maxRPCBatch)+" calls")
+		return
+	}
+	responses := []*rpcResponse{}
+	for _, raw := range batch {
+		if resp := s.rpcCall(r.Context(), raw); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		// A batch of notifications only has nothing to answer.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.writeRPC(w, r, http.StatusOK, responses)
+}
+
+// rpcCall runs one call; it returns nil for notifications.
+func (s *Server) rpcCall(ctx context.Context, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := (codec.JSON{}).Decode(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" || !validRPCID(req.ID) {
+		return &rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcInvalidRequest, Message: "Invalid Request"}}
+	}
+	if len(req.Params) > 0 && req.Params[0] != '{' && req.Params[0] != '[' {
+		return &rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcInvalidRequest, Message: "Invalid Request"}, ID: req.ID}
+	}
+
+	var result any
+	var err error
+	if method, ok := s.rpcMethod(req.Method); ok {
+		ctx, cancel := context.WithTimeout(ctx, SecToTimeout*time.Second)
+		result, err = method(ctx, req.Params)
+		cancel()
+	} else {
+		err = &rpcError{Code: rpcMethodNotFound, Message: "Method not found"}
+	}
+
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return &rpcResponse{JSONRPC: "2.0", Error: s.rpcErr(ctx, req.Method, err), ID: req.ID}
+	}
+	return &rpcResponse{JSONRPC: "2.0", Result: result, ID: req.ID}
+}
+
+// validRPCID reports whether id is absent, a string, a number or null.
+func validRPCID(id json.RawMessage) bool {
+	if id == nil || string(id) == "null" {
+		return true
+	}
+	switch id[0] {
+	case '"', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
+
+// decodeRPCParams reads named params strictly; absent params are an empty object.
+func decodeRPCParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if params[0] != '{' {
+		return &rpcError{Code: rpcInvalidParams, Message: "Params must be an object of named arguments"}
+	}
+	if err := (codec.JSON{}).Decode(params, v); err != nil {
+		reqErr := describeDecodeError(codec.JSON{}, err)
+		return &rpcError{Code: rpcInvalidParams, Message: strings.Replace(reqErr.message, "Invalid request body", "Invalid params", 1)}
+	}
+	return nil
+}
+
+func requireRPCID(id *int) error {
+	if id == nil {
+		return &rpcError{Code: rpcInvalidParams, Message: "id is required"}
+	}
+	return nil
+}
+
+func (s *Server) rpcCreateTask(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcTaskParams
+	if err := decodeRPCParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ID != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "id is assigned by the server"}
+	}
+	task, err := s.commandTask(p.Task)
+	if err != nil {
+		return nil, err
+	}
+	task.Owner = s.owner(ctx)
+	created, err := s.storage.CreateTask(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return v1.FromTask(*created), nil
+}
+
+func (s *Server) rpcGetTask(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcIDParams
+	if err := decodeRPCParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireRPCID(p.ID); err != nil {
+		return nil, err
+	}
+	task, err := s.storage.GetByID(ctx, *p.ID)
+	if err != nil {
+		return nil, err
+	}
+	return v1.FromTask(task), nil
+}
+
+func (s *Server) rpcListTasks(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcListParams
+	if err := decodeRPCParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	var details []validation.FieldError
+	statuses := make([]storage.TaskStatus, len(p.Statuses))
+	for i, name := range p.Statuses {
+		status, ok := name.Storage()
+		if !ok || name == "" {
+			details = append(details, validation.FieldError{Field: "statuses", Message: "unknown status " + strconv.Quote(string(name))})
+		}
+		statuses[i] = status
+	}
+	pg := page{limit: p.Limit, after: -1}
+	if p.Limit < 0 || p.Limit > MaxPageSize {
+		details = append(details, validation.FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(MaxPageSize)})
+	}
+	if p.After != nil {
+		if *p.After < 0 {
+			details = append(details, validation.FieldError{Field: "after", Message: "must be a non-negative task ID"})
+		}
+		pg.after = *p.After
+	}
+	if len(details) > 0 {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "Invalid params", Data: &rpcErrorData{Details: details}}
+	}
+
+	matched, err := s.findTasks(ctx, storage.Filter{Statuses: statuses, Owner: p.Owner, Query: p.Query})
+	if err != nil {
+		return nil, err
+	}
+	matched, next := pg.apply(matched)
+
+	list := rpcTaskList{Tasks: make([]v1.Task, len(matched))}
+	for i, task := range matched {
+		list.Tasks[i] = v1.FromTask(task)
+	}
+	if next != "" {
+		list.NextAfter = &matched[len(matched)-1].TaskID
+	}
+	return list, nil
+}
+
+func (s *Server) rpcUpdateTask(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcTaskParams
+	if err := decodeRPCParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireRPCID(p.ID); err != nil {
+		return nil, err
+	}
+	task, err := s.commandTask(p.Task)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(ctx, *p.ID); err != nil {
+		return nil, err
+	}
+	updated, err := s.storage.Update(ctx, *p.ID, &task)
+	if err != nil {
+		return nil, err
+	}
+	return v1.FromTask(*updated), nil
+}
+
+func (s *Server) rpcDeleteTask(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcIDParams
+	if err := decodeRPCParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := requireRPCID(p.ID); err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(ctx, *p.ID); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Delete(ctx, *p.ID); err != nil {
+		return nil, err
+	}
+	return rpcIDParams{ID: p.ID}, nil
+}
+
+// rpcErr maps errors the same way the HTTP handlers map them to status codes.
+func (s *Server) rpcErr(ctx context.Context, method string, err error) *rpcError {
+	var rpcE *rpcError
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &rpcE):
+		return rpcE
+	case errors.As(err, &reqErr):
+		e := &rpcError{Code: rpcInvalidParams, Message: reqErr.message}
+		if len(reqErr.details) > 0 {
+			e.Data = &rpcErrorData{Details: reqErr.details}
+		}
+		return e
+	case errors.Is(err, storage.ErrWrongArgument):
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	case errors.Is(err, storage.ErrTaskNotFound):
+		return &rpcError{Code: rpcTaskNotFound, Message: "Task not found"}
+	case errors.Is(err, errNotOwner):
+		return &rpcError{Code: rpcForbidden, Message: err.Error()}
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return &rpcError{Code: rpcQuotaExceeded, Message: err.Error()}
+	case errors.Is(err, storage.ErrStorageClosed):
+		return &rpcError{Code: rpcUnavailable, Message: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &rpcError{Code: rpcTimeout, Message: "Call timed out"}
+	default:
+		s.logger.ErrorContext(ctx, "rpc call failed", "method", method, "error", err)
+		return &rpcError{Code: rpcInternalError, Message: err.Error()}
+	}
+}
+
+// writeRPC sends a response or a batch of them; JSON-RPC is always JSON, whatever the Accept.
+func (s *Server) writeRPC(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to encode rpc response", "error", err)
+	}
+}
+
+func (s *Server) writeRPCError(w http.ResponseWriter, r *http.Request, status, code int, message string) {
+	s.writeRPC(w, r, status, &rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: code, Message: message}})
+}
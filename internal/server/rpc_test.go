@@ -0,0 +1,326 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+)
+
+func postRPC(handler http.Handler, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRPCMethods(t *testing.T) {
+	handler := setupServer().Handler()
+
+	steps := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "create",
+			body: `{"jsonrpc":"2.0","method":"task.create","params":{"task":{"header":"Buy milk","status":"in_progress"}},"id":1}`,
+			want: `{"jsonrpc":"2.0","result":{"id":0,"header":"Buy milk","description":"","status":"in_progress","owner":"ip:9dff98970af6f08a"},"id":1}`,
+		},
+		{
+			name: "get",
+			body: `{"jsonrpc":"2.0","method":"task.get","params":{"id":0},"id":"a"}`,
+			want: `{"jsonrpc":"2.0","result":{"id":0,"header":"Buy milk","description":"","status":"in_progress","owner":"ip:9dff98970af6f08a"},"id":"a"}`,
+		},
+		{
+			name: "update",
+			body: `{"jsonrpc":"2.0","method":"task.update","params":{"id":0,"task":{"header":"Buy oat milk","description":"2 liters","status":"completed"}},"id":2}`,
+			want: `{"jsonrpc":"2.0","result":{"id":0,"header":"Buy oat milk","description":"2 liters","status":"completed","owner":"ip:9dff98970af6f08a"},"id":2}`,
+		},
+		{
+			name: "null id is answered",
+			body: `{"jsonrpc":"2.0","method":"task.create","params":{"task":{"header":"Call mom"}},"id":null}`,
+			want: `{"jsonrpc":"2.0","result":{"id":1,"header":"Call mom","description":"","status":"assigned","owner":"ip:9dff98970af6f08a"},"id":null}`,
+		},
+		{
+			name: "list page",
+			body: `{"jsonrpc":"2.0","method":"task.list","params":{"limit":1},"id":3}`,
+			want: `{"jsonrpc":"2.0","result":{"tasks":[{"id":0,"header":"Buy oat milk","description":"2 liters","status":"completed","owner":"ip:9dff98970af6f08a"}],"next_after":0},"id":3}`,
+		},
+		{
+			name: "list next page",
+			body: `{"jsonrpc":"2.0","method":"task.list","params":{"limit":1,"after":0},"id":4}`,
+			want: `{"jsonrpc":"2.0","result":{"tasks":[{"id":1,"header":"Call mom","description":"","status":"assigned","owner":"ip:9dff98970af6f08a"}]},"id":4}`,
+		},
+		{
+			name: "list filtered without params",
+			body: `{"jsonrpc":"2.0","method":"task.list","params":{"statuses":["completed"],"query":"LITERS"},"id":5}`,
+			want: `{"jsonrpc":"2.0","result":{"tasks":[{"id":0,"header":"Buy oat milk","description":"2 liters","status":"completed","owner":"ip:9dff98970af6f08a"}]},"id":5}`,
+		},
+		{
+			name: "delete",
+			body: `{"jsonrpc":"2.0","method":"task.delete","params":{"id":1},"id":6}`,
+			want: `{"jsonrpc":"2.0","result":{"id":1},"id":6}`,
+		},
+		{
+			name: "list everything",
+			body: `{"jsonrpc":"2.0","method":"task.list","id":7}`,
+			want: `{"jsonrpc":"2.0","result":{"tasks":[{"id":0,"header":"Buy oat milk","description":"2 liters","status":"completed","owner":"ip:9dff98970af6f08a"}]},"id":7}`,
+		},
+	}
+
+	for _, step := range steps {
+		w := postRPC(handler, "", step.body)
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != step.want {
+			t.Fatalf("%s: expected 200 %s, got %d %s", step.name, step.want, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+			t.Errorf("%s: unexpected Content-Type %s", step.name, got)
+		}
+	}
+}
+
+func TestRPCErrors(t *testing.T) {
+	st := storage.NewStorage()
+	st.CreateTask(context.Background(), storage.Task{Header: "Alice's", Owner: tokenOwner("alice")})
+	handler := setupServerWith(st, WithOwnershipEnforcement()).Handler()
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "parse error",
+			body: `{"jsonrpc":"2.0","method":`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		{
+			name: "wrong version",
+			body: `{"jsonrpc":"1.0","method":"task.get","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		{
+			name: "not an object",
+			body: `42`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		{
+			name: "object id",
+			body: `{"jsonrpc":"2.0","method":"task.get","id":{}}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		{
+			name: "scalar params",
+			body: `{"jsonrpc":"2.0","method":"task.get","params":1,"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":1}`,
+		},
+		{
+			name: "unknown method",
+			body: `{"jsonrpc":"2.0","method":"task.archive","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}`,
+		},
+		{
+			name: "positional params",
+			body: `{"jsonrpc":"2.0","method":"task.get","params":[0],"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Params must be an object of named arguments"},"id":1}`,
+		},
+		{
+			name: "unknown param",
+			body: `{"jsonrpc":"2.0","method":"task.get","params":{"task_id":0},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params: unknown field \"task_id\""},"id":1}`,
+		},
+		{
+			name: "wrong param type",
+			body: `{"jsonrpc":"2.0","method":"task.get","params":{"id":"0"},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params: field \"id\" must be of type int"},"id":1}`,
+		},
+		{
+			name: "missing id",
+			body: `{"jsonrpc":"2.0","method":"task.delete","params":{},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"id is required"},"id":1}`,
+		},
+		{
+			name: "invalid task",
+			body: `{"jsonrpc":"2.0","method":"task.create","params":{"task":{"header":" ","status":"soon"}},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid task","data":{"details":[{"field":"task.status","message":"must be one of assigned, in_progress, completed, dropped"}]}},"id":1}`,
+		},
+		{
+			name: "empty header",
+			body: `{"jsonrpc":"2.0","method":"task.create","params":{"task":{"header":" "}},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid task","data":{"details":[{"field":"task.header","message":"must not be empty"}]}},"id":1}`,
+		},
+		{
+			name: "invalid page",
+			body: `{"jsonrpc":"2.0","method":"task.list","params":{"limit":5000,"after":-2,"statuses":["later"]},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":{"details":[{"field":"statuses","message":"unknown status \"later\""},{"field":"limit","message":"must be between 1 and 1000"},{"field":"after","message":"must be a non-negative task ID"}]}},"id":1}`,
+		},
+		{
+			name: "task not found",
+			body: `{"jsonrpc":"2.0","method":"task.get","params":{"id":42},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32001,"message":"Task not found"},"id":1}`,
+		},
+		{
+			name: "other owner",
+			body: `{"jsonrpc":"2.0","method":"task.delete","params":{"id":0},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32003,"message":"Task belongs to another owner"},"id":1}`,
+		},
+		{
+			name: "empty batch",
+			body: `[]`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		{
+			name: "oversized batch",
+			body: "[" + strings.Repeat(`{"jsonrpc":"2.0","method":"task.list"},`, 100) + `{"jsonrpc":"2.0","method":"task.list"}]`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"A batch must not contain more than 100 calls"},"id":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postRPC(handler, "bob", tt.body)
+			if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != tt.want {
+				t.Errorf("expected 200 %s, got %d %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if _, err := st.GetByID(context.Background(), 0); err != nil {
+		t.Errorf("expected the task to survive, got %v", err)
+	}
+}
+
+func TestRPCBatchAndNotifications(t *testing.T) {
+	st := storage.NewStorage()
+	handler := setupServerWith(st).Handler()
+
+	// Calls run in order, notifications are executed but not answered and failures stay with
+	// their call.
+	w := postRPC(handler, "", `[
+		{"jsonrpc":"2.0","method":"task.create","params":{"task":{"header":"First"}}},
+		{"jsonrpc":"2.0","method":"task.update","params":{"id":0,"task":{"header":"First","status":"completed"}},"id":1},
+		{"jsonrpc":"2.0","method":"task.get","params":{"id":7},"id":2},
+		{"foo":"bar"},
+		{"jsonrpc":"2.0","method":"task.list","params":{"statuses":["completed"]},"id":3}
+	]`)
+	want := `[{"jsonrpc":"2.0","result":{"id":0,"header":"First","description":"","status":"completed","owner":"ip:9dff98970af6f08a"},"id":1},` +
+		`{"jsonrpc":"2.0","error":{"code":-32001,"message":"Task not found"},"id":2},` +
+		`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},` +
+		`{"jsonrpc":"2.0","result":{"tasks":[{"id":0,"header":"First","description":"","status":"completed","owner":"ip:9dff98970af6f08a"}]},"id":3}]`
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != want {
+		t.Fatalf("expected 200 %s, got %d %s", want, w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"notification", `{"jsonrpc":"2.0","method":"task.create","params":{"task":{"header":"Second"}}}`},
+		{"failed notification", `{"jsonrpc":"2.0","method":"task.delete","params":{"id":99}}`},
+		{"unknown method notification", `{"jsonrpc":"2.0","method":"nope"}`},
+		{"batch of notifications", `[{"jsonrpc":"2.0","method":"task.delete","params":{"id":0}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postRPC(handler, "", tt.body)
+			if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+				t.Errorf("expected 204 without a body, got %d %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	tasks, _ := st.GetAll(context.Background())
+	if len(tasks) != 1 || tasks[0].Header != "Second" {
+		t.Errorf("expected the notifications to run, got %+v", tasks)
+	}
+}
+
+func TestRPCTransport(t *testing.T) {
+	handler := setupServer().Handler()
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		want        string
+		allow       string
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Method is not allowed"},"id":null}`,
+			allow:  "POST",
+		},
+		{
+			name:   "options",
+			method: http.MethodOptions,
+			status: http.StatusNoContent,
+			allow:  "POST, OPTIONS",
+		},
+		{
+			name:        "content type",
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        `{"jsonrpc":"2.0","method":"task.list","id":1}`,
+			status:      http.StatusUnsupportedMediaType,
+			want:        `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Content-Type must be application/json"},"id":null}`,
+		},
+		{
+			name:        "too large",
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `"` + strings.Repeat("x", 1<<20) + `"`,
+			status:      http.StatusRequestEntityTooLarge,
+			want:        `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Request body must not exceed 1048576 bytes"},"id":null}`,
+		},
+		{
+			name:        "empty body",
+			method:      http.MethodPost,
+			contentType: "application/json; charset=utf-8",
+			status:      http.StatusOK,
+			want:        `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/rpc", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.status || strings.TrimSpace(w.Body.String()) != tt.want {
+				t.Errorf("expected %d %s, got %d %s", tt.status, tt.want, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("expected Allow %q, got %q", tt.allow, got)
+			}
+		})
+	}
+}
+
+func TestRPCPreflight(t *testing.T) {
+	handler := corsServer([]string{"https://dashboard.example.com"}, false).Handler()
+
+	w := preflight(handler, "/rpc", "https://dashboard.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://dashboard.example.com" {
+		t.Errorf("expected Access-Control-Allow-Origin for the dashboard, got %q", got)
+	}
+	if got := w.Header().Get("Allow"); got != "POST, OPTIONS" {
+		t.Errorf("expected Allow %q, got %q", "POST, OPTIONS", got)
+	}
+}
@@ -2,37 +2,158 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/rand"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"todo/internal/codec"
+	"todo/internal/graphql"
+	"todo/internal/logging"
+	"todo/internal/ratelimit"
 	"todo/internal/storage"
+	"todo/internal/tracing"
+	"todo/internal/validation"
 )
 
 const SecToTimeout = 5
 
+type errorResponse struct {
+	Error     string                  `json:"error"`
+	Details   []validation.FieldError `json:"details,omitempty"`
+	RequestID string                  `json:"request_id,omitempty"`
+}
+
+type TaskStore interface {
+	CreateTask(ctx context.Context, task storage.Task) (*storage.Task, error)
+	Update(ctx context.Context, id int, updated *storage.Task) (*storage.Task, error)
+	Delete(ctx context.Context, id int) error
+	GetAll(ctx context.Context) ([]storage.Task, error)
+	GetByID(ctx context.Context, id int) (storage.Task, error)
+	CountByStatus() map[storage.TaskStatus]int
+	Ping() error
+	Close() error
+}
+
 type Server struct {
-	storage *storage.Storage
-	logger  *log.Logger
+	storage TaskStore
+	logger  *slog.Logger
+	metrics *serverMetrics
+	tracer  *tracing.Tracer
+	workers []*workerState
+
+	limits           validation.Limits
+	codecs           *codec.Registry
+	cors             *CORSConfig
+	limiters         map[string]*ratelimit.Limiter
+	trustedProxyHops int
+	certIdentities   map[string]string
+	ownerKey         []byte
+	enforceOwnership bool
+	watcher          TaskWatcher
+	graphql          *graphql.Schema
+
+	shuttingDown atomic.Bool
+	// streamsDone is closed on shutdown to end long-lived calls, which Shutdown does not wait out.
+	streamsDone chan struct{}
+	endStreams  func()
 }
 
-func NewServer(storage *storage.Storage, logger *log.Logger) *Server {
-	return &Server{
-		storage: storage,
+type Option func(*Server)
+
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(s *Server) {
+		s.tracer = tracer
+	}
+}
+
+func NewServer(storage TaskStore, logger *slog.Logger, opts ...Option) *Server {
+	s := &Server{
 		logger:  logger,
+		metrics: newServerMetrics(storage),
+		tracer:  tracing.NewTracer(tracing.TracerOptions{}),
+		limits:  validation.DefaultLimits(),
+		codecs:  codec.Default(),
+
+		streamsDone: make(chan struct{}),
+	}
+	s.endStreams = sync.OnceFunc(func() { close(s.streamsDone) })
+	if watcher, ok := storage.(TaskWatcher); ok {
+		s.watcher = watcher
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.ownerKey == nil {
+		s.ownerKey = make([]byte, 32)
+		_, _ = rand.Read(s.ownerKey)
+	}
+
+	s.storage = &instrumentedStore{next: storage, metrics: s.metrics, tracer: s.tracer, logger: logger}
+	s.graphql = s.graphqlSchema()
+	return s
+}
+
+// route binds a ServeMux pattern to its handler; path is the same route in OpenAPI notation,
+// empty for routes that are not part of the API description.
+type route struct {
+	pattern string
+	path    string
+	handler http.Handler
+}
+
+func (s *Server) routes() []route {
+	routes := []route{
+		{"/healthz", "/healthz", s.NegotiateMiddleware(s.HandleHealthz)},
+		{"/readyz", "/readyz", s.NegotiateMiddleware(s.HandleReadyz)},
+		{"/version", "/version", s.NegotiateMiddleware(s.HandleVersion)},
+		{"/metrics", "/metrics", s.metrics.registry.Handler()},
+		{"/openapi.json", "/openapi.json", http.HandlerFunc(s.HandleOpenAPI)},
+		{"/docs", "/docs", http.HandlerFunc(s.HandleDocs)},
 	}
+	for _, v := range apiVersions() {
+		routes = append(routes, s.versionRoutes(v)...)
+	}
+	return append(routes,
+		route{"/graphql", "", s.api("/graphql", s.HandleGraphQL)},
+		route{"/graphql/schema.graphql", "", s.api("/graphql", s.HandleGraphQLSchema)},
+		route{"/ws", "", s.api("/ws", s.HandleWS)},
+		route{"/rpc", "/rpc", s.api("/rpc", s.HandleRPC)},
+		route{"/ui/", "", s.api("/ui", s.webHandler().ServeHTTP)},
+		route{"/{$}", "", http.RedirectHandler("/ui/", http.StatusFound)},
+	)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		mux.Handle(rt.pattern, rt.handler)
+	}
+	return mux
+}
+
+func (s *Server) api(route string, next http.HandlerFunc) http.HandlerFunc {
+	return s.versionedAPI(apiVersion{}, route, next)
 }
 
-func (s *Server) LoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		s.logger.Printf("%s %s started", r.Method, r.URL.Path)
-		next(w, r)
-		s.logger.Printf("%s %s completed in %v", r.Method, r.URL.Path, time.Since(start))
+// versionedAPI serves route of version v. Versions of a route share its rate limits, so moving
+// to another prefix does not reset a client's budget, while metrics and traces tell them apart.
+func (s *Server) versionedAPI(v apiVersion, route string, next http.HandlerFunc) http.HandlerFunc {
+	if v.taskType != nil {
+		next = v.middleware(next)
 	}
+	next = s.RateLimitMiddleware(route, next)
+	next = s.LoggingMiddleware(next)
+	next = s.MetricsMiddleware(v.prefix()+route, next)
+	next = s.TracingMiddleware(v.prefix()+route, next)
+	next = s.IdentityMiddleware(next)
+	next = s.CORSMiddleware(next)
+	return s.RequestIDMiddleware(next)
 }
 
 func (s *Server) HandleTodos(w http.ResponseWriter, r *http.Request) {
@@ -45,9 +166,11 @@ func (s *Server) HandleTodos(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		s.createTodo(w, r)
 	case http.MethodGet:
-		s.getAllTodos(w)
+		s.getAllTodos(w, r)
+	case http.MethodOptions:
+		s.handleOptions(w, http.MethodGet, http.MethodPost)
 	default:
-		http.Error(w, "Method is not allowed", http.StatusMethodNotAllowed)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
 	}
 }
 
@@ -57,21 +180,26 @@ func (s *Server) HandleTodoByID(w http.ResponseWriter, r *http.Request) {
 
 	r = r.WithContext(ctx)
 
-	id, err := s.extractID(r.URL.Path)
+	if r.Method == http.MethodOptions {
+		s.handleOptions(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+		return
+	}
+
+	id, err := s.extractID(strings.TrimPrefix(r.URL.Path, versionOf(r).prefix()))
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, "Invalid ID")
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
-		s.getTodoByID(w, id)
+		s.getTodoByID(w, r, id)
 	case http.MethodPut:
 		s.updateTodo(w, r, id)
 	case http.MethodDelete:
-		s.deleteTodo(w, id)
+		s.deleteTodo(w, r, id)
 	default:
-		http.Error(w, "Method is not allowed", http.StatusMethodNotAllowed)
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
 	}
 }
 
@@ -84,110 +212,169 @@ func (s *Server) extractID(path string) (int, error) {
 }
 
 func (s *Server) createTodo(w http.ResponseWriter, r *http.Request) {
-	var task storage.Task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	task, ok := s.decodeTask(w, r)
+	if !ok {
 		return
 	}
 
-	created, err := s.storage.CreateTask(task)
+	task.Owner = s.owner(r.Context())
+	created, err := s.storage.CreateTask(r.Context(), task)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrWrongArgument):
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, storage.ErrQuotaExceeded):
+			s.writeError(w, r, http.StatusForbidden, err.Error())
+			return
+		case errors.Is(err, storage.ErrStorageClosed):
+			s.writeError(w, r, http.StatusServiceUnavailable, err.Error())
 			return
 		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			s.writeError(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	s.writeResponse(w, r, http.StatusCreated, versionOf(r).encode(*created))
+}
 
-	err = json.NewEncoder(w).Encode(created)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func (s *Server) getAllTodos(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, reqErr := parsePage(query)
+	if reqErr != nil {
+		s.writeRequestError(w, r, reqErr)
+		return
+	}
+	filter, reqErr := parseFilter(query)
+	if reqErr != nil {
+		s.writeRequestError(w, r, reqErr)
 		return
 	}
-}
-
-func (s *Server) getAllTodos(w http.ResponseWriter) {
-	tasksGot := s.storage.GetAll()
 
-	w.Header().Set("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(tasksGot)
+	tasksGot, err := s.findTasks(r.Context(), filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+
+	tasksGot, next := page.apply(tasksGot)
+	if next != "" {
+		// The next page keeps the filter of this one.
+		nextQuery, _ := url.ParseQuery(next)
+		for _, key := range filterParams {
+			if values, ok := query[key]; ok {
+				nextQuery[key] = values
+			}
+		}
+		w.Header().Set("Link", "<"+r.URL.Path+"?"+nextQuery.Encode()+">; rel=\"next\"")
+	}
+
+	s.writeResponse(w, r, http.StatusOK, versionOf(r).encodeList(tasksGot))
 }
 
-func (s *Server) getTodoByID(w http.ResponseWriter, id int) {
-	task, err := s.storage.GetByID(id)
+func (s *Server) getTodoByID(w http.ResponseWriter, r *http.Request, id int) {
+	task, err := s.storage.GetByID(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrTaskNotFound):
-			http.Error(w, "Task not found", http.StatusNotFound)
+			s.writeError(w, r, http.StatusNotFound, "Task not found")
 			return
 		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			s.writeError(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(task)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	s.writeResponse(w, r, http.StatusOK, versionOf(r).encode(task))
 }
 
 func (s *Server) updateTodo(w http.ResponseWriter, r *http.Request, id int) {
-	var task storage.Task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	task, ok := s.decodeTask(w, r)
+	if !ok {
+		return
+	}
+
+	if !s.authorizeTask(w, r, id) {
 		return
 	}
 
-	updated, err := s.storage.Update(id, &task)
+	updated, err := s.storage.Update(r.Context(), id, &task)
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrWrongArgument):
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			s.writeError(w, r, http.StatusBadRequest, err.Error())
 			return
 		case errors.Is(err, storage.ErrTaskNotFound):
-			http.Error(w, "Task not found", http.StatusNotFound)
+			s.writeError(w, r, http.StatusNotFound, "Task not found")
+			return
+		case errors.Is(err, storage.ErrStorageClosed):
+			s.writeError(w, r, http.StatusServiceUnavailable, err.Error())
 			return
 		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			s.writeError(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(updated)
+	s.writeResponse(w, r, http.StatusOK, versionOf(r).encode(*updated))
+}
 
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func (s *Server) deleteTodo(w http.ResponseWriter, r *http.Request, id int) {
+	if !s.authorizeTask(w, r, id) {
 		return
 	}
-}
 
-func (s *Server) deleteTodo(w http.ResponseWriter, id int) {
-	err := s.storage.Delete(id)
+	err := s.storage.Delete(r.Context(), id)
 
 	if err != nil {
 		switch {
 		case errors.Is(err, storage.ErrTaskNotFound):
-			http.Error(w, "Task not found", http.StatusNotFound)
+			s.writeError(w, r, http.StatusNotFound, "Task not found")
+			return
+		case errors.Is(err, storage.ErrStorageClosed):
+			s.writeError(w, r, http.StatusServiceUnavailable, err.Error())
 			return
 		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			s.writeError(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (s *Server) authorizeTask(w http.ResponseWriter, r *http.Request, id int) bool {
+	if !s.enforceOwnership {
+		return true
+	}
+
+	task, err := s.storage.GetByID(r.Context(), id)
+	if err != nil {
+		// Let the operation itself report missing tasks and storage failures.
+		return true
+	}
+	if !s.authorize(r, task.Owner) {
+		s.writeError(w, r, http.StatusForbidden, "Task belongs to another owner")
+		return false
+	}
+	return true
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	s.writeErrorResponse(w, r, code, errorResponse{Error: message})
+}
+
+func (s *Server) writeRequestError(w http.ResponseWriter, r *http.Request, err *requestError) {
+	s.writeErrorResponse(w, r, err.status, errorResponse{Error: err.message, Details: err.details})
+}
+
+func (s *Server) writeErrorResponse(w http.ResponseWriter, r *http.Request, code int, resp errorResponse) {
+	resp.RequestID = logging.RequestID(r.Context())
+	if code >= http.StatusInternalServerError {
+		s.logger.ErrorContext(r.Context(), "request failed", "status", code, "error", resp.Error)
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	s.writeResponse(w, r, code, resp)
+}
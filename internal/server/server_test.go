@@ -2,20 +2,21 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strconv"
 	"testing"
 	"todo/internal/storage"
 )
 
+// testOwnerKey makes anonymous owners stable: 192.0.2.1, the httptest client address, is
+// recorded as "ip:9dff98970af6f08a".
+var testOwnerKey = []byte("test")
+
 func setupServer() *Server {
-	st := storage.NewStorage()
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	return NewServer(st, logger)
+	return setupServerWith(storage.NewStorage())
 }
 
 func TestCreateTodo(t *testing.T) {
@@ -61,7 +62,7 @@ func TestGetAllTodos(t *testing.T) {
 	server := setupServer()
 
 	task := storage.Task{Header: "Test Task", Description: "Test Description", Status: storage.Assigned}
-	_, _ = server.storage.CreateTask(task)
+	_, _ = server.storage.CreateTask(context.Background(), task)
 
 	req := httptest.NewRequest(http.MethodGet, "/todos", nil)
 	w := httptest.NewRecorder()
@@ -86,7 +87,7 @@ func TestGetTodoByID(t *testing.T) {
 	server := setupServer()
 
 	task := storage.Task{Header: "Test Task", Description: "Test Description", Status: storage.Assigned}
-	created, _ := server.storage.CreateTask(task)
+	created, _ := server.storage.CreateTask(context.Background(), task)
 
 	tests := []struct {
 		name           string
@@ -123,7 +124,7 @@ func TestUpdateTodo(t *testing.T) {
 	server := setupServer()
 
 	task := storage.Task{Header: "Original Task", Description: "Original Description", Status: storage.Assigned}
-	created, _ := server.storage.CreateTask(task)
+	created, _ := server.storage.CreateTask(context.Background(), task)
 
 	tests := []struct {
 		name           string
@@ -169,7 +170,7 @@ func TestUpdateTodoStatus(t *testing.T) {
 	server := setupServer()
 
 	task := storage.Task{Header: "Test Task", Description: "Test", Status: storage.Assigned}
-	created, _ := server.storage.CreateTask(task)
+	created, _ := server.storage.CreateTask(context.Background(), task)
 
 	tests := []struct {
 		name           string
@@ -210,7 +211,7 @@ func TestDeleteTodo(t *testing.T) {
 	server := setupServer()
 
 	task := storage.Task{Header: "Test Task", Description: "Test Description", Status: storage.Assigned}
-	created, _ := server.storage.CreateTask(task)
+	created, _ := server.storage.CreateTask(context.Background(), task)
 
 	tests := []struct {
 		name           string
@@ -297,7 +298,7 @@ func TestGetAllTodosWithDifferentStatuses(t *testing.T) {
 	}
 
 	for _, task := range testTasks {
-		_, _ = server.storage.CreateTask(task)
+		_, _ = server.storage.CreateTask(context.Background(), task)
 	}
 
 	req := httptest.NewRequest(http.MethodGet, "/todos", nil)
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"todo/internal/storage"
+	"todo/internal/tracing"
+)
+
+// instrumentedStore records metrics, a tracing span and a debug log line for every storage call.
+type instrumentedStore struct {
+	next    TaskStore
+	metrics *serverMetrics
+	tracer  *tracing.Tracer
+	logger  *slog.Logger
+}
+
+func (st *instrumentedStore) observe(ctx context.Context, operation string) (context.Context, func(err error)) {
+	start := time.Now()
+	inFlight := st.metrics.storageInFlight.WithLabelValues(operation)
+	inFlight.Inc()
+	ctx, span := st.tracer.Start(ctx, "storage."+operation, tracing.KindInternal,
+		tracing.String("db.operation", operation))
+	return ctx, func(err error) {
+		inFlight.Dec()
+		span.RecordError(err)
+		span.End()
+		elapsed := time.Since(start)
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		st.metrics.storageDuration.WithLabelValues(operation, result).Observe(elapsed.Seconds())
+		st.logger.DebugContext(ctx, "storage operation", "operation", operation, "duration", elapsed, "error", err)
+	}
+}
+
+func (st *instrumentedStore) CreateTask(ctx context.Context, task storage.Task) (*storage.Task, error) {
+	ctx, done := st.observe(ctx, "create")
+	created, err := st.next.CreateTask(ctx, task)
+	done(err)
+	return created, err
+}
+
+func (st *instrumentedStore) Update(ctx context.Context, id int, updated *storage.Task) (*storage.Task, error) {
+	ctx, done := st.observe(ctx, "update")
+	task, err := st.next.Update(ctx, id, updated)
+	done(err)
+	return task, err
+}
+
+func (st *instrumentedStore) Delete(ctx context.Context, id int) error {
+	ctx, done := st.observe(ctx, "delete")
+	err := st.next.Delete(ctx, id)
+	done(err)
+	return err
+}
+
+func (st *instrumentedStore) GetAll(ctx context.Context) ([]storage.Task, error) {
+	ctx, done := st.observe(ctx, "get_all")
+	tasks, err := st.next.GetAll(ctx)
+	done(err)
+	return tasks, err
+}
+
+func (st *instrumentedStore) GetByID(ctx context.Context, id int) (storage.Task, error) {
+	ctx, done := st.observe(ctx, "get_by_id")
+	task, err := st.next.GetByID(ctx, id)
+	done(err)
+	return task, err
+}
+
+func (st *instrumentedStore) Find(ctx context.Context, filter storage.Filter) ([]storage.Task, error) {
+	ctx, done := st.observe(ctx, "find")
+	var tasks []storage.Task
+	var err error
+	if finder, ok := st.next.(TaskFinder); ok {
+		tasks, err = finder.Find(ctx, filter)
+	} else {
+		tasks, err = findAll(ctx, st.next, filter)
+	}
+	done(err)
+	return tasks, err
+}
+
+func (st *instrumentedStore) CountByStatus() map[storage.TaskStatus]int {
+	return st.next.CountByStatus()
+}
+
+func (st *instrumentedStore) Ping() error {
+	return st.next.Ping()
+}
+
+func (st *instrumentedStore) Close() error {
+	return st.next.Close()
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+	"todo/internal/certs"
+)
+
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+type TLSConfig struct {
+	CertFile       string
+	KeyFile        string
+	ClientCAFile   string
+	ClientAuth     string
+	ReloadInterval time.Duration
+}
+
+func (s *Server) buildTLS(cfg *TLSConfig) (*tls.Config, *certs.Reloader, error) {
+	reloader, err := certs.NewReloader(cfg.CertFile, cfg.KeyFile, cfg.ReloadInterval, func(err error) {
+		if err != nil {
+			s.logger.Error("certificate reload failed", "error", err)
+			return
+		}
+		s.logger.Info("certificate reloaded", "cert", cfg.CertFile)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	switch cfg.ClientAuth {
+	case "", ClientAuthNone:
+		return tlsConfig, reloader, nil
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, nil, fmt.Errorf("unknown client auth mode %q", cfg.ClientAuth)
+	}
+
+	if cfg.ClientCAFile == "" {
+		return nil, nil, fmt.Errorf("client auth mode %q requires a client CA file", cfg.ClientAuth)
+	}
+	pool, err := certs.LoadCertPool(cfg.ClientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig.ClientCAs = pool
+
+	return tlsConfig, reloader, nil
+}
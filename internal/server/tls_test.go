@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo/internal/storage"
+)
+
+type testPKI struct {
+	dir    string
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	caFile string
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	pki := &testPKI{dir: t.TempDir(), caCert: cert, caKey: key}
+	pki.caFile = filepath.Join(pki.dir, "ca.crt")
+	_ = os.WriteFile(pki.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	return pki
+}
+
+func (p *testPKI) issue(t *testing.T, name pkix.Name, usage x509.ExtKeyUsage) (tls.Certificate, string, string) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      name,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.caCert, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatalf("failed to issue certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	certFile := filepath.Join(p.dir, name.CommonName+".crt")
+	keyFile := filepath.Join(p.dir, name.CommonName+".key")
+	_ = os.WriteFile(certFile, certPEM, 0o600)
+	_ = os.WriteFile(keyFile, keyPEM, 0o600)
+
+	pair, _ := tls.X509KeyPair(certPEM, keyPEM)
+	return pair, certFile, keyFile
+}
+
+func (p *testPKI) client(clientCert *tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(p.caCert)
+	cfg := &tls.Config{RootCAs: pool}
+	if clientCert != nil {
+		cfg.Certificates = []tls.Certificate{*clientCert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true}}
+}
+
+func startTLSServer(t *testing.T, server *Server, tlsCfg *TLSConfig) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.TLS = tlsCfg
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, ln, cfg) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	})
+	return "https://" + ln.Addr().String()
+}
+
+func TestMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	_, serverCert, serverKey := pki.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+	aliceCert, _, _ := pki.issue(t, pkix.Name{CommonName: "alice", Organization: []string{"Example"}}, x509.ExtKeyUsageClientAuth)
+	bobCert, _, _ := pki.issue(t, pkix.Name{CommonName: "bob"}, x509.ExtKeyUsageClientAuth)
+
+	server := setupServerWith(storage.NewStorage(),
+		WithClientCertIdentities(map[string]string{"CN=alice,O=Example": "alice@example.com"}),
+		WithOwnershipEnforcement(),
+	)
+	url := startTLSServer(t, server, &TLSConfig{
+		CertFile:     serverCert,
+		KeyFile:      serverKey,
+		ClientCAFile: pki.caFile,
+		ClientAuth:   ClientAuthRequire,
+	})
+
+	alice := pki.client(&aliceCert)
+	resp, err := alice.Post(url+"/todos", "application/json", bytes.NewBufferString(`{"Header":"Secure task"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2, got %s", resp.Proto)
+	}
+
+	var created storage.Task
+	_ = json.NewDecoder(resp.Body).Decode(&created)
+	if created.Owner != "cert:alice@example.com" {
+		t.Errorf("expected owner 'cert:alice@example.com', got %q", created.Owner)
+	}
+
+	bob := pki.client(&bobCert)
+	req, _ := http.NewRequest(http.MethodDelete, url+"/todos/0", nil)
+	resp, err = bob.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status %d for another owner, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	if _, err := pki.client(nil).Get(url + "/todos"); err == nil {
+		t.Error("expected handshake to fail without a client certificate")
+	}
+}
+
+func TestTLSWithoutClientAuth(t *testing.T) {
+	pki := newTestPKI(t)
+	_, serverCert, serverKey := pki.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+
+	url := startTLSServer(t, setupServer(), &TLSConfig{CertFile: serverCert, KeyFile: serverKey})
+
+	resp, err := pki.client(nil).Get(url + "/healthz")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	pki := newTestPKI(t)
+	_, serverCert, serverKey := pki.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+
+	tests := []struct {
+		name string
+		cfg  TLSConfig
+	}{
+		{"missing certificate", TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key"}},
+		{"unknown client auth", TLSConfig{CertFile: serverCert, KeyFile: serverKey, ClientAuth: "sometimes"}},
+		{"client auth without CA", TLSConfig{CertFile: serverCert, KeyFile: serverKey, ClientAuth: ClientAuthRequire}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := setupServer().buildTLS(&tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestOwnershipEnforcement(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(), WithOwnershipEnforcement())
+	handler := server.Handler()
+
+	if w := postTodo(handler, "10.0.0.1:1", "alice"); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		expectedStatus int
+	}{
+		{"other owner cannot update", http.MethodPut, "bob", http.StatusForbidden},
+		{"other owner cannot delete", http.MethodDelete, "bob", http.StatusForbidden},
+		{"owner can update", http.MethodPut, "alice", http.StatusOK},
+		{"owner can delete", http.MethodDelete, "alice", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/todos/0", bytes.NewBufferString(`{"Header":"Renamed"}`))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"todo/internal/storage"
+	"todo/internal/taskio"
+	"todo/internal/validation"
+)
+
+// Import modes; importCreate and importUpdate are also the per-row actions.
+const (
+	importCreate = "create"
+	importUpdate = "update"
+	importUpsert = "upsert"
+)
+
+type taskFormat struct {
+	contentType string
+	extension   string
+	encode      func(w io.Writer, tasks []storage.Task) error
+	decode      func(r io.Reader, query url.Values) ([]taskio.Record, []string, error)
+}
+
+func taskFormats() map[string]taskFormat {
+	return map[string]taskFormat{
+		"csv": {
+			contentType: "text/csv; charset=utf-8",
+			extension:   "csv",
+			encode:      taskio.WriteCSV,
+			decode:      decodeCSV,
+		},
+		"todotxt": {
+			contentType: "text/plain; charset=utf-8",
+			extension:   "txt",
+			encode:      taskio.WriteTodoTxt,
+			decode: func(r io.Reader, _ url.Values) ([]taskio.Record, []string, error) {
+				records, err := taskio.ReadTodoTxt(r)
+				return records, nil, err
+			},
+		},
+		"markdown": {
+			contentType: "text/markdown; charset=utf-8",
+			extension:   "md",
+			encode:      taskio.WriteMarkdown,
+			decode: func(r io.Reader, _ url.Values) ([]taskio.Record, []string, error) {
+				records, err := taskio.ReadMarkdown(r)
+				return records, nil, err
+			},
+		},
+		"ical": {
+			contentType: "text/calendar; charset=utf-8",
+			extension:   "ics",
+			encode: func(w io.Writer, tasks []storage.Task) error {
+				return taskio.ICalWriter{Name: "Tasks", Stamp: time.Now()}.Write(w, tasks)
+			},
+			decode: func(r io.Reader, _ url.Values) ([]taskio.Record, []string, error) {
+				records, err := taskio.ReadICal(r)
+				return records, nil, err
+			},
+		},
+	}
+}
+
+// decodeCSV reads "map" query parameters of the form "Column name=Field" as explicit column mappings.
+func decodeCSV(r io.Reader, query url.Values) ([]taskio.Record, []string, error) {
+	columns := make(map[string]string)
+	for _, m := range query["map"] {
+		column, field, ok := strings.Cut(m, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid column mapping %q, expected COLUMN=FIELD", m)
+		}
+		columns[column] = strings.TrimSpace(field)
+	}
+	return taskio.CSVReader{Columns: columns}.Read(r)
+}
+
+func formatNames() []string {
+	names := make([]string, 0, len(taskFormats()))
+	for name := range taskFormats() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupFormat takes the format from the "format" query parameter, then from the media type
+// of an uploaded body, and defaults to CSV.
+func (s *Server) lookupFormat(w http.ResponseWriter, r *http.Request) (taskFormat, bool) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = formatByContentType(r.Header.Get("Content-Type"))
+	}
+	f, ok := taskFormats()[name]
+	if !ok {
+		s.writeErrorResponse(w, r, http.StatusBadRequest, errorResponse{
+			Error:   "Unsupported format",
+			Details: []validation.FieldError{{Field: "format", Message: "must be one of " + strings.Join(formatNames(), ", ")}},
+		})
+	}
+	return f, ok
+}
+
+func formatByContentType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		for name, f := range taskFormats() {
+			if t, _, _ := mime.ParseMediaType(f.contentType); t == mediaType {
+				return name
+			}
+		}
+	}
+	return "csv"
+}
+
+func (s *Server) HandleExport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), SecToTimeout*time.Second)
+	defer cancel()
+
+	r = r.WithContext(ctx)
+
+	switch r.Method {
+	case http.MethodGet:
+		if format, ok := s.lookupFormat(w, r); ok {
+			s.exportTodos(w, r, format, nil)
+		}
+	case http.MethodOptions:
+		s.handleOptions(w, http.MethodGet)
+	default:
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+	}
+}
+
+func (s *Server) HandleImport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), SecToTimeout*time.Second)
+	defer cancel()
+
+	r = r.WithContext(ctx)
+
+	switch r.Method {
+	case http.MethodPost:
+		if format, ok := s.lookupFormat(w, r); ok {
+			s.importTodos(w, r, format, importCreate)
+		}
+	case http.MethodOptions:
+		s.handleOptions(w, http.MethodPost)
+	default:
+		s.writeError(w, r, http.StatusMethodNotAllowed, "Method is not allowed")
+	}
+}
+
+// exportTodos writes every task accepted by keep, or all tasks when keep is nil.
+func (s *Server) exportTodos(w http.ResponseWriter, r *http.Request, format taskFormat, keep func(storage.Task) bool) {
+	all, err := s.storage.GetAll(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tasks := all[:0]
+	for _, task := range all {
+		if keep == nil || keep(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	sortByID(tasks)
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.`+format.extension+`"`)
+	if err := format.encode(w, tasks); err != nil {
+		// The status line is already sent; the client sees a truncated file.
+		s.logger.ErrorContext(r.Context(), "export failed", "error", err)
+	}
+}
+
+type importRow struct {
+	Line   int                     `json:"line"`
+	Action string                  `json:"action"`
+	TaskID *int                    `json:"task_id,omitempty"`
+	Errors []validation.FieldError `json:"errors,omitempty"`
+}
+
+type importReport struct {
+	DryRun         bool        `json:"dry_run"`
+	Mode           string      `json:"mode"`
+	Valid          bool        `json:"valid"`
+	Created        int         `json:"created"`
+	Updated        int         `json:"updated"`
+	Failed         int         `json:"failed"`
+	IgnoredColumns []string    `json:"ignored_columns,omitempty"`
+	Rows           []importRow `json:"rows"`
+}
+
+// importTodos validates every row before changing anything: a file with invalid rows is
+// rejected as a whole with 422, or reported with 200 in dry-run mode. Rows that fail while
+// being applied, for example on the owner quota, are reported without undoing the others.
+func (s *Server) importTodos(w http.ResponseWriter, r *http.Request, format taskFormat, defaultMode string) {
+	query := r.URL.Query()
+	report := importReport{Mode: query.Get("mode"), Valid: true, Rows: []importRow{}}
+	var details []validation.FieldError
+	switch report.Mode {
+	case "":
+		report.Mode = defaultMode
+	case importCreate, importUpsert:
+	default:
+		details = append(details, validation.FieldError{Field: "mode", Message: "must be create or upsert"})
+	}
+	if v := query.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			details = append(details, validation.FieldError{Field: "dry_run", Message: "must be true or false"})
+		}
+		report.DryRun = dryRun
+	}
+	if len(details) > 0 {
+		s.writeRequestError(w, r, &requestError{status: http.StatusBadRequest, message: "Invalid query parameters", details: details})
+		return
+	}
+
+	records, ignored, err := format.decode(http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes), query)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.writeError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit))
+			return
+		}
+		s.writeError(w, r, http.StatusBadRequest, "Invalid import file: "+err.Error())
+		return
+	}
+	report.IgnoredColumns = ignored
+
+	tasks := make([]storage.Task, len(records))
+	for i, rec := range records {
+		row, task := s.planImportRow(r, report.Mode, rec)
+		if len(row.Errors) > 0 {
+			report.Valid = false
+		}
+		report.Rows = append(report.Rows, row)
+		tasks[i] = task
+	}
+
+	if !report.Valid || report.DryRun {
+		code := http.StatusOK
+		if !report.Valid && !report.DryRun {
+			code = http.StatusUnprocessableEntity
+		}
+		for _, row := range report.Rows {
+			report.count(row)
+		}
+		s.writeResponse(w, r, code, report)
+		return
+	}
+
+	for i := range report.Rows {
+		s.applyImportRow(r, &report.Rows[i], tasks[i])
+		report.count(report.Rows[i])
+	}
+	s.writeResponse(w, r, http.StatusOK, report)
+}
+
+func (rep *importReport) count(row importRow) {
+	switch {
+	case len(row.Errors) > 0:
+		rep.Failed++
+	case row.Action == importCreate:
+		rep.Created++
+	default:
+		rep.Updated++
+	}
+}
+
+func (s *Server) planImportRow(r *http.Request, mode string, rec taskio.Record) (importRow, storage.Task) {
+	row := importRow{Line: rec.Line, Action: importCreate, Errors: rec.Errors}
+	task := rec.Task
+
+	if err := s.limits.Task(&task); err != nil {
+		var verr *validation.Error
+		if errors.As(err, &verr) {
+			row.Errors = append(row.Errors, verr.Fields...)
+		}
+	}
+
+	if mode != importUpsert || !rec.HasID {
+		return row, task
+	}
+	existing, err := s.storage.GetByID(r.Context(), task.TaskID)
+	switch {
+	case err == nil:
+		row.Action = importUpdate
+		row.TaskID = &existing.TaskID
+		if !s.authorize(r, existing.Owner) {
+			row.Errors = append(row.Errors, validation.FieldError{Field: "TaskID", Message: "task belongs to another owner"})
+		}
+	case errors.Is(err, storage.ErrTaskNotFound):
+	default:
+		row.Errors = append(row.Errors, validation.FieldError{Field: "TaskID", Message: err.Error()})
+	}
+	return row, task
+}
+
+func (s *Server) applyImportRow(r *http.Request, row *importRow, task storage.Task) {
+	var saved *storage.Task
+	var err error
+	if row.Action == importUpdate {
+		saved, err = s.storage.Update(r.Context(), task.TaskID, &task)
+	} else {
+		task.Owner = s.owner(r.Context())
+		saved, err = s.storage.CreateTask(r.Context(), task)
+	}
+	if err != nil {
+		row.Errors = append(row.Errors, validation.FieldError{Message: err.Error()})
+		return
+	}
+	row.TaskID = &saved.TaskID
+}
@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+func postImport(handler http.Handler, query, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/todos/import?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	req.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func decodeReport(t *testing.T, w *httptest.ResponseRecorder) importReport {
+	t.Helper()
+
+	var report importReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	return report
+}
+
+func TestExportCSV(t *testing.T) {
+	server := setupServer()
+	ctx := context.Background()
+	for _, header := range []string{"First", "Second", "Third"} {
+		_, _ = server.storage.CreateTask(ctx, storage.Task{Header: header, Status: storage.InProgress})
+	}
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos/export?format=csv", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected CSV, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="todos.csv"` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || strings.Join(rows[0], ",") != "TaskID,Header,Description,Status,Owner" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+	for i, row := range rows[1:] {
+		if row[1] != []string{"First", "Second", "Third"}[i] || row[3] != "InProgress" {
+			t.Errorf("unexpected row %v", row)
+		}
+	}
+}
+
+func TestExportUnsupportedFormat(t *testing.T) {
+	w := httptest.NewRecorder()
+	setupServer().Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos/export?format=xlsx", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestImportCreate(t *testing.T) {
+	server := setupServer()
+	_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Existing"})
+
+	body := "TaskID,Title,Notes,Status,Priority\n0,Buy milk,At the store,completed,high\n,Write report,,,low\n"
+	w := postImport(server.Handler(), "format=csv", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	report := decodeReport(t, w)
+	if report.Created != 2 || report.Updated != 0 || report.Failed != 0 || !report.Valid {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(report.IgnoredColumns) != 1 || report.IgnoredColumns[0] != "Priority" {
+		t.Errorf("expected Priority to be ignored, got %v", report.IgnoredColumns)
+	}
+
+	tasks, _ := server.storage.GetAll(context.Background())
+	if len(tasks) != 3 {
+		t.Fatalf("expected create mode to add new tasks, got %d tasks", len(tasks))
+	}
+	created, _ := server.storage.GetByID(context.Background(), *report.Rows[0].TaskID)
+	if created.Header != "Buy milk" || created.Status != storage.Completed || created.Owner != "ip:9dff98970af6f08a" {
+		t.Errorf("unexpected created task %+v", created)
+	}
+}
+
+func TestImportUpsert(t *testing.T) {
+	server := setupServer()
+	_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Old", Owner: "ip:9dff98970af6f08a"})
+
+	body := "TaskID,Header,Status\n0,Renamed,dropped\n42,New task,\n"
+	report := decodeReport(t, postImport(server.Handler(), "mode=upsert", body))
+
+	if report.Updated != 1 || report.Created != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.Rows[0].Action != importUpdate || report.Rows[1].Action != importCreate {
+		t.Errorf("unexpected actions %+v", report.Rows)
+	}
+
+	updated, _ := server.storage.GetByID(context.Background(), 0)
+	if updated.Header != "Renamed" || updated.Status != storage.Dropped {
+		t.Errorf("unexpected updated task %+v", updated)
+	}
+}
+
+func TestImportValidation(t *testing.T) {
+	body := "Header,Status\nGood,assigned\n,done\nAlso good,\n"
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+	}{
+		{"rejected as a whole", "", http.StatusUnprocessableEntity},
+		{"dry run", "dry_run=true", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupServer()
+			w := postImport(server.Handler(), tt.query, body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+
+			report := decodeReport(t, w)
+			if report.Valid || report.Failed != 1 || report.Created != 2 {
+				t.Errorf("unexpected report %+v", report)
+			}
+			bad := report.Rows[1]
+			wantErrors := []validation.FieldError{
+				{Field: "Status", Message: "must be Assigned, InProgress, Completed or Dropped"},
+				{Field: "Header", Message: "must not be empty"},
+			}
+			if bad.Line != 3 || len(bad.Errors) != 2 || bad.Errors[0] != wantErrors[0] || bad.Errors[1] != wantErrors[1] {
+				t.Errorf("unexpected row report %+v", bad)
+			}
+
+			if tasks, _ := server.storage.GetAll(context.Background()); len(tasks) != 0 {
+				t.Errorf("expected nothing to be imported, got %d tasks", len(tasks))
+			}
+		})
+	}
+}
+
+func TestImportDryRunValid(t *testing.T) {
+	server := setupServer()
+	report := decodeReport(t, postImport(server.Handler(), "dry_run=1", "Header\nOne\nTwo\n"))
+
+	if !report.DryRun || !report.Valid || report.Created != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if tasks, _ := server.storage.GetAll(context.Background()); len(tasks) != 0 {
+		t.Errorf("expected a dry run to change nothing, got %d tasks", len(tasks))
+	}
+}
+
+func TestImportQuota(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(storage.WithMaxTasksPerOwner(1)))
+	report := decodeReport(t, postImport(server.Handler(), "", "Header\nOne\nTwo\n"))
+
+	if report.Created != 1 || report.Failed != 1 || len(report.Rows[1].Errors) != 1 {
+		t.Errorf("expected the second row to hit the quota, got %+v", report)
+	}
+}
+
+func TestImportOwnership(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(), WithOwnershipEnforcement())
+	_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Theirs", Owner: "token:someone"})
+
+	w := postImport(server.Handler(), "mode=upsert", "TaskID,Header\n0,Mine now\n")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+	if task, _ := server.storage.GetByID(context.Background(), 0); task.Header != "Theirs" {
+		t.Errorf("expected the task to be unchanged, got %+v", task)
+	}
+}
+
+func TestImportBadRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		body     string
+		wantCode int
+	}{
+		{"unknown mode", "mode=replace", "Header\nx\n", http.StatusBadRequest},
+		{"invalid dry_run", "dry_run=maybe", "Header\nx\n", http.StatusBadRequest},
+		{"unknown format", "format=xlsx", "Header\nx\n", http.StatusBadRequest},
+		{"no header column", "", "Notes\nx\n", http.StatusBadRequest},
+		{"invalid mapping", "map=Title", "Title\nx\n", http.StatusBadRequest},
+		{"mapping to unknown field", "map=Title%3DPriority", "Title\nx\n", http.StatusBadRequest},
+		{"malformed CSV", "", "Header\n\"unterminated\n", http.StatusBadRequest},
+		{"too large", "", "Header\n" + strings.Repeat("x", 2<<20), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postImport(setupServer().Handler(), tt.query, tt.body); w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := setupServer()
+	ctx := context.Background()
+	_, _ = source.storage.CreateTask(ctx, storage.Task{Header: "=SUM(A1:A2)", Description: "multi\nline, with comma", Status: storage.Completed})
+	_, _ = source.storage.CreateTask(ctx, storage.Task{Header: "Plain"})
+
+	w := httptest.NewRecorder()
+	source.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos/export", nil))
+
+	target := setupServer()
+	if report := decodeReport(t, postImport(target.Handler(), "", w.Body.String())); report.Created != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	want, _ := source.storage.GetAll(ctx)
+	got, _ := target.storage.GetAll(ctx)
+	sortByID(want)
+	sortByID(got)
+	for i := range want {
+		if got[i].Header != want[i].Header || got[i].Description != want[i].Description || got[i].Status != want[i].Status {
+			t.Errorf("expected %+v, got %+v", want[i], got[i])
+		}
+	}
+}
+
+func TestTodoTxtExportImport(t *testing.T) {
+	server := setupServer()
+	ctx := context.Background()
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "(A) Call mum +family", Description: "Sunday", Owner: "ip:9dff98970af6f08a"})
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "Pay rent", Status: storage.InProgress, Owner: "ip:9dff98970af6f08a"})
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos/export?format=todotxt", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="todos.txt"` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	want := "(A) Call mum +family id:0 note:Sunday owner:ip%3A9dff98970af6f08a\n" +
+		"Pay rent id:1 status:in_progress owner:ip%3A9dff98970af6f08a\n"
+	if w.Body.String() != want {
+		t.Fatalf("unexpected export:\n%s", w.Body.String())
+	}
+
+	// Check off the second item and add a new one, as a user would in their editor.
+	edited := "(A) Call mum +family id:0 note:Sunday\n" +
+		"x Pay rent id:1 status:in_progress\n" +
+		"Water plants @home\n"
+	report := decodeReport(t, postImport(server.Handler(), "format=todotxt&mode=upsert", edited))
+	if report.Updated != 2 || report.Created != 1 || report.Failed != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	paid, _ := server.storage.GetByID(ctx, 1)
+	if paid.Status != storage.Completed {
+		t.Errorf("expected the checked item to be completed, got %+v", paid)
+	}
+	created, _ := server.storage.GetByID(ctx, *report.Rows[2].TaskID)
+	if created.Header != "Water plants @home" || created.Status != storage.Assigned {
+		t.Errorf("unexpected created task %+v", created)
+	}
+}
+
+func TestMarkdownExportImport(t *testing.T) {
+	server := setupServer()
+	ctx := context.Background()
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "Write changelog", Status: storage.Completed})
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "Tag the release"})
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos/export?format=markdown", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Errorf("expected Markdown, got %q", ct)
+	}
+	want := "## Assigned\n\n- [ ] Tag the release <!-- id:1 -->\n\n## Completed\n\n- [x] Write changelog <!-- id:0 -->\n"
+	if w.Body.String() != want {
+		t.Fatalf("unexpected export:\n%s", w.Body.String())
+	}
+
+	body := "- [x] Tag the release <!-- id:1 -->\n  - [ ] Announce it\n"
+	req := httptest.NewRequest(http.MethodPost, "/todos/import?mode=upsert", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/markdown")
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	report := decodeReport(t, w)
+	if report.Updated != 1 || report.Created != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if tagged, _ := server.storage.GetByID(ctx, 1); tagged.Status != storage.Completed {
+		t.Errorf("expected the checked item to be completed, got %+v", tagged)
+	}
+}
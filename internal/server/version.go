@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"todo/internal/api/legacy"
+	v1 "todo/internal/api/v1"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+// apiVersion is one wire contract for tasks. Each version is served under its own prefix next
+// to the others, so adding /v2 means a new package under internal/api and an entry in
+// apiVersions; handlers only ever see storage.Task.
+type apiVersion struct {
+	// name is the path segment, also used to keep OpenAPI names apart; empty for the
+	// unversioned routes.
+	name       string
+	deprecated bool
+	taskType   reflect.Type
+	// fields maps storage.Task field names to their names on the wire.
+	fields       map[string]string
+	statusSchema func() map[string]any
+
+	decode     func(decode func(v any) *requestError) (storage.Task, *requestError)
+	encode     func(task storage.Task) any
+	encodeList func(tasks []storage.Task) any
+}
+
+func apiVersions() []apiVersion {
+	legacyAPI := newAPIVersion("", legacy.FromTask, legacy.Task.Storage, taskStatusSchema)
+	legacyAPI.deprecated = true
+	return []apiVersion{
+		legacyAPI,
+		newAPIVersion("v1", v1.FromTask, v1.Task.Storage, v1TaskStatusSchema),
+	}
+}
+
+func newAPIVersion[T any](name string, fromTask func(storage.Task) T, toStorage func(T) (storage.Task, error), statusSchema func() map[string]any) apiVersion {
+	taskType := reflect.TypeFor[T]()
+	return apiVersion{
+		name:         name,
+		taskType:     taskType,
+		fields:       jsonFieldNames(taskType),
+		statusSchema: statusSchema,
+		decode: func(decode func(v any) *requestError) (storage.Task, *requestError) {
+			var dto T
+			if reqErr := decode(&dto); reqErr != nil {
+				return storage.Task{}, reqErr
+			}
+			task, err := toStorage(dto)
+			if err != nil {
+				return storage.Task{}, invalidTask(err)
+			}
+			return task, nil
+		},
+		encode: func(task storage.Task) any {
+			return fromTask(task)
+		},
+		encodeList: func(tasks []storage.Task) any {
+			dtos := make([]T, len(tasks))
+			for i, task := range tasks {
+				dtos[i] = fromTask(task)
+			}
+			return dtos
+		},
+	}
+}
+
+func jsonFieldNames(t reflect.Type) map[string]string {
+	names := make(map[string]string, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		names[field.Name] = name
+	}
+	return names
+}
+
+func (v apiVersion) prefix() string {
+	if v.name == "" {
+		return ""
+	}
+	return "/" + v.name
+}
+
+// component names an OpenAPI component of the version, e.g. V1Task.
+func (v apiVersion) component(name string) string {
+	return strings.ToUpper(v.name) + name
+}
+
+func (v apiVersion) operationID(id string) string {
+	if v.name == "" {
+		return id
+	}
+	return v.name + strings.ToUpper(id[:1]) + id[1:]
+}
+
+// fieldErrors renames validation details from storage.Task fields to the version's fields.
+func (v apiVersion) fieldErrors(fields []validation.FieldError) []validation.FieldError {
+	renamed := make([]validation.FieldError, len(fields))
+	for i, f := range fields {
+		if name, ok := v.fields[f.Field]; ok {
+			f.Field = name
+		}
+		renamed[i] = f
+	}
+	return renamed
+}
+
+type versionKey struct{}
+
+func (v apiVersion) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, v)))
+	}
+}
+
+// versionOf is the contract of the route serving r; handlers called directly speak the
+// unversioned one.
+func versionOf(r *http.Request) apiVersion {
+	if v, ok := r.Context().Value(versionKey{}).(apiVersion); ok {
+		return v
+	}
+	return apiVersions()[0]
+}
+
+func (s *Server) versionRoutes(v apiVersion) []route {
+	p := v.prefix()
+	return []route{
+		{p + "/todos", p + "/todos", s.versionedAPI(v, "/todos", s.NegotiateMiddleware(s.HandleTodos))},
+		{p + "/todos/", p + "/todos/{id}", s.versionedAPI(v, "/todos/{id}", s.NegotiateMiddleware(s.HandleTodoByID))},
+		{p + "/todos/export", p + "/todos/export", s.versionedAPI(v, "/todos/export", s.HandleExport)},
+		{p + "/todos/import", p + "/todos/import", s.versionedAPI(v, "/todos/import", s.NegotiateMiddleware(s.HandleImport))},
+		{p + "/todos.ics", p + "/todos.ics", SubscriptionTokenMiddleware(s.versionedAPI(v, "/todos.ics", s.HandleCalendar))},
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVersionsShareStorage(t *testing.T) {
+	handler := setupServer().Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/todos", strings.NewReader(`{"header":"Buy milk","status":"in_progress"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	want := `{"id":0,"header":"Buy milk","description":"","status":"in_progress","owner":"ip:9dff98970af6f08a"}` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/todos/0", `{"TaskID":0,"Header":"Buy milk","Description":"","Status":1,"Owner":"ip:9dff98970af6f08a"}`},
+		{"/v1/todos/0", `{"id":0,"header":"Buy milk","description":"","status":"in_progress","owner":"ip:9dff98970af6f08a"}`},
+		{"/v1/todos", `[{"id":0,"header":"Buy milk","description":"","status":"in_progress","owner":"ip:9dff98970af6f08a"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVersionedRequestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantError  string
+		wantFields []string
+	}{
+		{"v1 validation", "/v1/todos", `{"header":" ","description":"a\u0000"}`, "Invalid task", []string{"header", "description"}},
+		{"v1 unknown status", "/v1/todos", `{"header":"x","status":"done"}`, "Invalid task", []string{"status"}},
+		{"v1 unknown field", "/v1/todos", `{"header":"x","TaskStatus":1}`, `Invalid request body: unknown field "TaskStatus"`, nil},
+		{"v1 status type", "/v1/todos", `{"header":"x","status":[]}`, `Invalid request body: field "status" must be of type string`, nil},
+		{"legacy validation", "/todos", `{"Header":"","Status":9}`, "Invalid task", []string{"Header", "Status"}},
+		{"legacy status name", "/todos", `{"Header":"x","Status":"completed"}`, `Invalid request body: field "Status" must be of type int`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			setupServer().Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp errorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error != tt.wantError {
+				t.Errorf("expected error %q, got %q", tt.wantError, resp.Error)
+			}
+			var fields []string
+			for _, d := range resp.Details {
+				fields = append(fields, d.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("expected fields %v, got %v", tt.wantFields, fields)
+			}
+		})
+	}
+}
+
+func TestVersionedByIDRoutes(t *testing.T) {
+	handler := setupServer().Handler()
+	postTodo(handler, "192.0.2.1:1234", "")
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/todos/0", strings.NewReader(`{"header":"Updated","status":2}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"completed"`) {
+		t.Fatalf("expected the update to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/todos/abc", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid ID, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/todos/0", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+}
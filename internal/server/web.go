@@ -0,0 +1,436 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"todo/internal/api/legacy"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+const (
+	csrfCookieName = "todo_csrf"
+	csrfFieldName  = "csrf_token"
+)
+
+//go:embed assets/web
+var webAssets embed.FS
+
+var webTemplates = parseWebTemplates()
+
+func parseWebTemplates() map[string]*template.Template {
+	funcs := template.FuncMap{
+		"statusClass": func(status storage.TaskStatus) string { return strings.ToLower(status.String()) },
+	}
+
+	pages := make(map[string]*template.Template)
+	for _, page := range []string{"list", "show", "form", "error"} {
+		pages[page] = template.Must(template.New("layout.html").Funcs(funcs).ParseFS(webAssets,
+			"assets/web/templates/layout.html", "assets/web/templates/"+page+".html"))
+	}
+	return pages
+}
+
+type webFilter struct {
+	Status string
+	Query  string
+}
+
+type webPage struct {
+	Title     string
+	CSRFToken string
+	Statuses  []storage.TaskStatus
+	Limits    validation.Limits
+
+	Tasks  []storage.Task
+	Task   storage.Task
+	Filter webFilter
+	Errors []validation.FieldError
+
+	Message string
+	Action  string
+	// Next is where forms on the page return after submitting.
+	Next string
+}
+
+// Row is the page data for the per-task partials of a list page.
+func (p webPage) Row(task storage.Task) webPage {
+	p.Task = task
+	return p
+}
+
+type csrfKey struct{}
+
+func (s *Server) webHandler() http.Handler {
+	static, err := fs.Sub(webAssets, "assets/web")
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ui/{$}", s.webList)
+	mux.HandleFunc("GET /ui/new", s.webNew)
+	mux.HandleFunc("POST /ui/todos", s.webCreate)
+	mux.HandleFunc("GET /ui/todos/{id}", s.webShow)
+	mux.HandleFunc("GET /ui/todos/{id}/edit", s.webEdit)
+	mux.HandleFunc("POST /ui/todos/{id}", s.webUpdate)
+	mux.HandleFunc("POST /ui/todos/{id}/status", s.webSetStatus)
+	mux.HandleFunc("POST /ui/todos/{id}/delete", s.webDelete)
+	mux.Handle("GET /ui/static/", http.StripPrefix("/ui/", http.FileServerFS(static)))
+	mux.HandleFunc("/ui/", func(w http.ResponseWriter, r *http.Request) {
+		s.renderWebError(w, r, http.StatusNotFound, "Page not found")
+	})
+	return s.CSRFMiddleware(mux)
+}
+
+// CSRFMiddleware issues a random token in a cookie and requires every unsafe request to echo it
+// in a form field (double-submit cookie). Requests the browser marks as cross-site are rejected
+// before the token is checked.
+func (s *Server) CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if c, err := r.Cookie(csrfCookieName); err == nil && validCSRFToken(c.Value) {
+			token = c.Value
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if token == "" {
+				token = newCSRFToken()
+				http.SetCookie(w, &http.Cookie{
+					Name:     csrfCookieName,
+					Value:    token,
+					Path:     "/ui/",
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteStrictMode,
+				})
+			}
+		default:
+			r.Body = http.MaxBytesReader(w, r.Body, s.limits.MaxBodyBytes)
+			if !sameOrigin(r) {
+				s.renderWebError(w, r, http.StatusForbidden, "Cross-site request rejected")
+				return
+			}
+			if err := r.ParseForm(); err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					s.renderWebError(w, r, http.StatusRequestEntityTooLarge, "The form is too large")
+					return
+				}
+				s.renderWebError(w, r, http.StatusBadRequest, "Invalid form")
+				return
+			}
+			submitted := r.PostFormValue(csrfFieldName)
+			if token == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				s.renderWebError(w, r, http.StatusForbidden, "Invalid or missing CSRF token, reload the page and try again")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfKey{}, token)))
+	})
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validCSRFToken(token string) bool {
+	_, err := hex.DecodeString(token)
+	return err == nil && len(token) == 64
+}
+
+// sameOrigin uses Sec-Fetch-Site and Origin when the browser sends them; older browsers
+// send neither and rely on the token alone.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func (s *Server) newWebPage(r *http.Request, title string) webPage {
+	token, _ := r.Context().Value(csrfKey{}).(string)
+	return webPage{
+		Title:     title,
+		CSRFToken: token,
+		Statuses:  storage.Statuses,
+		Limits:    s.limits,
+	}
+}
+
+func (s *Server) renderWeb(w http.ResponseWriter, r *http.Request, code int, name string, page webPage) {
+	var buf bytes.Buffer
+	if err := webTemplates[name].Execute(&buf, page); err != nil {
+		s.logger.ErrorContext(r.Context(), "template failed", "template", name, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'; form-action 'self'")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Referrer-Policy", "same-origin")
+	w.WriteHeader(code)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (s *Server) renderWebError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	if code >= http.StatusInternalServerError {
+		s.logger.ErrorContext(r.Context(), "request failed", "status", code, "error", message)
+	}
+	page := s.newWebPage(r, http.StatusText(code))
+	page.Message = message
+	s.renderWeb(w, r, code, "error", page)
+}
+
+// webStorageError maps storage errors to the same status codes as the JSON API.
+func webStorageError(err error) (int, string) {
+	switch {
+	case errors.Is(err, storage.ErrTaskNotFound):
+		return http.StatusNotFound, "Task not found"
+	case errors.Is(err, storage.ErrWrongArgument):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return http.StatusForbidden, "Task quota exceeded"
+	case errors.Is(err, storage.ErrStorageClosed):
+		return http.StatusServiceUnavailable, "The server is shutting down, try again later"
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
+
+// webTask loads the task named in the URL, rendering an error page when that fails.
+func (s *Server) webTask(w http.ResponseWriter, r *http.Request) (storage.Task, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		s.renderWebError(w, r, http.StatusNotFound, "Task not found")
+		return storage.Task{}, false
+	}
+
+	task, err := s.storage.GetByID(r.Context(), id)
+	if err != nil {
+		code, msg := webStorageError(err)
+		s.renderWebError(w, r, code, msg)
+		return storage.Task{}, false
+	}
+	return task, true
+}
+
+func (s *Server) webOwnTask(w http.ResponseWriter, r *http.Request) (storage.Task, bool) {
+	task, ok := s.webTask(w, r)
+	if ok && !s.authorize(r, task.Owner) {
+		s.renderWebError(w, r, http.StatusForbidden, "Task belongs to another owner")
+		return storage.Task{}, false
+	}
+	return task, ok
+}
+
+func (s *Server) webList(w http.ResponseWriter, r *http.Request) {
+	page := s.newWebPage(r, "Tasks")
+	page.Filter = webFilter{Query: r.URL.Query().Get("q")}
+	page.Next = r.URL.RequestURI()
+
+	filter := storage.Filter{Query: page.Filter.Query}
+	if v := r.URL.Query().Get("status"); v != "" {
+		parsed, err := storage.ParseTaskStatus(v)
+		if err != nil {
+			s.renderWebError(w, r, http.StatusBadRequest, "Unknown status "+strconv.Quote(v))
+			return
+		}
+		filter.Statuses = []storage.TaskStatus{parsed}
+		page.Filter.Status = parsed.String()
+	}
+
+	tasks, err := s.findTasks(r.Context(), filter)
+	if err != nil {
+		code, msg := webStorageError(err)
+		s.renderWebError(w, r, code, msg)
+		return
+	}
+	sortByID(tasks)
+	page.Tasks = tasks
+
+	s.renderWeb(w, r, http.StatusOK, "list", page)
+}
+
+func (s *Server) webShow(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.webTask(w, r)
+	if !ok {
+		return
+	}
+
+	page := s.newWebPage(r, "#"+strconv.Itoa(task.TaskID)+" "+task.Header)
+	page.Task = task
+	page.Next = r.URL.Path
+	s.renderWeb(w, r, http.StatusOK, "show", page)
+}
+
+func (s *Server) webNew(w http.ResponseWriter, r *http.Request) {
+	page := s.newWebPage(r, "New task")
+	page.Action = "/ui/todos"
+	page.Next = "/ui/"
+	s.renderWeb(w, r, http.StatusOK, "form", page)
+}
+
+func (s *Server) webEdit(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.webOwnTask(w, r)
+	if !ok {
+		return
+	}
+
+	page := s.newWebPage(r, "Edit task #"+strconv.Itoa(task.TaskID))
+	page.Task = task
+	page.Action = "/ui/todos/" + strconv.Itoa(task.TaskID)
+	page.Next = page.Action
+	s.renderWeb(w, r, http.StatusOK, "form", page)
+}
+
+// parseTaskForm reads the task fields of a submitted form. Browsers send CRLF line breaks
+// in textareas, which are normalized to LF.
+func parseTaskForm(r *http.Request) (storage.Task, []validation.FieldError) {
+	task := storage.Task{
+		Header:      r.PostFormValue("Header"),
+		Description: strings.ReplaceAll(r.PostFormValue("Description"), "\r\n", "\n"),
+	}
+
+	var errs []validation.FieldError
+	if v := r.PostFormValue("Status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, validation.FieldError{Field: "Status", Message: "must be a number"})
+		}
+		task.Status = storage.TaskStatus(status)
+	}
+	return task, errs
+}
+
+// saveWebTask validates a submitted form and stores it with save, re-rendering the form with
+// the submitted values on failure.
+func (s *Server) saveWebTask(w http.ResponseWriter, r *http.Request, page webPage,
+	save func(storage.Task) (*storage.Task, error)) {
+	task, errs := parseTaskForm(r)
+	if len(errs) == 0 {
+		if err := s.limits.Task(&task); err != nil {
+			var verr *validation.Error
+			if errors.As(err, &verr) {
+				errs = verr.Fields
+			}
+		}
+	}
+	page.Task = task
+	if len(errs) > 0 {
+		page.Errors = errs
+		s.renderWeb(w, r, http.StatusUnprocessableEntity, "form", page)
+		return
+	}
+
+	saved, err := save(task)
+	if err != nil {
+		code, msg := webStorageError(err)
+		page.Message = msg
+		s.renderWeb(w, r, code, "form", page)
+		return
+	}
+	http.Redirect(w, r, "/ui/todos/"+strconv.Itoa(saved.TaskID), http.StatusSeeOther)
+}
+
+func (s *Server) webCreate(w http.ResponseWriter, r *http.Request) {
+	page := s.newWebPage(r, "New task")
+	page.Action = "/ui/todos"
+	page.Next = "/ui/"
+
+	s.saveWebTask(w, r, page, func(task storage.Task) (*storage.Task, error) {
+		task.Owner = s.owner(r.Context())
+		return s.storage.CreateTask(r.Context(), task)
+	})
+}
+
+func (s *Server) webUpdate(w http.ResponseWriter, r *http.Request) {
+	current, ok := s.webOwnTask(w, r)
+	if !ok {
+		return
+	}
+
+	page := s.newWebPage(r, "Edit task #"+strconv.Itoa(current.TaskID))
+	page.Action = "/ui/todos/" + strconv.Itoa(current.TaskID)
+	page.Next = page.Action
+
+	s.saveWebTask(w, r, page, func(task storage.Task) (*storage.Task, error) {
+		return s.storage.Update(r.Context(), current.TaskID, &task)
+	})
+}
+
+// webSetStatus answers with the updated task as JSON when called from the page script,
+// and redirects back otherwise.
+func (s *Server) webSetStatus(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.webOwnTask(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := storage.ParseTaskStatus(r.PostFormValue("Status"))
+	if err != nil {
+		s.renderWebError(w, r, http.StatusBadRequest, "Unknown status")
+		return
+	}
+	task.Status = status
+
+	updated, err := s.storage.Update(r.Context(), task.TaskID, &task)
+	if err != nil {
+		code, msg := webStorageError(err)
+		s.renderWebError(w, r, code, msg)
+		return
+	}
+
+	if r.Header.Get("Accept") == "application/json" {
+		s.writeResponse(w, r, http.StatusOK, legacy.FromTask(*updated))
+		return
+	}
+	http.Redirect(w, r, localRedirect(r.PostFormValue("next"), "/ui/todos/"+strconv.Itoa(task.TaskID)), http.StatusSeeOther)
+}
+
+func (s *Server) webDelete(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.webOwnTask(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.storage.Delete(r.Context(), task.TaskID); err != nil {
+		code, msg := webStorageError(err)
+		s.renderWebError(w, r, code, msg)
+		return
+	}
+	http.Redirect(w, r, "/ui/", http.StatusSeeOther)
+}
+
+// localRedirect accepts only paths inside the UI so forms cannot be used as open redirects.
+func localRedirect(next, fallback string) string {
+	if !strings.HasPrefix(next, "/ui/") || strings.ContainsAny(next, "\\\r\n") {
+		return fallback
+	}
+	return next
+}
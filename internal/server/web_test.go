@@ -0,0 +1,303 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+)
+
+// webSession is a browser stand-in that carries the CSRF cookie between requests.
+type webSession struct {
+	t       *testing.T
+	handler http.Handler
+	cookie  *http.Cookie
+}
+
+func newWebSession(t *testing.T, handler http.Handler) *webSession {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ui/new", nil))
+	for _, c := range w.Result().Cookies() {
+		if c.Name == csrfCookieName {
+			return &webSession{t: t, handler: handler, cookie: c}
+		}
+	}
+	t.Fatal("no CSRF cookie issued")
+	return nil
+}
+
+func (ws *webSession) get(target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.AddCookie(ws.cookie)
+	w := httptest.NewRecorder()
+	ws.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ws *webSession) post(target string, form url.Values, headers ...string) *httptest.ResponseRecorder {
+	if form.Get(csrfFieldName) == "" {
+		form.Set(csrfFieldName, ws.cookie.Value)
+	}
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	req.AddCookie(ws.cookie)
+	w := httptest.NewRecorder()
+	ws.handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestWebRootRedirect(t *testing.T) {
+	w := httptest.NewRecorder()
+	setupServer().Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/ui/" {
+		t.Errorf("expected a redirect to /ui/, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestWebList(t *testing.T) {
+	server := setupServer()
+	ctx := context.Background()
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "Buy milk", Description: "weekly shopping"})
+	_, _ = server.storage.CreateTask(ctx, storage.Task{Header: "<script>alert(1)</script>", Status: storage.Completed})
+	ws := newWebSession(t, server.Handler())
+
+	tests := []struct {
+		name    string
+		target  string
+		want    []string
+		notWant []string
+	}{
+		{"all tasks", "/ui/", []string{"Buy milk", "&lt;script&gt;alert(1)&lt;/script&gt;"}, []string{"<script>alert"}},
+		{"status filter", "/ui/?status=completed", []string{"&lt;script&gt;"}, []string{"Buy milk"}},
+		{"search", "/ui/?q=SHOPPING", []string{"Buy milk"}, []string{"&lt;script&gt;"}},
+		{"nothing matches", "/ui/?q=nothing", []string{"match the filter"}, []string{"<table>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ws.get(tt.target)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			body := w.Body.String()
+			for _, s := range tt.want {
+				if !strings.Contains(body, s) {
+					t.Errorf("page does not contain %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(body, s) {
+					t.Errorf("page contains %q", s)
+				}
+			}
+		})
+	}
+
+	if w := ws.get("/ui/?status=unknown"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown status, got %d", w.Code)
+	}
+}
+
+func TestWebCreateEditDelete(t *testing.T) {
+	server := setupServer()
+	ws := newWebSession(t, server.Handler())
+
+	w := ws.post("/ui/todos", url.Values{"Header": {"  Buy milk "}, "Description": {"Line 1\r\nLine 2"}, "Status": {"1"}})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/ui/todos/0" {
+		t.Fatalf("expected a redirect to the new task, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	task, err := server.storage.GetByID(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Header != "Buy milk" || task.Description != "Line 1\nLine 2" || task.Status != storage.InProgress {
+		t.Errorf("unexpected task: %+v", task)
+	}
+	if task.Owner == "" {
+		t.Error("expected the owner to be set")
+	}
+
+	show := ws.get("/ui/todos/0")
+	if show.Code != http.StatusOK || !strings.Contains(show.Body.String(), "Buy milk") {
+		t.Errorf("unexpected detail page: %d", show.Code)
+	}
+	edit := ws.get("/ui/todos/0/edit")
+	if edit.Code != http.StatusOK || !strings.Contains(edit.Body.String(), `value="Buy milk"`) {
+		t.Errorf("expected the edit form to be filled in, got %d", edit.Code)
+	}
+
+	w = ws.post("/ui/todos/0", url.Values{"Header": {"Buy oat milk"}, "Status": {"2"}})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected a redirect after update, got %d", w.Code)
+	}
+	task, _ = server.storage.GetByID(context.Background(), 0)
+	if task.Header != "Buy oat milk" || task.Status != storage.Completed || task.Description != "" {
+		t.Errorf("unexpected task after update: %+v", task)
+	}
+
+	w = ws.post("/ui/todos/0/delete", url.Values{})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/ui/" {
+		t.Fatalf("expected a redirect to the list, got %d", w.Code)
+	}
+	if w := ws.get("/ui/todos/0"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 after delete, got %d", w.Code)
+	}
+}
+
+func TestWebValidation(t *testing.T) {
+	server := setupServer()
+	ws := newWebSession(t, server.Handler())
+
+	w := ws.post("/ui/todos", url.Values{"Header": {" "}, "Description": {"Keep me"}, "Status": {"7"}})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Header: must not be empty", "Status: must be one of", "Keep me"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("form does not contain %q", want)
+		}
+	}
+	if tasks, _ := server.storage.GetAll(context.Background()); len(tasks) != 0 {
+		t.Errorf("expected no task to be created, got %d", len(tasks))
+	}
+}
+
+func TestWebStatusChange(t *testing.T) {
+	server := setupServer()
+	_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Task"})
+	ws := newWebSession(t, server.Handler())
+
+	w := ws.post("/ui/todos/0/status", url.Values{"Status": {"2"}, "next": {"/ui/?status=assigned"}})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/ui/?status=assigned" {
+		t.Errorf("expected a redirect back to the list, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = ws.post("/ui/todos/0/status", url.Values{"Status": {"3"}, "next": {"https://evil.example.com/"}})
+	if w.Header().Get("Location") != "/ui/todos/0" {
+		t.Errorf("expected external redirects to be ignored, got %q", w.Header().Get("Location"))
+	}
+
+	w = ws.post("/ui/todos/0/status", url.Values{"Status": {"1"}}, "Accept", "application/json")
+	var task storage.Task
+	if err := json.NewDecoder(w.Body).Decode(&task); err != nil || task.Status != storage.InProgress {
+		t.Errorf("expected the updated task as JSON, got %v %+v", err, task)
+	}
+
+	if w := ws.post("/ui/todos/0/status", url.Values{"Status": {"9"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown status, got %d", w.Code)
+	}
+	if w := ws.post("/ui/todos/42/status", url.Values{"Status": {"1"}}); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a missing task, got %d", w.Code)
+	}
+}
+
+func TestWebCSRF(t *testing.T) {
+	server := setupServer()
+	handler := server.Handler()
+	ws := newWebSession(t, handler)
+
+	cookie := ws.cookie
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("expected an HttpOnly SameSite=Strict cookie, got %+v", cookie)
+	}
+
+	form := func() url.Values { return url.Values{"Header": {"Task"}} }
+
+	tests := []struct {
+		name    string
+		request func() *httptest.ResponseRecorder
+	}{
+		{"missing token", func() *httptest.ResponseRecorder {
+			f := form()
+			f.Set(csrfFieldName, "")
+			req := httptest.NewRequest(http.MethodPost, "/ui/todos", strings.NewReader(f.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(cookie)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}},
+		{"wrong token", func() *httptest.ResponseRecorder {
+			f := form()
+			f.Set(csrfFieldName, strings.Repeat("0", 64))
+			return ws.post("/ui/todos", f)
+		}},
+		{"missing cookie", func() *httptest.ResponseRecorder {
+			f := form()
+			f.Set(csrfFieldName, cookie.Value)
+			req := httptest.NewRequest(http.MethodPost, "/ui/todos", strings.NewReader(f.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}},
+		{"cross-origin", func() *httptest.ResponseRecorder {
+			return ws.post("/ui/todos", form(), "Origin", "https://evil.example.com")
+		}},
+		{"cross-site fetch", func() *httptest.ResponseRecorder {
+			return ws.post("/ui/todos", form(), "Sec-Fetch-Site", "cross-site")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := tt.request(); w.Code != http.StatusForbidden {
+				t.Errorf("expected status 403, got %d", w.Code)
+			}
+		})
+	}
+
+	if tasks, _ := server.storage.GetAll(context.Background()); len(tasks) != 0 {
+		t.Errorf("expected no task to be created, got %d", len(tasks))
+	}
+	if w := ws.post("/ui/todos", form(), "Origin", "http://example.com", "Sec-Fetch-Site", "same-origin"); w.Code != http.StatusSeeOther {
+		t.Errorf("expected a same-origin request to succeed, got %d", w.Code)
+	}
+}
+
+func TestWebOwnership(t *testing.T) {
+	server := setupServerWith(storage.NewStorage(), WithOwnershipEnforcement())
+	_, _ = server.storage.CreateTask(context.Background(), storage.Task{Header: "Task", Owner: "token:someone"})
+	ws := newWebSession(t, server.Handler())
+
+	if w := ws.get("/ui/todos/0/edit"); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for the edit form, got %d", w.Code)
+	}
+	if w := ws.post("/ui/todos/0/delete", url.Values{}); w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for delete, got %d", w.Code)
+	}
+}
+
+func TestWebStaticAndErrors(t *testing.T) {
+	ws := newWebSession(t, setupServer().Handler())
+
+	css := ws.get("/ui/static/app.css")
+	if css.Code != http.StatusOK || !strings.HasPrefix(css.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("expected the stylesheet, got %d %q", css.Code, css.Header().Get("Content-Type"))
+	}
+	if w := ws.get("/ui/static/../templates/layout.html"); w.Code == http.StatusOK {
+		t.Error("expected templates not to be served")
+	}
+
+	w := ws.get("/ui/missing")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "Page not found") {
+		t.Errorf("expected an HTML 404 page, got %d", w.Code)
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'self'") {
+		t.Errorf("expected a content security policy, got %q", csp)
+	}
+	if w := ws.get("/ui/todos/abc"); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an invalid ID, got %d", w.Code)
+	}
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	v1 "todo/internal/api/v1"
+	"todo/internal/codec"
+	"todo/internal/storage"
+	"todo/internal/validation"
+	"todo/internal/websocket"
+)
+
+// wsSendBuffer is how many messages may wait for a slow client before it is disconnected.
+const wsSendBuffer = 64
+
+var (
+	// wsPingInterval is how often the server pings; a client that sends nothing, pongs
+	// included, for two intervals is disconnected.
+	wsPingInterval = 30 * time.Second
+	wsWriteTimeout = 10 * time.Second
+)
+
+// wsRequest is a command from the client. id is chosen by the client and echoed in the reply.
+type wsRequest struct {
+	Type   string    `json:"type"`
+	ID     string    `json:"id,omitempty"`
+	TaskID *int      `json:"task_id,omitempty"`
+	Task   *v1.Task  `json:"task,omitempty"`
+	Filter *wsFilter `json:"filter,omitempty"`
+}
+
+type wsFilter struct {
+	Statuses []v1.Status `json:"statuses,omitempty"`
+	Owner    string      `json:"owner,omitempty"`
+	Query    string      `json:"query,omitempty"`
+}
+
+type wsResult struct {
+	Type   string   `json:"type"`
+	ID     string   `json:"id,omitempty"`
+	Task   *v1.Task `json:"task,omitempty"`
+	TaskID *int     `json:"task_id,omitempty"`
+}
+
+// wsSnapshot answers subscribe with the tasks matching the filter; events follow it.
+type wsSnapshot struct {
+	Type  string    `json:"type"`
+	ID    string    `json:"id,omitempty"`
+	Tasks []v1.Task `json:"tasks"`
+}
+
+type wsEvent struct {
+	Type  string  `json:"type"`
+	Event string  `json:"event"`
+	Task  v1.Task `json:"task"`
+}
+
+// wsError carries the status and body the same failure gets over HTTP.
+type wsError struct {
+	Type    string                  `json:"type"`
+	ID      string                  `json:"id,omitempty"`
+	Status  int                     `json:"status"`
+	Error   string                  `json:"error"`
+	Details []validation.FieldError `json:"details,omitempty"`
+}
+
+// wsClient is one /ws connection. The handler goroutine reads and runs commands in order;
+// replies and events go through a bounded queue to a writer goroutine, so neither storage
+// events nor other clients ever wait for a slow one.
+type wsClient struct {
+	s    *Server
+	conn *websocket.Conn
+	ctx  context.Context
+	out  chan []byte
+	// The timing is fixed per connection when it starts.
+	pingInterval time.Duration
+	writeTimeout time.Duration
+	// running tracks the goroutines that must end before the handler returns.
+	running sync.WaitGroup
+
+	mu       sync.Mutex
+	filter   *storage.Filter
+	watching bool
+}
+
+func (s *Server) HandleWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	c := &wsClient{
+		s:            s,
+		conn:         conn,
+		ctx:          ctx,
+		out:          make(chan []byte, wsSendBuffer),
+		pingInterval: wsPingInterval,
+		writeTimeout: wsWriteTimeout,
+	}
+	conn.SetReadLimit(s.limits.MaxBodyBytes)
+	conn.SetReadDeadline(time.Now().Add(2 * c.pingInterval))
+	conn.SetPongHandler(func([]byte) {
+		conn.SetReadDeadline(time.Now().Add(2 * c.pingInterval))
+	})
+
+	c.running.Add(2)
+	go func() {
+		defer c.running.Done()
+		c.writeLoop()
+	}()
+	go func() {
+		defer c.running.Done()
+		c.keepAlive()
+	}()
+
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * c.pingInterval))
+		if typ != websocket.TextMessage {
+			c.close(websocket.CloseUnsupportedData, "Messages must be JSON text")
+			continue
+		}
+		c.handle(data)
+	}
+
+	cancel()
+	c.running.Wait()
+}
+
+func (c *wsClient) writeLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case data := <-c.out:
+			c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close(websocket.ClosePolicyViolation, "Too slow")
+				return
+			}
+		}
+	}
+}
+
+// keepAlive pings the client and ends the connection when the server shuts down.
+func (c *wsClient) keepAlive() {
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.s.streamsDone:
+			c.close(websocket.CloseGoingAway, "Server is shutting down")
+			return
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// close starts the closing handshake; the read loop ends once the client answers.
+func (c *wsClient) close(code int, reason string) {
+	c.conn.Close(code, reason)
+}
+
+// send queues msg without blocking; a client whose queue is full is disconnected and has to
+// reconnect and subscribe again.
+func (c *wsClient) send(msg any) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.s.logger.ErrorContext(c.ctx, "encoding websocket message", "error", err)
+		return
+	}
+	select {
+	case c.out <- data:
+	default:
+		c.close(websocket.ClosePolicyViolation, "Too slow")
+	}
+}
+
+func (c *wsClient) sendError(id string, err error) {
+	reqErr := c.s.wsRequestError(c.ctx, err)
+	c.send(wsError{Type: "error", ID: id, Status: reqErr.status, Error: reqErr.message, Details: reqErr.details})
+}
+
+func (c *wsClient) handle(data []byte) {
+	var req wsRequest
+	if err := (codec.JSON{}).Decode(data, &req); err != nil {
+		c.sendError("", describeDecodeError(codec.JSON{}, err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, SecToTimeout*time.Second)
+	defer cancel()
+
+	var err error
+	switch req.Type {
+	case "subscribe":
+		err = c.subscribe(ctx, req)
+	case "unsubscribe":
+		c.mu.Lock()
+		c.filter = nil
+		c.mu.Unlock()
+		c.send(wsResult{Type: "unsubscribed", ID: req.ID})
+	case "create":
+		err = c.create(ctx, req)
+	case "update":
+		err = c.update(ctx, req)
+	case "delete":
+		err = c.delete(ctx, req)
+	default:
+		err = badRequest("Unknown message type %q", req.Type)
+	}
+	if err != nil {
+		c.sendError(req.ID, err)
+	}
+}
+
+// subscribe sets the filter for events and answers with the matching tasks. The snapshot is
+// taken and queued under the lock the event goroutine needs, so every change after it arrives
+// as an event.
+func (c *wsClient) subscribe(ctx context.Context, req wsRequest) error {
+	if c.s.watcher == nil {
+		return &requestError{status: http.StatusNotImplemented, message: "The storage does not publish changes"}
+	}
+	var filter storage.Filter
+	if req.Filter != nil {
+		statuses := make([]storage.TaskStatus, len(req.Filter.Statuses))
+		for i, name := range req.Filter.Statuses {
+			status, ok := name.Storage()
+			if !ok || name == "" {
+				reqErr := badRequest("Invalid filter")
+				reqErr.details = []validation.FieldError{{Field: "filter.statuses", Message: "unknown status " + strconv.Quote(string(name))}}
+				return reqErr
+			}
+			statuses[i] = status
+		}
+		filter = storage.Filter{Statuses: statuses, Owner: req.Filter.Owner, Query: req.Filter.Query}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.watching {
+		events, cancel := c.s.watcher.Subscribe(watchBuffer)
+		c.watching = true
+		c.running.Add(1)
+		go func() {
+			defer c.running.Done()
+			defer cancel()
+			c.watch(events)
+		}()
+	}
+
+	tasks, err := c.s.findTasks(ctx, filter)
+	if err != nil {
+		return err
+	}
+	snapshot := wsSnapshot{Type: "subscribed", ID: req.ID, Tasks: make([]v1.Task, len(tasks))}
+	for i, task := range tasks {
+		snapshot.Tasks[i] = v1.FromTask(task)
+	}
+	c.filter = &filter
+	c.send(snapshot)
+	return nil
+}
+
+// watch forwards the storage events that match the filter until the connection ends.
+func (c *wsClient) watch(events <-chan storage.Event) {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				if c.s.shuttingDown.Load() {
+					c.close(websocket.CloseGoingAway, "Server is shutting down")
+				} else {
+					c.close(websocket.ClosePolicyViolation, "Too slow")
+				}
+				return
+			}
+			c.mu.Lock()
+			if c.filter != nil && c.filter.Match(event.Task) {
+				c.send(wsEvent{Type: "event", Event: event.Type.String(), Task: v1.FromTask(event.Task)})
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (c *wsClient) create(ctx context.Context, req wsRequest) error {
+	task, err := c.s.commandTask(req.Task)
+	if err != nil {
+		return err
+	}
+	task.Owner = c.s.owner(ctx)
+	created, err := c.s.storage.CreateTask(ctx, task)
+	if err != nil {
+		return err
+	}
+	result := v1.FromTask(*created)
+	c.send(wsResult{Type: "created", ID: req.ID, Task: &result})
+	return nil
+}
+
+func (c *wsClient) update(ctx context.Context, req wsRequest) error {
+	if req.TaskID == nil {
+		return badRequest("task_id is required")
+	}
+	task, err := c.s.commandTask(req.Task)
+	if err != nil {
+		return err
+	}
+	if err := c.s.checkOwner(ctx, *req.TaskID); err != nil {
+		return err
+	}
+	updated, err := c.s.storage.Update(ctx, *req.TaskID, &task)
+	if err != nil {
+		return err
+	}
+	result := v1.FromTask(*updated)
+	c.send(wsResult{Type: "updated", ID: req.ID, Task: &result})
+	return nil
+}
+
+func (c *wsClient) delete(ctx context.Context, req wsRequest) error {
+	if req.TaskID == nil {
+		return badRequest("task_id is required")
+	}
+	if err := c.s.checkOwner(ctx, *req.TaskID); err != nil {
+		return err
+	}
+	if err := c.s.storage.Delete(ctx, *req.TaskID); err != nil {
+		return err
+	}
+	c.send(wsResult{Type: "deleted", ID: req.ID, TaskID: req.TaskID})
+	return nil
+}
+
+// wsRequestError maps a failed command to the status and message the HTTP API would answer.
+func (s *Server) wsRequestError(ctx context.Context, err error) *requestError {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr
+	case errors.Is(err, errNotOwner):
+		return &requestError{status: http.StatusForbidden, message: err.Error()}
+	case errors.Is(err, storage.ErrWrongArgument):
+		return badRequest("%s", err.Error())
+	case errors.Is(err, storage.ErrTaskNotFound):
+		return &requestError{status: http.StatusNotFound, message: "Task not found"}
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return &requestError{status: http.StatusForbidden, message: err.Error()}
+	case errors.Is(err, storage.ErrStorageClosed):
+		return &requestError{status: http.StatusServiceUnavailable, message: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &requestError{status: http.StatusGatewayTimeout, message: "Command timed out"}
+	default:
+		s.logger.ErrorContext(ctx, "websocket command failed", "error", err)
+		return &requestError{status: http.StatusInternalServerError, message: err.Error()}
+	}
+}
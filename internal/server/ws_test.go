@@ -0,0 +1,334 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"todo/internal/storage"
+	"todo/internal/websocket"
+)
+
+type wsTestClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func dialWS(t *testing.T, baseURL, token string) *wsTestClient {
+	t.Helper()
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	conn, _, err := websocket.Dial(context.Background(), baseURL+"/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close(websocket.CloseNormal, "") })
+	return &wsTestClient{t: t, conn: conn}
+}
+
+func (c *wsTestClient) send(msg string) {
+	c.t.Helper()
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsTestClient) read() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return string(data)
+}
+
+// expect reads len(want) messages in any order, since replies and events are sent by
+// different goroutines.
+func (c *wsTestClient) expect(want ...string) {
+	c.t.Helper()
+	got := make([]string, len(want))
+	for i := range got {
+		got[i] = c.read()
+	}
+	slices.Sort(got)
+	want = slices.Clone(want)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		c.t.Fatalf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func (c *wsTestClient) expectClose(code int) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := c.conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != code {
+			c.t.Errorf("expected close %d, got %v", code, err)
+		}
+		return
+	}
+}
+
+func TestWSCollaboration(t *testing.T) {
+	st := storage.NewStorage()
+	st.CreateTask(context.Background(), storage.Task{Header: "Existing", Status: storage.Completed})
+	srv := httptest.NewServer(setupServerWith(st).Handler())
+	defer srv.Close()
+
+	alice := dialWS(t, srv.URL, "alice")
+	bob := dialWS(t, srv.URL, "bob")
+
+	alice.send(`{"type":"subscribe","id":"s"}`)
+	alice.expect(`{"type":"subscribed","id":"s","tasks":[{"id":0,"header":"Existing","description":"","status":"completed","owner":""}]}`)
+	bob.send(`{"type":"subscribe","id":"s","filter":{"statuses":["in_progress"]}}`)
+	bob.expect(`{"type":"subscribed","id":"s","tasks":[]}`)
+
+	alice.send(`{"type":"create","id":"1","task":{"header":"Plan sprint"}}`)
+	alice.expect(
+		`{"type":"created","id":"1","task":{"id":1,"header":"Plan sprint","description":"","status":"assigned","owner":"token:2bd806c97f0e00af"}}`,
+		`{"type":"event","event":"created","task":{"id":1,"header":"Plan sprint","description":"","status":"assigned","owner":"token:2bd806c97f0e00af"}}`,
+	)
+
+	bob.send(`{"type":"update","id":"2","task_id":1,"task":{"header":"Plan sprint","status":"in_progress"}}`)
+	bob.expect(
+		`{"type":"updated","id":"2","task":{"id":1,"header":"Plan sprint","description":"","status":"in_progress","owner":"token:2bd806c97f0e00af"}}`,
+		`{"type":"event","event":"updated","task":{"id":1,"header":"Plan sprint","description":"","status":"in_progress","owner":"token:2bd806c97f0e00af"}}`,
+	)
+	alice.expect(`{"type":"event","event":"updated","task":{"id":1,"header":"Plan sprint","description":"","status":"in_progress","owner":"token:2bd806c97f0e00af"}}`)
+
+	// Changes through the HTTP API reach the subscribers too.
+	req := httptest.NewRequest(http.MethodDelete, "/v1/todos/1", nil)
+	w := httptest.NewRecorder()
+	setupServerWith(st).Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	deleted := `{"type":"event","event":"deleted","task":{"id":1,"header":"Plan sprint","description":"","status":"in_progress","owner":"token:2bd806c97f0e00af"}}`
+	alice.expect(deleted)
+	bob.expect(deleted)
+
+	bob.send(`{"type":"unsubscribe","id":"u"}`)
+	bob.expect(`{"type":"unsubscribed","id":"u"}`)
+	alice.send(`{"type":"delete","id":"3","task_id":0}`)
+	alice.expect(
+		`{"type":"deleted","id":"3","task_id":0}`,
+		`{"type":"event","event":"deleted","task":{"id":0,"header":"Existing","description":"","status":"completed","owner":""}}`,
+	)
+
+	// Bob hears nothing more: the next message is the reply to his own command.
+	bob.send(`{"type":"create","id":"4","task":{"header":"Review","status":"in_progress"}}`)
+	bob.expect(`{"type":"created","id":"4","task":{"id":2,"header":"Review","description":"","status":"in_progress","owner":"token:81b637d8fcd2c6da"}}`)
+	alice.expect(`{"type":"event","event":"created","task":{"id":2,"header":"Review","description":"","status":"in_progress","owner":"token:81b637d8fcd2c6da"}}`)
+}
+
+func TestWSErrors(t *testing.T) {
+	st := storage.NewStorage()
+	st.CreateTask(context.Background(), storage.Task{Header: "Alice's", Owner: "token:2bd806c97f0e00af"})
+	srv := httptest.NewServer(setupServerWith(st, WithOwnershipEnforcement()).Handler())
+	defer srv.Close()
+	c := dialWS(t, srv.URL, "bob")
+
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{
+			name: "malformed",
+			msg:  `{"type":`,
+			want: `{"type":"error","status":400,"error":"Invalid request body: malformed JSON"}`,
+		},
+		{
+			name: "unknown field",
+			msg:  `{"type":"create","taks":{}}`,
+			want: `{"type":"error","status":400,"error":"Invalid request body: unknown field \"taks\""}`,
+		},
+		{
+			name: "unknown type",
+			msg:  `{"type":"list","id":"1"}`,
+			want: `{"type":"error","id":"1","status":400,"error":"Unknown message type \"list\""}`,
+		},
+		{
+			name: "missing task",
+			msg:  `{"type":"create","id":"2"}`,
+			want: `{"type":"error","id":"2","status":400,"error":"task is required"}`,
+		},
+		{
+			name: "invalid task",
+			msg:  `{"type":"create","id":"3","task":{"header":"","description":"ok"}}`,
+			want: `{"type":"error","id":"3","status":400,"error":"Invalid task","details":[{"field":"task.header","message":"must not be empty"}]}`,
+		},
+		{
+			name: "unknown status",
+			msg:  `{"type":"create","id":"4","task":{"header":"x","status":"later"}}`,
+			want: `{"type":"error","id":"4","status":400,"error":"Invalid task","details":[{"field":"task.status","message":"must be one of assigned, in_progress, completed, dropped"}]}`,
+		},
+		{
+			name: "missing task id",
+			msg:  `{"type":"delete","id":"5"}`,
+			want: `{"type":"error","id":"5","status":400,"error":"task_id is required"}`,
+		},
+		{
+			name: "not found",
+			msg:  `{"type":"update","id":"6","task_id":9,"task":{"header":"x"}}`,
+			want: `{"type":"error","id":"6","status":404,"error":"Task not found"}`,
+		},
+		{
+			name: "other owner",
+			msg:  `{"type":"delete","id":"7","task_id":0}`,
+			want: `{"type":"error","id":"7","status":403,"error":"Task belongs to another owner"}`,
+		},
+		{
+			name: "bad filter",
+			msg:  `{"type":"subscribe","id":"8","filter":{"statuses":["assigned",""]}}`,
+			want: `{"type":"error","id":"8","status":400,"error":"Invalid filter","details":[{"field":"filter.statuses","message":"unknown status \"\""}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.t = t
+			c.send(tt.msg)
+			c.expect(tt.want)
+		})
+	}
+
+	// Errors leave the connection usable.
+	c.t = t
+	c.send(`{"type":"create","id":"ok","task":{"header":"Bob's"}}`)
+	c.expect(`{"type":"created","id":"ok","task":{"id":1,"header":"Bob's","description":"","status":"assigned","owner":"token:81b637d8fcd2c6da"}}`)
+
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	c.expectClose(websocket.CloseUnsupportedData)
+}
+
+func TestWSUpgradeRequired(t *testing.T) {
+	w := httptest.NewRecorder()
+	setupServer().Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if w.Code != http.StatusUpgradeRequired || w.Header().Get("Upgrade") != "websocket" {
+		t.Errorf("expected 426 with Upgrade: websocket, got %d %q", w.Code, w.Header().Get("Upgrade"))
+	}
+}
+
+// TestWSSlowClient checks that a client which stops reading is dropped while the others keep
+// getting every event.
+func TestWSSlowClient(t *testing.T) {
+	defer func(timeout time.Duration) { wsWriteTimeout = timeout }(wsWriteTimeout)
+	wsWriteTimeout = 200 * time.Millisecond
+	st := storage.NewStorage()
+	srv := httptest.NewServer(setupServerWith(st).Handler())
+	defer srv.Close()
+
+	slow := dialWS(t, srv.URL, "")
+	slow.send(`{"type":"subscribe"}`)
+	slow.expect(`{"type":"subscribed","tasks":[]}`)
+	fast := dialWS(t, srv.URL, "")
+	fast.send(`{"type":"subscribe"}`)
+	fast.expect(`{"type":"subscribed","tasks":[]}`)
+
+	description := strings.Repeat("x", 10000)
+	for i := range 4000 {
+		if _, err := st.CreateTask(context.Background(), storage.Task{Header: "Task", Description: description}); err != nil {
+			t.Fatal(err)
+		}
+		if msg := fast.read(); !strings.HasPrefix(msg, `{"type":"event","event":"created","task":{"id":`) {
+			t.Fatalf("event %d: unexpected message %.80s", i, msg)
+		}
+	}
+
+	slow.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := slow.conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			t.Fatal("expected the slow client to be disconnected")
+		}
+		break
+	}
+}
+
+func TestWSKeepAlive(t *testing.T) {
+	defer func(interval time.Duration) { wsPingInterval = interval }(wsPingInterval)
+	wsPingInterval = 50 * time.Millisecond
+	srv := httptest.NewServer(setupServer().Handler())
+	defer srv.Close()
+
+	// A reading client answers the pings and stays connected.
+	live := dialWS(t, srv.URL, "")
+	messages := make(chan string)
+	go func() {
+		defer close(messages)
+		for {
+			_, data, err := live.conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			messages <- string(data)
+		}
+	}()
+	idle := dialWS(t, srv.URL, "")
+
+	time.Sleep(10 * wsPingInterval)
+	live.send(`{"type":"create","id":"1","task":{"header":"Still here"}}`)
+	if msg := <-messages; !strings.HasPrefix(msg, `{"type":"created","id":"1"`) {
+		t.Errorf("expected the live client to be served, got %q", msg)
+	}
+
+	// The idle client never read, so it never answered a ping.
+	idle.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		typ, _, err := idle.conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Error("expected the idle client to be disconnected")
+			}
+			break
+		}
+		t.Errorf("unexpected message of type %d", typ)
+	}
+}
+
+func TestShutdownEndsWS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := setupServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.serve(ctx, ln, nil, DefaultConfig()) }()
+
+	c := dialWS(t, "http://"+ln.Addr().String(), "")
+	c.send(`{"type":"subscribe","id":"s"}`)
+	c.expect(`{"type":"subscribed","id":"s","tasks":[]}`)
+
+	cancel()
+	c.expectClose(websocket.CloseGoingAway)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
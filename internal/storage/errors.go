@@ -5,4 +5,6 @@ import "errors"
 var (
 	ErrTaskNotFound  = errors.New("task is not found")
 	ErrWrongArgument = errors.New("wrong argument")
+	ErrStorageClosed = errors.New("storage is closed")
+	ErrQuotaExceeded = errors.New("task quota exceeded")
 )
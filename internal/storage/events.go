@@ -0,0 +1,100 @@
+package storage
+
+import "sync"
+
+type EventType int
+
+const (
+	EventCreated EventType = iota + 1
+	EventUpdated
+	EventDeleted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCreated:
+		return "created"
+	case EventUpdated:
+		return "updated"
+	case EventDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a committed change; deleted tasks carry their last state.
+type Event struct {
+	Type EventType
+	Task Task
+}
+
+// Broker fans committed changes out to subscribers. Backends call Publish in commit order,
+// usually while still holding the lock that orders their writes.
+type Broker struct {
+	mu          sync.Mutex
+	closed      bool
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	events chan Event
+}
+
+// Subscribe delivers every later change in commit order until cancel is called or the broker
+// is closed. Writers never wait for subscribers: one whose buffer is full is dropped and its
+// channel closed, so a closed channel before cancel means the subscriber fell behind.
+func (b *Broker) Subscribe(buffer int) (events <-chan Event, cancel func()) {
+	sub := &subscriber{events: make(chan Event, buffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.events)
+		return sub.events, func() {}
+	}
+	if b.subscribers == nil {
+		b.subscribers = make(map[*subscriber]struct{})
+	}
+	b.subscribers[sub] = struct{}{}
+
+	return sub.events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.unsubscribe(sub)
+	}
+}
+
+func (b *Broker) Publish(typ EventType, task Task) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- Event{Type: typ, Task: task}:
+		default:
+			b.unsubscribe(sub)
+		}
+	}
+}
+
+// Close ends every subscription; later subscribers get a closed channel.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subscribers {
+		b.unsubscribe(sub)
+	}
+}
+
+func (b *Broker) unsubscribe(sub *subscriber) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscribe delivers the changes of the storage; see Broker.Subscribe.
+func (s *Storage) Subscribe(buffer int) (events <-chan Event, cancel func()) {
+	return s.events.Subscribe(buffer)
+}
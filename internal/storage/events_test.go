@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	s := NewStorage()
+	events, cancel := s.Subscribe(10)
+	defer cancel()
+
+	created, _ := s.CreateTask(ctx, Task{Header: "Task"})
+	_, _ = s.Update(ctx, created.TaskID, &Task{Header: "Renamed", Status: Completed})
+	_ = s.Delete(ctx, created.TaskID)
+	_ = s.Delete(ctx, created.TaskID)
+
+	want := []Event{
+		{Type: EventCreated, Task: Task{TaskID: 0, Header: "Task"}},
+		{Type: EventUpdated, Task: Task{TaskID: 0, Header: "Renamed", Status: Completed}},
+		{Type: EventDeleted, Task: Task{TaskID: 0, Header: "Renamed", Status: Completed}},
+	}
+	for i, w := range want {
+		select {
+		case got := <-events:
+			if got != w {
+				t.Errorf("event %d: expected %+v, got %+v", i, w, got)
+			}
+		default:
+			t.Fatalf("event %d: expected %+v, got nothing", i, w)
+		}
+	}
+	select {
+	case got := <-events:
+		t.Errorf("unexpected event %+v for a failed delete", got)
+	default:
+	}
+}
+
+func TestSubscribeDropsSlowSubscribers(t *testing.T) {
+	ctx := context.Background()
+	s := NewStorage()
+	slow, cancelSlow := s.Subscribe(1)
+	defer cancelSlow()
+	fast, cancelFast := s.Subscribe(10)
+	defer cancelFast()
+
+	_, _ = s.CreateTask(ctx, Task{Header: "First"})
+	_, _ = s.CreateTask(ctx, Task{Header: "Second"})
+
+	if got := <-slow; got.Task.Header != "First" {
+		t.Errorf("expected the buffered event, got %+v", got)
+	}
+	if _, ok := <-slow; ok {
+		t.Error("expected the slow subscriber to be dropped")
+	}
+	if len(fast) != 2 {
+		t.Errorf("expected the other subscriber to get both events, got %d", len(fast))
+	}
+}
+
+func TestSubscriptionsEndOnClose(t *testing.T) {
+	s := NewStorage()
+	events, cancel := s.Subscribe(1)
+	_ = s.Close()
+	if _, ok := <-events; ok {
+		t.Error("expected the channel to be closed")
+	}
+	cancel()
+
+	late, cancel := s.Subscribe(1)
+	defer cancel()
+	if _, ok := <-late; ok {
+		t.Error("expected subscriptions after Close to be closed")
+	}
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+)
+
+// Filter selects tasks by all of its conditions; the zero Filter matches every task.
+type Filter struct {
+	Statuses []TaskStatus
+	Owner    string
+	// Query matches a case-insensitive substring of the header or the description.
+	Query string
+}
+
+func (f Filter) Match(task Task) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, task.Status) {
+		return false
+	}
+	if f.Owner != "" && task.Owner != f.Owner {
+		return false
+	}
+	return f.Query == "" || strings.Contains(SearchText(task), strings.ToLower(f.Query))
+}
+
+// SearchText is the text Query is matched against.
+func SearchText(task Task) string {
+	return strings.ToLower(task.Header + "\n" + task.Description)
+}
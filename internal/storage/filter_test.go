@@ -0,0 +1,31 @@
+package storage
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	task := Task{Header: "Buy Milk", Description: "two bottles", Status: InProgress, Owner: "alice"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"zero filter", Filter{}, true},
+		{"status", Filter{Statuses: []TaskStatus{Assigned, InProgress}}, true},
+		{"other status", Filter{Statuses: []TaskStatus{Completed}}, false},
+		{"owner", Filter{Owner: "alice"}, true},
+		{"other owner", Filter{Owner: "bob"}, false},
+		{"query in header ignores case", Filter{Query: "milk"}, true},
+		{"query in description", Filter{Query: "BOTTLES"}, true},
+		{"query across fields", Filter{Query: "milk two"}, false},
+		{"all conditions", Filter{Statuses: []TaskStatus{InProgress}, Owner: "alice", Query: "buy"}, true},
+		{"one condition fails", Filter{Statuses: []TaskStatus{InProgress}, Owner: "alice", Query: "tea"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(task); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration is one step of the schema. Versions start at 1 and increase by one; a released
+// migration is never edited, a new one is appended instead.
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// migrations is the schema, written in the SQLite dialect.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create tasks",
+		Statements: []string{
+			`CREATE TABLE tasks (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				header      TEXT    NOT NULL,
+				description TEXT    NOT NULL DEFAULT '',
+				status      INTEGER NOT NULL DEFAULT 0,
+				owner       TEXT    NOT NULL DEFAULT '',
+				search      TEXT    NOT NULL DEFAULT ''
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "index filters",
+		Statements: []string{
+			`CREATE INDEX tasks_status ON tasks (status, id)`,
+			`CREATE INDEX tasks_owner ON tasks (owner, status)`,
+			// The trigram index answers substring queries of three or more characters; it reads
+			// the search column of tasks and is kept current by the triggers.
+			`CREATE VIRTUAL TABLE tasks_search USING fts5 (search, content = 'tasks', content_rowid = 'id', tokenize = 'trigram')`,
+			`CREATE TRIGGER tasks_search_insert AFTER INSERT ON tasks BEGIN
+				INSERT INTO tasks_search (rowid, search) VALUES (new.id, new.search);
+			END`,
+			`CREATE TRIGGER tasks_search_delete AFTER DELETE ON tasks BEGIN
+				INSERT INTO tasks_search (tasks_search, rowid, search) VALUES ('delete', old.id, old.search);
+			END`,
+			`CREATE TRIGGER tasks_search_update AFTER UPDATE OF search ON tasks BEGIN
+				INSERT INTO tasks_search (tasks_search, rowid, search) VALUES ('delete', old.id, old.search);
+				INSERT INTO tasks_search (rowid, search) VALUES (new.id, new.search);
+			END`,
+		},
+	},
+}
+
+// LatestVersion is the schema version this build migrates to.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrate brings the schema of db up to LatestVersion and returns the versions it applied.
+// Every migration runs in its own transaction together with its schema_migrations row, so a
+// failed one leaves the database at the previous version. A database migrated by a newer
+// build is refused rather than used with a schema this one does not know.
+func Migrate(ctx context.Context, db *sql.DB) ([]int, error) {
+	return migrate(ctx, db, migrations)
+}
+
+func migrate(ctx context.Context, db *sql.DB, migrations []Migration) ([]int, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if latest := migrations[len(migrations)-1].Version; current > latest {
+		return nil, fmt.Errorf("database schema version %d is newer than %d supported by this build", current, latest)
+	}
+
+	var applied []int
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := apply(ctx, db, m); err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m.Version)
+	}
+	return applied, nil
+}
+
+func apply(ctx context.Context, db *sql.DB, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SchemaVersion returns the version of the last applied migration; Migrate creates the table
+// it reads.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,86 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testDriver is the SQLite driver the tests run against, registered by sqlite_test.go.
+const testDriver = "sqlite"
+
+// testDSN names a fresh database file.
+func testDSN(t *testing.T) string {
+	t.Helper()
+	return filepath.Join(t.TempDir(), "tasks.db")
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(testDriver, testDSN(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	applied, err := Migrate(ctx, db)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if want := []int{1, 2}; !slices.Equal(applied, want) {
+		t.Errorf("applied %v, want %v", applied, want)
+	}
+	applied, err = Migrate(ctx, db)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("second Migrate() = %v, %v; want nothing applied", applied, err)
+	}
+	version, err := SchemaVersion(ctx, db)
+	if err != nil || version != LatestVersion() {
+		t.Errorf("SchemaVersion() = %d, %v; want %d", version, err, LatestVersion())
+	}
+
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (99, 'future', '')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(ctx, db); err == nil {
+		t.Error("Migrate() of a newer schema succeeded, want an error")
+	}
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	steps := []Migration{
+		{Version: 1, Name: "first", Statements: []string{`CREATE TABLE first (id INTEGER)`}},
+		{Version: 2, Name: "broken", Statements: []string{`CREATE TABLE second (id INTEGER)`, `NOT SQL`}},
+	}
+
+	applied, err := migrate(ctx, db, steps)
+	if err == nil {
+		t.Fatal("migrate() succeeded, want an error")
+	}
+	if want := []int{1}; !slices.Equal(applied, want) {
+		t.Errorf("applied %v, want %v", applied, want)
+	}
+	if version, _ := SchemaVersion(ctx, db); version != 1 {
+		t.Errorf("SchemaVersion() = %d, want 1", version)
+	}
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'second'`).Scan(&count)
+	if count != 0 {
+		t.Error("table of the failed migration was kept")
+	}
+
+	steps[1].Statements = steps[1].Statements[:1]
+	if applied, err := migrate(ctx, db, steps); err != nil || !slices.Equal(applied, []int{2}) {
+		t.Errorf("migrate() after the fix = %v, %v; want [2]", applied, err)
+	}
+}
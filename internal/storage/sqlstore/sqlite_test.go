@@ -0,0 +1,3 @@
+package sqlstore
+
+import _ "modernc.org/sqlite"
@@ -0,0 +1,327 @@
+// Package sqlstore keeps tasks in a database/sql database. The SQL is written for SQLite; the
+// driver is registered by the binary, under the name passed to Open.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"todo/internal/storage"
+	"unicode/utf8"
+)
+
+// pingTimeout bounds the calls whose interface has no context.
+const pingTimeout = 5 * time.Second
+
+type Store struct {
+	db     *sql.DB
+	closed atomic.Bool
+	// writeMu orders the write transactions, so events are published in commit order.
+	writeMu sync.Mutex
+	events  storage.Broker
+
+	maxTasksPerOwner int
+}
+
+type Option func(*Store)
+
+// WithMaxTasksPerOwner limits how many tasks a single non-empty Owner may hold; 0 disables the quota.
+func WithMaxTasksPerOwner(n int) Option {
+	return func(s *Store) {
+		s.maxTasksPerOwner = n
+	}
+}
+
+// Open connects to the database and migrates its schema to the latest version.
+func Open(ctx context.Context, driver, dsn string, opts ...Option) (*Store, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; one connection avoids "database is locked" errors without
+	// driver-specific DSN options and keeps a ":memory:" database alive.
+	db.SetMaxOpenConns(1)
+	if _, err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &Store{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// DB returns the underlying database, for example to run Migrate or SchemaVersion.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Store) CreateTask(ctx context.Context, task storage.Task) (*storage.Task, error) {
+	if task.Header == "" {
+		return nil, storage.ErrWrongArgument
+	}
+	err := s.write(ctx, func(tx *sql.Tx) (*storage.Event, error) {
+		if task.Owner != "" && s.maxTasksPerOwner > 0 {
+			var count int
+			err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks WHERE owner = ?`, task.Owner).Scan(&count)
+			if err != nil {
+				return nil, err
+			}
+			if count >= s.maxTasksPerOwner {
+				return nil, storage.ErrQuotaExceeded
+			}
+		}
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO tasks (header, description, status, owner, search) VALUES (?, ?, ?, ?, ?)`,
+			task.Header, task.Description, task.Status, task.Owner, storage.SearchText(task))
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		task.TaskID = int(id)
+		return &storage.Event{Type: storage.EventCreated, Task: task}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Update replaces the header, description and status; the owner is kept.
+func (s *Store) Update(ctx context.Context, id int, updated *storage.Task) (*storage.Task, error) {
+	if updated == nil || updated.Header == "" {
+		return nil, storage.ErrWrongArgument
+	}
+	var task storage.Task
+	err := s.write(ctx, func(tx *sql.Tx) (*storage.Event, error) {
+		var err error
+		task, err = getByID(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		task.Header = updated.Header
+		task.Description = updated.Description
+		task.Status = updated.Status
+		_, err = tx.ExecContext(ctx,
+			`UPDATE tasks SET header = ?, description = ?, status = ?, search = ? WHERE id = ?`,
+			task.Header, task.Description, task.Status, storage.SearchText(task), id)
+		return &storage.Event{Type: storage.EventUpdated, Task: task}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (s *Store) Delete(ctx context.Context, id int) error {
+	return s.write(ctx, func(tx *sql.Tx) (*storage.Event, error) {
+		task, err := getByID(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, id)
+		return &storage.Event{Type: storage.EventDeleted, Task: task}, err
+	})
+}
+
+// Import writes tasks in one transaction, replacing the tasks with the same IDs; a task with
+// a negative TaskID gets a new one. It enforces no quota and publishes no events, as it is
+// meant for moving data in before the server starts.
+func (s *Store) Import(ctx context.Context, tasks []storage.Task) error {
+	for _, task := range tasks {
+		if task.Header == "" {
+			return fmt.Errorf("%w: task %d has an empty header", storage.ErrWrongArgument, task.TaskID)
+		}
+	}
+	return s.write(ctx, func(tx *sql.Tx) (*storage.Event, error) {
+		for _, task := range tasks {
+			var id any
+			if task.TaskID >= 0 {
+				id = task.TaskID
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO tasks (id, header, description, status, owner, search)
+				VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (id) DO UPDATE SET header = excluded.header, description = excluded.description,
+					status = excluded.status, owner = excluded.owner, search = excluded.search`,
+				id, task.Header, task.Description, task.Status, task.Owner, storage.SearchText(task))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+}
+
+// write runs fn in a transaction that is committed when fn returns no error, then publishes
+// the event fn returned.
+func (s *Store) write(ctx context.Context, fn func(tx *sql.Tx) (*storage.Event, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.closed.Load() {
+		return storage.ErrStorageClosed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return s.dbError(err)
+	}
+	defer tx.Rollback()
+	event, err := fn(tx)
+	if err != nil {
+		return s.dbError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return s.dbError(err)
+	}
+	if event != nil {
+		s.events.Publish(event.Type, event.Task)
+	}
+	return nil
+}
+
+func (s *Store) GetAll(ctx context.Context) ([]storage.Task, error) {
+	return s.Find(ctx, storage.Filter{})
+}
+
+// Find selects the tasks matching filter, ordered by ID, with the help of the indexes.
+func (s *Store) Find(ctx context.Context, filter storage.Filter) ([]storage.Task, error) {
+	if s.closed.Load() {
+		return nil, storage.ErrStorageClosed
+	}
+
+	var where []string
+	var args []any
+	if len(filter.Statuses) > 0 {
+		where = append(where, "status IN (?"+strings.Repeat(", ?", len(filter.Statuses)-1)+")")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter.Owner != "" {
+		where = append(where, "owner = ?")
+		args = append(args, filter.Owner)
+	}
+	if query := strings.ToLower(filter.Query); query != "" {
+		if utf8.RuneCountInString(query) >= 3 {
+			// A quoted FTS5 string is a phrase: its trigrams must follow each other.
+			where = append(where, "id IN (SELECT rowid FROM tasks_search WHERE tasks_search MATCH ?)")
+			args = append(args, `"`+strings.ReplaceAll(query, `"`, `""`)+`"`)
+		} else {
+			// Too short for a trigram; LIKE scans the search column instead.
+			where = append(where, `search LIKE ? ESCAPE '\'`)
+			args = append(args, "%"+likeEscaper.Replace(query)+"%")
+		}
+	}
+
+	query := `SELECT id, header, description, status, owner FROM tasks`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, s.dbError(err)
+	}
+	defer rows.Close()
+
+	tasks := []storage.Task{}
+	for rows.Next() {
+		var task storage.Task
+		if err := rows.Scan(&task.TaskID, &task.Header, &task.Description, &task.Status, &task.Owner); err != nil {
+			return nil, s.dbError(err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, s.dbError(rows.Err())
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *Store) GetByID(ctx context.Context, id int) (storage.Task, error) {
+	if s.closed.Load() {
+		return storage.Task{}, storage.ErrStorageClosed
+	}
+	task, err := getByID(ctx, s.db, id)
+	return task, s.dbError(err)
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func getByID(ctx context.Context, q querier, id int) (storage.Task, error) {
+	task := storage.Task{TaskID: id}
+	err := q.QueryRowContext(ctx, `SELECT header, description, status, owner FROM tasks WHERE id = ?`, id).
+		Scan(&task.Header, &task.Description, &task.Status, &task.Owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Task{}, storage.ErrTaskNotFound
+	}
+	return task, err
+}
+
+// CountByStatus returns no counts when the database cannot be read.
+func (s *Store) CountByStatus() map[storage.TaskStatus]int {
+	counts := make(map[storage.TaskStatus]int)
+	if s.closed.Load() {
+		return counts
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM tasks GROUP BY status`)
+	if err != nil {
+		return counts
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status storage.TaskStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return make(map[storage.TaskStatus]int)
+		}
+		counts[status] = count
+	}
+	return counts
+}
+
+// Subscribe delivers the committed changes; see storage.Broker.Subscribe.
+func (s *Store) Subscribe(buffer int) (events <-chan storage.Event, cancel func()) {
+	return s.events.Subscribe(buffer)
+}
+
+func (s *Store) Ping() error {
+	if s.closed.Load() {
+		return storage.ErrStorageClosed
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	return s.db.PingContext(ctx)
+}
+
+// Close waits for the running write, ends the subscriptions and closes the database.
+func (s *Store) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.closed.Swap(true) {
+		return nil
+	}
+	s.events.Close()
+	return s.db.Close()
+}
+
+// dbError reports the failure of a call that raced with Close as ErrStorageClosed.
+func (s *Store) dbError(err error) error {
+	if err != nil && s.closed.Load() {
+		return storage.ErrStorageClosed
+	}
+	return err
+}
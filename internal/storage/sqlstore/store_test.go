@@ -0,0 +1,266 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"todo/internal/storage"
+)
+
+func openStore(t *testing.T, opts ...Option) *Store {
+	t.Helper()
+	s, err := Open(context.Background(), testDriver, testDSN(t), opts...)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStoreCRUD(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+
+	if _, err := s.CreateTask(ctx, storage.Task{}); !errors.Is(err, storage.ErrWrongArgument) {
+		t.Errorf("CreateTask() without header error = %v, want ErrWrongArgument", err)
+	}
+	created, err := s.CreateTask(ctx, storage.Task{Header: "Buy milk", Description: "2 l", Owner: "alice"})
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	got, err := s.GetByID(ctx, created.TaskID)
+	if err != nil || got != *created {
+		t.Errorf("GetByID() = %+v, %v; want %+v", got, err, *created)
+	}
+
+	updated, err := s.Update(ctx, created.TaskID, &storage.Task{Header: "Buy tea", Status: storage.Completed, Owner: "bob"})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	want := storage.Task{TaskID: created.TaskID, Header: "Buy tea", Status: storage.Completed, Owner: "alice"}
+	if *updated != want {
+		t.Errorf("Update() = %+v, want %+v", *updated, want)
+	}
+	if _, err := s.Update(ctx, 999, &storage.Task{Header: "x"}); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("Update() of a missing task error = %v, want ErrTaskNotFound", err)
+	}
+	if _, err := s.Update(ctx, created.TaskID, &storage.Task{}); !errors.Is(err, storage.ErrWrongArgument) {
+		t.Errorf("Update() without header error = %v, want ErrWrongArgument", err)
+	}
+
+	if err := s.Delete(ctx, created.TaskID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Delete(ctx, created.TaskID); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrTaskNotFound", err)
+	}
+	if _, err := s.GetByID(ctx, created.TaskID); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("GetByID() of a deleted task error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestStoreQuota(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t, WithMaxTasksPerOwner(2))
+
+	for i := range 2 {
+		if _, err := s.CreateTask(ctx, storage.Task{Header: fmt.Sprint(i), Owner: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.CreateTask(ctx, storage.Task{Header: "over", Owner: "alice"}); !errors.Is(err, storage.ErrQuotaExceeded) {
+		t.Errorf("CreateTask() over quota error = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := s.CreateTask(ctx, storage.Task{Header: "other", Owner: "bob"}); err != nil {
+		t.Errorf("CreateTask() of another owner error = %v", err)
+	}
+	if _, err := s.CreateTask(ctx, storage.Task{Header: "anonymous"}); err != nil {
+		t.Errorf("CreateTask() without owner error = %v", err)
+	}
+}
+
+// TestStoreFind checks that the SQL filters select the same tasks as storage.Filter.Match.
+func TestStoreFind(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+	tasks := []storage.Task{
+		{Header: "Buy milk", Description: "two bottles", Status: storage.Assigned, Owner: "alice"},
+		{Header: "Купить МОЛОКО", Status: storage.InProgress, Owner: "alice"},
+		{Header: "Fix 100% of bugs", Description: `say "done"`, Status: storage.Completed, Owner: "bob"},
+		{Header: "snake_case", Status: storage.Dropped},
+		{Header: "milk", Description: "Chocolate", Status: storage.InProgress, Owner: "bob"},
+	}
+	for _, task := range tasks {
+		if _, err := s.CreateTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := s.GetAll(ctx)
+	if err != nil || len(all) != len(tasks) {
+		t.Fatalf("GetAll() = %d tasks, %v; want %d", len(all), err, len(tasks))
+	}
+
+	filters := []storage.Filter{
+		{},
+		{Statuses: []storage.TaskStatus{storage.InProgress}},
+		{Statuses: []storage.TaskStatus{storage.Assigned, storage.Completed}},
+		{Owner: "alice"},
+		{Owner: "nobody"},
+		{Query: "milk"},
+		{Query: "молоко"},
+		{Query: "MI"},
+		{Query: "%"},
+		{Query: "_"},
+		{Query: "100%"},
+		{Query: `"done"`},
+		{Query: "milk two"},
+		{Query: "late"},
+		{Statuses: []storage.TaskStatus{storage.InProgress}, Owner: "bob", Query: "milk"},
+	}
+	for _, filter := range filters {
+		t.Run(fmt.Sprintf("%+v", filter), func(t *testing.T) {
+			var want []int
+			for _, task := range all {
+				if filter.Match(task) {
+					want = append(want, task.TaskID)
+				}
+			}
+			found, err := s.Find(ctx, filter)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			var got []int
+			for _, task := range found {
+				got = append(got, task.TaskID)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("Find() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	if _, err := s.Update(ctx, all[0].TaskID, &storage.Task{Header: "Buy bread"}); err != nil {
+		t.Fatal(err)
+	}
+	found, _ := s.Find(ctx, storage.Filter{Query: "bread"})
+	if len(found) != 1 || found[0].TaskID != all[0].TaskID {
+		t.Errorf("Find() after an update = %+v, want task %d", found, all[0].TaskID)
+	}
+	if err := s.Delete(ctx, all[0].TaskID); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := s.Find(ctx, storage.Filter{Query: "bread"}); len(found) != 0 {
+		t.Errorf("Find() after a delete = %+v, want none", found)
+	}
+}
+
+func TestStoreEvents(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+	events, cancel := s.Subscribe(10)
+	defer cancel()
+
+	created, _ := s.CreateTask(ctx, storage.Task{Header: "a"})
+	s.Update(ctx, created.TaskID, &storage.Task{Header: "b"})
+	s.Update(ctx, 999, &storage.Task{Header: "c"})
+	s.Delete(ctx, created.TaskID)
+
+	want := []storage.Event{
+		{Type: storage.EventCreated, Task: storage.Task{TaskID: created.TaskID, Header: "a"}},
+		{Type: storage.EventUpdated, Task: storage.Task{TaskID: created.TaskID, Header: "b"}},
+		{Type: storage.EventDeleted, Task: storage.Task{TaskID: created.TaskID, Header: "b"}},
+	}
+	for _, w := range want {
+		if got := <-events; got != w {
+			t.Errorf("event = %+v, want %+v", got, w)
+		}
+	}
+	select {
+	case got := <-events:
+		t.Errorf("unexpected event %+v", got)
+	default:
+	}
+}
+
+func TestStoreImport(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+	s.CreateTask(ctx, storage.Task{Header: "replaced"})
+
+	err := s.Import(ctx, []storage.Task{
+		{TaskID: 0, Header: "zero", Owner: "alice"},
+		{TaskID: 1, Header: "one", Status: storage.Completed},
+		{TaskID: 7, Header: "seven"},
+		{TaskID: -1, Header: "new"},
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	all, _ := s.GetAll(ctx)
+	var got []string
+	for _, task := range all {
+		got = append(got, fmt.Sprintf("%d:%s", task.TaskID, task.Header))
+	}
+	if want := []string{"0:zero", "1:one", "7:seven", "8:new"}; !slices.Equal(got, want) {
+		t.Errorf("tasks after Import() = %v, want %v", got, want)
+	}
+	if found, _ := s.Find(ctx, storage.Filter{Query: "seven"}); len(found) != 1 {
+		t.Errorf("imported task is not in the search index: %+v", found)
+	}
+	created, _ := s.CreateTask(ctx, storage.Task{Header: "next"})
+	if created.TaskID != 9 {
+		t.Errorf("CreateTask() after Import() got ID %d, want 9", created.TaskID)
+	}
+
+	err = s.Import(ctx, []storage.Task{{TaskID: 20, Header: "valid"}, {TaskID: 21}})
+	if !errors.Is(err, storage.ErrWrongArgument) {
+		t.Errorf("Import() with an empty header error = %v, want ErrWrongArgument", err)
+	}
+	if _, err := s.GetByID(ctx, 20); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("failed Import() kept task 20: %v", err)
+	}
+}
+
+func TestStoreCountByStatus(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+	for _, status := range []storage.TaskStatus{storage.Assigned, storage.Completed, storage.Completed} {
+		s.CreateTask(ctx, storage.Task{Header: "task", Status: status})
+	}
+	want := map[storage.TaskStatus]int{storage.Assigned: 1, storage.Completed: 2}
+	if got := s.CountByStatus(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("CountByStatus() = %v, want %v", got, want)
+	}
+}
+
+func TestStoreClose(t *testing.T) {
+	ctx := context.Background()
+	s := openStore(t)
+	created, _ := s.CreateTask(ctx, storage.Task{Header: "a"})
+	events, cancel := s.Subscribe(1)
+	defer cancel()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("subscription is open after Close()")
+	}
+	if _, err := s.CreateTask(ctx, storage.Task{Header: "b"}); !errors.Is(err, storage.ErrStorageClosed) {
+		t.Errorf("CreateTask() error = %v, want ErrStorageClosed", err)
+	}
+	if _, err := s.GetByID(ctx, created.TaskID); !errors.Is(err, storage.ErrStorageClosed) {
+		t.Errorf("GetByID() error = %v, want ErrStorageClosed", err)
+	}
+	if _, err := s.GetAll(ctx); !errors.Is(err, storage.ErrStorageClosed) {
+		t.Errorf("GetAll() error = %v, want ErrStorageClosed", err)
+	}
+	if err := s.Ping(); !errors.Is(err, storage.ErrStorageClosed) {
+		t.Errorf("Ping() error = %v, want ErrStorageClosed", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
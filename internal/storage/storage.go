@@ -1,56 +1,112 @@
 package storage
 
 import (
+	"context"
 	"sync"
 )
 
 type Storage struct {
 	counter int
+	closed  bool
 	mutex   sync.RWMutex
 	tasks   map[int]Task
+
+	maxTasksPerOwner int
+	ownerCounts      map[string]int
+
+	events Broker
 }
 
-func NewStorage() *Storage {
-	return &Storage{
-		counter: 0,
-		tasks:   make(map[int]Task),
+type Option func(*Storage)
+
+// WithMaxTasksPerOwner limits how many tasks a single non-empty Owner may hold; 0 disables the quota.
+func WithMaxTasksPerOwner(n int) Option {
+	return func(s *Storage) {
+		s.maxTasksPerOwner = n
 	}
 }
 
-func (s *Storage) CreateTask(task Task) (*Task, error) {
+func NewStorage(opts ...Option) *Storage {
+	s := &Storage{
+		counter:     0,
+		tasks:       make(map[int]Task),
+		ownerCounts: make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Storage) CreateTask(ctx context.Context, task Task) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if task.Header == "" {
 		return nil, ErrWrongArgument
 	}
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
+
+	if task.Owner != "" && s.maxTasksPerOwner > 0 && s.ownerCounts[task.Owner] >= s.maxTasksPerOwner {
+		return nil, ErrQuotaExceeded
+	}
 
 	task.TaskID = s.counter
 	s.counter++
 	s.tasks[task.TaskID] = task
+	if task.Owner != "" {
+		s.ownerCounts[task.Owner]++
+	}
+	s.events.Publish(EventCreated, task)
 
 	return &task, nil
 }
 
-func (s *Storage) Delete(id int) error {
+func (s *Storage) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrStorageClosed
+	}
 
-	if _, exists := s.tasks[id]; !exists {
+	task, exists := s.tasks[id]
+	if !exists {
 		return ErrTaskNotFound
 	}
 
 	delete(s.tasks, id)
+	if task.Owner != "" {
+		s.ownerCounts[task.Owner]--
+		if s.ownerCounts[task.Owner] == 0 {
+			delete(s.ownerCounts, task.Owner)
+		}
+	}
+	s.events.Publish(EventDeleted, task)
 	return nil
 }
 
-func (s *Storage) Update(id int, updated *Task) (*Task, error) {
+func (s *Storage) Update(ctx context.Context, id int, updated *Task) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if updated.Header == "" {
 		return nil, ErrWrongArgument
 	}
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if s.closed {
+		return nil, ErrStorageClosed
+	}
 	task, exists := s.tasks[id]
 	if !exists {
 		return nil, ErrTaskNotFound
@@ -59,11 +115,16 @@ func (s *Storage) Update(id int, updated *Task) (*Task, error) {
 	task.Description = updated.Description
 	task.Status = updated.Status
 	s.tasks[id] = task
+	s.events.Publish(EventUpdated, task)
 
 	return &task, nil
 }
 
-func (s *Storage) GetAll() []Task {
+func (s *Storage) GetAll(ctx context.Context) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
@@ -72,10 +133,26 @@ func (s *Storage) GetAll() []Task {
 		result = append(result, task)
 	}
 
+	return result, nil
+}
+
+func (s *Storage) CountByStatus() map[TaskStatus]int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make(map[TaskStatus]int, len(Statuses))
+	for _, task := range s.tasks {
+		result[task.Status]++
+	}
+
 	return result
 }
 
-func (s *Storage) GetByID(id int) (Task, error) {
+func (s *Storage) GetByID(ctx context.Context, id int) (Task, error) {
+	if err := ctx.Err(); err != nil {
+		return Task{}, err
+	}
+
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 	task, exists := s.tasks[id]
@@ -84,3 +161,23 @@ func (s *Storage) GetByID(id int) (Task, error) {
 	}
 	return task, nil
 }
+
+func (s *Storage) Ping() error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.closed {
+		return ErrStorageClosed
+	}
+	return nil
+}
+
+// Close flushes pending writes and rejects further mutations.
+// The in-memory backend has nothing to flush, so it only marks itself closed and ends
+// the subscriptions.
+func (s *Storage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	s.events.Close()
+	return nil
+}
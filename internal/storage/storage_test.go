@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"testing"
 )
@@ -9,7 +10,7 @@ func TestCreate(t *testing.T) {
 	storage := NewStorage()
 
 	task := Task{Header: "Test", Description: "Description"}
-	_, err := storage.CreateTask(task)
+	_, err := storage.CreateTask(context.Background(), task)
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -20,7 +21,7 @@ func TestCreateEmptyTitle(t *testing.T) {
 	storage := NewStorage()
 
 	task := Task{Header: "", Description: "Description"}
-	_, err := storage.CreateTask(task)
+	_, err := storage.CreateTask(context.Background(), task)
 
 	if !errors.Is(err, ErrWrongArgument) {
 		t.Errorf("expected ErrEmptyTitle, got %v", err)
@@ -30,9 +31,9 @@ func TestCreateEmptyTitle(t *testing.T) {
 func TestGetByID(t *testing.T) {
 	storage := NewStorage()
 	task := Task{Header: "Test"}
-	created, _ := storage.CreateTask(task)
+	created, _ := storage.CreateTask(context.Background(), task)
 
-	found, err := storage.GetByID(created.TaskID)
+	found, err := storage.GetByID(context.Background(), created.TaskID)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -44,7 +45,7 @@ func TestGetByID(t *testing.T) {
 func TestGetByIDNotFound(t *testing.T) {
 	storage := NewStorage()
 
-	_, err := storage.GetByID(999)
+	_, err := storage.GetByID(context.Background(), 999)
 	if !errors.Is(err, ErrTaskNotFound) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
@@ -53,9 +54,9 @@ func TestGetByIDNotFound(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	storage := NewStorage()
 	task := Task{Header: "Original"}
-	created, _ := storage.CreateTask(task)
+	created, _ := storage.CreateTask(context.Background(), task)
 
-	updated, err := storage.Update(created.TaskID, &Task{Header: "Updated", Status: 0})
+	updated, err := storage.Update(context.Background(), created.TaskID, &Task{Header: "Updated", Status: 0})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -67,14 +68,14 @@ func TestUpdate(t *testing.T) {
 func TestDelete(t *testing.T) {
 	storage := NewStorage()
 	task := Task{Header: "Test"}
-	created, _ := storage.CreateTask(task)
+	created, _ := storage.CreateTask(context.Background(), task)
 
-	err := storage.Delete(created.TaskID)
+	err := storage.Delete(context.Background(), created.TaskID)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	_, err = storage.GetByID(created.TaskID)
+	_, err = storage.GetByID(context.Background(), created.TaskID)
 	if !errors.Is(err, ErrTaskNotFound) {
 		t.Errorf("expected ErrNotFound after delete, got %v", err)
 	}
@@ -96,7 +97,7 @@ func TestCreateWithStatus(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			task := Task{Header: "Test", Description: "Description", Status: tt.status}
-			created, err := s.CreateTask(task)
+			created, err := s.CreateTask(context.Background(), task)
 
 			if err != nil {
 				t.Fatalf("expected no error, got %v", err)
@@ -111,7 +112,7 @@ func TestCreateWithStatus(t *testing.T) {
 func TestUpdateStatus(t *testing.T) {
 	s := NewStorage()
 	task := Task{Header: "Test", Status: Assigned}
-	created, _ := s.CreateTask(task)
+	created, _ := s.CreateTask(context.Background(), task)
 
 	tests := []struct {
 		name      string
@@ -125,7 +126,7 @@ func TestUpdateStatus(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			updated, err := s.Update(created.TaskID, &Task{Header: "Test", Status: tt.newStatus})
+			updated, err := s.Update(context.Background(), created.TaskID, &Task{Header: "Test", Status: tt.newStatus})
 			if err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
@@ -147,13 +148,13 @@ func TestGetAllWithDifferentStatuses(t *testing.T) {
 	}
 
 	for _, task := range tasks {
-		_, err := s.CreateTask(task)
+		_, err := s.CreateTask(context.Background(), task)
 		if err != nil {
 			t.Fatalf("failed to create task: %v", err)
 		}
 	}
 
-	all := s.GetAll()
+	all, _ := s.GetAll(context.Background())
 	if len(all) != 4 {
 		t.Errorf("expected 4 tasks, got %d", len(all))
 	}
@@ -172,9 +173,9 @@ func TestGetAllWithDifferentStatuses(t *testing.T) {
 func TestUpdateStatusOtherFields(t *testing.T) {
 	s := NewStorage()
 	task := Task{Header: "Original", Description: "Original Description", Status: Assigned}
-	created, _ := s.CreateTask(task)
+	created, _ := s.CreateTask(context.Background(), task)
 
-	updated, err := s.Update(created.TaskID, &Task{
+	updated, err := s.Update(context.Background(), created.TaskID, &Task{
 		Header:      "Original",
 		Description: "Original Description",
 		Status:      Completed,
@@ -193,3 +194,161 @@ func TestUpdateStatusOtherFields(t *testing.T) {
 		t.Errorf("expected status Completed, got %d", updated.Status)
 	}
 }
+
+func TestMutationsAfterClose(t *testing.T) {
+	s := NewStorage()
+	created, _ := s.CreateTask(context.Background(), Task{Header: "Test"})
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := s.CreateTask(context.Background(), Task{Header: "Late"}); !errors.Is(err, ErrStorageClosed) {
+		t.Errorf("expected ErrStorageClosed on create, got %v", err)
+	}
+	if _, err := s.Update(context.Background(), created.TaskID, &Task{Header: "Late"}); !errors.Is(err, ErrStorageClosed) {
+		t.Errorf("expected ErrStorageClosed on update, got %v", err)
+	}
+	if err := s.Delete(context.Background(), created.TaskID); !errors.Is(err, ErrStorageClosed) {
+		t.Errorf("expected ErrStorageClosed on delete, got %v", err)
+	}
+	if _, err := s.GetByID(context.Background(), created.TaskID); err != nil {
+		t.Errorf("expected reads to keep working after close, got %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	s := NewStorage()
+
+	if err := s.Ping(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_ = s.Close()
+	if err := s.Ping(); !errors.Is(err, ErrStorageClosed) {
+		t.Errorf("expected ErrStorageClosed, got %v", err)
+	}
+}
+
+func TestCountByStatus(t *testing.T) {
+	s := NewStorage()
+
+	for _, status := range []TaskStatus{Assigned, Assigned, Completed} {
+		_, _ = s.CreateTask(context.Background(), Task{Header: "Test", Status: status})
+	}
+
+	counts := s.CountByStatus()
+	if counts[Assigned] != 2 || counts[Completed] != 1 || counts[InProgress] != 0 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestTaskStatusString(t *testing.T) {
+	tests := []struct {
+		status   TaskStatus
+		expected string
+	}{
+		{Assigned, "Assigned"},
+		{InProgress, "InProgress"},
+		{Completed, "Completed"},
+		{Dropped, "Dropped"},
+		{TaskStatus(42), "TaskStatus(42)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.String(); got != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, got)
+		}
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	s := NewStorage()
+	created, _ := s.CreateTask(context.Background(), Task{Header: "Test"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.CreateTask(ctx, Task{Header: "Test"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled on create, got %v", err)
+	}
+	if _, err := s.GetByID(ctx, created.TaskID); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled on get, got %v", err)
+	}
+	if _, err := s.GetAll(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled on get all, got %v", err)
+	}
+	if _, err := s.Update(ctx, created.TaskID, &Task{Header: "Test"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled on update, got %v", err)
+	}
+	if err := s.Delete(ctx, created.TaskID); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled on delete, got %v", err)
+	}
+}
+
+func TestOwnerQuota(t *testing.T) {
+	s := NewStorage(WithMaxTasksPerOwner(2))
+	ctx := context.Background()
+
+	first, _ := s.CreateTask(ctx, Task{Header: "1", Owner: "alice"})
+	if _, err := s.CreateTask(ctx, Task{Header: "2", Owner: "alice"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := s.CreateTask(ctx, Task{Header: "3", Owner: "alice"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := s.CreateTask(ctx, Task{Header: "1", Owner: "bob"}); err != nil {
+		t.Errorf("expected other owners to be unaffected, got %v", err)
+	}
+
+	_ = s.Delete(ctx, first.TaskID)
+	if _, err := s.CreateTask(ctx, Task{Header: "3", Owner: "alice"}); err != nil {
+		t.Errorf("expected quota to be released after delete, got %v", err)
+	}
+}
+
+func TestUpdateKeepsOwner(t *testing.T) {
+	s := NewStorage()
+	created, _ := s.CreateTask(context.Background(), Task{Header: "Test", Owner: "alice"})
+
+	updated, err := s.Update(context.Background(), created.TaskID, &Task{Header: "Updated", Owner: "mallory"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.Owner != "alice" {
+		t.Errorf("expected owner 'alice', got %s", updated.Owner)
+	}
+}
+
+func TestParseTaskStatus(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    TaskStatus
+		wantErr bool
+	}{
+		{"Assigned", Assigned, false},
+		{"inprogress", InProgress, false},
+		{"in-progress", InProgress, false},
+		{"IN_PROGRESS", InProgress, false},
+		{" completed ", Completed, false},
+		{"3", Dropped, false},
+		{"done", 0, true},
+		{"4", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseTaskStatus(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrWrongArgument) {
+					t.Errorf("expected ErrWrongArgument, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("expected %v, got %v (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
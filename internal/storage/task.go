@@ -1,5 +1,11 @@
 package storage
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type TaskStatus int
 
 const (
@@ -9,9 +15,39 @@ const (
 	Dropped
 )
 
+var Statuses = []TaskStatus{Assigned, InProgress, Completed, Dropped}
+
+func (s TaskStatus) String() string {
+	switch s {
+	case Assigned:
+		return "Assigned"
+	case InProgress:
+		return "InProgress"
+	case Completed:
+		return "Completed"
+	case Dropped:
+		return "Dropped"
+	default:
+		return "TaskStatus(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
 type Task struct {
 	TaskID      int
 	Header      string
 	Description string
 	Status      TaskStatus
+	Owner       string
+}
+
+// ParseTaskStatus accepts a status name in any case, with optional "-", "_" or space
+// separators ("in-progress"), or its numeric value.
+func ParseTaskStatus(s string) (TaskStatus, error) {
+	name := strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.TrimSpace(s))
+	for _, status := range Statuses {
+		if strings.EqualFold(name, status.String()) || name == strconv.Itoa(int(status)) {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown task status %q", ErrWrongArgument, s)
 }
@@ -0,0 +1,212 @@
+package taskio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"todo/internal/storage"
+)
+
+// CSVColumns is the export column order; it only ever grows at the end.
+var CSVColumns = []string{"TaskID", "Header", "Description", "Status", "Owner"}
+
+var csvAliases = map[string]string{
+	"taskid": "TaskID", "task_id": "TaskID", "id": "TaskID",
+	"header": "Header", "title": "Header", "name": "Header", "summary": "Header",
+	"description": "Description", "desc": "Description", "notes": "Description", "details": "Description",
+	"status": "Status", "state": "Status",
+	"owner": "Owner",
+}
+
+// ErrMissingHeader is returned when the CSV has no column mapped to Header.
+var ErrMissingHeader = errors.New("no column is mapped to Header")
+
+// formulaPrefixes start cells that spreadsheets evaluate as formulas.
+const formulaPrefixes = "=+-@\t\r"
+
+// WriteCSV writes a header row and one row per task, flushing every 100 rows so large
+// exports stream. Text cells that a spreadsheet would run as a formula get a leading quote,
+// which ReadCSV removes.
+func WriteCSV(w io.Writer, tasks []storage.Task) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+	for i, task := range tasks {
+		row := []string{
+			strconv.Itoa(task.TaskID),
+			escapeFormula(task.Header),
+			escapeFormula(task.Description),
+			task.Status.String(),
+			escapeFormula(task.Owner),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+		if i%100 == 99 {
+			cw.Flush()
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func unescapeFormula(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// CSVReader maps CSV columns to task fields by header name. Columns maps header names to
+// field names and takes precedence over the built-in aliases (title, notes, state, ...).
+type CSVReader struct {
+	Columns map[string]string
+	// KeepOwner imports Owner columns, which are otherwise recognized but skipped; only for
+	// trusted files such as a snapshot moved to another storage.
+	KeepOwner bool
+}
+
+// Read returns one record per data row and the header names that were not mapped to a field.
+func (c CSVReader) Read(r io.Reader) ([]Record, []string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("%w: the file is empty", ErrMissingHeader)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	fields, ignored, err := c.mapHeader(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []Record
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if isBlank(row) {
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+		rec := Record{Line: line}
+		for i, value := range row {
+			if i >= len(fields) {
+				rec.fail("", fmt.Sprintf("row has %d columns, the header has %d", len(row), len(header)))
+				break
+			}
+			if fields[i] == "Owner" && !c.KeepOwner {
+				continue
+			}
+			setField(&rec, fields[i], value)
+		}
+		records = append(records, rec)
+	}
+	return records, ignored, nil
+}
+
+func (c CSVReader) mapHeader(header []string) ([]string, []string, error) {
+	explicit := make(map[string]string, len(c.Columns))
+	for column, field := range c.Columns {
+		if !validField(field) {
+			return nil, nil, fmt.Errorf("cannot map column %q to unknown field %q", column, field)
+		}
+		explicit[strings.ToLower(strings.TrimSpace(column))] = field
+	}
+
+	fields := make([]string, len(header))
+	seen := map[string]bool{}
+	var ignored []string
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		field, ok := explicit[key]
+		if !ok {
+			field, ok = csvAliases[key]
+		}
+		if !ok {
+			ignored = append(ignored, name)
+			continue
+		}
+		if seen[field] {
+			return nil, nil, fmt.Errorf("more than one column is mapped to %s", field)
+		}
+		seen[field] = true
+		fields[i] = field
+	}
+	if !seen["Header"] {
+		return nil, nil, ErrMissingHeader
+	}
+	return fields, ignored, nil
+}
+
+func validField(field string) bool {
+	for _, f := range CSVColumns {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func setField(rec *Record, field, value string) {
+	switch field {
+	case "TaskID":
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 0 {
+			rec.fail("TaskID", "must be a non-negative integer")
+			return
+		}
+		rec.Task.TaskID = id
+		rec.HasID = true
+	case "Header":
+		rec.Task.Header = unescapeFormula(value)
+	case "Description":
+		rec.Task.Description = strings.ReplaceAll(unescapeFormula(value), "\r\n", "\n")
+	case "Status":
+		if strings.TrimSpace(value) == "" {
+			return
+		}
+		status, err := storage.ParseTaskStatus(value)
+		if err != nil {
+			rec.fail("Status", "must be Assigned, InProgress, Completed or Dropped")
+			return
+		}
+		rec.Task.Status = status
+	case "Owner":
+		rec.Task.Owner = unescapeFormula(value)
+	}
+}
+
+func isBlank(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
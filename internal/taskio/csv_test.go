@@ -0,0 +1,159 @@
+package taskio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+func TestWriteCSV(t *testing.T) {
+	tasks := []storage.Task{
+		{TaskID: 1, Header: "Buy milk", Description: "2 litres,\nskimmed", Status: storage.InProgress, Owner: "ip:10.0.0.1"},
+		{TaskID: 2, Header: "=HYPERLINK(\"http://evil\")", Status: storage.Dropped},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "TaskID,Header,Description,Status,Owner\n" +
+		"1,Buy milk,\"2 litres,\nskimmed\",InProgress,ip:10.0.0.1\n" +
+		"2,\"'=HYPERLINK(\"\"http://evil\"\")\",,Dropped,\n"
+	if buf.String() != want {
+		t.Errorf("unexpected CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	tasks := []storage.Task{
+		{TaskID: 3, Header: "+1 for the idea", Description: "-minus", Status: storage.Completed},
+		{TaskID: 7, Header: "Plain", Description: "Line 1\nLine 2", Owner: "=token:1"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+	export := buf.String()
+	records, ignored, err := CSVReader{}.Read(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ignored) != 0 {
+		t.Errorf("expected no ignored columns, got %v", ignored)
+	}
+	for i, rec := range records {
+		want := tasks[i]
+		want.Owner = ""
+		if !rec.HasID || rec.Task != want {
+			t.Errorf("row %d: expected %+v, got %+v", i, want, rec.Task)
+		}
+	}
+
+	records, _, err = CSVReader{KeepOwner: true}.Read(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, rec := range records {
+		if rec.Task != tasks[i] {
+			t.Errorf("row %d with KeepOwner: expected %+v, got %+v", i, tasks[i], rec.Task)
+		}
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		reader      CSVReader
+		input       string
+		want        []Record
+		wantIgnored []string
+		wantErr     error
+	}{
+		{
+			name:  "aliases and ignored columns",
+			input: "\ufeffTitle,Notes,State,Priority\nBuy milk,At the store,in-progress,high\n",
+			want: []Record{
+				{Line: 2, Task: storage.Task{Header: "Buy milk", Description: "At the store", Status: storage.InProgress}},
+			},
+			wantIgnored: []string{"Priority"},
+		},
+		{
+			name:   "explicit mapping",
+			reader: CSVReader{Columns: map[string]string{"Task name": "Header", "Title": "Description"}},
+			input:  "Task name,Title\nBuy milk,Details\n",
+			want: []Record{
+				{Line: 2, Task: storage.Task{Header: "Buy milk", Description: "Details"}},
+			},
+		},
+		{
+			name:  "row errors",
+			input: "id,header,status\nx,First,unknown\n\n,Second,\n5,Third,2,extra\n",
+			want: []Record{
+				{Line: 2, Task: storage.Task{Header: "First"}, Errors: []validation.FieldError{
+					{Field: "TaskID", Message: "must be a non-negative integer"},
+					{Field: "Status", Message: "must be Assigned, InProgress, Completed or Dropped"},
+				}},
+				{Line: 4, Task: storage.Task{Header: "Second"}},
+				{Line: 5, Task: storage.Task{TaskID: 5, Header: "Third", Status: storage.Completed}, HasID: true, Errors: []validation.FieldError{
+					{Field: "", Message: "row has 4 columns, the header has 3"},
+				}},
+			},
+		},
+		{
+			name:    "no header column",
+			input:   "Description\nfoo\n",
+			wantErr: ErrMissingHeader,
+		},
+		{
+			name:    "empty file",
+			input:   "",
+			wantErr: ErrMissingHeader,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, ignored, err := tt.reader.Read(strings.NewReader(tt.input))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(records, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, records)
+			}
+			if !reflect.DeepEqual(ignored, tt.wantIgnored) {
+				t.Errorf("expected ignored %v, got %v", tt.wantIgnored, ignored)
+			}
+		})
+	}
+}
+
+func TestCSVReaderInvalidMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader CSVReader
+		input  string
+	}{
+		{"unknown field", CSVReader{Columns: map[string]string{"A": "Priority"}}, "A,Header\n"},
+		{"duplicate field", CSVReader{}, "Title,Header\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := tt.reader.Read(strings.NewReader(tt.input)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
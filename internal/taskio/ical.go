@@ -0,0 +1,251 @@
+package taskio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"todo/internal/storage"
+	"unicode/utf8"
+)
+
+// ErrNotCalendar is returned for input that is not an iCalendar object.
+var ErrNotCalendar = errors.New("input is not an iCalendar object")
+
+const (
+	icalUIDPrefix = "task-"
+	icalUIDSuffix = "@todo"
+	icalLineLimit = 75
+)
+
+var icalStatuses = map[storage.TaskStatus]string{
+	storage.Assigned:   "NEEDS-ACTION",
+	storage.InProgress: "IN-PROCESS",
+	storage.Completed:  "COMPLETED",
+	storage.Dropped:    "CANCELLED",
+}
+
+// ICalUID is the UID of a task in exported calendars; ReadICal maps it back to the task ID.
+func ICalUID(id int) string {
+	return icalUIDPrefix + strconv.Itoa(id) + icalUIDSuffix
+}
+
+func parseICalUID(uid string) (int, bool) {
+	if !strings.HasPrefix(uid, icalUIDPrefix) || !strings.HasSuffix(uid, icalUIDSuffix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(uid, icalUIDPrefix), icalUIDSuffix))
+	return id, err == nil && id >= 0
+}
+
+// ICalWriter renders tasks as RFC 5545 VTODO components. Stamp is written as DTSTAMP.
+type ICalWriter struct {
+	Name  string
+	Stamp time.Time
+}
+
+func (c ICalWriter) Write(w io.Writer, tasks []storage.Task) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//todo//todo server//EN")
+	line("CALSCALE", "GREGORIAN")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeICalText(c.Name))
+	}
+	stamp := c.Stamp.UTC().Format("20060102T150405Z")
+	for _, task := range tasks {
+		line("BEGIN", "VTODO")
+		line("UID", ICalUID(task.TaskID))
+		line("DTSTAMP", stamp)
+		line("SUMMARY", escapeICalText(task.Header))
+		if task.Description != "" {
+			line("DESCRIPTION", escapeICalText(task.Description))
+		}
+		if status, ok := icalStatuses[task.Status]; ok {
+			line("STATUS", status)
+		}
+		if task.Status == storage.Completed {
+			line("PERCENT-COMPLETE", "100")
+		}
+		line("END", "VTODO")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeFolded splits content lines longer than 75 octets without breaking UTF-8 sequences.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := icalLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the limit.
+		limit = icalLineLimit - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// escapeICalText maps every line break, including a bare "\r", to \n: a raw CR would end
+// the content line early for readers that split on it.
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+func unescapeICalText(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			sb.WriteByte('\n')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+type icalLine struct {
+	number int
+	name   string
+	value  string
+}
+
+// unfoldICal joins continuation lines and splits each content line into its name and value;
+// parameters are dropped.
+func unfoldICal(r io.Reader) ([]icalLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var lines []icalLine
+	var current strings.Builder
+	start, number := 0, 0
+	flush := func() error {
+		if current.Len() == 0 {
+			return nil
+		}
+		l, err := splitICalLine(current.String())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", start, err)
+		}
+		l.number = start
+		lines = append(lines, l)
+		current.Reset()
+		return nil
+	}
+
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			current.WriteString(text[1:])
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		start = number
+		current.WriteString(text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func splitICalLine(s string) (icalLine, error) {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if quoted {
+				continue
+			}
+			name, _, _ := strings.Cut(s[:i], ";")
+			return icalLine{name: strings.ToUpper(name), value: s[i+1:]}, nil
+		}
+	}
+	return icalLine{}, fmt.Errorf("%w: malformed content line %q", ErrNotCalendar, s)
+}
+
+// ReadICal returns one record per VTODO. Tasks exported by this server keep their ID through
+// the UID; any other UID produces a new task. Components other than VTODO are skipped.
+func ReadICal(r io.Reader) ([]Record, error) {
+	lines, err := unfoldICal(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || lines[0].name != "BEGIN" || !strings.EqualFold(lines[0].value, "VCALENDAR") {
+		return nil, ErrNotCalendar
+	}
+
+	var records []Record
+	var rec *Record
+	depth := 0
+	for _, l := range lines {
+		switch {
+		case l.name == "BEGIN":
+			depth++
+			if depth == 2 && strings.EqualFold(l.value, "VTODO") {
+				rec = &Record{Line: l.number}
+			}
+		case l.name == "END":
+			if depth == 2 && rec != nil {
+				records = append(records, *rec)
+				rec = nil
+			}
+			depth--
+		case rec != nil && depth == 2:
+			setICalProperty(rec, l)
+		}
+	}
+	if depth != 0 || rec != nil {
+		return nil, fmt.Errorf("%w: unterminated component", ErrNotCalendar)
+	}
+	return records, nil
+}
+
+func setICalProperty(rec *Record, l icalLine) {
+	switch l.name {
+	case "UID":
+		if id, ok := parseICalUID(l.value); ok {
+			rec.Task.TaskID = id
+			rec.HasID = true
+		}
+	case "SUMMARY":
+		rec.Task.Header = unescapeICalText(l.value)
+	case "DESCRIPTION":
+		rec.Task.Description = unescapeICalText(l.value)
+	case "STATUS":
+		for status, name := range icalStatuses {
+			if strings.EqualFold(l.value, name) {
+				rec.Task.Status = status
+				return
+			}
+		}
+		rec.fail("Status", "must be NEEDS-ACTION, IN-PROCESS, COMPLETED or CANCELLED")
+	}
+}
@@ -0,0 +1,184 @@
+package taskio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"todo/internal/storage"
+	"todo/internal/validation"
+	"unicode/utf8"
+)
+
+func TestICalWriter(t *testing.T) {
+	tasks := []storage.Task{
+		{TaskID: 1, Header: "Buy milk, bread; eggs", Description: `2 litres\n` + "\nskimmed", Status: storage.Completed, Owner: "ip:10.0.0.1"},
+		{TaskID: 2, Header: "Plan", Status: storage.InProgress},
+	}
+
+	var buf bytes.Buffer
+	err := ICalWriter{Name: "Tasks", Stamp: time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("", 3600))}.Write(&buf, tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//todo//todo server//EN\r\n" +
+		"CALSCALE:GREGORIAN\r\n" +
+		"X-WR-CALNAME:Tasks\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:task-1@todo\r\n" +
+		"DTSTAMP:20240501T113000Z\r\n" +
+		"SUMMARY:Buy milk\\, bread\\; eggs\r\n" +
+		"DESCRIPTION:2 litres\\\\n\\nskimmed\r\n" +
+		"STATUS:COMPLETED\r\n" +
+		"PERCENT-COMPLETE:100\r\n" +
+		"END:VTODO\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:task-2@todo\r\n" +
+		"DTSTAMP:20240501T113000Z\r\n" +
+		"SUMMARY:Plan\r\n" +
+		"STATUS:IN-PROCESS\r\n" +
+		"END:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+	if buf.String() != want {
+		t.Errorf("unexpected calendar:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestICalFolding(t *testing.T) {
+	header := strings.Repeat("я", 100)
+	var buf bytes.Buffer
+	if err := (ICalWriter{}).Write(&buf, []storage.Task{{Header: header}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line splits a UTF-8 sequence: %q", line)
+		}
+	}
+
+	records, err := ReadICal(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Task.Header != header {
+		t.Errorf("expected the header to survive folding, got %+v", records)
+	}
+}
+
+func TestEscapeICalText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a, b; c \\ d", `a\, b\; c \\ d`},
+		{"Line 1\nLine 2", `Line 1\nLine 2`},
+		{"Line 1\r\nLine 2", `Line 1\nLine 2`},
+		{"Line 1\rLine 2", `Line 1\nLine 2`},
+		{"\r\r\n\n", `\n\n\n`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeICalText(tt.input); got != tt.expected {
+			t.Errorf("escapeICalText(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (ICalWriter{}).Write(&buf, []storage.Task{{Header: "Buy\rmilk", Description: "a\rb"}}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.ReplaceAll(buf.String(), "\r\n", ""), "\r") {
+		t.Errorf("expected no bare CR in the output, got %q", buf.String())
+	}
+	records, err := ReadICal(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Task.Header != "Buy\nmilk" || records[0].Task.Description != "a\nb" {
+		t.Errorf("expected bare CRs to read back as line feeds, got %+v", records)
+	}
+}
+
+func TestICalRoundTrip(t *testing.T) {
+	tasks := []storage.Task{
+		{TaskID: 3, Header: "A, b; c \\ d", Description: "Line 1\nLine 2", Status: storage.Dropped},
+		{TaskID: 0, Header: "Plain"},
+	}
+
+	var buf bytes.Buffer
+	if err := (ICalWriter{}).Write(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadICal(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, rec := range records {
+		if !rec.HasID || rec.Task != tasks[i] {
+			t.Errorf("component %d: expected %+v, got %+v", i, tasks[i], rec.Task)
+		}
+	}
+}
+
+func TestReadICal(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Record
+		wantErr error
+	}{
+		{
+			name: "foreign calendar",
+			input: "BEGIN:VCALENDAR\nVERSION:2.0\n" +
+				"BEGIN:VEVENT\nSUMMARY:Meeting\nEND:VEVENT\n" +
+				"BEGIN:VTODO\nUID:0f8e@example.com\nSUMMARY;LANGUAGE=en:Write\n  report\n" +
+				"DESCRIPTION;ALTREP=\"cid:part1\":Q2\\, draft\nSTATUS:needs-action\n" +
+				"BEGIN:VALARM\nACTION:DISPLAY\nDESCRIPTION:Reminder\nEND:VALARM\nEND:VTODO\n" +
+				"BEGIN:VTODO\nUID:task-12@todo\nSUMMARY:Known\nSTATUS:DONE\nEND:VTODO\n" +
+				"END:VCALENDAR\n",
+			want: []Record{
+				{Line: 6, Task: storage.Task{Header: "Write report", Description: "Q2, draft"}},
+				{Line: 17, Task: storage.Task{TaskID: 12, Header: "Known"}, HasID: true, Errors: []validation.FieldError{
+					{Field: "Status", Message: "must be NEEDS-ACTION, IN-PROCESS, COMPLETED or CANCELLED"},
+				}},
+			},
+		},
+		{
+			name:    "not a calendar",
+			input:   "TaskID,Header\n1,Buy milk\n",
+			wantErr: ErrNotCalendar,
+		},
+		{
+			name:    "unterminated",
+			input:   "BEGIN:VCALENDAR\nBEGIN:VTODO\nSUMMARY:x\n",
+			wantErr: ErrNotCalendar,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ReadICal(strings.NewReader(tt.input))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(records, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, records)
+			}
+		})
+	}
+}
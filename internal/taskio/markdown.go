@@ -0,0 +1,239 @@
+package taskio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"todo/internal/storage"
+)
+
+var markdownHeadings = map[storage.TaskStatus]string{
+	storage.Assigned:   "Assigned",
+	storage.InProgress: "In progress",
+	storage.Completed:  "Completed",
+	storage.Dropped:    "Dropped",
+}
+
+var (
+	markdownItem    = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)]) +\[([ xX])\](?: +(.*))?$`)
+	markdownList    = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)]) `)
+	markdownHeading = regexp.MustCompile(`^ {0,3}#{1,6}(?: +(.*?))?(?: +#+)? *$`)
+	markdownID      = regexp.MustCompile(` *<!-- *id:(\d+) *-->$`)
+	markdownSpecial = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+		"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "~", `\~`)
+)
+
+// WriteMarkdown renders tasks as checklists grouped under a heading per status. Task IDs are
+// kept in HTML comments, which are invisible once rendered, so the list can be imported back.
+func WriteMarkdown(w io.Writer, tasks []storage.Task) error {
+	bw := bufio.NewWriter(w)
+	first := true
+	for _, status := range storage.Statuses {
+		var group []storage.Task
+		for _, task := range tasks {
+			if task.Status == status {
+				group = append(group, task)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+		if !first {
+			bw.WriteString("\n")
+		}
+		first = false
+		fmt.Fprintf(bw, "## %s\n\n", markdownHeadings[status])
+		for _, task := range group {
+			writeMarkdownItem(bw, task)
+		}
+	}
+	return bw.Flush()
+}
+
+func writeMarkdownItem(w *bufio.Writer, task storage.Task) {
+	box, header := "[ ]", markdownSpecial.Replace(task.Header)
+	switch task.Status {
+	case storage.Completed:
+		box = "[x]"
+	case storage.Dropped:
+		box, header = "[x]", "~~"+header+"~~"
+	}
+	fmt.Fprintf(w, "- %s %s <!-- id:%d -->\n", box, header, task.TaskID)
+
+	if task.Description == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(task.Description, "\r\n", "\n"), "\n") {
+		if line == "" {
+			w.WriteString("\n")
+			continue
+		}
+		// A description line that looks like a list item, heading or code fence would change
+		// how the rest of the file is read.
+		if trimmed := strings.TrimLeft(line, " "); markdownSignificant(trimmed) {
+			line = line[:len(line)-len(trimmed)] + `\` + trimmed
+		}
+		w.WriteString("  " + line + "\n")
+	}
+}
+
+type markdownOpen struct {
+	record int
+	indent int
+	blanks int
+}
+
+// ReadMarkdown turns every checklist item, nested ones included, into a record. The checkbox
+// decides whether the task is closed; a heading naming a status, like those WriteMarkdown
+// writes, or a struck-through item refines it. Indented text under an item that is not a
+// checklist item itself becomes its description; anything else is ignored.
+func ReadMarkdown(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var records []Record
+	var descriptions [][]string
+	var open []markdownOpen
+	var section *storage.TaskStatus
+	fence := ""
+
+	for number := 1; scanner.Scan(); number++ {
+		line := expandIndent(strings.TrimSuffix(scanner.Text(), "\r"))
+		if number == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == len(line) {
+			line = ""
+		}
+
+		if fence == "" {
+			if m := markdownHeading.FindStringSubmatch(line); m != nil {
+				open, section = nil, nil
+				if status, err := storage.ParseTaskStatus(m[1]); err == nil {
+					section = &status
+				}
+				continue
+			}
+			if m := markdownItem.FindStringSubmatch(line); m != nil {
+				for len(open) > 0 && open[len(open)-1].indent > indent {
+					open = open[:len(open)-1]
+				}
+				records = append(records, markdownRecord(number, m[3] != " ", m[4], section))
+				descriptions = append(descriptions, nil)
+				open = append(open, markdownOpen{record: len(records) - 1, indent: indent + len(m[2]) + 1})
+				continue
+			}
+		}
+
+		if line == "" {
+			if len(open) > 0 {
+				open[len(open)-1].blanks++
+			}
+			continue
+		}
+		for len(open) > 0 && open[len(open)-1].indent > indent {
+			open = open[:len(open)-1]
+		}
+		if trimmed := strings.TrimLeft(line, " "); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			switch {
+			case fence == "":
+				fence = trimmed[:3]
+			case strings.HasPrefix(trimmed, fence):
+				fence = ""
+			}
+		}
+		if len(open) == 0 {
+			continue
+		}
+
+		top := &open[len(open)-1]
+		text := line[top.indent:]
+		if trimmed := strings.TrimLeft(text, " "); fence == "" && strings.HasPrefix(trimmed, `\`) && markdownSignificant(trimmed[1:]) {
+			text = text[:len(text)-len(trimmed)] + trimmed[1:]
+		}
+		desc := &descriptions[top.record]
+		if len(*desc) > 0 {
+			for ; top.blanks > 0; top.blanks-- {
+				*desc = append(*desc, "")
+			}
+		}
+		top.blanks = 0
+		*desc = append(*desc, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		records[i].Task.Description = strings.Join(descriptions[i], "\n")
+	}
+	return records, nil
+}
+
+func markdownRecord(number int, checked bool, text string, section *storage.TaskStatus) Record {
+	rec := Record{Line: number}
+	if m := markdownID.FindStringSubmatchIndex(text); m != nil {
+		id, err := strconv.Atoi(text[m[2]:m[3]])
+		if err == nil {
+			rec.Task.TaskID = id
+			rec.HasID = true
+		}
+		text = text[:m[0]]
+	}
+
+	struck := false
+	if len(text) > 4 && strings.HasPrefix(text, "~~") && strings.HasSuffix(text, "~~") {
+		text, struck = text[2:len(text)-2], true
+	}
+	rec.Task.Header = unescapeMarkdown(text)
+
+	switch {
+	case checked && (struck || section != nil && *section == storage.Dropped):
+		rec.Task.Status = storage.Dropped
+	case checked:
+		rec.Task.Status = storage.Completed
+	case section != nil && *section == storage.InProgress:
+		rec.Task.Status = storage.InProgress
+	default:
+		rec.Task.Status = storage.Assigned
+	}
+	return rec
+}
+
+// unescapeMarkdown removes backslashes in front of ASCII punctuation, as CommonMark does.
+func unescapeMarkdown(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", s[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// markdownSignificant reports whether an unindented line starts a list item, heading or code fence.
+func markdownSignificant(line string) bool {
+	return markdownList.MatchString(line) || strings.HasPrefix(line, "#") ||
+		strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")
+}
+
+// expandIndent replaces tabs in the leading whitespace with spaces up to the next multiple of four.
+func expandIndent(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ':
+			sb.WriteByte(' ')
+		case '\t':
+			sb.WriteString(strings.Repeat(" ", 4-sb.Len()%4))
+		default:
+			return sb.String() + s[i:]
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,110 @@
+package taskio
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	tasks := []storage.Task{
+		{TaskID: 1, Header: "Buy *milk*", Description: "At the store\n\n- not a task\n# not a heading", Status: storage.Assigned},
+		{TaskID: 2, Header: "Pay rent", Status: storage.Completed},
+		{TaskID: 3, Header: "Learn Haskell", Status: storage.Dropped},
+		{TaskID: 4, Header: "Call mum", Status: storage.Assigned},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "## Assigned\n\n" +
+		"- [ ] Buy \\*milk\\* <!-- id:1 -->\n" +
+		"  At the store\n" +
+		"\n" +
+		"  \\- not a task\n" +
+		"  \\# not a heading\n" +
+		"- [ ] Call mum <!-- id:4 -->\n" +
+		"\n## Completed\n\n" +
+		"- [x] Pay rent <!-- id:2 -->\n" +
+		"\n## Dropped\n\n" +
+		"- [x] ~~Learn Haskell~~ <!-- id:3 -->\n"
+	if buf.String() != want {
+		t.Errorf("unexpected Markdown:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	tasks := []storage.Task{
+		{TaskID: 0, Header: "Plain"},
+		{TaskID: 1, Header: "[link](x) <b> `code` ~~no~~ \\ _u_", Description: "Line 1\n\n    indented\n```\n- [ ] in a fence\n```\n1. numbered", Status: storage.Assigned},
+		{TaskID: 2, Header: "Started", Description: "  padded  ", Status: storage.InProgress},
+		{TaskID: 3, Header: "Done", Status: storage.Completed},
+		{TaskID: 4, Header: "Dropped", Status: storage.Dropped},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadMarkdown(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(tasks) {
+		t.Fatalf("expected %d records, got %+v", len(tasks), records)
+	}
+	for i, rec := range records {
+		if !rec.HasID || rec.Task != tasks[i] {
+			t.Errorf("item %d: expected %+v, got %+v", i, tasks[i], rec.Task)
+		}
+	}
+}
+
+func TestReadMarkdown(t *testing.T) {
+	input := "\ufeff# Release 1.2\n" +
+		"\n" +
+		"Some intro text.\n" +
+		"\n" +
+		"- [ ] Ship the release\n" +
+		"  Notes for the release\n" +
+		"  - [x] Write changelog\n" +
+		"\t  * plain bullet in the changelog notes\n" +
+		"  - [ ] Tag the commit <!-- id:7 -->\n" +
+		"  back in the release notes\n" +
+		"* [X] ~~Old plan~~\n" +
+		"1. [ ] Numbered\n" +
+		"- plain bullet\n" +
+		"\n" +
+		"## In progress\n" +
+		"- [ ] Review PR\n" +
+		"- [x] Merged already\n" +
+		"\n" +
+		"### Dropped ###\n" +
+		"+ [x] Abandoned\n" +
+		"```\n" +
+		"- [ ] inside a code block\n" +
+		"```\n"
+
+	records, err := ReadMarkdown(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Record{
+		{Line: 5, Task: storage.Task{Header: "Ship the release", Description: "Notes for the release\nback in the release notes"}},
+		{Line: 7, Task: storage.Task{Header: "Write changelog", Description: "  * plain bullet in the changelog notes", Status: storage.Completed}},
+		{Line: 9, Task: storage.Task{TaskID: 7, Header: "Tag the commit"}, HasID: true},
+		{Line: 11, Task: storage.Task{Header: "Old plan", Status: storage.Dropped}},
+		{Line: 12, Task: storage.Task{Header: "Numbered"}},
+		{Line: 16, Task: storage.Task{Header: "Review PR", Status: storage.InProgress}},
+		{Line: 17, Task: storage.Task{Header: "Merged already", Status: storage.Completed}},
+		{Line: 20, Task: storage.Task{Header: "Abandoned", Status: storage.Dropped}},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", want, records)
+	}
+}
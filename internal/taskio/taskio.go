@@ -0,0 +1,20 @@
+// Package taskio converts tasks to and from file formats used for bulk export and import.
+package taskio
+
+import (
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+// Record is one task read from an import file. Errors holds problems found while parsing the
+// record; field validation is left to the caller.
+type Record struct {
+	Line   int
+	Task   storage.Task
+	HasID  bool
+	Errors []validation.FieldError
+}
+
+func (r *Record) fail(field, message string) {
+	r.Errors = append(r.Errors, validation.FieldError{Field: field, Message: message})
+}
@@ -0,0 +1,239 @@
+package taskio
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo/internal/storage"
+)
+
+const todoTxtDate = "2006-01-02"
+
+// Tags written by WriteTodoTxt. Other key:value extensions, like +project and @context, are
+// part of the task header.
+const (
+	todoTxtID     = "id"
+	todoTxtStatus = "status"
+	todoTxtNote   = "note"
+	todoTxtOwner  = "owner"
+)
+
+// TodoTxtItem is one line of a todo.txt file: "x COMPLETED CREATED TEXT" for done items and
+// "(P) CREATED TEXT" for open ones, every part but TEXT being optional.
+// See https://github.com/todotxt/todo.txt for the format.
+type TodoTxtItem struct {
+	Done      bool
+	Priority  byte
+	Completed time.Time
+	Created   time.Time
+	Text      string
+}
+
+func ParseTodoTxt(line string) TodoTxtItem {
+	var it TodoTxtItem
+	rest := line
+	if after, ok := strings.CutPrefix(rest, "x "); ok {
+		it.Done = true
+		rest = after
+		if date, after, ok := cutTodoTxtDate(rest); ok {
+			it.Completed = date
+			rest = after
+			if date, after, ok := cutTodoTxtDate(rest); ok {
+				it.Created = date
+				rest = after
+			}
+		}
+		it.Text = rest
+		return it
+	}
+
+	if len(rest) >= 4 && rest[0] == '(' && rest[1] >= 'A' && rest[1] <= 'Z' && rest[2] == ')' && rest[3] == ' ' {
+		it.Priority = rest[1]
+		rest = rest[4:]
+	}
+	if date, after, ok := cutTodoTxtDate(rest); ok {
+		it.Created = date
+		rest = after
+	}
+	it.Text = rest
+	return it
+}
+
+func cutTodoTxtDate(s string) (time.Time, string, bool) {
+	value, rest, ok := strings.Cut(s, " ")
+	if !ok || len(value) != len(todoTxtDate) {
+		return time.Time{}, s, false
+	}
+	date, err := time.Parse(todoTxtDate, value)
+	if err != nil {
+		return time.Time{}, s, false
+	}
+	return date, rest, true
+}
+
+func (it TodoTxtItem) String() string {
+	var sb strings.Builder
+	if it.Done {
+		sb.WriteString("x ")
+		if !it.Completed.IsZero() {
+			sb.WriteString(it.Completed.Format(todoTxtDate) + " ")
+		}
+	} else if it.Priority != 0 {
+		sb.WriteString("(" + string(it.Priority) + ") ")
+	}
+	// A creation date without a completion date would be read back as the completion date.
+	if !it.Created.IsZero() && (!it.Done || !it.Completed.IsZero()) {
+		sb.WriteString(it.Created.Format(todoTxtDate) + " ")
+	}
+	sb.WriteString(it.Text)
+	return sb.String()
+}
+
+// todoTxtReserved reports whether a word, without its leading backslashes, looks like one of
+// the tags written by WriteTodoTxt.
+func todoTxtReserved(word string) bool {
+	key, _, ok := strings.Cut(strings.TrimLeft(word, `\`), ":")
+	return ok && (key == todoTxtID || key == todoTxtStatus || key == todoTxtNote || key == todoTxtOwner)
+}
+
+// todoTxtMarker reports whether the first word of a header, without its leading backslashes,
+// is the "x" completion marker.
+func todoTxtMarker(word string) bool {
+	return strings.TrimLeft(word, `\`) == "x"
+}
+
+// escapeTodoTxtHeader prefixes a backslash to the header words that would be read back as tags
+// or as the completion marker. Such words that already start with a backslash get another one,
+// so todoTxtRecord can always strip exactly one.
+func escapeTodoTxtHeader(header string) string {
+	words := strings.Split(header, " ")
+	for i, word := range words {
+		if todoTxtReserved(word) || i == 0 && todoTxtMarker(word) {
+			words[i] = `\` + word
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// todoTxtLine renders a task. The header is written as it is apart from escaping, so
+// priorities, creation dates, projects and contexts kept in it survive a round trip; the other
+// fields become tags.
+func todoTxtLine(task storage.Task) string {
+	closed := task.Status == storage.Completed || task.Status == storage.Dropped
+	line := escapeTodoTxtHeader(task.Header)
+	if closed {
+		// The header's own creation date needs a completion date in front of it; the real
+		// completion date is not stored, so the creation date stands in for it.
+		if created, _, ok := cutTodoTxtDate(task.Header); ok {
+			line = created.Format(todoTxtDate) + " " + line
+		}
+		line = "x " + line
+	}
+
+	tags := []string{todoTxtID + ":" + strconv.Itoa(task.TaskID)}
+	switch task.Status {
+	case storage.InProgress:
+		tags = append(tags, todoTxtStatus+":in_progress")
+	case storage.Dropped:
+		tags = append(tags, todoTxtStatus+":dropped")
+	}
+	if task.Description != "" {
+		tags = append(tags, todoTxtNote+":"+url.QueryEscape(task.Description))
+	}
+	if task.Owner != "" {
+		tags = append(tags, todoTxtOwner+":"+url.QueryEscape(task.Owner))
+	}
+	return line + " " + strings.Join(tags, " ")
+}
+
+// todoTxtRecord converts a line back to a task. The "x" marker decides whether the task is
+// closed; a status tag only chooses between Assigned and InProgress or Completed and Dropped,
+// so checking or unchecking an item in an editor takes effect.
+func todoTxtRecord(number int, line string) Record {
+	rec := Record{Line: number}
+	it := ParseTodoTxt(line)
+
+	var words []string
+	status := storage.Assigned
+	for _, word := range strings.Split(it.Text, " ") {
+		key, value, ok := strings.Cut(word, ":")
+		switch {
+		case strings.HasPrefix(word, `\`) && todoTxtReserved(word):
+			word = word[1:]
+		case !ok:
+		case key == todoTxtID:
+			id, err := strconv.Atoi(value)
+			if err != nil || id < 0 {
+				break
+			}
+			rec.Task.TaskID = id
+			rec.HasID = true
+			continue
+		case key == todoTxtStatus:
+			s, err := storage.ParseTaskStatus(value)
+			if err != nil {
+				rec.fail("Status", "must be assigned, in_progress, completed or dropped")
+			}
+			status = s
+			continue
+		case key == todoTxtNote:
+			note, err := url.QueryUnescape(value)
+			if err != nil {
+				rec.fail("Description", "must be URL-encoded")
+			}
+			rec.Task.Description = note
+			continue
+		case key == todoTxtOwner:
+			// Imported tasks belong to whoever imports them.
+			continue
+		}
+		words = append(words, word)
+	}
+
+	closed := status == storage.Completed || status == storage.Dropped
+	switch {
+	case it.Done && !closed:
+		status = storage.Completed
+	case !it.Done && closed:
+		status = storage.Assigned
+	}
+	rec.Task.Status = status
+
+	it.Done, it.Completed, it.Text = false, time.Time{}, strings.Join(words, " ")
+	rec.Task.Header = it.String()
+	if first, _, _ := strings.Cut(rec.Task.Header, " "); strings.HasPrefix(first, `\`) && todoTxtMarker(first) {
+		rec.Task.Header = rec.Task.Header[1:]
+	}
+	return rec
+}
+
+func WriteTodoTxt(w io.Writer, tasks []storage.Task) error {
+	bw := bufio.NewWriter(w)
+	for _, task := range tasks {
+		bw.WriteString(todoTxtLine(task))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// ReadTodoTxt returns a record for every non-empty line.
+func ReadTodoTxt(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var records []Record
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if number == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		records = append(records, todoTxtRecord(number, line))
+	}
+	return records, scanner.Err()
+}
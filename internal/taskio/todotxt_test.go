@@ -0,0 +1,130 @@
+package taskio
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"todo/internal/storage"
+	"todo/internal/validation"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(todoTxtDate, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParseTodoTxt(t *testing.T) {
+	tests := []struct {
+		line string
+		want TodoTxtItem
+	}{
+		{"Call mum", TodoTxtItem{Text: "Call mum"}},
+		{"(A) Call mum +family @phone", TodoTxtItem{Priority: 'A', Text: "Call mum +family @phone"}},
+		{"(B) 2024-01-02 Pay rent due:2024-02-01", TodoTxtItem{Priority: 'B', Created: date("2024-01-02"), Text: "Pay rent due:2024-02-01"}},
+		{"2024-01-02 Pay rent", TodoTxtItem{Created: date("2024-01-02"), Text: "Pay rent"}},
+		{"x 2024-03-04 2024-01-02 Pay rent", TodoTxtItem{Done: true, Completed: date("2024-03-04"), Created: date("2024-01-02"), Text: "Pay rent"}},
+		{"x 2024-03-04 Pay rent", TodoTxtItem{Done: true, Completed: date("2024-03-04"), Text: "Pay rent"}},
+		{"x (A) Pay rent", TodoTxtItem{Done: true, Text: "(A) Pay rent"}},
+		{"(a) lower case is not a priority", TodoTxtItem{Text: "(a) lower case is not a priority"}},
+		{"xylophone lessons", TodoTxtItem{Text: "xylophone lessons"}},
+		{"2024-13-40 is not a date", TodoTxtItem{Text: "2024-13-40 is not a date"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := ParseTodoTxt(tt.line)
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+			if got.String() != tt.line {
+				t.Errorf("expected %q to be written back unchanged, got %q", tt.line, got.String())
+			}
+		})
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	tasks := []storage.Task{
+		{TaskID: 0, Header: "Call mum +family @phone", Status: storage.Assigned},
+		{TaskID: 1, Header: "(A) 2024-01-02 Pay rent due:2024-02-01", Description: "Bank: 100% + fees\nLine 2", Status: storage.InProgress},
+		{TaskID: 2, Header: "2024-01-02 Book flights @laptop", Status: storage.Completed},
+		{TaskID: 3, Header: "(C) Learn Haskell", Status: storage.Dropped},
+		{TaskID: 4, Header: "See  http://example.com:8080/a  twice", Description: "  padded  "},
+		{TaskID: 5, Header: "x marks the spot", Status: storage.Assigned},
+		{TaskID: 6, Header: "x", Status: storage.Dropped},
+		{TaskID: 7, Header: "check id:5 again", Status: storage.InProgress},
+		{TaskID: 8, Header: "call at note:later", Status: storage.Completed},
+		{TaskID: 9, Header: "status:open owner:me", Description: "note:kept"},
+		{TaskID: 10, Header: `\x \\id:1 \plain x`},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTodoTxt(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadTodoTxt(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(tasks) {
+		t.Fatalf("expected %d records, got %d", len(tasks), len(records))
+	}
+	for i, rec := range records {
+		if !rec.HasID || rec.Task != tasks[i] || len(rec.Errors) != 0 {
+			t.Errorf("line %d: expected %+v, got %+v", i+1, tasks[i], rec)
+		}
+	}
+}
+
+func TestWriteTodoTxt(t *testing.T) {
+	tasks := []storage.Task{
+		{TaskID: 1, Header: "(A) Call mum +family", Description: "Sunday, 10am", Status: storage.InProgress, Owner: "ip:10.0.0.1"},
+		{TaskID: 2, Header: "2024-01-02 Pay rent", Status: storage.Completed},
+		{TaskID: 3, Header: "Learn Haskell", Status: storage.Dropped},
+		{TaskID: 4, Header: "x marks the spot id:5", Status: storage.Assigned},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTodoTxt(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "(A) Call mum +family id:1 status:in_progress note:Sunday%2C+10am owner:ip%3A10.0.0.1\n" +
+		"x 2024-01-02 2024-01-02 Pay rent id:2\n" +
+		"x Learn Haskell id:3 status:dropped\n" +
+		`\x marks the spot \id:5 id:4` + "\n"
+	if buf.String() != want {
+		t.Errorf("unexpected todo.txt:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestReadTodoTxt(t *testing.T) {
+	input := "\ufeffx 2024-05-01 Pay rent id:2 status:in_progress\r\n" +
+		"\n" +
+		"Learn Haskell id:3 status:dropped owner:ip%3A10.0.0.1\n" +
+		"(B) New item +home @errands id:abc\n" +
+		"Broken note:%zz status:later\n"
+
+	records, err := ReadTodoTxt(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Record{
+		{Line: 1, Task: storage.Task{TaskID: 2, Header: "Pay rent", Status: storage.Completed}, HasID: true},
+		{Line: 3, Task: storage.Task{TaskID: 3, Header: "Learn Haskell", Status: storage.Assigned}, HasID: true},
+		{Line: 4, Task: storage.Task{Header: "(B) New item +home @errands id:abc"}},
+		{Line: 5, Task: storage.Task{Header: "Broken"}, Errors: []validation.FieldError{
+			{Field: "Description", Message: "must be URL-encoded"},
+			{Field: "Status", Message: "must be assigned, in_progress, completed or dropped"},
+		}},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("expected %+v, got %+v", want, records)
+	}
+}
@@ -0,0 +1,255 @@
+package todopb
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ServiceName prefixes the paths of the service methods, e.g. ServiceName + "/GetTask".
+const ServiceName = "/todo.v1.TaskService"
+
+// Code is a gRPC status code.
+type Code uint32
+
+const (
+	OK                Code = 0
+	Canceled          Code = 1
+	Unknown           Code = 2
+	InvalidArgument   Code = 3
+	DeadlineExceeded  Code = 4
+	NotFound          Code = 5
+	PermissionDenied  Code = 7
+	ResourceExhausted Code = 8
+	Unimplemented     Code = 12
+	Internal          Code = 13
+	Unavailable       Code = 14
+	Unauthenticated   Code = 16
+)
+
+var codeNames = map[Code]string{
+	OK: "OK", Canceled: "CANCELLED", Unknown: "UNKNOWN", InvalidArgument: "INVALID_ARGUMENT",
+	DeadlineExceeded: "DEADLINE_EXCEEDED", NotFound: "NOT_FOUND", PermissionDenied: "PERMISSION_DENIED",
+	ResourceExhausted: "RESOURCE_EXHAUSTED", Unimplemented: "UNIMPLEMENTED", Internal: "INTERNAL",
+	Unavailable: "UNAVAILABLE", Unauthenticated: "UNAUTHENTICATED",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return "CODE(" + strconv.Itoa(int(c)) + ")"
+}
+
+// FieldViolation is a google.rpc.BadRequest.FieldViolation.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// Status is a non-OK outcome of a call, sent in the grpc-status, grpc-message and
+// grpc-status-details-bin trailers.
+type Status struct {
+	Code    Code
+	Message string
+	// Violations are sent as a google.rpc.BadRequest detail.
+	Violations []FieldViolation
+}
+
+func Errorf(code Code, format string, args ...any) *Status {
+	return &Status{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (s *Status) Error() string {
+	msg := "rpc error: " + s.Code.String() + ": " + s.Message
+	for _, v := range s.Violations {
+		msg += "; " + v.Field + ": " + v.Description
+	}
+	return msg
+}
+
+const badRequestType = "type.googleapis.com/google.rpc.BadRequest"
+
+// Details encodes s as a google.rpc.Status for the grpc-status-details-bin trailer.
+func (s *Status) Details() []byte {
+	var e encoder
+	e.varint(1, uint64(s.Code))
+	e.string(2, s.Message)
+	if len(s.Violations) > 0 {
+		var badRequest encoder
+		for _, v := range s.Violations {
+			var violation encoder
+			violation.string(1, v.Field)
+			violation.string(2, v.Description)
+			badRequest.bytes(1, violation.buf)
+		}
+		var detail encoder
+		detail.string(1, badRequestType)
+		detail.bytes(2, badRequest.buf)
+		e.bytes(3, detail.buf)
+	}
+	return e.buf
+}
+
+// ParseDetails decodes a google.rpc.Status; details other than BadRequest are skipped.
+func ParseDetails(data []byte) (*Status, error) {
+	s := &Status{}
+	err := parse(data, func(f field) error {
+		switch f.num {
+		case 1:
+			s.Code = Code(f.varint)
+			return expect(f, wireVarint)
+		case 2:
+			s.Message = f.string()
+			return expect(f, wireBytes)
+		case 3:
+			if err := expect(f, wireBytes); err != nil {
+				return err
+			}
+			return s.parseDetail(f.data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Status) parseDetail(data []byte) error {
+	var typeURL string
+	var value []byte
+	err := parse(data, func(f field) error {
+		switch f.num {
+		case 1:
+			typeURL = f.string()
+		case 2:
+			value = f.data
+		}
+		return nil
+	})
+	if err != nil || typeURL != badRequestType {
+		return err
+	}
+	return parse(value, func(f field) error {
+		if f.num != 1 {
+			return nil
+		}
+		var v FieldViolation
+		err := parse(f.data, func(f field) error {
+			switch f.num {
+			case 1:
+				v.Field = f.string()
+			case 2:
+				v.Description = f.string()
+			}
+			return nil
+		})
+		s.Violations = append(s.Violations, v)
+		return err
+	})
+}
+
+// Trailers returns the status trailers of the response.
+func (s *Status) Trailers() map[string]string {
+	trailers := map[string]string{
+		"Grpc-Status":  strconv.Itoa(int(s.Code)),
+		"Grpc-Message": EncodeMessage(s.Message),
+	}
+	if len(s.Violations) > 0 {
+		trailers["Grpc-Status-Details-Bin"] = base64.RawStdEncoding.EncodeToString(s.Details())
+	}
+	return trailers
+}
+
+// StatusFromTrailers reads the outcome of a call; a nil result means OK.
+func StatusFromTrailers(get func(key string) string) (*Status, error) {
+	raw := get("Grpc-Status")
+	if raw == "" {
+		return nil, errors.New("missing grpc-status trailer")
+	}
+	code, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("bad grpc-status %q", raw)
+	}
+	if code == uint64(OK) {
+		return nil, nil
+	}
+
+	s := &Status{Code: Code(code), Message: DecodeMessage(get("Grpc-Message"))}
+	if bin := get("Grpc-Status-Details-Bin"); bin != "" {
+		data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(bin, "="))
+		if err != nil {
+			return nil, fmt.Errorf("bad grpc-status-details-bin: %w", err)
+		}
+		details, err := ParseDetails(data)
+		if err != nil {
+			return nil, err
+		}
+		s.Violations = details.Violations
+	}
+	return s, nil
+}
+
+// EncodeMessage percent-encodes a grpc-message value as the protocol requires.
+func EncodeMessage(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= 0x20 && c <= 0x7e && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func DecodeMessage(msg string) string {
+	decoded, err := url.PathUnescape(msg)
+	if err != nil {
+		return msg
+	}
+	return decoded
+}
+
+// MaxMessageSize bounds the messages ReadFrame accepts.
+const MaxMessageSize = 4 << 20
+
+// WriteFrame writes m as one length-prefixed, uncompressed message.
+func WriteFrame(w io.Writer, m Message) error {
+	data := m.Marshal()
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// ReadFrame reads one length-prefixed message. It returns io.EOF when the stream ends
+// between messages and a *Status for compressed or oversized ones.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, Errorf(Internal, "truncated message header")
+		}
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, Errorf(Unimplemented, "compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > MaxMessageSize {
+		return nil, Errorf(ResourceExhausted, "message of %d bytes exceeds the limit of %d", size, MaxMessageSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, Errorf(Internal, "truncated message: %v", err)
+	}
+	return data, nil
+}
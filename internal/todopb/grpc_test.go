@@ -0,0 +1,97 @@
+package todopb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	msgs := []*Task{{Id: 1, Header: "first"}, {}}
+	for _, m := range msgs {
+		if err := WriteFrame(&buf, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := buf.Bytes()[:5]; !bytes.Equal(got, []byte{0, 0, 0, 0, 9}) {
+		t.Errorf("unexpected prefix %x", got)
+	}
+
+	for _, want := range msgs {
+		data, err := ReadFrame(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := &Task{}
+		if err := got.Unmarshal(data); err != nil {
+			t.Fatal(err)
+		}
+		if *got != *want {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	}
+	if _, err := ReadFrame(&buf); err != io.EOF {
+		t.Errorf("expected io.EOF after the last frame, got %v", err)
+	}
+}
+
+func TestReadFrameErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		want  Code
+	}{
+		{"compressed", []byte{1, 0, 0, 0, 0}, Unimplemented},
+		{"too large", []byte{0, 0xff, 0, 0, 0}, ResourceExhausted},
+		{"truncated header", []byte{0, 0}, Internal},
+		{"truncated body", []byte{0, 0, 0, 0, 3, 8}, Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadFrame(bytes.NewReader(tt.frame))
+			var st *Status
+			if !errors.As(err, &st) || st.Code != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStatusTrailers(t *testing.T) {
+	st := &Status{
+		Code:    InvalidArgument,
+		Message: "Invalid task: 100% wrong\nтекст",
+		Violations: []FieldViolation{
+			{Field: "task.header", Description: "must not be empty"},
+			{Field: "task.status", Description: "must be between 0 and 3"},
+		},
+	}
+	header := http.Header{}
+	for k, v := range st.Trailers() {
+		header.Set(k, v)
+	}
+	if got := header.Get("Grpc-Message"); got != "Invalid task: 100%25 wrong%0A%D1%82%D0%B5%D0%BA%D1%81%D1%82" {
+		t.Errorf("unexpected grpc-message %q", got)
+	}
+
+	got, err := StatusFromTrailers(header.Get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, st) {
+		t.Errorf("expected %+v, got %+v", st, got)
+	}
+
+	ok, err := StatusFromTrailers(http.Header{"Grpc-Status": {"0"}}.Get)
+	if ok != nil || err != nil {
+		t.Errorf("expected OK, got %v, %v", ok, err)
+	}
+	if _, err := StatusFromTrailers(http.Header{}.Get); err == nil {
+		t.Error("expected an error without grpc-status")
+	}
+}
@@ -0,0 +1,317 @@
+package todopb
+
+type TaskStatus int32
+
+const (
+	TaskStatusAssigned   TaskStatus = 0
+	TaskStatusInProgress TaskStatus = 1
+	TaskStatusCompleted  TaskStatus = 2
+	TaskStatusDropped    TaskStatus = 3
+)
+
+type EventType int32
+
+const (
+	EventTypeUnspecified EventType = 0
+	EventTypeCreated     EventType = 1
+	EventTypeUpdated     EventType = 2
+	EventTypeDeleted     EventType = 3
+)
+
+type Task struct {
+	Id          int64
+	Header      string
+	Description string
+	Status      TaskStatus
+	Owner       string
+}
+
+func (t *Task) Marshal() []byte {
+	var e encoder
+	e.int64(1, t.Id)
+	e.string(2, t.Header)
+	e.string(3, t.Description)
+	e.varint(4, uint64(int64(t.Status)))
+	e.string(5, t.Owner)
+	return e.buf
+}
+
+func (t *Task) Unmarshal(data []byte) error {
+	*t = Task{}
+	return parse(data, func(f field) error {
+		switch f.num {
+		case 1:
+			t.Id = f.int64()
+			return expect(f, wireVarint)
+		case 2:
+			t.Header = f.string()
+			return expect(f, wireBytes)
+		case 3:
+			t.Description = f.string()
+			return expect(f, wireBytes)
+		case 4:
+			t.Status = TaskStatus(f.int32())
+			return expect(f, wireVarint)
+		case 5:
+			t.Owner = f.string()
+			return expect(f, wireBytes)
+		}
+		return nil
+	})
+}
+
+// unmarshalTask decodes an embedded Task, keeping the last occurrence like protobuf does.
+func unmarshalTask(f field, dst **Task) error {
+	if err := expect(f, wireBytes); err != nil {
+		return err
+	}
+	task := &Task{}
+	if err := task.Unmarshal(f.data); err != nil {
+		return err
+	}
+	*dst = task
+	return nil
+}
+
+type CreateTaskRequest struct {
+	Task *Task
+}
+
+func (m *CreateTaskRequest) Marshal() []byte {
+	var e encoder
+	if m.Task != nil {
+		e.message(1, m.Task)
+	}
+	return e.buf
+}
+
+func (m *CreateTaskRequest) Unmarshal(data []byte) error {
+	*m = CreateTaskRequest{}
+	return parse(data, func(f field) error {
+		if f.num == 1 {
+			return unmarshalTask(f, &m.Task)
+		}
+		return nil
+	})
+}
+
+type GetTaskRequest struct {
+	Id int64
+}
+
+func (m *GetTaskRequest) Marshal() []byte {
+	var e encoder
+	e.int64(1, m.Id)
+	return e.buf
+}
+
+func (m *GetTaskRequest) Unmarshal(data []byte) error {
+	*m = GetTaskRequest{}
+	return parse(data, func(f field) error {
+		if f.num == 1 {
+			m.Id = f.int64()
+			return expect(f, wireVarint)
+		}
+		return nil
+	})
+}
+
+type UpdateTaskRequest struct {
+	Task *Task
+}
+
+func (m *UpdateTaskRequest) Marshal() []byte {
+	var e encoder
+	if m.Task != nil {
+		e.message(1, m.Task)
+	}
+	return e.buf
+}
+
+func (m *UpdateTaskRequest) Unmarshal(data []byte) error {
+	*m = UpdateTaskRequest{}
+	return parse(data, func(f field) error {
+		if f.num == 1 {
+			return unmarshalTask(f, &m.Task)
+		}
+		return nil
+	})
+}
+
+type DeleteTaskRequest struct {
+	Id int64
+}
+
+func (m *DeleteTaskRequest) Marshal() []byte {
+	var e encoder
+	e.int64(1, m.Id)
+	return e.buf
+}
+
+func (m *DeleteTaskRequest) Unmarshal(data []byte) error {
+	*m = DeleteTaskRequest{}
+	return parse(data, func(f field) error {
+		if f.num == 1 {
+			m.Id = f.int64()
+			return expect(f, wireVarint)
+		}
+		return nil
+	})
+}
+
+type DeleteTaskResponse struct{}
+
+func (m *DeleteTaskResponse) Marshal() []byte {
+	return nil
+}
+
+func (m *DeleteTaskResponse) Unmarshal(data []byte) error {
+	return parse(data, func(field) error { return nil })
+}
+
+type ListTasksRequest struct {
+	Statuses  []TaskStatus
+	Owner     string
+	Query     string
+	PageSize  int32
+	PageToken string
+}
+
+func (m *ListTasksRequest) Marshal() []byte {
+	var e encoder
+	e.packed(1, statusValues(m.Statuses))
+	e.string(2, m.Owner)
+	e.string(3, m.Query)
+	e.varint(4, uint64(int64(m.PageSize)))
+	e.string(5, m.PageToken)
+	return e.buf
+}
+
+func (m *ListTasksRequest) Unmarshal(data []byte) error {
+	*m = ListTasksRequest{}
+	return parse(data, func(f field) error {
+		switch f.num {
+		case 1:
+			return appendStatuses(f, &m.Statuses)
+		case 2:
+			m.Owner = f.string()
+			return expect(f, wireBytes)
+		case 3:
+			m.Query = f.string()
+			return expect(f, wireBytes)
+		case 4:
+			m.PageSize = f.int32()
+			return expect(f, wireVarint)
+		case 5:
+			m.PageToken = f.string()
+			return expect(f, wireBytes)
+		}
+		return nil
+	})
+}
+
+type ListTasksResponse struct {
+	Tasks         []*Task
+	NextPageToken string
+}
+
+func (m *ListTasksResponse) Marshal() []byte {
+	var e encoder
+	for _, task := range m.Tasks {
+		e.message(1, task)
+	}
+	e.string(2, m.NextPageToken)
+	return e.buf
+}
+
+func (m *ListTasksResponse) Unmarshal(data []byte) error {
+	*m = ListTasksResponse{}
+	return parse(data, func(f field) error {
+		switch f.num {
+		case 1:
+			var task *Task
+			if err := unmarshalTask(f, &task); err != nil {
+				return err
+			}
+			m.Tasks = append(m.Tasks, task)
+		case 2:
+			m.NextPageToken = f.string()
+			return expect(f, wireBytes)
+		}
+		return nil
+	})
+}
+
+type WatchRequest struct {
+	Statuses []TaskStatus
+	Owner    string
+}
+
+func (m *WatchRequest) Marshal() []byte {
+	var e encoder
+	e.packed(1, statusValues(m.Statuses))
+	e.string(2, m.Owner)
+	return e.buf
+}
+
+func (m *WatchRequest) Unmarshal(data []byte) error {
+	*m = WatchRequest{}
+	return parse(data, func(f field) error {
+		switch f.num {
+		case 1:
+			return appendStatuses(f, &m.Statuses)
+		case 2:
+			m.Owner = f.string()
+			return expect(f, wireBytes)
+		}
+		return nil
+	})
+}
+
+type TaskEvent struct {
+	Type EventType
+	Task *Task
+}
+
+func (m *TaskEvent) Marshal() []byte {
+	var e encoder
+	e.varint(1, uint64(int64(m.Type)))
+	if m.Task != nil {
+		e.message(2, m.Task)
+	}
+	return e.buf
+}
+
+func (m *TaskEvent) Unmarshal(data []byte) error {
+	*m = TaskEvent{}
+	return parse(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.Type = EventType(f.int32())
+			return expect(f, wireVarint)
+		case 2:
+			return unmarshalTask(f, &m.Task)
+		}
+		return nil
+	})
+}
+
+func statusValues(statuses []TaskStatus) []uint64 {
+	values := make([]uint64, len(statuses))
+	for i, s := range statuses {
+		// Negative enum values are sign-extended like int32 fields.
+		values[i] = uint64(int64(s))
+	}
+	return values
+}
+
+func appendStatuses(f field, dst *[]TaskStatus) error {
+	values, err := f.uints()
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		*dst = append(*dst, TaskStatus(int32(v)))
+	}
+	return nil
+}
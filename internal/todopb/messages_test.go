@@ -0,0 +1,115 @@
+package todopb
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		zero Message
+	}{
+		{"empty task", &Task{}, &Task{}},
+		{"task", &Task{Id: 42, Header: "Заголовок", Description: "d", Status: TaskStatusDropped, Owner: "token:ab"}, &Task{}},
+		{"negative status", &Task{Status: -1}, &Task{}},
+		{"create", &CreateTaskRequest{Task: &Task{Header: "h"}}, &CreateTaskRequest{}},
+		{"create with empty task", &CreateTaskRequest{Task: &Task{}}, &CreateTaskRequest{}},
+		{"get", &GetTaskRequest{Id: 1 << 40}, &GetTaskRequest{}},
+		{"update", &UpdateTaskRequest{Task: &Task{Id: 3, Status: TaskStatusCompleted}}, &UpdateTaskRequest{}},
+		{"delete", &DeleteTaskRequest{Id: 7}, &DeleteTaskRequest{}},
+		{"list", &ListTasksRequest{
+			Statuses: []TaskStatus{TaskStatusAssigned, TaskStatusDropped},
+			Owner:    "ip:1.2.3.4", Query: "milk", PageSize: 50, PageToken: "12",
+		}, &ListTasksRequest{}},
+		{"list response", &ListTasksResponse{Tasks: []*Task{{Id: 1}, {Id: 2, Header: "b"}}, NextPageToken: "2"}, &ListTasksResponse{}},
+		{"watch", &WatchRequest{Statuses: []TaskStatus{TaskStatusInProgress}, Owner: "o"}, &WatchRequest{}},
+		{"event", &TaskEvent{Type: EventTypeDeleted, Task: &Task{Id: 9}}, &TaskEvent{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.zero.Unmarshal(tt.msg.Marshal()); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(tt.zero, tt.msg) {
+				t.Errorf("expected %+v, got %+v", tt.msg, tt.zero)
+			}
+		})
+	}
+}
+
+func TestWireCompatibility(t *testing.T) {
+	// Encodings produced by protoc-generated code for the same values.
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"task", &Task{Id: 150, Header: "a", Status: TaskStatusCompleted}, "08 9601 120161 2002"},
+		{"negative enum", &Task{Status: -1}, "20 ffffffffffffffffff01"},
+		{"packed statuses", &WatchRequest{Statuses: []TaskStatus{1, 3}}, "0a 02 0103"},
+		{"empty embedded task", &CreateTaskRequest{Task: &Task{}}, "0a 00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := hex.DecodeString(strings.ReplaceAll(tt.want, " ", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.msg.Marshal(); !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %x, got %x", want, got)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Message
+		wantErr bool
+	}{
+		{"unknown fields are skipped", "3001" + "3a0178" + "4d01000000" + "510100000000000000" + "1001", &GetTaskRequest{}, false},
+		{"unpacked statuses", "0801" + "0803", &WatchRequest{Statuses: []TaskStatus{1, 3}}, false},
+		{"last scalar wins", "0801" + "0802", &GetTaskRequest{Id: 2}, false},
+		{"truncated string", "1205616263", nil, true},
+		{"truncated varint", "08ff", nil, true},
+		{"zero field number", "0001", nil, true},
+		{"group wire type", "0b", nil, true},
+		{"wrong wire type", "0a0101", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got Message = &GetTaskRequest{}
+			if _, ok := tt.want.(*WatchRequest); ok {
+				got = &WatchRequest{}
+			}
+
+			err = got.Unmarshal(data)
+			if tt.wantErr {
+				if !errors.Is(err, ErrMalformed) {
+					t.Errorf("expected ErrMalformed, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
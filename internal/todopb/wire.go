@@ -0,0 +1,167 @@
+// Package todopb holds the messages of todo.proto and the gRPC framing used to exchange them,
+// written against the protobuf wire format without generated code.
+package todopb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrMalformed is returned for messages that are not valid protobuf.
+var ErrMalformed = errors.New("malformed protobuf message")
+
+type Message interface {
+	Marshal() []byte
+	// Unmarshal replaces the message with data; unknown fields are skipped.
+	Unmarshal(data []byte) error
+}
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(num, typ int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(num)<<3|uint64(typ))
+}
+
+// varint writes a non-zero value; proto3 leaves zero scalars out.
+func (e *encoder) varint(num int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(num, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) int64(num int, v int64) {
+	e.varint(num, uint64(v))
+}
+
+func (e *encoder) string(num int, s string) {
+	if s == "" {
+		return
+	}
+	e.bytes(num, []byte(s))
+}
+
+func (e *encoder) bytes(num int, b []byte) {
+	e.tag(num, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// message writes m even when it is empty, so presence survives the round trip.
+func (e *encoder) message(num int, m Message) {
+	e.bytes(num, m.Marshal())
+}
+
+func (e *encoder) packed(num int, values []uint64) {
+	if len(values) == 0 {
+		return
+	}
+	var b []byte
+	for _, v := range values {
+		b = binary.AppendUvarint(b, v)
+	}
+	e.bytes(num, b)
+}
+
+// field is one decoded field: varint holds varint and fixed values, data the payload of
+// length-delimited ones.
+type field struct {
+	num    int
+	typ    int
+	varint uint64
+	data   []byte
+}
+
+func (f field) int64() int64 {
+	return int64(f.varint)
+}
+
+func (f field) int32() int32 {
+	return int32(f.varint)
+}
+
+func (f field) string() string {
+	return string(f.data)
+}
+
+// uints reads a repeated scalar, which may be packed or one value per field.
+func (f field) uints() ([]uint64, error) {
+	if f.typ == wireVarint {
+		return []uint64{f.varint}, nil
+	}
+	if f.typ != wireBytes {
+		return nil, fmt.Errorf("%w: field %d has wire type %d", ErrMalformed, f.num, f.typ)
+	}
+	var values []uint64
+	for b := f.data; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: bad varint in field %d", ErrMalformed, f.num)
+		}
+		values = append(values, v)
+		b = b[n:]
+	}
+	return values, nil
+}
+
+// parse calls fn for every field in order; fn checks wire types of known fields with expect.
+func parse(data []byte, fn func(f field) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 || key>>3 == 0 || key>>3 > 1<<29-1 {
+			return fmt.Errorf("%w: bad field key", ErrMalformed)
+		}
+		data = data[n:]
+		f := field{num: int(key >> 3), typ: int(key & 7)}
+
+		switch f.typ {
+		case wireVarint:
+			if f.varint, n = binary.Uvarint(data); n <= 0 {
+				return fmt.Errorf("%w: bad varint in field %d", ErrMalformed, f.num)
+			}
+		case wireFixed64:
+			if n = 8; len(data) < n {
+				return fmt.Errorf("%w: truncated field %d", ErrMalformed, f.num)
+			}
+			f.varint = binary.LittleEndian.Uint64(data)
+		case wireFixed32:
+			if n = 4; len(data) < n {
+				return fmt.Errorf("%w: truncated field %d", ErrMalformed, f.num)
+			}
+			f.varint = uint64(binary.LittleEndian.Uint32(data))
+		case wireBytes:
+			size, m := binary.Uvarint(data)
+			if m <= 0 || size > uint64(len(data)-m) {
+				return fmt.Errorf("%w: truncated field %d", ErrMalformed, f.num)
+			}
+			f.data = data[m : m+int(size)]
+			n = m + int(size)
+		default:
+			return fmt.Errorf("%w: unsupported wire type %d in field %d", ErrMalformed, f.typ, f.num)
+		}
+		data = data[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expect(f field, typ int) error {
+	if f.typ != typ {
+		return fmt.Errorf("%w: field %d has wire type %d, expected %d", ErrMalformed, f.num, f.typ, typ)
+	}
+	return nil
+}
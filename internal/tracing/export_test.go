@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleSpan() SpanData {
+	var sc SpanContext
+	copy(sc.TraceID[:], []byte("0123456789abcdef"))
+	copy(sc.SpanID[:], []byte("span0001"))
+	sc.Flags = FlagSampled
+
+	start := time.Unix(1700000000, 0)
+	return SpanData{
+		Name:          "GET /todos",
+		Kind:          KindServer,
+		SpanContext:   sc,
+		Start:         start,
+		End:           start.Add(time.Millisecond),
+		Attributes:    []Attribute{String("http.route", "/todos"), Int("http.response.status_code", 200), Bool("ok", true)},
+		StatusCode:    StatusError,
+		StatusMessage: "failed",
+	}
+}
+
+func TestEncodeOTLP(t *testing.T) {
+	payload, err := EncodeOTLP("todo", []SpanData{sampleSpan()})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var decoded otlpRequest
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+
+	rs := decoded.ResourceSpans[0]
+	if *rs.Resource.Attributes[0].Value.StringValue != "todo" {
+		t.Errorf("expected service.name 'todo', got %v", rs.Resource.Attributes)
+	}
+	span := rs.ScopeSpans[0].Spans[0]
+	if span.TraceID != "30313233343536373839616263646566" {
+		t.Errorf("unexpected trace id %s", span.TraceID)
+	}
+	if span.StartTimeUnixNano != "1700000000000000000" {
+		t.Errorf("unexpected start time %s", span.StartTimeUnixNano)
+	}
+	if span.Kind != int(KindServer) || span.Status.Code != int(StatusError) {
+		t.Errorf("unexpected kind/status %d/%d", span.Kind, span.Status.Code)
+	}
+	if *span.Attributes[1].Value.IntValue != "200" {
+		t.Errorf("expected int attribute encoded as string, got %v", span.Attributes[1])
+	}
+	if !*span.Attributes[2].Value.BoolValue {
+		t.Errorf("expected bool attribute, got %v", span.Attributes[2])
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exp, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := exp.Export(context.Background(), "todo", []SpanData{sampleSpan()}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := exp.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		var req otlpRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			t.Errorf("expected valid OTLP/JSON line, got %v", err)
+		}
+	}
+}
+
+func TestHTTPExporter(t *testing.T) {
+	var body bytes.Buffer
+	var contentType string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		_, _ = io.Copy(&body, r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	exp := &HTTPExporter{URL: collector.URL + "/v1/traces"}
+	if err := exp.Export(context.Background(), "todo", []SpanData{sampleSpan()}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("expected application/json, got %q", contentType)
+	}
+	if !strings.Contains(body.String(), `"name":"GET /todos"`) {
+		t.Errorf("unexpected payload %s", body.String())
+	}
+}
+
+func TestHTTPExporterError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	exp := &HTTPExporter{URL: collector.URL}
+	if err := exp.Export(context.Background(), "todo", []SpanData{sampleSpan()}); err == nil {
+		t.Error("expected error on non-2xx response")
+	}
+}
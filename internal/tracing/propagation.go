@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const TraceparentHeader = "traceparent"
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent parses a W3C Trace Context traceparent header value.
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, errInvalidTraceparent
+	}
+
+	version := parts[0]
+	if len(version) != 2 || version == "ff" || !isLowerHex(version) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	// Version 00 has exactly four fields; future versions may append more.
+	if version == "00" && len(parts) != 4 {
+		return SpanContext{}, errInvalidTraceparent
+	}
+
+	var sc SpanContext
+	if len(parts[1]) != 32 || !isLowerHex(parts[1]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	_, _ = hex.Decode(sc.TraceID[:], []byte(parts[1]))
+
+	if len(parts[2]) != 16 || !isLowerHex(parts[2]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	_, _ = hex.Decode(sc.SpanID[:], []byte(parts[2]))
+
+	if len(parts[3]) != 2 || !isLowerHex(parts[3]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	flags, _ := strconv.ParseUint(parts[3], 16, 8)
+	sc.Flags = byte(flags)
+
+	if !sc.IsValid() {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	sc.Remote = true
+	return sc, nil
+}
+
+func FormatTraceparent(sc SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// Extract returns ctx carrying the remote parent from h, or ctx unchanged if there is none.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	sc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+func Inject(ctx context.Context, h http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, FormatTraceparent(sc))
+}
+
+// Transport wraps outgoing requests in client spans and propagates the trace context.
+type Transport struct {
+	Base   http.RoundTripper
+	Tracer *Tracer
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx, span := t.Tracer.Start(req.Context(), "HTTP "+req.Method, KindClient,
+		String("http.request.method", req.Method),
+		String("url.full", req.URL.String()),
+		String("server.address", req.URL.Host),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	Inject(ctx, req.Header)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttributes(Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(StatusError, resp.Status)
+	}
+	return resp, nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"valid sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"valid unsampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"empty", "", true},
+		{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", true},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true},
+		{"forbidden version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"short trace id", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for %q", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("unexpected trace id %s", sc.TraceID)
+			}
+			if sc.SpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("unexpected span id %s", sc.SpanID)
+			}
+		})
+	}
+}
+
+func TestFormatTraceparentRoundTrip(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, _ := ParseTraceparent(value)
+
+	if got := FormatTraceparent(sc); got != value {
+		t.Errorf("expected %s, got %s", value, got)
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	in := http.Header{}
+	in.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx := Extract(context.Background(), in)
+	out := http.Header{}
+	Inject(ctx, out)
+
+	if out.Get(TraceparentHeader) != in.Get(TraceparentHeader) {
+		t.Errorf("expected traceparent to propagate, got %q", out.Get(TraceparentHeader))
+	}
+
+	empty := http.Header{}
+	Inject(context.Background(), empty)
+	if empty.Get(TraceparentHeader) != "" {
+		t.Error("expected no traceparent without an active span")
+	}
+}
+
+func TestTransportPropagatesTraceContext(t *testing.T) {
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(TraceparentHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	exp := &memoryExporter{}
+	tracer := NewTracer(TracerOptions{Exporter: exp})
+	stop := runTracer(t, tracer)
+
+	ctx, parent := tracer.Start(context.Background(), "handler", KindServer)
+	client := &http.Client{Transport: &Transport{Tracer: tracer}}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+	stop()
+
+	sc, err := ParseTraceparent(received)
+	if err != nil {
+		t.Fatalf("expected a valid traceparent upstream, got %q", received)
+	}
+	if sc.TraceID != parent.SpanContext().TraceID {
+		t.Error("expected upstream request to continue the trace")
+	}
+	if len(exp.spans) != 2 || exp.spans[0].Kind != KindClient {
+		t.Fatalf("expected a client span to be exported, got %+v", exp.spans)
+	}
+	if sc.SpanID != exp.spans[0].SpanContext.SpanID {
+		t.Error("expected upstream parent to be the client span")
+	}
+}
@@ -0,0 +1,155 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryExporter struct {
+	mutex  sync.Mutex
+	spans  []SpanData
+	closed bool
+}
+
+func (e *memoryExporter) Export(_ context.Context, _ string, spans []SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.closed = true
+	return nil
+}
+
+func runTracer(t *testing.T, tracer *Tracer) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tracer.Run(ctx) }()
+	return func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("expected no error from Run, got %v", err)
+		}
+	}
+}
+
+func TestStartChildSpan(t *testing.T) {
+	exp := &memoryExporter{}
+	tracer := NewTracer(TracerOptions{Exporter: exp})
+	stop := runTracer(t, tracer)
+
+	ctx, parent := tracer.Start(context.Background(), "parent", KindServer)
+	_, child := tracer.Start(ctx, "child", KindInternal, String("key", "value"))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	parent.End()
+	parent.End()
+
+	stop()
+
+	if len(exp.spans) != 2 {
+		t.Fatalf("expected 2 exported spans, got %d", len(exp.spans))
+	}
+	childData, parentData := exp.spans[0], exp.spans[1]
+	if childData.SpanContext.TraceID != parentData.SpanContext.TraceID {
+		t.Error("expected child to share the parent trace id")
+	}
+	if childData.Parent.SpanID != parentData.SpanContext.SpanID {
+		t.Error("expected child parent span id to be the parent span")
+	}
+	if parentData.Parent.IsValid() {
+		t.Error("expected root span to have no parent")
+	}
+	if childData.StatusCode != StatusError || childData.StatusMessage != "boom" {
+		t.Errorf("expected error status, got %d %q", childData.StatusCode, childData.StatusMessage)
+	}
+	if !exp.closed {
+		t.Error("expected exporter to be closed on shutdown")
+	}
+}
+
+func TestRemoteParent(t *testing.T) {
+	tracer := NewTracer(TracerOptions{})
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+	_, span := tracer.Start(ctx, "server", KindServer)
+
+	sc := span.SpanContext()
+	if sc.TraceID != remote.TraceID {
+		t.Errorf("expected trace id %s, got %s", remote.TraceID, sc.TraceID)
+	}
+	if sc.SpanID == remote.SpanID {
+		t.Error("expected a new span id")
+	}
+}
+
+func TestUnsampledSpansAreNotExported(t *testing.T) {
+	exp := &memoryExporter{}
+	tracer := NewTracer(TracerOptions{Exporter: exp})
+	stop := runTracer(t, tracer)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "server", KindServer)
+	span.End()
+
+	stop()
+
+	if len(exp.spans) != 0 {
+		t.Errorf("expected no exported spans, got %d", len(exp.spans))
+	}
+}
+
+func TestBatchTimeoutExports(t *testing.T) {
+	exp := &memoryExporter{}
+	tracer := NewTracer(TracerOptions{Exporter: exp, BatchTimeout: 10 * time.Millisecond})
+	stop := runTracer(t, tracer)
+	defer stop()
+
+	_, span := tracer.Start(context.Background(), "op", KindInternal)
+	span.End()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		exp.mutex.Lock()
+		n := len(exp.spans)
+		exp.mutex.Unlock()
+		if n == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("expected span to be exported by the batch timer")
+}
+
+func TestQueueOverflowDropsSpans(t *testing.T) {
+	tracer := NewTracer(TracerOptions{Exporter: &memoryExporter{}, QueueSize: 1})
+
+	for i := 0; i < 3; i++ {
+		_, span := tracer.Start(context.Background(), "op", KindInternal)
+		span.End()
+	}
+
+	if tracer.Dropped() != 2 {
+		t.Errorf("expected 2 dropped spans, got %d", tracer.Dropped())
+	}
+}
+
+func TestNilSpanIsSafe(t *testing.T) {
+	var span *Span
+	span.SetAttributes(String("k", "v"))
+	span.RecordError(errors.New("boom"))
+	span.End()
+
+	if span.SpanContext().IsValid() {
+		t.Error("expected invalid span context for nil span")
+	}
+}
@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"todo/internal/storage"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Limits struct {
+	MaxBodyBytes         int64
+	MaxHeaderLength      int
+	MaxDescriptionLength int
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxBodyBytes:         1 << 20,
+		MaxHeaderLength:      200,
+		MaxDescriptionLength: 10000,
+	}
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error lists every invalid field. It matches storage.ErrWrongArgument with errors.Is.
+type Error struct {
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return storage.ErrWrongArgument
+}
+
+func (e *Error) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Task trims surrounding whitespace from the header and checks every client-controlled field.
+func (l Limits) Task(task *storage.Task) error {
+	errs := &Error{}
+
+	task.Header = strings.TrimSpace(task.Header)
+	if task.Header == "" {
+		errs.add("Header", "must not be empty")
+	}
+	l.text(errs, "Header", task.Header, l.MaxHeaderLength, false)
+	l.text(errs, "Description", task.Description, l.MaxDescriptionLength, true)
+
+	if !validStatus(task.Status) {
+		errs.add("Status", "must be one of 0 (Assigned), 1 (InProgress), 2 (Completed), 3 (Dropped)")
+	}
+
+	if len(errs.Fields) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (l Limits) text(errs *Error, field, value string, maxLength int, multiline bool) {
+	if !utf8.ValidString(value) {
+		errs.add(field, "must be valid UTF-8")
+		return
+	}
+	if n := utf8.RuneCountInString(value); maxLength > 0 && n > maxLength {
+		errs.add(field, "must be at most %d characters, got %d", maxLength, n)
+	}
+	for _, r := range value {
+		if multiline && (r == '\n' || r == '\r' || r == '\t') {
+			continue
+		}
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			errs.add(field, "must not contain control characters")
+			return
+		}
+	}
+}
+
+func validStatus(status storage.TaskStatus) bool {
+	for _, s := range storage.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"todo/internal/storage"
+)
+
+func TestTask(t *testing.T) {
+	limits := Limits{MaxHeaderLength: 10, MaxDescriptionLength: 20}
+
+	tests := []struct {
+		name        string
+		task        storage.Task
+		wantFields  []string
+		wantsHeader string
+	}{
+		{"valid", storage.Task{Header: "Buy milk", Description: "line 1\nline 2\ttab"}, nil, "Buy milk"},
+		{"header is trimmed", storage.Task{Header: "  Buy milk \n"}, nil, "Buy milk"},
+		{"empty header", storage.Task{Header: "   "}, []string{"Header"}, ""},
+		{"header too long", storage.Task{Header: strings.Repeat("я", 11)}, []string{"Header"}, ""},
+		{"header length counts runes", storage.Task{Header: strings.Repeat("я", 10)}, nil, strings.Repeat("я", 10)},
+		{"newline in header", storage.Task{Header: "Buy\nmilk"}, []string{"Header"}, ""},
+		{"control character in description", storage.Task{Header: "Buy", Description: "bell\x07"}, []string{"Description"}, ""},
+		{"line separator in description", storage.Task{Header: "Buy", Description: "a\u2028b"}, []string{"Description"}, ""},
+		{"invalid UTF-8", storage.Task{Header: "Buy \xff"}, []string{"Header"}, ""},
+		{"description too long", storage.Task{Header: "Buy", Description: strings.Repeat("a", 21)}, []string{"Description"}, ""},
+		{"unknown status", storage.Task{Header: "Buy", Status: 7}, []string{"Status"}, ""},
+		{"several problems", storage.Task{Header: "", Status: -1}, []string{"Header", "Status"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			err := limits.Task(&task)
+
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if task.Header != tt.wantsHeader {
+					t.Errorf("expected header %q, got %q", tt.wantsHeader, task.Header)
+				}
+				return
+			}
+
+			var verr *Error
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if len(verr.Fields) != len(tt.wantFields) {
+				t.Fatalf("expected fields %v, got %v", tt.wantFields, verr.Fields)
+			}
+			for i, field := range tt.wantFields {
+				if verr.Fields[i].Field != field {
+					t.Errorf("expected field %s, got %s", field, verr.Fields[i].Field)
+				}
+			}
+			if !errors.Is(err, storage.ErrWrongArgument) {
+				t.Error("expected validation error to match storage.ErrWrongArgument")
+			}
+		})
+	}
+}
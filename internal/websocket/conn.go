@@ -0,0 +1,396 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the opcode of a frame.
+type MessageType int
+
+const (
+	continuationFrame MessageType = 0
+
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+	CloseMessage  MessageType = 8
+	PingMessage   MessageType = 9
+	PongMessage   MessageType = 10
+)
+
+func (t MessageType) control() bool {
+	return t >= CloseMessage
+}
+
+// Close codes from RFC 6455, section 7.4.1.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const (
+	// DefaultReadLimit bounds the size of a message, fragments included.
+	DefaultReadLimit = 1 << 20
+	// closeTimeout is how long Close waits for the peer to answer before dropping the
+	// connection.
+	closeTimeout      = time.Second
+	maxControlPayload = 125
+)
+
+// ErrClosed is returned by writes after a close frame was sent.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// CloseError is returned by ReadMessage once the peer closed the connection, or after the
+// connection was failed because the peer broke the protocol.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: close %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: close %d: %s", e.Code, e.Reason)
+}
+
+// Conn is an established websocket connection. One goroutine may read while others write;
+// writes are serialized.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	client      bool
+	subprotocol string
+
+	readLimit   int64
+	pongHandler func(data []byte)
+	readErr     error
+	// failed is set when this side failed the connection and waits for the peer to read the
+	// close frame before dropping it.
+	failed bool
+
+	writeMu       sync.Mutex
+	writeDeadline time.Time
+	closeSent     bool
+	closeOnce     sync.Once
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, client bool, subprotocol string) *Conn {
+	return &Conn{conn: conn, br: br, client: client, subprotocol: subprotocol, readLimit: DefaultReadLimit}
+}
+
+// Subprotocol returns the negotiated subprotocol, or "" if none was agreed on.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadLimit sets the maximum size of a message; larger messages fail the connection with
+// CloseMessageTooBig.
+func (c *Conn) SetReadLimit(n int64) {
+	c.readLimit = n
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline applies to WriteMessage calls; control frames carry their own deadline.
+func (c *Conn) SetWriteDeadline(t time.Time) {
+	c.writeMu.Lock()
+	c.writeDeadline = t
+	c.writeMu.Unlock()
+}
+
+// SetPongHandler sets a function called from ReadMessage for every pong received. Pings are
+// always answered with a pong.
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
+// ReadMessage returns the next text or binary message, handling control frames on the way.
+// After a close frame or a protocol violation every call returns the same error and the
+// underlying connection is closed.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	typ, msg, err := c.readMessage()
+	if err != nil {
+		c.readErr = err
+		if !c.failed {
+			c.closeConn()
+		}
+		return 0, nil, err
+	}
+	return typ, msg, nil
+}
+
+func (c *Conn) readMessage() (MessageType, []byte, error) {
+	var typ MessageType
+	var msg []byte
+	for {
+		fin, opcode, payload, err := c.readFrame(int64(len(msg)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.WriteControl(PongMessage, payload, time.Now().Add(closeTimeout)); err != nil && !errors.Is(err, ErrClosed) {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				c.pongHandler(payload)
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.closeReceived(payload)
+		case continuationFrame:
+			if typ == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		case TextMessage, BinaryMessage:
+			if typ != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected a continuation frame")
+			}
+			typ = opcode
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+
+		msg = append(msg, payload...)
+		if fin {
+			if typ == TextMessage && !utf8.Valid(msg) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8 in text message")
+			}
+			return typ, msg, nil
+		}
+	}
+}
+
+// readFrame reads one frame; read is the size of the message so far.
+func (c *Conn) readFrame(read int64) (bool, MessageType, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := MessageType(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	if masked == c.client {
+		// Clients must mask every frame and servers must not.
+		return false, 0, nil, c.fail(CloseProtocolError, "bad masking")
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n := binary.BigEndian.Uint64(ext[:])
+		if n > 1<<62 {
+			return false, 0, nil, c.fail(CloseProtocolError, "frame length overflows")
+		}
+		length = int64(n)
+	}
+
+	if opcode.control() && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if !opcode.control() && read+length > c.readLimit {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		mask(key, payload)
+	}
+	return fin, opcode, payload, nil
+}
+
+func mask(key [4]byte, data []byte) {
+	for i := range data {
+		data[i] ^= key[i%4]
+	}
+}
+
+// closeReceived answers a close frame from the peer and reports it as a *CloseError.
+func (c *Conn) closeReceived(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+		if !validCloseCode(closeErr.Code) || !utf8.ValidString(closeErr.Reason) {
+			return c.fail(CloseProtocolError, "invalid close frame")
+		}
+	}
+
+	echo := closeErr.Code
+	if echo == CloseNoStatus {
+		echo = CloseNormal
+	}
+	c.writeClose(echo, "")
+	return closeErr
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011, code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// fail closes the connection after a protocol violation by the peer. Dropping it right away
+// could reset it before the peer reads why, so the close frame gets some time to arrive.
+func (c *Conn) fail(code int, reason string) error {
+	c.failed = true
+	c.writeClose(code, reason)
+	time.AfterFunc(closeTimeout, c.closeConn)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// WriteMessage sends a text or binary message in a single frame.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("websocket: %d is not a data message type", typ)
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeFrame(typ, data, c.writeDeadline)
+}
+
+// WriteControl sends a ping or pong frame. Close frames are sent by Close.
+func (c *Conn) WriteControl(typ MessageType, data []byte, deadline time.Time) error {
+	if typ != PingMessage && typ != PongMessage {
+		return fmt.Errorf("websocket: %d is not a ping or pong", typ)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload too long")
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeFrame(typ, data, deadline)
+}
+
+// writeFrame is called with writeMu held.
+func (c *Conn) writeFrame(typ MessageType, data []byte, deadline time.Time) error {
+	if c.closeSent {
+		return ErrClosed
+	}
+
+	frame := make([]byte, 0, 14+len(data))
+	frame = append(frame, 0x80|byte(typ))
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(data); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		var key [4]byte
+		rand.Read(key[:])
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, data...)
+		mask(key, frame[start:])
+	} else {
+		frame = append(frame, data...)
+	}
+
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close starts the closing handshake. The connection is dropped once the peer answers, which
+// a running ReadMessage notices, or after a short timeout.
+func (c *Conn) Close(code int, reason string) error {
+	if !c.writeClose(code, reason) {
+		return ErrClosed
+	}
+	time.AfterFunc(closeTimeout, c.closeConn)
+	return nil
+}
+
+// writeClose sends a close frame unless one was sent already, and reports whether it did.
+func (c *Conn) writeClose(code int, reason string) bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return false
+	}
+
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+		for !utf8.ValidString(reason) {
+			reason = reason[:len(reason)-1]
+		}
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	c.writeFrame(CloseMessage, payload, time.Now().Add(closeTimeout))
+	c.closeSent = true
+	return true
+}
+
+func (c *Conn) closeConn() {
+	c.closeOnce.Do(func() {
+		c.conn.Close()
+	})
+}
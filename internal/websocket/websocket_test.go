@@ -0,0 +1,288 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer upgrades every request and sends each message back; the error that ended the
+// read loop is delivered on the returned channel.
+func echoServer(t *testing.T, setup func(c *Conn)) (*httptest.Server, <-chan error) {
+	t.Helper()
+	done := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r, []string{"chat", "echo"})
+		if err != nil {
+			done <- err
+			return
+		}
+		if setup != nil {
+			setup(c)
+		}
+		for {
+			typ, msg, err := c.ReadMessage()
+			if err != nil {
+				done <- err
+				return
+			}
+			if err := c.WriteMessage(typ, msg); err != nil {
+				done <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, done
+}
+
+func dial(t *testing.T, srv *httptest.Server, protocols string) *Conn {
+	t.Helper()
+	header := http.Header{}
+	if protocols != "" {
+		header.Set("Sec-WebSocket-Protocol", protocols)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := Dial(ctx, srv.URL, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEcho(t *testing.T) {
+	srv, done := echoServer(t, nil)
+	c := dial(t, srv, "other, echo, chat")
+	if c.Subprotocol() != "echo" {
+		t.Errorf("expected subprotocol echo, got %q", c.Subprotocol())
+	}
+
+	messages := []struct {
+		typ  MessageType
+		data []byte
+	}{
+		{TextMessage, []byte("hello")},
+		{BinaryMessage, []byte{0, 1, 2, 255}},
+		{TextMessage, []byte{}},
+		{TextMessage, bytes.Repeat([]byte("a"), 300)},
+		{BinaryMessage, bytes.Repeat([]byte{7}, 70000)},
+	}
+	for _, m := range messages {
+		if err := c.WriteMessage(m.typ, m.data); err != nil {
+			t.Fatal(err)
+		}
+		typ, data, err := c.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != m.typ || !bytes.Equal(data, m.data) {
+			t.Errorf("expected %d message of %d bytes, got %d message of %d bytes", m.typ, len(m.data), typ, len(data))
+		}
+	}
+
+	if err := c.Close(CloseNormal, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	var closeErr *CloseError
+	if err := <-done; !errors.As(err, &closeErr) || closeErr.Code != CloseNormal || closeErr.Reason != "bye" {
+		t.Errorf("expected the server to see close 1000 bye, got %v", err)
+	}
+	if _, _, err := c.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != CloseNormal {
+		t.Errorf("expected the close to be echoed, got %v", err)
+	}
+	if err := c.WriteMessage(TextMessage, []byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestNoSubprotocol(t *testing.T) {
+	srv, _ := echoServer(t, nil)
+	if c := dial(t, srv, "graphql-ws"); c.Subprotocol() != "" {
+		t.Errorf("expected no subprotocol, got %q", c.Subprotocol())
+	}
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	valid := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *http.Request)
+		status int
+		header string
+	}{
+		{"method", func(r *http.Request) { r.Method = http.MethodPost }, http.StatusMethodNotAllowed, "Allow"},
+		{"http2", func(r *http.Request) { r.ProtoMajor = 2 }, http.StatusHTTPVersionNotSupported, ""},
+		{"no upgrade", func(r *http.Request) { r.Header.Del("Upgrade") }, http.StatusUpgradeRequired, "Upgrade"},
+		{"version", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }, http.StatusUpgradeRequired, "Sec-WebSocket-Version"},
+		{"key", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "short") }, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(r)
+			w := httptest.NewRecorder()
+			if _, err := Upgrade(w, r, nil); !errors.Is(err, ErrBadHandshake) {
+				t.Errorf("expected ErrBadHandshake, got %v", err)
+			}
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.header != "" && w.Header().Get(tt.header) == "" {
+				t.Errorf("expected a %s header", tt.header)
+			}
+		})
+	}
+}
+
+func TestDialRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "go away", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, resp, err := Dial(context.Background(), srv.URL, nil)
+	if !errors.Is(err, ErrBadHandshake) || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected a rejected handshake, got %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); strings.TrimSpace(string(body)) != "go away" {
+		t.Errorf("expected the response body, got %q", body)
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	pongs := make(chan string, 1)
+	srv, _ := echoServer(t, func(c *Conn) {
+		c.SetPongHandler(func(data []byte) { pongs <- string(data) })
+		if err := c.WriteControl(PingMessage, []byte("are you there"), time.Now().Add(time.Second)); err != nil {
+			t.Error(err)
+		}
+	})
+	c := dial(t, srv, "")
+
+	// Reading answers the ping; the echo proves the server went on reading.
+	if err := c.WriteMessage(TextMessage, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, msg, err := c.ReadMessage(); err != nil || string(msg) != "x" {
+		t.Fatalf("unexpected echo %q: %v", msg, err)
+	}
+	select {
+	case data := <-pongs:
+		if data != "are you there" {
+			t.Errorf("expected the ping payload back, got %q", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no pong")
+	}
+
+	if err := c.WriteControl(PingMessage, bytes.Repeat([]byte("x"), 126), time.Time{}); err == nil {
+		t.Error("expected an oversized ping to be refused")
+	}
+}
+
+// frame builds a masked client frame by hand.
+func frame(fin bool, opcode byte, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	b := []byte{first}
+	switch {
+	case len(payload) <= 125:
+		b = append(b, 0x80|byte(len(payload)))
+	default:
+		b = append(b, 0x80|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(payload)))
+	}
+	key := [4]byte{1, 2, 3, 4}
+	b = append(b, key[:]...)
+	start := len(b)
+	b = append(b, payload...)
+	mask(key, b[start:])
+	return b
+}
+
+func TestFragmentedMessage(t *testing.T) {
+	srv, _ := echoServer(t, nil)
+	c := dial(t, srv, "")
+
+	var raw []byte
+	raw = append(raw, frame(false, 1, []byte("hel"))...)
+	raw = append(raw, frame(true, 9, []byte("mid"))...)
+	raw = append(raw, frame(true, 0, []byte("lo"))...)
+	if _, err := c.conn.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, msg, err := c.ReadMessage(); err != nil || string(msg) != "hello" {
+		t.Errorf("expected hello, got %q: %v", msg, err)
+	}
+}
+
+func TestProtocolViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		code int
+	}{
+		{"unmasked", []byte{0x81, 0x01, 'a'}, CloseProtocolError},
+		{"reserved bits", append([]byte{0xc1}, frame(true, 1, []byte("a"))[1:]...), CloseProtocolError},
+		{"unknown opcode", frame(true, 3, nil), CloseProtocolError},
+		{"lone continuation", frame(true, 0, []byte("a")), CloseProtocolError},
+		{"fragmented ping", frame(false, 9, nil), CloseProtocolError},
+		{"invalid utf-8", frame(true, 1, []byte{0xff, 0xfe}), CloseInvalidPayload},
+		{"too big", frame(true, 2, make([]byte, 200)), CloseMessageTooBig},
+		{"bad close code", frame(true, 8, []byte{0x03, 0xe7}), CloseProtocolError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, done := echoServer(t, func(c *Conn) { c.SetReadLimit(100) })
+			c := dial(t, srv, "")
+			if _, err := c.conn.Write(tt.raw); err != nil {
+				t.Fatal(err)
+			}
+
+			var closeErr *CloseError
+			if _, _, err := c.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != tt.code {
+				t.Errorf("expected close %d, got %v", tt.code, err)
+			}
+			if err := <-done; !errors.As(err, &closeErr) || closeErr.Code != tt.code {
+				t.Errorf("expected the server to fail with %d, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestCloseWithoutAnswer(t *testing.T) {
+	srv, _ := echoServer(t, nil)
+	c := dial(t, srv, "")
+
+	// The server answers, but nobody reads on this side: the timeout drops the connection.
+	if err := c.Close(CloseGoingAway, strings.Repeat("é", 100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(CloseGoingAway, ""); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected a second close to fail, got %v", err)
+	}
+	time.Sleep(closeTimeout + 100*time.Millisecond)
+	if _, err := c.conn.Write([]byte{0}); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+}
@@ -0,0 +1,394 @@
+// Package client is a Go SDK for the TODO HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned by the client wrap these. Their messages are the ones the server reports.
+var (
+	ErrTaskNotFound  = errors.New("task is not found")
+	ErrWrongArgument = errors.New("wrong argument")
+	ErrStorageClosed = errors.New("storage is closed")
+	ErrQuotaExceeded = errors.New("task quota exceeded")
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError is a non-2xx response from the server.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Details    []FieldError
+	RequestID  string
+	RetryAfter time.Duration
+
+	body []byte
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("todo api: %d %s", e.StatusCode, e.Message)
+	for _, d := range e.Details {
+		msg += fmt.Sprintf("; %s: %s", d.Field, d.Message)
+	}
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrTaskNotFound
+	case e.StatusCode == http.StatusBadRequest, e.StatusCode == http.StatusRequestEntityTooLarge,
+		e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrWrongArgument
+	case e.StatusCode == http.StatusForbidden && e.Message == ErrQuotaExceeded.Error():
+		return ErrQuotaExceeded
+	case e.StatusCode == http.StatusServiceUnavailable && e.Message == ErrStorageClosed.Error():
+		return ErrStorageClosed
+	default:
+		return nil
+	}
+}
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	userAgent  string
+	transport  http.RoundTripper
+
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken sends the token as a bearer credential; the server uses it to identify the task owner.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithRetries sets how many times a request is retried after 429 or 5xx responses and the
+// exponential backoff bounds; a Retry-After from the server takes precedence over the backoff.
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithTransport sends requests through rt instead of the HTTP client's own transport, for
+// example a tracing transport that records client spans and sends the W3C traceparent, so the
+// server's spans join the caller's trace.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("base URL %q must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  "todo-client",
+		maxRetries: 3,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.transport != nil {
+		hc := *c.httpClient
+		hc.Transport = c.transport
+		c.httpClient = &hc
+	}
+	return c, nil
+}
+
+// apiPrefix selects the version of the API contract the client speaks.
+const apiPrefix = "/v1"
+
+func (c *Client) Create(ctx context.Context, task Task) (*Task, error) {
+	var created wireTask
+	if _, err := c.do(ctx, http.MethodPost, apiPrefix+"/todos", nil, toWire(task), &created); err != nil {
+		return nil, err
+	}
+	return fromWire(created)
+}
+
+func (c *Client) Get(ctx context.Context, id int) (*Task, error) {
+	var task wireTask
+	if _, err := c.do(ctx, http.MethodGet, apiPrefix+"/todos/"+strconv.Itoa(id), nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return fromWire(task)
+}
+
+func (c *Client) Update(ctx context.Context, id int, task Task) (*Task, error) {
+	var updated wireTask
+	if _, err := c.do(ctx, http.MethodPut, apiPrefix+"/todos/"+strconv.Itoa(id), nil, toWire(task), &updated); err != nil {
+		return nil, err
+	}
+	return fromWire(updated)
+}
+
+func (c *Client) Delete(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, apiPrefix+"/todos/"+strconv.Itoa(id), nil, nil, nil)
+	return err
+}
+
+// List iterates over pages of at most pageSize tasks ordered by ID; a pageSize of 0 fetches
+// everything in one page. Iteration stops after the first error, which is yielded with a nil page.
+func (c *Client) List(ctx context.Context, pageSize int) iter.Seq2[[]Task, error] {
+	return c.Find(ctx, Filter{}, pageSize)
+}
+
+// Find is List restricted to the tasks matching filter, which the server applies.
+func (c *Client) Find(ctx context.Context, filter Filter, pageSize int) iter.Seq2[[]Task, error] {
+	return func(yield func([]Task, error) bool) {
+		query := filter.query()
+		if pageSize > 0 {
+			query.Set("limit", strconv.Itoa(pageSize))
+		}
+
+		for query != nil {
+			var dtos []wireTask
+			resp, err := c.do(ctx, http.MethodGet, apiPrefix+"/todos", query, nil, &dtos)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			tasks := make([]Task, len(dtos))
+			for i, dto := range dtos {
+				task, err := fromWire(dto)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				tasks[i] = *task
+			}
+
+			query, err = nextPage(resp.Header.Get("Link"))
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(tasks, nil) {
+				return
+			}
+		}
+	}
+}
+
+// All collects every task.
+func (c *Client) All(ctx context.Context) ([]Task, error) {
+	var all []Task
+	for tasks, err := range c.List(ctx, 100) {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, tasks...)
+	}
+	return all, nil
+}
+
+func nextPage(link string) (url.Values, error) {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return nil, fmt.Errorf("parse next page link: %w", err)
+		}
+		return u.Query(), nil
+	}
+	return nil, nil
+}
+
+// rawBody is sent as it is instead of being encoded as JSON.
+type rawBody struct {
+	contentType string
+	data        []byte
+}
+
+// do sends in as JSON, or as it is when it is a rawBody, and decodes a successful response into
+// out, which may also be a *[]byte to receive the body unparsed.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) (*http.Response, error) {
+	var body []byte
+	var contentType string
+	switch in := in.(type) {
+	case nil:
+	case rawBody:
+		body, contentType = in.data, in.contentType
+	default:
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+		contentType = "application/json"
+	}
+	accept := "application/json"
+	if _, ok := out.(*[]byte); ok {
+		accept = "*/*"
+	}
+
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", accept)
+		req.Header.Set("User-Agent", c.userAgent)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || !idempotent(method) || attempt >= c.maxRetries {
+				return nil, err
+			}
+			if err := c.sleep(ctx, attempt, 0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if raw, ok := out.(*[]byte); ok {
+				if *raw, err = io.ReadAll(resp.Body); err != nil {
+					return nil, fmt.Errorf("read response: %w", err)
+				}
+			} else if out != nil && resp.StatusCode != http.StatusNoContent {
+				if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+					return nil, fmt.Errorf("decode response: %w", err)
+				}
+			}
+			return resp, nil
+		}
+
+		apiErr := readError(resp)
+		if attempt >= c.maxRetries || !retryable(method, resp.StatusCode) {
+			return nil, apiErr
+		}
+		if err := c.sleep(ctx, attempt, apiErr.RetryAfter); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	}
+
+	var body struct {
+		Error     string       `json:"error"`
+		Details   []FieldError `json:"details"`
+		RequestID string       `json:"request_id"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	apiErr.body = data
+	if err := json.Unmarshal(data, &body); err == nil && body.Error != "" {
+		apiErr.Message = body.Error
+		apiErr.Details = body.Details
+		apiErr.RequestID = body.RequestID
+	}
+	return apiErr
+}
+
+// retryable reports whether a failed request may be sent again. 429 and 503 are rejected before
+// any change is made; other 5xx responses are retried only when repeating the request is safe.
+func retryable(method string, code int) bool {
+	switch {
+	case code == http.StatusTooManyRequests, code == http.StatusServiceUnavailable:
+		return true
+	case code >= http.StatusInternalServerError && code != http.StatusNotImplemented:
+		return idempotent(method)
+	default:
+		return false
+	}
+}
+
+func idempotent(method string) bool {
+	return method != http.MethodPost && method != http.MethodPatch
+}
+
+func (c *Client) sleep(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := retryAfter
+	if delay == 0 {
+		delay = c.minBackoff << attempt
+		if delay > c.maxBackoff || delay <= 0 {
+			delay = c.maxBackoff
+		}
+		// Full jitter spreads retries from many clients hitting the same outage.
+		delay = time.Duration(rand.Int64N(int64(delay) + 1))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,333 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+	"todo/internal/server"
+	"todo/internal/storage"
+	"todo/internal/tracing"
+)
+
+func newTestClient(t *testing.T, handler http.Handler, opts ...Option) *Client {
+	t.Helper()
+
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	opts = append([]Option{WithRetries(3, time.Millisecond, 10*time.Millisecond)}, opts...)
+	c, err := New(ts.URL, opts...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
+
+func newAPI(opts ...storage.Option) http.Handler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return server.NewServer(storage.NewStorage(opts...), logger).Handler()
+}
+
+func TestCRUD(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newAPI(), WithToken("secret"))
+
+	created, err := c.Create(ctx, Task{Header: "Buy milk", Description: "At the store"})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if created.Header != "Buy milk" || created.Owner == "" {
+		t.Errorf("unexpected created task: %+v", created)
+	}
+
+	got, err := c.Get(ctx, created.TaskID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if *got != *created {
+		t.Errorf("expected %+v, got %+v", created, got)
+	}
+
+	updated, err := c.Update(ctx, created.TaskID, Task{Header: "Buy milk", Status: Completed})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if updated.Status != Completed {
+		t.Errorf("expected status %v, got %v", Completed, updated.Status)
+	}
+
+	if err := c.Delete(ctx, created.TaskID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := c.Get(ctx, created.TaskID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound after delete, got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newAPI(storage.WithMaxTasksPerOwner(2)), WithToken("owner"))
+
+	tests := []struct {
+		name   string
+		call   func() error
+		target error
+		status int
+	}{
+		{
+			name:   "missing task",
+			call:   func() error { _, err := c.Get(ctx, 42); return err },
+			target: ErrTaskNotFound,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "delete missing task",
+			call:   func() error { return c.Delete(ctx, 42) },
+			target: ErrTaskNotFound,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "empty header",
+			call:   func() error { _, err := c.Create(ctx, Task{Header: "  "}); return err },
+			target: ErrWrongArgument,
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "quota exceeded",
+			call: func() error {
+				for i := 0; i < 3; i++ {
+					if _, err := c.Create(ctx, Task{Header: "Task " + strconv.Itoa(i)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			target: ErrQuotaExceeded,
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.target) {
+				t.Fatalf("expected %v, got %v", tt.target, err)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %T", err)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, apiErr.StatusCode)
+			}
+			if apiErr.RequestID == "" {
+				t.Error("expected the request ID to be reported")
+			}
+		})
+	}
+}
+
+func TestValidationDetails(t *testing.T) {
+	c := newTestClient(t, newAPI())
+
+	_, err := c.Create(context.Background(), Task{Header: "Task", Status: TaskStatus(9)})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "status" {
+		t.Errorf("expected a status field error, got %+v", apiErr.Details)
+	}
+}
+
+func TestListPages(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newAPI())
+	for i := 0; i < 5; i++ {
+		if _, err := c.Create(ctx, Task{Header: "Task " + strconv.Itoa(i)}); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	var sizes []int
+	for tasks, err := range c.List(ctx, 2) {
+		if err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		sizes = append(sizes, len(tasks))
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("expected pages of 2, 2 and 1 tasks, got %v", sizes)
+	}
+
+	all, err := c.All(ctx)
+	if err != nil {
+		t.Fatalf("all failed: %v", err)
+	}
+	if len(all) != 5 {
+		t.Errorf("expected 5 tasks, got %d", len(all))
+	}
+
+	pages := 0
+	for range c.List(ctx, 1) {
+		pages++
+		break
+	}
+	if pages != 1 {
+		t.Errorf("expected iteration to stop after break, got %d pages", pages)
+	}
+}
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newAPI())
+	tasks := []Task{
+		{Header: "Write report", Status: Assigned},
+		{Header: "Read report", Status: Completed},
+		{Header: "Buy milk", Status: Assigned},
+		{Header: "Send report", Status: Dropped},
+	}
+	for _, task := range tasks {
+		if _, err := c.Create(ctx, task); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	var got []string
+	filter := Filter{Statuses: []TaskStatus{Assigned, Completed}, Query: "REPORT"}
+	for page, err := range c.Find(ctx, filter, 1) {
+		if err != nil {
+			t.Fatalf("find failed: %v", err)
+		}
+		for _, task := range page {
+			got = append(got, task.Header)
+		}
+	}
+	if want := []string{"Write report", "Read report"}; !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// flaky fails the first n requests with the given status before passing them to next.
+func flaky(n int32, status int, next http.Handler) (http.Handler, *atomic.Int32) {
+	var calls atomic.Int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			http.Error(w, `{"error":"temporary"}`, status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &calls
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		status    int
+		create    bool
+		wantErr   bool
+		wantCalls int32
+	}{
+		{"get retried after 500", 2, http.StatusInternalServerError, false, false, 3},
+		{"get retried after 429", 1, http.StatusTooManyRequests, false, false, 2},
+		{"get gives up after max retries", 10, http.StatusBadGateway, false, true, 4},
+		{"create retried after 503", 1, http.StatusServiceUnavailable, true, false, 2},
+		{"create not retried after 500", 1, http.StatusInternalServerError, true, true, 1},
+		{"client errors not retried", 1, http.StatusConflict, false, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, calls := flaky(tt.failures, tt.status, newAPI())
+			c := newTestClient(t, handler)
+
+			var err error
+			if tt.create {
+				_, err = c.Create(context.Background(), Task{Header: "Task"})
+			} else {
+				_, err = c.All(context.Background())
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("expected %d requests, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	handler, _ := flaky(100, http.StatusServiceUnavailable, newAPI())
+	c := newTestClient(t, handler, WithRetries(100, time.Second, time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Get(ctx, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("backoff did not respect context cancellation")
+	}
+}
+
+func TestNewRejectsInvalidURL(t *testing.T) {
+	for _, raw := range []string{"localhost:8080", "ftp://example.com", "://"} {
+		if _, err := New(raw); err == nil {
+			t.Errorf("expected an error for %q", raw)
+		}
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	var got atomic.Value
+	api := newAPI()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get(tracing.TraceparentHeader))
+		api.ServeHTTP(w, r)
+	})
+	tracer := tracing.NewTracer(tracing.TracerOptions{})
+	c := newTestClient(t, handler, WithTransport(&tracing.Transport{Tracer: tracer}))
+
+	ctx, span := tracer.Start(context.Background(), "caller", tracing.KindInternal)
+	defer span.End()
+	if _, err := c.Create(ctx, Task{Header: "Traced"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	header, _ := got.Load().(string)
+	sc, err := tracing.ParseTraceparent(header)
+	if err != nil {
+		t.Fatalf("expected a valid traceparent, got %q: %v", header, err)
+	}
+	if sc.TraceID != span.SpanContext().TraceID {
+		t.Errorf("expected trace %s, got %s", span.SpanContext().TraceID, sc.TraceID)
+	}
+	if sc.SpanID == span.SpanContext().SpanID {
+		t.Error("expected the request to carry its own client span")
+	}
+
+	untraced := newTestClient(t, handler)
+	if _, err := untraced.Create(context.Background(), Task{Header: "Plain"}); err != nil {
+		t.Fatal(err)
+	}
+	if header, _ := got.Load().(string); header != "" {
+		t.Errorf("expected no traceparent without a tracing transport, got %q", header)
+	}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TaskStatus is where a task is in its workflow.
+type TaskStatus int
+
+const (
+	Assigned TaskStatus = iota
+	InProgress
+	Completed
+	Dropped
+)
+
+// Statuses lists every status in workflow order.
+var Statuses = []TaskStatus{Assigned, InProgress, Completed, Dropped}
+
+func (s TaskStatus) String() string {
+	switch s {
+	case Assigned:
+		return "Assigned"
+	case InProgress:
+		return "InProgress"
+	case Completed:
+		return "Completed"
+	case Dropped:
+		return "Dropped"
+	default:
+		return "TaskStatus(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// ParseTaskStatus accepts a status name in any case, with optional "-", "_" or space
+// separators, or its number.
+func ParseTaskStatus(s string) (TaskStatus, error) {
+	name := strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.TrimSpace(s))
+	for _, status := range Statuses {
+		if strings.EqualFold(name, status.String()) || name == strconv.Itoa(int(status)) {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown task status %q", ErrWrongArgument, s)
+}
+
+type Task struct {
+	TaskID      int
+	Header      string
+	Description string
+	Status      TaskStatus
+	Owner       string
+}
+
+// Filter selects tasks by all of its conditions; the zero Filter matches every task.
+type Filter struct {
+	Statuses []TaskStatus
+	Owner    string
+	// Query matches a case-insensitive substring of the header or the description.
+	Query string
+}
+
+// query encodes the filter as the parameters of a task list.
+func (f Filter) query() url.Values {
+	query := url.Values{}
+	for _, status := range f.Statuses {
+		query.Add("status", toWire(Task{Status: status}).Status)
+	}
+	if f.Owner != "" {
+		query.Set("owner", f.Owner)
+	}
+	if f.Query != "" {
+		query.Set("q", f.Query)
+	}
+	return query
+}
+
+func (f Filter) Match(task Task) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, task.Status) {
+		return false
+	}
+	if f.Owner != "" && task.Owner != f.Owner {
+		return false
+	}
+	text := strings.ToLower(task.Header + "\n" + task.Description)
+	return f.Query == "" || strings.Contains(text, strings.ToLower(f.Query))
+}
+
+// wireStatuses are the status names of the /v1 contract, indexed by TaskStatus.
+var wireStatuses = []string{"assigned", "in_progress", "completed", "dropped"}
+
+// wireTask is a task in the /v1 JSON contract.
+type wireTask struct {
+	ID          int    `json:"id"`
+	Header      string `json:"header"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Owner       string `json:"owner"`
+}
+
+// toWire sends an unknown status as its number, which the server rejects with a field error.
+func toWire(task Task) wireTask {
+	status := strconv.Itoa(int(task.Status))
+	if int(task.Status) >= 0 && int(task.Status) < len(wireStatuses) {
+		status = wireStatuses[task.Status]
+	}
+	return wireTask{
+		ID:          task.TaskID,
+		Header:      task.Header,
+		Description: task.Description,
+		Status:      status,
+		Owner:       task.Owner,
+	}
+}
+
+func fromWire(dto wireTask) (*Task, error) {
+	status := slices.Index(wireStatuses, dto.Status)
+	if status < 0 {
+		return nil, fmt.Errorf("decode response: unknown task status %q", dto.Status)
+	}
+	return &Task{
+		TaskID:      dto.ID,
+		Header:      dto.Header,
+		Description: dto.Description,
+		Status:      TaskStatus(status),
+		Owner:       dto.Owner,
+	}, nil
+}
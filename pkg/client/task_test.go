@@ -0,0 +1,85 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	v1 "todo/internal/api/v1"
+	"todo/internal/storage"
+)
+
+func TestParseTaskStatus(t *testing.T) {
+	tests := []struct {
+		input string
+		want  TaskStatus
+	}{
+		{"assigned", Assigned},
+		{"In-Progress", InProgress},
+		{"in_progress", InProgress},
+		{" completed ", Completed},
+		{"3", Dropped},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTaskStatus(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTaskStatus(%q) = %v, %v; expected %v", tt.input, got, err, tt.want)
+		}
+	}
+	if _, err := ParseTaskStatus("later"); !errors.Is(err, ErrWrongArgument) {
+		t.Errorf("expected ErrWrongArgument, got %v", err)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	task := Task{Header: "Write Report", Description: "for the board", Status: InProgress, Owner: "token:abc"}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"zero", Filter{}, true},
+		{"status", Filter{Statuses: []TaskStatus{Assigned, InProgress}}, true},
+		{"other status", Filter{Statuses: []TaskStatus{Completed}}, false},
+		{"owner", Filter{Owner: "token:abc"}, true},
+		{"other owner", Filter{Owner: "token:def"}, false},
+		{"query in header", Filter{Query: "report"}, true},
+		{"query in description", Filter{Query: "BOARD"}, true},
+		{"query missing", Filter{Query: "invoice"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Match(task); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestContractMatchesServer guards the copies of the server's contract kept by the client.
+func TestContractMatchesServer(t *testing.T) {
+	for i, status := range Statuses {
+		if wireStatuses[i] != string(v1.StatusOf(storage.TaskStatus(status))) {
+			t.Errorf("status %v is sent as %q, the server calls it %q", status, wireStatuses[i], v1.StatusOf(storage.TaskStatus(status)))
+		}
+	}
+
+	errs := []struct{ client, server error }{
+		{ErrTaskNotFound, storage.ErrTaskNotFound},
+		{ErrWrongArgument, storage.ErrWrongArgument},
+		{ErrStorageClosed, storage.ErrStorageClosed},
+		{ErrQuotaExceeded, storage.ErrQuotaExceeded},
+	}
+	for _, e := range errs {
+		if e.client.Error() != e.server.Error() {
+			t.Errorf("client error %q does not match the server's %q", e.client, e.server)
+		}
+	}
+
+	task := Task{TaskID: 4, Header: "h", Description: "d", Status: Completed, Owner: "o"}
+	got, err := fromWire(toWire(task))
+	if err != nil || *got != task {
+		t.Errorf("expected %+v to survive the wire format, got %+v, %v", task, got, err)
+	}
+	if _, err := fromWire(wireTask{Status: "later"}); err == nil {
+		t.Error("expected an unknown status to be rejected")
+	}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Formats accepted by Export and Import.
+const (
+	FormatCSV      = "csv"
+	FormatICal     = "ical"
+	FormatMarkdown = "markdown"
+	FormatTodoTxt  = "todotxt"
+)
+
+var formatContentTypes = map[string]string{
+	FormatCSV:      "text/csv; charset=utf-8",
+	FormatICal:     "text/calendar; charset=utf-8",
+	FormatMarkdown: "text/markdown; charset=utf-8",
+	FormatTodoTxt:  "text/plain; charset=utf-8",
+}
+
+// ImportMode selects what Import does with rows that carry an existing task ID.
+type ImportMode string
+
+const (
+	// ImportCreate always adds new tasks.
+	ImportCreate ImportMode = "create"
+	// ImportUpsert updates the task with the row's ID and creates the rest.
+	ImportUpsert ImportMode = "upsert"
+)
+
+type ImportOptions struct {
+	Mode   ImportMode
+	DryRun bool
+}
+
+type ImportRow struct {
+	Line   int          `json:"line"`
+	Action string       `json:"action"`
+	TaskID *int         `json:"task_id,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// ImportReport is the per-row outcome of an import.
+type ImportReport struct {
+	DryRun         bool        `json:"dry_run"`
+	Mode           ImportMode  `json:"mode"`
+	Valid          bool        `json:"valid"`
+	Created        int         `json:"created"`
+	Updated        int         `json:"updated"`
+	Failed         int         `json:"failed"`
+	IgnoredColumns []string    `json:"ignored_columns,omitempty"`
+	Rows           []ImportRow `json:"rows"`
+}
+
+// Export downloads all tasks as a file in the given format.
+func (c *Client) Export(ctx context.Context, format string) ([]byte, error) {
+	var data []byte
+	query := url.Values{"format": {format}}
+	if _, err := c.do(ctx, http.MethodGet, apiPrefix+"/todos/export", query, nil, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Import uploads a file in the given format. When the file has invalid rows nothing is changed
+// and the report is returned together with an *APIError wrapping ErrWrongArgument.
+func (c *Client) Import(ctx context.Context, format string, data []byte, opts ImportOptions) (*ImportReport, error) {
+	query := url.Values{"format": {format}}
+	if opts.Mode != "" {
+		query.Set("mode", string(opts.Mode))
+	}
+	if opts.DryRun {
+		query.Set("dry_run", strconv.FormatBool(opts.DryRun))
+	}
+	contentType, ok := formatContentTypes[format]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+
+	var report ImportReport
+	_, err := c.do(ctx, http.MethodPost, apiPrefix+"/todos/import", query, rawBody{contentType, data}, &report)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity {
+		if json.Unmarshal(apiErr.body, &report) == nil {
+			return &report, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newAPI())
+
+	if _, err := c.Create(ctx, Task{Header: "Buy milk"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.Export(ctx, FormatTodoTxt)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "Buy milk id:0") {
+		t.Fatalf("unexpected export %q", data)
+	}
+
+	edited := strings.Replace(string(data), "Buy milk", "x Buy milk", 1) + "Call mum\n"
+	report, err := c.Import(ctx, FormatTodoTxt, []byte(edited), ImportOptions{Mode: ImportUpsert})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if report.Updated != 1 || report.Created != 1 || !report.Valid {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if task, _ := c.Get(ctx, 0); task.Status != Completed {
+		t.Errorf("expected the task to be completed, got %+v", task)
+	}
+}
+
+func TestImportInvalidRows(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newAPI())
+
+	report, err := c.Import(ctx, FormatCSV, []byte("Header,Status\nBuy milk,unknown\n"), ImportOptions{})
+	if !errors.Is(err, ErrWrongArgument) {
+		t.Fatalf("expected ErrWrongArgument, got %v", err)
+	}
+	if report == nil || report.Valid || len(report.Rows) != 1 || len(report.Rows[0].Errors) == 0 {
+		t.Fatalf("expected a report with the row error, got %+v", report)
+	}
+
+	report, err = c.Import(ctx, FormatCSV, []byte("Header,Status\nBuy milk,unknown\n"), ImportOptions{DryRun: true})
+	if err != nil || !report.DryRun || report.Valid {
+		t.Errorf("expected a dry-run report, got %+v, %v", report, err)
+	}
+}